@@ -0,0 +1,67 @@
+package graphql
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrOperationNotAllowed is returned, wrapped, when a client configured with
+// WithAllowList is asked to send an operation that isn't on the allow-list.
+var ErrOperationNotAllowed = errors.New("operation not on the allow-list")
+
+// WithAllowList makes the client refuse to send any operation that isn't
+// on the allow-list, before it reaches the transport. It prevents documents
+// that haven't been reviewed and registered from being sent by accident.
+//
+// Each allow-list entry is either the hex-encoded SHA-256 hash of an allowed
+// document (the same hash used for persisted queries), or the name of an
+// allowed operation. Operations are allowed if either their hash or their
+// name is on the allow-list. Use ReadAllowList to load entries from a file.
+func WithAllowList(entries ...string) ClientOption {
+	return func(c *Client) {
+		if c.allowList == nil {
+			c.allowList = make(map[string]bool)
+		}
+		for _, e := range entries {
+			c.allowList[e] = true
+		}
+	}
+}
+
+// ReadAllowList reads allow-list entries from r, one per line.
+// Blank lines and lines starting with "#" are ignored.
+func ReadAllowList(r io.Reader) ([]string, error) {
+	var entries []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries, s.Err()
+}
+
+// checkAllowList returns an error wrapping ErrOperationNotAllowed if the
+// client has an allow-list and query isn't on it.
+func (c *Client) checkAllowList(query string) error {
+	if c.allowList == nil {
+		return nil
+	}
+	hash := queryHash(query)
+	if c.allowList[hash] {
+		return nil
+	}
+	name := operationName(query)
+	if name != "" && c.allowList[name] {
+		return nil
+	}
+	if name == "" {
+		name = "anonymous " + operationType(query)
+	}
+	return fmt.Errorf("%w: %s with hash %s", ErrOperationNotAllowed, name, hash)
+}
@@ -0,0 +1,66 @@
+package graphql_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestWithAllowList(t *testing.T) {
+	var requests int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	sum := sha256.Sum256([]byte("{viewer{login}}"))
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithAllowList(hex.EncodeToString(sum[:]), "GetViewerName"))
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	if _, err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Do(context.Background(), "query GetViewerName{viewer{name}}", nil); err != nil {
+		t.Fatal(err)
+	}
+	var unreviewed struct {
+		Viewer struct {
+			Email string
+		}
+	}
+	_, err := client.Query(context.Background(), &unreviewed, nil)
+	if !errors.Is(err, graphql.ErrOperationNotAllowed) {
+		t.Errorf("got error: %v, want: ErrOperationNotAllowed", err)
+	}
+	if got, want := requests, 2; got != want {
+		t.Errorf("got %d requests, want: %d", got, want)
+	}
+}
+
+func TestReadAllowList(t *testing.T) {
+	got, err := graphql.ReadAllowList(strings.NewReader(`
+# Reviewed operations.
+GetViewer
+	9f2e4c0a2d5d1c3e0b1a5f7e6d4c3b2a1f0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"GetViewer", "9f2e4c0a2d5d1c3e0b1a5f7e6d4c3b2a1f0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
@@ -0,0 +1,180 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/merico-dev/graphql/ident"
+	"github.com/merico-dev/graphql/internal/jsonutil"
+)
+
+// MutateBatch executes the independent mutations ms as a single GraphQL
+// mutation request, populating the response of each into it. Each element
+// of ms should be a pointer to struct that corresponds to the GraphQL
+// schema, like the argument of Mutate, and variables[i] holds the
+// variables of ms[i]. It's a bulk write for servers without batch mutations.
+//
+// The top-level fields of the mutations are aliased so that they don't
+// conflict, and their variables are renamed. The server executes top-level
+// mutation fields serially, in order, as if the mutations were sent one
+// after the other.
+//
+// MutateBatch returns the GraphQL errors of each mutation: errors[i] holds
+// the errors whose path is within ms[i]. Errors that aren't about a field,
+// such as validation errors, are returned for every mutation.
+func (c *Client) MutateBatch(ctx context.Context, ms []interface{}, variables []map[string]interface{}, opts ...RequestOption) ([][]DataError, error) {
+	if len(variables) > len(ms) {
+		return nil, fmt.Errorf("got variables for %d mutations, but only %d mutations", len(variables), len(ms))
+	}
+	query, vars, err := constructMutationBatch(ms, variables)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(ctx, query, vars, opts)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Data != nil {
+		var data map[string]json.RawMessage
+		err = c.unmarshal(bytes.NewReader(resp.Data), &data)
+		if err != nil {
+			return nil, &DecodeError{Err: err}
+		}
+		for i, m := range ms {
+			prefix := batchPrefix(i)
+			fields := make(map[string]json.RawMessage)
+			for k, v := range data {
+				if strings.HasPrefix(k, prefix) {
+					fields[k[len(prefix):]] = v
+				}
+			}
+			b, err := c.marshal(fields)
+			if err != nil {
+				return nil, err
+			}
+			err = jsonutil.UnmarshalGraphQL(b, m, c.decodeOptions...)
+			if err != nil {
+				return nil, &DecodeError{Err: err}
+			}
+		}
+	}
+	errs := make([][]DataError, len(ms))
+	for _, e := range resp.Errors {
+		i, key, ok := batchIndex(e.Path)
+		if !ok || i >= len(ms) {
+			for i := range errs {
+				errs[i] = append(errs[i], e)
+			}
+			continue
+		}
+		// Report the path as if the mutation was sent on its own.
+		e.Path = append([]interface{}{key}, e.Path[1:]...)
+		errs[i] = append(errs[i], e)
+	}
+	return errs, nil
+}
+
+// batchPrefix returns the prefix of the aliases and variable names
+// of the i-th mutation of a batch.
+func batchPrefix(i int) string {
+	return fmt.Sprintf("batch%d_", i)
+}
+
+// batchIndex returns the index of the mutation of a batch that path is
+// within, and the unaliased key of its first element. It reports whether
+// path is within a mutation of a batch.
+func batchIndex(path []interface{}) (i int, key string, ok bool) {
+	if len(path) == 0 {
+		return 0, "", false
+	}
+	alias, ok := path[0].(string)
+	if !ok || !strings.HasPrefix(alias, "batch") {
+		return 0, "", false
+	}
+	n, err := fmt.Sscanf(alias, "batch%d_", &i)
+	if n != 1 || err != nil {
+		return 0, "", false
+	}
+	prefix := batchPrefix(i)
+	if !strings.HasPrefix(alias, prefix) {
+		return 0, "", false
+	}
+	return i, alias[len(prefix):], true
+}
+
+// constructMutationBatch constructs a single mutation that performs the
+// mutations ms, with the corresponding variables. It returns the mutation
+// along with its variables.
+func constructMutationBatch(ms []interface{}, variables []map[string]interface{}) (string, map[string]interface{}, error) {
+	allVariables := make(map[string]interface{})
+	var buf bytes.Buffer
+	var fragments fragmentDefinitions // Shared by all mutations, so they can't use variables.
+	buf.WriteString("{")
+	for i, m := range ms {
+		t := reflect.TypeOf(m)
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			return "", nil, fmt.Errorf("mutation %d is %T, want a pointer to struct", i, m)
+		}
+		var vars map[string]interface{}
+		if i < len(variables) {
+			vars = variables[i]
+		}
+		prefix := batchPrefix(i)
+		for k, v := range vars {
+			allVariables[prefix+k] = v
+		}
+		for j := 0; j < t.NumField(); j++ {
+			f := t.Field(j)
+			value, _, ok := jsonutil.LookupTag(f.Tag)
+			if !ok {
+				if f.Anonymous {
+					return "", nil, fmt.Errorf("mutation %d has embedded field %s, which can't be batched", i, f.Name)
+				}
+				value = ident.ParseMixedCaps(f.Name).ToLowerCamelCase()
+			}
+			key, field := splitAlias(value)
+			if buf.Len() > 1 {
+				buf.WriteString(",")
+			}
+			var selection bytes.Buffer
+			writeQuery(&selection, f.Type, false, vars, &fragments, nil, "")
+			buf.WriteString(prefix + key + ":")
+			buf.WriteString(strings.ReplaceAll(field+selection.String(), "$", "$"+prefix))
+		}
+	}
+	buf.WriteString("}")
+	for _, name := range fragments.names {
+		if strings.Contains(fragments.definitions[name], "$") {
+			return "", nil, fmt.Errorf("fragment %s uses variables, so it can't be batched", name)
+		}
+	}
+	fragments.writeTo(&buf)
+	if len(allVariables) > 0 {
+		return "mutation(" + queryArguments(allVariables) + ")" + buf.String(), allVariables, nil
+	}
+	return "mutation" + buf.String(), nil, nil
+}
+
+// splitAlias splits the graphql tag value of a field into the field's
+// response key, which is its alias if it has one, and the field without
+// the alias.
+//
+// E.g., "starred: addStar(id: $id)" -> "starred", "addStar(id: $id)".
+func splitAlias(value string) (key, field string) {
+	value = strings.TrimSpace(value)
+	end := strings.IndexAny(value, "(@{")
+	if end == -1 {
+		end = len(value)
+	}
+	if i := strings.IndexByte(value[:end], ':'); i != -1 {
+		return strings.TrimSpace(value[:i]), strings.TrimSpace(value[i+1:])
+	}
+	return strings.TrimSpace(value[:end]), value
+}
@@ -0,0 +1,65 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestClient_MutateBatch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"mutation($batch0_id:ID!$batch1_id:ID!$batch1_reason:String!){batch0_addStar:addStar(id: $batch0_id){count},batch1_removed:removeStar(id: $batch1_id, reason: $batch1_reason){count},batch1_viewer:viewer{login}}","variables":{"batch0_id":"1","batch1_id":"2","batch1_reason":"spam"}}`+"\n"; got != want {
+			t.Errorf("got body:\n%v\nwant:\n%v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{
+			"data": {"batch0_addStar": {"count": 5}, "batch1_removed": null, "batch1_viewer": {"login": "gopher"}},
+			"errors": [
+				{"message": "not starred", "path": ["batch1_removed"]},
+				{"message": "rate limited"}
+			]
+		}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var add struct {
+		AddStar struct {
+			Count graphql.Int
+		} `graphql:"addStar(id: $id)"`
+	}
+	var remove struct {
+		Removed *struct {
+			Count graphql.Int
+		} `graphql:"removed: removeStar(id: $id, reason: $reason)"`
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	errs, err := client.MutateBatch(context.Background(), []interface{}{&add, &remove}, []map[string]interface{}{
+		{"id": graphql.ID("1")},
+		{"id": graphql.ID("2"), "reason": graphql.String("spam")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := add.AddStar.Count, graphql.Int(5); got != want {
+		t.Errorf("got count: %v, want: %v", got, want)
+	}
+	if remove.Removed != nil {
+		t.Errorf("got removed: %+v, want nil", remove.Removed)
+	}
+	if got, want := remove.Viewer.Login, graphql.String("gopher"); got != want {
+		t.Errorf("got login: %v, want: %v", got, want)
+	}
+	if got, want := fmt.Sprint(errs), "[[rate limited] [not starred rate limited]]"; got != want {
+		t.Errorf("got errors: %v, want: %v", got, want)
+	}
+	if got, want := fmt.Sprint(errs[1][0].Path), "[removed]"; got != want {
+		t.Errorf("got path: %v, want: %v", got, want)
+	}
+}
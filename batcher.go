@@ -0,0 +1,259 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExtendBatcher coalesces individual requests for the same field with
+// different arguments, such as "fetch repository X", made concurrently by
+// independent callers, into single queries. The requests made within
+// a short window are selected as aliased copies of the field, as for
+// a graphql-extend field, and each caller gets its copy of the result.
+//
+// It turns N round trips into one without callers knowing about each
+// other, in the manner of a dataloader. Use NewExtendBatcher to create one.
+type ExtendBatcher[T any] struct {
+	c        *Client
+	field    string          // graphql tag value of the field, e.g. "repository(owner: $owner, name: $name)".
+	variable string          // Name of the variable holding the arguments of the copies, e.g. "repository".
+	refs     map[string]bool // Variables referenced by field.
+	query    reflect.Type    // Query struct type with a []T graphql-extend field.
+
+	window  time.Duration
+	maxSize int
+	opts    []RequestOption
+
+	mu      sync.Mutex
+	pending *extendBatch[T] // Batch collecting requests, if any.
+}
+
+// extendBatch is a batch of requests made with an ExtendBatcher.
+type extendBatch[T any] struct {
+	variables []map[string]interface{} // Variables of each request.
+	results   []extendResult[T]        // Results of each request, set once done is closed.
+	timer     *time.Timer              // Sends the batch once its window ends.
+	done      chan struct{}            // Closed once the batch is sent and results are set.
+
+	ctx     context.Context // Context the batch is sent with.
+	cancel  context.CancelFunc
+	waiting int // Requests still waiting for their result; guarded by ExtendBatcher.mu.
+}
+
+type extendResult[T any] struct {
+	v          T
+	dataErrors []DataError
+	err        error
+}
+
+// defaultBatchWindow is how long an ExtendBatcher collects requests
+// into a batch, unless set with WithBatchWindow.
+const defaultBatchWindow = 10 * time.Millisecond
+
+// defaultMaxBatchSize is the maximum number of requests of a batch, unless
+// set with WithMaxBatchSize or limited by WithMaxExtendAliases.
+const defaultMaxBatchSize = 100
+
+// BatcherOption configures an ExtendBatcher.
+type BatcherOption func(*batcherConfig)
+
+type batcherConfig struct {
+	window  time.Duration
+	maxSize int
+	opts    []RequestOption
+}
+
+// WithBatchWindow makes an ExtendBatcher collect requests for d after the
+// first request of a batch before sending it. Longer windows make larger
+// batches at the cost of latency. By default, it's 10ms.
+func WithBatchWindow(d time.Duration) BatcherOption {
+	return func(cfg *batcherConfig) {
+		cfg.window = d
+	}
+}
+
+// WithMaxBatchSize makes an ExtendBatcher send a batch as soon as it has
+// n requests, rather than waiting for its window to end. By default, it's
+// the limit of WithMaxExtendAliases if the client has one, and 100 otherwise.
+func WithMaxBatchSize(n int) BatcherOption {
+	return func(cfg *batcherConfig) {
+		cfg.maxSize = n
+	}
+}
+
+// WithBatchRequestOptions makes an ExtendBatcher send batches with opts.
+func WithBatchRequestOptions(opts ...RequestOption) BatcherOption {
+	return func(cfg *batcherConfig) {
+		cfg.opts = append(cfg.opts, opts...)
+	}
+}
+
+// NewExtendBatcher returns an ExtendBatcher that sends batches using c.
+// field is the field to select, written as in a graphql tag, with
+// arguments bound to variables, e.g., "repository(owner: $owner, name: $name)".
+// It must not have an alias. T is the type of the field, which is
+// decoded like the field of a query struct.
+func NewExtendBatcher[T any](c *Client, field string, opts ...BatcherOption) *ExtendBatcher[T] {
+	cfg := batcherConfig{window: defaultBatchWindow, maxSize: defaultMaxBatchSize}
+	if c.maxExtendAliases > 0 {
+		cfg.maxSize = c.maxExtendAliases
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &ExtendBatcher[T]{
+		c:        c,
+		field:    field,
+		variable: extendVariable(field),
+		refs:     referencedVariables(field),
+		query:    extendQueryType(reflect.TypeOf([]T(nil)), field),
+		window:   cfg.window,
+		maxSize:  cfg.maxSize,
+		opts:     cfg.opts,
+	}
+}
+
+// Load requests the field with the arguments in variables, waiting for
+// the batch it's added to to be sent. It returns the result of the field,
+// along with the GraphQL errors whose path is within it, reported as if
+// the field was selected on its own. Errors that aren't about a field,
+// such as validation errors, are returned for every request of the batch.
+// A failure of the batch's request is returned for every request.
+//
+// If ctx is done before the result arrives, Load returns ctx.Err(). The
+// batch is canceled once every request in it was given up on.
+func (b *ExtendBatcher[T]) Load(ctx context.Context, variables map[string]interface{}) (T, []DataError, error) {
+	var zero T
+	if ctx.Err() != nil {
+		return zero, nil, ctx.Err()
+	}
+	for name := range b.refs {
+		if _, ok := variables[name]; !ok {
+			return zero, nil, fmt.Errorf("field %s references variable $%s, which is missing from the variables", b.field, name)
+		}
+	}
+	b.mu.Lock()
+	batch := b.pending
+	if batch == nil {
+		batch = &extendBatch[T]{done: make(chan struct{})}
+		batch.ctx, batch.cancel = context.WithCancel(context.Background())
+		batch.timer = time.AfterFunc(b.window, func() { b.flush(batch) })
+		b.pending = batch
+	}
+	i := len(batch.variables)
+	batch.variables = append(batch.variables, variables)
+	batch.waiting++
+	if len(batch.variables) >= b.maxSize {
+		batch.timer.Stop()
+		b.pending = nil
+		go b.send(batch)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-batch.done:
+		r := batch.results[i]
+		return r.v, r.dataErrors, r.err
+	case <-ctx.Done():
+		b.mu.Lock()
+		batch.waiting--
+		if batch.waiting == 0 {
+			batch.cancel()
+		}
+		b.mu.Unlock()
+		return zero, nil, ctx.Err()
+	}
+}
+
+// flush sends batch once its window ended, unless it was sent already
+// because it filled up.
+func (b *ExtendBatcher[T]) flush(batch *extendBatch[T]) {
+	b.mu.Lock()
+	if b.pending != batch {
+		b.mu.Unlock()
+		return
+	}
+	b.pending = nil
+	b.mu.Unlock()
+	b.send(batch)
+}
+
+// send sends batch, which no longer accepts requests, and sets the
+// results of its requests.
+func (b *ExtendBatcher[T]) send(batch *extendBatch[T]) {
+	defer close(batch.done)
+	defer batch.cancel()
+	batch.results = make([]extendResult[T], len(batch.variables))
+	if batch.ctx.Err() != nil {
+		return // Every request was given up on.
+	}
+	q := reflect.New(b.query)
+	dataErrors, err := b.c.Query(batch.ctx, q.Interface(), map[string]interface{}{b.variable: batch.variables}, b.opts...)
+	if err != nil {
+		for i := range batch.results {
+			batch.results[i].err = err
+		}
+		return
+	}
+	values := q.Elem().Field(0)
+	for i := range batch.results {
+		if i < values.Len() {
+			batch.results[i].v = values.Index(i).Interface().(T)
+		}
+	}
+	for _, e := range dataErrors {
+		i, ok := extendIndex(b.variable, e.Path)
+		if !ok || i >= len(batch.results) {
+			for i := range batch.results {
+				batch.results[i].dataErrors = append(batch.results[i].dataErrors, e)
+			}
+			continue
+		}
+		// Report the path as if the field was selected on its own.
+		e.Path = append([]interface{}{b.variable}, e.Path[1:]...)
+		batch.results[i].dataErrors = append(batch.results[i].dataErrors, e)
+	}
+}
+
+// extendVariable returns the name of the variable holding the arguments
+// of the copies of the graphql-extend field written as field in its tag,
+// which is also the prefix of their aliases.
+// E.g., "repository(owner: $owner, name: $name)" -> "repository".
+func extendVariable(field string) string {
+	if i := strings.IndexAny(field, `(:[$!@{`); i != -1 {
+		return field[:i]
+	}
+	return field
+}
+
+// extendQueryType returns a query struct type selecting field, written as
+// in a graphql tag, as a graphql-extend field of the slice type t.
+func extendQueryType(t reflect.Type, field string) reflect.Type {
+	return reflect.StructOf([]reflect.StructField{{
+		Name: "Batch",
+		Type: t,
+		Tag:  reflect.StructTag(fmt.Sprintf(`graphql:%q graphql-extend:"true"`, field)),
+	}})
+}
+
+// extendIndex returns the index of the copy of the graphql-extend field
+// whose variable is variable that path is within. It reports whether path
+// is within a copy of the field.
+//
+// E.g., "repository", ["repository__3", "issues"] -> 3, true.
+func extendIndex(variable string, path []interface{}) (int, bool) {
+	if len(path) == 0 {
+		return 0, false
+	}
+	alias, ok := path[0].(string)
+	if !ok || !strings.HasPrefix(alias, variable+"__") {
+		return 0, false
+	}
+	i, err := strconv.Atoi(alias[len(variable)+2:])
+	return i, err == nil && i >= 0
+}
@@ -0,0 +1,113 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestExtendBatcher(t *testing.T) {
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		body := mustRead(req.Body)
+		for _, want := range []string{
+			`repository__0:repository(name: $repository__0__name){name,stars}`,
+			`repository__2:repository(name: $repository__2__name){name,stars}`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("got body: %v, want it to contain: %v", body, want)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {
+			"repository__0": {"name": "a", "stars": 1},
+			"repository__1": null,
+			"repository__2": {"name": "c", "stars": 3}
+		}, "errors": [
+			{"message": "not found", "path": ["repository__1"]},
+			{"message": "rate limited"}
+		]}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type repository struct {
+		Name  graphql.String
+		Stars graphql.Int
+	}
+	batcher := graphql.NewExtendBatcher[repository](client, "repository(name: $name)",
+		graphql.WithMaxBatchSize(3), graphql.WithBatchWindow(time.Hour))
+
+	names := []string{"a", "b", "c"}
+	repos := make([]repository, len(names))
+	errs := make([][]graphql.DataError, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			// Add the requests to the batch in order.
+			time.Sleep(time.Duration(i) * 10 * time.Millisecond)
+			var err error
+			repos[i], errs[i], err = batcher.Load(context.Background(), map[string]interface{}{"name": graphql.String(name)})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i, name)
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("got %v requests, want 1", requests)
+	}
+	if repos[0].Name != "a" || repos[0].Stars != 1 || repos[1].Name != "" || repos[2].Name != "c" || repos[2].Stars != 3 {
+		t.Errorf("got repositories: %+v", repos)
+	}
+	if len(errs[0]) != 1 || errs[0][0].Message != "rate limited" {
+		t.Errorf("got errors of request 0: %+v", errs[0])
+	}
+	if len(errs[1]) != 2 || errs[1][0].Message != "not found" || errs[1][0].Path[0] != "repository" {
+		t.Errorf("got errors of request 1: %+v", errs[1])
+	}
+}
+
+func TestExtendBatcher_window(t *testing.T) {
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user__0": {"login": "gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+	batcher := graphql.NewExtendBatcher[struct{ Login graphql.String }](client, "user(id: $id)", graphql.WithBatchWindow(time.Millisecond))
+
+	user, _, err := batcher.Load(context.Background(), map[string]interface{}{"id": graphql.ID("1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Login != "gopher" {
+		t.Errorf("got login: %q, want: %q", user.Login, "gopher")
+	}
+
+	_, _, err = batcher.Load(context.Background(), map[string]interface{}{})
+	if err == nil || err.Error() != "field user(id: $id) references variable $id, which is missing from the variables" {
+		t.Errorf("got error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = batcher.Load(ctx, map[string]interface{}{"id": graphql.ID("2")})
+	if err != context.Canceled {
+		t.Errorf("got error: %v, want: %v", err, context.Canceled)
+	}
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("got %v requests, want 1", requests)
+	}
+}
@@ -0,0 +1,277 @@
+package graphql
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores responses to queries. Implementations must be safe
+// for concurrent use by multiple goroutines.
+type Cache interface {
+	// Get returns the entry stored for key, if any.
+	Get(key string) (*CacheEntry, bool)
+	// Set stores entry for key.
+	Set(key string, entry *CacheEntry)
+}
+
+// CacheEntry is a response stored in a Cache.
+type CacheEntry struct {
+	Response *Response // Response, which must not be modified.
+	Stored   time.Time // When the response was received.
+}
+
+// WithCache makes the client cache responses to queries in cache, and
+// respond to identical queries from it for ttl instead of sending them.
+// Queries are identical if they have the same document, ignoring
+// insignificant whitespace, commas and comments, variables, URL, and
+// headers. Only responses without GraphQL errors are cached.
+// Mutations and subscriptions aren't cached.
+//
+// Use NoCache to bypass the cache for a request.
+func WithCache(cache Cache, ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		rc := c.responseCache()
+		rc.cache = cache
+		rc.ttl = ttl
+	}
+}
+
+// WithStaleWhileRevalidate makes the client respond from its cache with
+// entries that expired less than window ago, refreshing them in the
+// background, rather than waiting for a fresh response. It smooths over
+// slow or flaky servers, at the cost of responses being stale for up to
+// window. If onRefresh is non-nil, it's called with the query and the
+// result of each background refresh.
+// It has effect only along with WithCache.
+func WithStaleWhileRevalidate(window time.Duration, onRefresh func(query string, resp *Response, err error)) ClientOption {
+	return func(c *Client) {
+		rc := c.responseCache()
+		rc.staleWindow = window
+		rc.onRefresh = onRefresh
+	}
+}
+
+// NoCache makes the request bypass the client's cache. Its response
+// isn't taken from the cache, nor stored in it.
+func NoCache() RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.noCache = true
+	}
+}
+
+// responseCache is the response cache of a client.
+type responseCache struct {
+	cache       Cache         // Responses, or nil if caching is disabled.
+	ttl         time.Duration // How long entries are fresh.
+	staleWindow time.Duration // How long expired entries are served while refreshing them.
+	onRefresh   func(query string, resp *Response, err error)
+
+	mu         sync.Mutex
+	refreshing map[string]bool // Keys being refreshed in the background.
+}
+
+// responseCache returns the response cache of the client,
+// creating it if needed.
+func (c *Client) responseCache() *responseCache {
+	if c.cache == nil {
+		c.cache = new(responseCache)
+	}
+	return c.cache
+}
+
+// doCached executes the GraphQL request in, made with cfg,
+// responding from the client's cache if possible.
+func (c *Client) doCached(ctx context.Context, in requestBody, cfg *requestConfig) (*Response, error) {
+	key, err := c.cacheKey(in, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if entry, ok := c.cache.cache.Get(key); ok {
+		age := time.Since(entry.Stored)
+		if age < c.cache.ttl {
+			return entry.response(), nil
+		} else if age < c.cache.ttl+c.cache.staleWindow {
+			c.refresh(key, in, cfg)
+			return entry.response(), nil
+		}
+	}
+	return c.fetch(ctx, key, in, cfg)
+}
+
+// fetch executes the GraphQL request in, made with cfg,
+// and stores its response in the cache for key if it's cacheable.
+func (c *Client) fetch(ctx context.Context, key string, in requestBody, cfg *requestConfig) (*Response, error) {
+	resp, err := c.execute(ctx, in, cfg)
+	if err == nil && resp.Errors == nil {
+		c.cache.cache.Set(key, &CacheEntry{Response: resp, Stored: time.Now()})
+	}
+	return resp, err
+}
+
+// refresh refreshes the cache entry for key in the background,
+// unless it's already being refreshed.
+func (c *Client) refresh(key string, in requestBody, cfg *requestConfig) {
+	rc := c.cache
+	rc.mu.Lock()
+	if rc.refreshing[key] {
+		rc.mu.Unlock()
+		return
+	}
+	if rc.refreshing == nil {
+		rc.refreshing = make(map[string]bool)
+	}
+	rc.refreshing[key] = true
+	rc.mu.Unlock()
+
+	go func() {
+		// The refresh outlives the request that triggered it,
+		// so it can't use that request's context.
+		resp, err := c.fetch(context.Background(), key, in, cfg)
+		rc.mu.Lock()
+		delete(rc.refreshing, key)
+		rc.mu.Unlock()
+		if rc.onRefresh != nil {
+			rc.onRefresh(in.Query, resp, err)
+		}
+	}()
+}
+
+// response returns a copy of the cached response,
+// so that callers modifying it don't modify the cache.
+func (e *CacheEntry) response() *Response {
+	resp := *e.Response
+	return &resp
+}
+
+// cacheKey returns the cache key of the GraphQL request in, made with cfg.
+// It's a hash of everything that may affect the response.
+func (c *Client) cacheKey(in requestBody, cfg *requestConfig) (string, error) {
+	endpoint, err := c.endpoint(cfg)
+	if err != nil {
+		return "", err
+	}
+	variables, err := json.Marshal(in.Variables) // Map keys are sorted, so the encoding is deterministic.
+	if err != nil {
+		return "", err
+	}
+	extensions, err := json.Marshal(in.Extensions)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	write := func(s string) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	write(endpoint)
+	keys := make([]string, 0, len(cfg.header))
+	for k := range cfg.header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		write(k)
+		for _, v := range cfg.header[k] {
+			write(v)
+		}
+	}
+	write(normalizeDocument(in.Query))
+	write(in.OperationName)
+	write(string(variables))
+	write(string(extensions))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// normalizeDocument returns the GraphQL document doc without insignificant
+// whitespace, commas and comments, so that equivalent documents written
+// differently, such as minified and formatted ones, normalize the same.
+// Names and numbers are kept apart by a single space.
+//
+// E.g., "query ($id: ID!) {\n  node(id: $id) { id }\n}" -> "query($id:ID!){node(id:$id){id}}".
+func normalizeDocument(doc string) string {
+	var b strings.Builder
+	space := false
+	for i := 0; i < len(doc); i++ {
+		c := doc[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			space = true
+			continue
+		case c == '#':
+			for i < len(doc) && doc[i] != '\n' && doc[i] != '\r' {
+				i++
+			}
+			space = true
+			continue
+		case c == '"':
+			end := stringEnd(doc, i)
+			b.WriteString(doc[i:end])
+			i = end - 1
+		default:
+			if s := b.String(); space && s != "" && isNameByte(s[len(s)-1]) && isNameByte(c) {
+				b.WriteByte(' ')
+			}
+			b.WriteByte(c)
+		}
+		space = false
+	}
+	return b.String()
+}
+
+// NewLRUCache returns a Cache that holds up to capacity entries,
+// evicting the least recently used entry to make room for new ones.
+func NewLRUCache(capacity int) Cache {
+	return &lruCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// lruCache is a Cache with a least recently used eviction policy.
+type lruCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // Values are *lruEntry.
+	order   *list.List               // Most recently used at front.
+}
+
+type lruEntry struct {
+	key   string
+	entry *CacheEntry
+}
+
+func (l *lruCache) Get(key string) (*CacheEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(e)
+	return e.Value.(*lruEntry).entry, true
+}
+
+func (l *lruCache) Set(key string, entry *CacheEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.entries[key]; ok {
+		e.Value.(*lruEntry).entry = entry
+		l.order.MoveToFront(e)
+		return
+	}
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, entry: entry})
+	for l.order.Len() > l.capacity {
+		e := l.order.Back()
+		l.order.Remove(e)
+		delete(l.entries, e.Value.(*lruEntry).key)
+	}
+}
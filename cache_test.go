@@ -0,0 +1,132 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+// countingHandler responds to queries with the number of requests it got.
+func countingHandler(requests *int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, fmt.Sprintf(`{"data": {"count": %d}}`, n))
+	})
+}
+
+func TestWithCache(t *testing.T) {
+	var requests int32
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: countingHandler(&requests)}},
+		graphql.WithCache(graphql.NewLRUCache(10), time.Hour))
+
+	query := func(variables map[string]interface{}, opts ...graphql.RequestOption) int {
+		var q struct {
+			Count graphql.Int `graphql:"count(id: $id)"`
+		}
+		_, err := client.Query(context.Background(), &q, variables, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int(q.Count)
+	}
+	one := map[string]interface{}{"id": graphql.ID("1")}
+	two := map[string]interface{}{"id": graphql.ID("2")}
+	got := []int{
+		query(one),
+		query(one),
+		query(two),
+		query(one, graphql.WithHeader("Authorization", "other")),
+		query(one, graphql.NoCache()),
+		query(one),
+	}
+	if want := []int{1, 1, 2, 3, 4, 1}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got counts: %v, want: %v", got, want)
+	}
+}
+
+func TestWithCache_equivalentDocuments(t *testing.T) {
+	var requests int32
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: countingHandler(&requests)}},
+		graphql.WithCache(graphql.NewLRUCache(10), time.Hour))
+
+	exec := func(doc string) int {
+		var q struct{ Count graphql.Int }
+		_, err := client.Exec(context.Background(), doc, &q, map[string]interface{}{"id": "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int(q.Count)
+	}
+	got := []int{
+		exec(`query($id:ID!){count(id:$id)}`),
+		exec("query ($id: ID!) {\n  count(id: $id) # Cached.\n}"),
+		exec(`query Count($id:ID!){count(id:$id)}`),
+		exec(`query($id:ID!){count(id:$id,label:"a b")}`),
+		exec(`query($id:ID!){count(id:$id,label:"a  b")}`),
+	}
+	if want := []int{1, 1, 2, 3, 4}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got counts: %v, want: %v", got, want)
+	}
+}
+
+func TestWithStaleWhileRevalidate(t *testing.T) {
+	var requests int32
+	refreshed := make(chan string, 1)
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: countingHandler(&requests)}},
+		graphql.WithCache(graphql.NewLRUCache(10), 10*time.Millisecond),
+		graphql.WithStaleWhileRevalidate(time.Hour, func(query string, resp *graphql.Response, err error) {
+			if err != nil {
+				t.Error(err)
+			}
+			refreshed <- string(resp.Data)
+		}))
+
+	count := func() int {
+		var q struct{ Count graphql.Int }
+		_, err := client.Query(context.Background(), &q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int(q.Count)
+	}
+	if got := count(); got != 1 {
+		t.Errorf("got count: %v, want: 1", got)
+	}
+	time.Sleep(20 * time.Millisecond)
+	// The expired entry is served while it's refreshed.
+	if got := count(); got != 1 {
+		t.Errorf("got stale count: %v, want: 1", got)
+	}
+	if got, want := <-refreshed, `{"count": 2}`; got != want {
+		t.Errorf("got refreshed data: %v, want: %v", got, want)
+	}
+	if got := count(); got != 2 {
+		t.Errorf("got refreshed count: %v, want: 2", got)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	cache := graphql.NewLRUCache(2)
+	cache.Set("a", &graphql.CacheEntry{})
+	cache.Set("b", &graphql.CacheEntry{})
+	cache.Get("a")
+	cache.Set("c", &graphql.CacheEntry{})
+	for _, tc := range []struct {
+		key    string
+		wantOK bool
+	}{
+		{"a", true},
+		{"b", false}, // Least recently used.
+		{"c", true},
+	} {
+		if _, ok := cache.Get(tc.key); ok != tc.wantOK {
+			t.Errorf("Get(%q): got ok: %v, want: %v", tc.key, ok, tc.wantOK)
+		}
+	}
+}
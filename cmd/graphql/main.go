@@ -0,0 +1,245 @@
+// graphql sends a GraphQL query to a server and prints the response as
+// indented JSON. It's meant for debugging the documents that the graphql
+// package generates, and for trying out queries by hand.
+//
+// Usage:
+//
+//	graphql [flags] url [file]
+//	graphql schema [flags] url
+//
+// The query document is read from file, or from standard input if file is
+// omitted or "-". Variables are given with -var flags, whose values are
+// parsed as JSON if possible and used as strings otherwise:
+//
+//	graphql -var owner=shurcooL -var first=10 \
+//		-header "Authorization: Bearer $TOKEN" \
+//		https://api.github.com/graphql query.graphql
+//
+// graphql exits with status 1 if the request failed, or if the response
+// has errors.
+//
+// The schema subcommand introspects the server, and writes its schema as
+// the JSON result of the introspection query, as SDL, or both, so that it
+// can be checked in and diffed as the server changes:
+//
+//	graphql schema -json schema.json -sdl schema.graphql https://example.com/graphql
+//
+// It writes the SDL to standard output if neither file is given.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "graphql:", err)
+		os.Exit(1)
+	}
+}
+
+// run runs the command with the arguments args, reading the query document
+// from stdin if no file is given, and writing the response to stdout.
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) > 0 && args[0] == "schema" {
+		return runSchema(args[1:], stdout)
+	}
+	fs := flag.NewFlagSet("graphql", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: graphql [flags] url [file]")
+		fmt.Fprintln(fs.Output(), "       graphql schema [flags] url")
+		fs.PrintDefaults()
+	}
+	var (
+		cf        clientFlags
+		vars      = make(variablesFlag)
+		operation = fs.String("operation", "", "name of the operation to execute in a document with several")
+		dryRun    = fs.Bool("n", false, "print the request instead of sending it")
+	)
+	cf.register(fs)
+	fs.Var(vars, "var", "variable to send, as `key=value`; may be repeated")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	url := fs.Arg(0)
+
+	doc, err := readDocument(fs.Arg(1), stdin)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		return printJSON(stdout, struct {
+			Query         string                 `json:"query"`
+			OperationName string                 `json:"operationName,omitempty"`
+			Variables     map[string]interface{} `json:"variables,omitempty"`
+		}{doc, *operation, vars})
+	}
+
+	var opts []graphql.RequestOption
+	if *operation != "" {
+		opts = append(opts, graphql.WithOperationName(*operation))
+	}
+	client := cf.newClient(url)
+	ctx, cancel := context.WithTimeout(context.Background(), cf.timeout)
+	defer cancel()
+	resp, err := client.Do(ctx, doc, vars, opts...)
+	if resp == nil {
+		return err
+	}
+	out := response{Data: resp.Data, Extensions: resp.Extensions}
+	for _, e := range resp.Errors {
+		out.Errors = append(out.Errors, responseError(e))
+	}
+	printErr := printJSON(stdout, out)
+	switch {
+	case err != nil:
+		return err
+	case printErr != nil:
+		return printErr
+	case len(resp.Errors) > 0:
+		return fmt.Errorf("response has %d error(s)", len(resp.Errors))
+	}
+	return nil
+}
+
+// clientFlags are the flags that configure the client, shared by the
+// commands.
+type clientFlags struct {
+	token   string
+	headers headersFlag
+	retries int
+	timeout time.Duration
+}
+
+func (cf *clientFlags) register(fs *flag.FlagSet) {
+	// The token isn't the flag's default, which usage messages print.
+	fs.StringVar(&cf.token, "token", "", "bearer token to authenticate with; if empty, $GRAPHQL_TOKEN")
+	fs.Var(&cf.headers, "header", "header to send, as `\"Key: value\"`; may be repeated")
+	fs.IntVar(&cf.retries, "retries", 0, "number of times to retry transient failures")
+	fs.DurationVar(&cf.timeout, "timeout", time.Minute, "time limit for the request, including retries")
+}
+
+// newClient returns a client for the GraphQL server at url,
+// configured by the flags.
+func (cf *clientFlags) newClient(url string) *graphql.Client {
+	var opts []graphql.RequestOption
+	token := cf.token
+	if token == "" {
+		token = os.Getenv("GRAPHQL_TOKEN")
+	}
+	if token != "" {
+		opts = append(opts, graphql.WithHeader("Authorization", "Bearer "+token))
+	}
+	for _, h := range cf.headers {
+		opts = append(opts, graphql.WithHeader(h[0], h[1]))
+	}
+	return graphql.NewClient(url, nil,
+		graphql.WithUserAgent("graphql-cli"),
+		graphql.WithRequestOptions(opts...),
+		graphql.WithRetry(cf.retries+1, graphql.ExponentialBackoff(time.Second, time.Minute)),
+		graphql.WithRetryHints(graphql.ExtensionRetryHints()),
+	)
+}
+
+// readDocument reads the query document from the file named name,
+// or from stdin if name is empty or "-".
+func readDocument(name string, stdin io.Reader) (string, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if name == "" || name == "-" {
+		b, err = io.ReadAll(stdin)
+	} else {
+		b, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return "", err
+	}
+	doc := strings.TrimSpace(string(b))
+	if doc == "" {
+		return "", errors.New("empty query document")
+	}
+	return doc, nil
+}
+
+// response is a GraphQL response, as printed.
+type response struct {
+	Data       json.RawMessage            `json:"data,omitempty"`
+	Errors     []responseError            `json:"errors,omitempty"`
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
+}
+
+// responseError is a graphql.DataError, with the field names of the
+// GraphQL specification.
+type responseError struct {
+	Message   string `json:"message"`
+	Locations []struct {
+		Line   int `json:"line"`
+		Column int `json:"column"`
+	} `json:"locations,omitempty"`
+	Path       []interface{}              `json:"path,omitempty"`
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// variablesFlag is a flag.Value that collects key=value variables.
+// Values that are valid JSON are decoded, so that numbers, booleans,
+// lists and input objects can be given; other values are strings.
+type variablesFlag map[string]interface{}
+
+func (f variablesFlag) String() string { return "" }
+
+func (f variablesFlag) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("variable %q isn't of the form key=value", s)
+	}
+	var v interface{}
+	if json.Unmarshal([]byte(value), &v) != nil {
+		v = value
+	}
+	f[key] = v
+	return nil
+}
+
+// headersFlag is a flag.Value that collects "Key: value" headers.
+type headersFlag [][2]string
+
+func (f *headersFlag) String() string { return "" }
+
+func (f *headersFlag) Set(s string) error {
+	key, value, ok := strings.Cut(s, ":")
+	if !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("header %q isn't of the form \"Key: value\"", s)
+	}
+	*f = append(*f, [2]string{http.CanonicalHeaderKey(strings.TrimSpace(key)), strings.TrimSpace(value)})
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/merico-dev/graphql/introspection"
+)
+
+func TestRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("Authorization"), "Bearer secret"; got != want {
+			t.Errorf("got Authorization: %q, want: %q", got, want)
+		}
+		if got, want := req.Header.Get("X-Trace"), "1"; got != want {
+			t.Errorf("got X-Trace: %q, want: %q", got, want)
+		}
+		var in struct {
+			Query     string
+			Variables map[string]interface{}
+		}
+		err := json.NewDecoder(req.Body).Decode(&in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := in.Query, "query($first: Int!){viewer{login}}"; got != want {
+			t.Errorf("got query: %q, want: %q", got, want)
+		}
+		if got, want := in.Variables, map[string]interface{}{"first": 10.0, "owner": "shurcooL"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got variables: %v, want: %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	}))
+	defer srv.Close()
+
+	var stdout bytes.Buffer
+	err := run([]string{
+		"-token", "secret",
+		"-header", "x-trace: 1",
+		"-var", "first=10",
+		"-var", "owner=shurcooL",
+		srv.URL,
+	}, strings.NewReader("query($first: Int!){viewer{login}}\n"), &stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "{\n  \"data\": {\n    \"viewer\": {\n      \"login\": \"gopher\"\n    }\n  }\n}\n"; got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRun_dataErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"errors": [{"message": "boom", "path": ["viewer"]}]}`)
+	}))
+	defer srv.Close()
+
+	var stdout bytes.Buffer
+	err := run([]string{srv.URL, "-"}, strings.NewReader("{viewer{login}}"), &stdout)
+	if err == nil || err.Error() != "response has 1 error(s)" {
+		t.Errorf("got error: %v, want: response has 1 error(s)", err)
+	}
+	if got, want := stdout.String(), "{\n  \"errors\": [\n    {\n      \"message\": \"boom\",\n      \"path\": [\n        \"viewer\"\n      ]\n    }\n  ]\n}\n"; got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestClientFlags_token(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("Authorization"), "Bearer from-env"; got != want {
+			t.Errorf("got Authorization: %q, want: %q", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	}))
+	defer srv.Close()
+	t.Setenv("GRAPHQL_TOKEN", "from-env")
+
+	// Usage messages don't print the token.
+	fs := flag.NewFlagSet("graphql", flag.ContinueOnError)
+	var cf clientFlags
+	cf.register(fs)
+	var usage bytes.Buffer
+	fs.SetOutput(&usage)
+	fs.PrintDefaults()
+	if strings.Contains(usage.String(), "from-env") {
+		t.Errorf("got usage with the token:\n%s", usage.String())
+	}
+
+	var stdout bytes.Buffer
+	err := run([]string{srv.URL}, strings.NewReader("{viewer{login}}"), &stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVariablesFlag(t *testing.T) {
+	f := make(variablesFlag)
+	for _, s := range []string{"n=1", `ids=["1","2"]`, "name=gopher", "empty=", `input={"a":true}`} {
+		err := f.Set(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := variablesFlag{
+		"n":     1.0,
+		"ids":   []interface{}{"1", "2"},
+		"name":  "gopher",
+		"empty": "",
+		"input": map[string]interface{}{"a": true},
+	}
+	if !reflect.DeepEqual(f, want) {
+		t.Errorf("got: %v, want: %v", f, want)
+	}
+	if err := f.Set("novalue"); err == nil {
+		t.Error("got nil error for a variable without a value")
+	}
+}
+
+func TestRunSchema(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data": {"__schema": {
+			"queryType": {"name": "Query"},
+			"types": [
+				{"kind": "OBJECT", "name": "Query", "fields": [{"name": "hello", "args": [], "type": {"kind": "SCALAR", "name": "String"}}]},
+				{"kind": "SCALAR", "name": "String"}
+			],
+			"directives": []
+		}}}`)
+	}))
+	defer srv.Close()
+
+	var stdout bytes.Buffer
+	err := run([]string{"schema", srv.URL}, nil, &stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "type Query {\n  hello: String\n}\n"; got != want {
+		t.Errorf("got SDL:\n%s\nwant:\n%s", got, want)
+	}
+
+	dir := t.TempDir()
+	jsonFile, sdlFile := filepath.Join(dir, "schema.json"), filepath.Join(dir, "schema.graphql")
+	stdout.Reset()
+	err = run([]string{"schema", "-json", jsonFile, "-sdl", sdlFile, srv.URL}, nil, &stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("got output: %q, want none", stdout.String())
+	}
+	sdl, err := os.ReadFile(sdlFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(sdl), "type Query {\n  hello: String\n}\n"; got != want {
+		t.Errorf("got SDL file:\n%s\nwant:\n%s", got, want)
+	}
+	b, err := os.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r introspection.Response
+	err = json.Unmarshal(b, &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.Schema.QueryType.Name, "Query"; got != want {
+		t.Errorf("got query type: %q, want: %q", got, want)
+	}
+}
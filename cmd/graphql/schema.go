@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/merico-dev/graphql/introspection"
+)
+
+// runSchema runs the schema subcommand with the arguments args,
+// writing the SDL to stdout if no output file is given.
+func runSchema(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("graphql schema", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: graphql schema [flags] url")
+		fs.PrintDefaults()
+	}
+	var (
+		cf       clientFlags
+		jsonFile = fs.String("json", "", "write the introspection result as JSON to `file`")
+		sdlFile  = fs.String("sdl", "", "write the schema as SDL to `file`")
+	)
+	cf.register(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	client := cf.newClient(fs.Arg(0))
+	ctx, cancel := context.WithTimeout(context.Background(), cf.timeout)
+	defer cancel()
+	schema, err := client.Introspect(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *jsonFile != "" {
+		var buf bytes.Buffer
+		err := printJSON(&buf, introspection.Response{Schema: *schema})
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(*jsonFile, buf.Bytes(), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	sdl := introspection.SDL(schema)
+	switch {
+	case *sdlFile != "":
+		return os.WriteFile(*sdlFile, []byte(sdl), 0644)
+	case *jsonFile == "":
+		_, err := io.WriteString(stdout, sdl)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	gotoken "go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/merico-dev/graphql/ident"
+	"github.com/merico-dev/graphql/introspection"
+)
+
+// builtinScalars maps the built-in scalars of GraphQL to their Go types.
+var builtinScalars = map[string]string{
+	"Int":     "graphql.Int",
+	"Float":   "graphql.Float",
+	"String":  "graphql.String",
+	"Boolean": "graphql.Boolean",
+	"ID":      "graphql.ID",
+}
+
+// generator generates Go code for the operations of documents.
+type generator struct {
+	schema *introspection.Schema
+	buf    bytes.Buffer
+
+	declared map[string]bool // Schema types declared, or to be declared.
+	pending  []string        // Schema types to declare.
+	names    map[string]bool // Go names of the declarations of operations.
+}
+
+// generate returns the Go source of package pkg, with query structs and
+// variables functions for the operations of docs, keyed by file name,
+// and the types of the schema they use.
+func generate(schema *introspection.Schema, pkg string, files []string, docs []*document) ([]byte, error) {
+	g := &generator{
+		schema:   schema,
+		declared: make(map[string]bool),
+		names:    make(map[string]bool),
+	}
+	for i, doc := range docs {
+		for _, op := range doc.operations {
+			err := g.operation(files[i], doc, op)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", files[i], err)
+			}
+		}
+	}
+	sort.Strings(g.pending)
+	for len(g.pending) > 0 {
+		name := g.pending[0]
+		g.pending = g.pending[1:]
+		err := g.declare(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by graphqlgen. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	if strings.Contains(g.buf.String(), "graphql.") {
+		out.WriteString("import \"github.com/merico-dev/graphql\"\n")
+	}
+	out.Write(g.buf.Bytes())
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+// operation writes the query struct and variables function of op.
+func (g *generator) operation(file string, doc *document, op *operation) error {
+	if op.name == "" {
+		return fmt.Errorf("%s without a name", op.typ)
+	}
+	var root *introspection.TypeName
+	switch op.typ {
+	case "query":
+		root = g.schema.QueryType
+	case "mutation":
+		root = g.schema.MutationType
+	case "subscription":
+		root = g.schema.SubscriptionType
+	default:
+		return fmt.Errorf("operation %s has unknown type %q", op.name, op.typ)
+	}
+	if root == nil {
+		return fmt.Errorf("operation %s: schema has no %s type", op.name, op.typ)
+	}
+	typeName := ident.ParseLowerCamelCase(op.name).ToMixedCaps() + ident.ParseLowerCamelCase(op.typ).ToMixedCaps()
+	if g.names[typeName] {
+		return fmt.Errorf("operation %s: %s is declared more than once", op.name, typeName)
+	}
+	g.names[typeName] = true
+	fields, err := g.selectionSet(doc, root.Name, op.selections)
+	if err != nil {
+		return fmt.Errorf("operation %s: %w", op.name, err)
+	}
+	fmt.Fprintf(&g.buf, "\n// %s is the %s %s of %s.\ntype %s %s\n", typeName, op.typ, op.name, filepath.Base(file), typeName, fields)
+	if len(op.variables) == 0 {
+		return nil
+	}
+
+	funcName := ident.ParseLowerCamelCase(op.name).ToMixedCaps() + "Variables"
+	if g.names[funcName] {
+		return fmt.Errorf("operation %s: %s is declared more than once", op.name, funcName)
+	}
+	g.names[funcName] = true
+	var params, entries []string
+	for _, v := range op.variables {
+		typ, err := g.inputType(v.typ)
+		if err != nil {
+			return fmt.Errorf("operation %s: variable $%s: %w", op.name, v.name, err)
+		}
+		param := v.name
+		if gotoken.IsKeyword(param) || param == "graphql" {
+			param += "_"
+		}
+		params = append(params, param+" "+typ)
+		entries = append(entries, fmt.Sprintf("%q: %s,\n", v.name, param))
+	}
+	fmt.Fprintf(&g.buf, "\n// %s returns the variables of %s.\nfunc %s(%s) map[string]interface{} {\nreturn map[string]interface{}{\n%s}\n}\n",
+		funcName, typeName, funcName, strings.Join(params, ", "), strings.Join(entries, ""))
+	return nil
+}
+
+// selectionSet returns the struct type for the selections on the type typeName.
+func (g *generator) selectionSet(doc *document, typeName string, selections []selection) (string, error) {
+	var b strings.Builder
+	seen := make(map[string]bool)
+	err := g.fields(&b, doc, typeName, selections, seen)
+	if err != nil {
+		return "", err
+	}
+	return "struct {\n" + b.String() + "}", nil
+}
+
+// fields writes the struct fields for the selections on the type typeName
+// to b, skipping the fields whose Go name is in seen.
+func (g *generator) fields(b *strings.Builder, doc *document, typeName string, selections []selection, seen map[string]bool) error {
+	t := g.schema.Type(typeName)
+	if t == nil {
+		return fmt.Errorf("schema has no type %s", typeName)
+	}
+	for _, s := range selections {
+		switch {
+		case s.spread != "":
+			f := doc.fragments[s.spread]
+			if f == nil {
+				return fmt.Errorf("fragment %s isn't defined", s.spread)
+			}
+			if f.on == typeName && s.directives == "" {
+				err := g.fields(b, doc, typeName, f.selections, seen)
+				if err != nil {
+					return fmt.Errorf("fragment %s: %w", f.name, err)
+				}
+				continue
+			}
+			err := g.fragmentField(b, doc, ident.ParseLowerCamelCase(f.name).ToMixedCaps(), f.on, s.directives, f.selections, seen)
+			if err != nil {
+				return fmt.Errorf("fragment %s: %w", f.name, err)
+			}
+		case s.inline:
+			if (s.on == "" || s.on == typeName) && s.directives == "" {
+				err := g.fields(b, doc, typeName, s.selections, seen)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			on := s.on
+			if on == "" {
+				on = typeName
+			}
+			err := g.fragmentField(b, doc, on+"Fragment", on, s.directives, s.selections, seen)
+			if err != nil {
+				return err
+			}
+		case s.name == "__typename":
+			name := "Typename"
+			tag := s.name
+			if s.alias != "" {
+				name = ident.ParseLowerCamelCase(s.alias).ToMixedCaps()
+				tag = s.alias + ": " + s.name
+			}
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			fmt.Fprintf(b, "%s graphql.String %s\n", name, structTag(tag+s.directives))
+		default:
+			f := t.Field(s.name)
+			if f == nil {
+				return fmt.Errorf("type %s has no field %s", typeName, s.name)
+			}
+			key := s.name
+			if s.alias != "" {
+				key = s.alias
+			}
+			name := ident.ParseLowerCamelCase(key).ToMixedCaps()
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			typ, err := g.outputType(doc, f.Type, s)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", key, err)
+			}
+			tag := s.name + s.arguments + s.directives
+			if s.alias != "" {
+				tag = s.alias + ": " + tag
+			}
+			if tag == ident.ParseMixedCaps(name).ToLowerCamelCase() {
+				fmt.Fprintf(b, "%s %s\n", name, typ)
+			} else {
+				fmt.Fprintf(b, "%s %s %s\n", name, typ, structTag(tag))
+			}
+		}
+	}
+	return nil
+}
+
+// fragmentField writes a struct field named name for a fragment on the
+// type on, selecting selections, to b.
+func (g *generator) fragmentField(b *strings.Builder, doc *document, name, on, directives string, selections []selection, seen map[string]bool) error {
+	if seen[name] {
+		return nil
+	}
+	seen[name] = true
+	typ, err := g.selectionSet(doc, on, selections)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(b, "%s %s %s\n", name, typ, structTag("... on "+on+directives))
+	return nil
+}
+
+// structTag returns a struct tag literal with value as its graphql key.
+func structTag(value string) string {
+	tag := "graphql:" + strconv.Quote(value)
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// outputType returns the Go type of a field of type t, selected by s.
+// Nullable types are pointers, except for lists.
+func (g *generator) outputType(doc *document, t introspection.TypeRef, s selection) (string, error) {
+	nonNull := false
+	if t.Kind == introspection.NonNull && t.OfType != nil {
+		nonNull = true
+		t = *t.OfType
+	}
+	var typ string
+	switch t.Kind {
+	case introspection.List:
+		if t.OfType == nil {
+			return "", fmt.Errorf("list type has no element type")
+		}
+		elem, err := g.outputType(doc, *t.OfType, s)
+		return "[]" + elem, err
+	case introspection.Object, introspection.Interface, introspection.Union:
+		if len(s.selections) == 0 {
+			return "", fmt.Errorf("field of type %s must have a selection set", t.Name)
+		}
+		var err error
+		typ, err = g.selectionSet(doc, t.Name, s.selections)
+		if err != nil {
+			return "", err
+		}
+	default:
+		if len(s.selections) > 0 {
+			return "", fmt.Errorf("field of type %s can't have a selection set", t.Name)
+		}
+		typ = g.namedType(t.Name)
+	}
+	if !nonNull {
+		typ = "*" + typ
+	}
+	return typ, nil
+}
+
+// inputType returns the Go type of a variable of type t.
+// Nullable types are pointers, except for lists.
+func (g *generator) inputType(t typeRef) (string, error) {
+	var typ string
+	if t.elem != nil {
+		elem, err := g.inputType(*t.elem)
+		if !t.nonNull {
+			// The graphql package declares variables of pointer types as
+			// nullable, and of slice types as non-null lists.
+			return "*[]" + elem, err
+		}
+		return "[]" + elem, err
+	}
+	named := g.schema.Type(t.name)
+	if named == nil {
+		return "", fmt.Errorf("schema has no type %s", t.name)
+	}
+	switch {
+	case t.name == "ID":
+		// The graphql package declares variables of Go type string as IDs,
+		// whereas graphql.ID values are declared with their dynamic type.
+		typ = "string"
+	case named.Kind == introspection.Scalar || named.Kind == introspection.Enum || named.Kind == introspection.InputObject:
+		typ = g.namedType(t.name)
+	default:
+		return "", fmt.Errorf("type %s isn't an input type", t.name)
+	}
+	if !t.nonNull {
+		typ = "*" + typ
+	}
+	return typ, nil
+}
+
+// inputRefType returns the Go type of an input object field of type t.
+func (g *generator) inputRefType(t introspection.TypeRef) (string, error) {
+	ref, err := typeRefOf(t)
+	if err != nil {
+		return "", err
+	}
+	if ref.elem != nil {
+		// Nullable lists are omitted when they're nil, rather than
+		// declared; see omitempty.
+		ref.nonNull = true
+	}
+	return g.inputType(ref)
+}
+
+// typeRefOf converts the introspected type reference t.
+func typeRefOf(t introspection.TypeRef) (typeRef, error) {
+	var ref typeRef
+	if t.Kind == introspection.NonNull && t.OfType != nil {
+		ref.nonNull = true
+		t = *t.OfType
+	}
+	switch {
+	case t.Kind == introspection.List && t.OfType != nil:
+		elem, err := typeRefOf(*t.OfType)
+		if err != nil {
+			return typeRef{}, err
+		}
+		ref.elem = &elem
+	case t.Name != "":
+		ref.name = t.Name
+	default:
+		return typeRef{}, fmt.Errorf("malformed type reference")
+	}
+	return ref, nil
+}
+
+// namedType returns the Go type of the scalar, enum or input object type
+// name, making sure that it's declared. Their Go types are named like the
+// GraphQL types, which the graphql package declares variables with.
+func (g *generator) namedType(name string) string {
+	if typ, ok := builtinScalars[name]; ok {
+		return typ
+	}
+	if !g.declared[name] {
+		g.declared[name] = true
+		g.pending = append(g.pending, name)
+	}
+	return name
+}
+
+// declare writes the declaration of the scalar, enum or input object type name.
+func (g *generator) declare(name string) error {
+	t := g.schema.Type(name)
+	if t == nil {
+		return fmt.Errorf("schema has no type %s", name)
+	}
+	if g.names[name] {
+		return fmt.Errorf("type %s conflicts with a declaration of an operation", name)
+	}
+	switch t.Kind {
+	case introspection.Scalar:
+		fmt.Fprintf(&g.buf, "\n// %s is the %s scalar.\ntype %s string\n", name, name, name)
+	case introspection.Enum:
+		fmt.Fprintf(&g.buf, "\n// %s is the %s enum.\ntype %s string\n\n// Values of %s.\nconst (\n", name, name, name, name)
+		var names strings.Builder
+		for _, v := range t.EnumValues {
+			value := name + ident.ParseScreamingSnakeCase(v.Name).ToMixedCaps()
+			fmt.Fprintf(&g.buf, "%s %s = %q\n", value, name, v.Name)
+			fmt.Fprintf(&names, "%s: %q,\n", value, v.Name)
+		}
+		g.buf.WriteString(")\n")
+		// Register the values, so that invalid ones are rejected before being sent.
+		fmt.Fprintf(&g.buf, "\nfunc init() {\ngraphql.RegisterEnum(\"%s!\", map[%s]string{\n%s})\n}\n", name, name, names.String())
+	case introspection.InputObject:
+		var fields strings.Builder
+		for _, f := range t.InputFields {
+			typ, err := g.inputRefType(f.Type)
+			if err != nil {
+				return fmt.Errorf("input %s: field %s: %w", name, f.Name, err)
+			}
+			tag := f.Name
+			if f.Type.Kind != introspection.NonNull {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(&fields, "%s %s `json:%q`\n", ident.ParseLowerCamelCase(f.Name).ToMixedCaps(), typ, tag)
+		}
+		fmt.Fprintf(&g.buf, "\n// %s is the %s input object.\ntype %s struct {\n%s}\n", name, name, name, fields.String())
+	default:
+		return fmt.Errorf("type %s isn't a scalar, enum or input object", name)
+	}
+	sort.Strings(g.pending)
+	return nil
+}
@@ -0,0 +1,120 @@
+// graphqlgen generates Go query structs for the operations of GraphQL
+// documents, checked against a schema, for use with the graphql package.
+//
+// Usage:
+//
+//	graphqlgen -schema file [-package name] [-o file] operations.graphql...
+//
+// The schema is read from an SDL file, or from a file with the JSON result
+// of the introspection query if its name ends with ".json", such as one
+// written by "graphql schema -json". Each named operation of the documents
+// gets a struct type named after it, such as GetRepositoryQuery for the
+// query GetRepository, with the graphql tags that make the graphql package
+// construct the operation, and a function returning its variables, such as
+// GetRepositoryVariables, if it has any:
+//
+//	var q GetRepositoryQuery
+//	_, err := client.Query(ctx, &q, GetRepositoryVariables("shurcooL", "graphql"))
+//
+// Fragments are inlined into the structs of the operations spreading them.
+// Nullable fields are pointers, except for lists. The enums, input objects
+// and custom scalars that operations use are declared as types named like
+// their GraphQL types, which the graphql package declares variables with.
+// Custom scalars are declared as strings. Enums are registered with
+// graphql.RegisterEnum, so that values that aren't of them are rejected
+// before operations are sent.
+//
+// The generated code is written to standard output, unless -o is given.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/merico-dev/graphql/introspection"
+)
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "graphqlgen:", err)
+		os.Exit(1)
+	}
+}
+
+// run runs the command with the arguments args, writing the generated
+// code to stdout if no output file is given.
+func run(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("graphqlgen", flag.ContinueOnError)
+	schemaFile := fs.String("schema", "", "schema `file`, as SDL or introspection JSON")
+	pkg := fs.String("package", "main", "package `name` of the generated code")
+	output := fs.String("o", "", "write the generated code to `file`")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: graphqlgen -schema file [-package name] [-o file] operations.graphql...")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *schemaFile == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	schema, err := readSchema(*schemaFile)
+	if err != nil {
+		return err
+	}
+	var docs []*document
+	for _, file := range fs.Args() {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		doc, err := parseDocument(string(b))
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		docs = append(docs, doc)
+	}
+	src, err := generate(schema, *pkg, fs.Args(), docs)
+	if err != nil {
+		return err
+	}
+	if *output != "" {
+		return os.WriteFile(*output, src, 0644)
+	}
+	_, err = stdout.Write(src)
+	return err
+}
+
+// readSchema reads the schema from file, which holds SDL, or the JSON
+// result of the introspection query if its name ends with ".json".
+func readSchema(file string) (*introspection.Schema, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(file, ".json") {
+		s, err := introspection.ParseSDL(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		return s, nil
+	}
+	var r introspection.Response
+	err = json.Unmarshal(b, &r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return &r.Schema, nil
+}
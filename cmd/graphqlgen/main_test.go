@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSchema = `
+scalar DateTime
+enum IssueState { OPEN CLOSED }
+input IssueFilters { states: [IssueState!] since: DateTime }
+interface Node { id: ID! }
+type Issue implements Node { id: ID! number: Int! state: IssueState! createdAt: DateTime! }
+type Repository implements Node { id: ID! description: String issues(first: Int!, filterBy: IssueFilters): [Issue!]! }
+type Query { repository(owner: String!, name: String!): Repository node(id: ID!): Node }
+`
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	schemaFile, opsFile := filepath.Join(dir, "schema.graphql"), filepath.Join(dir, "ops.graphql")
+	mustWriteFile(t, schemaFile, testSchema)
+	mustWriteFile(t, opsFile, `
+# Issues of a repository.
+query GetRepository($owner: String!, $name: String!, $filters: IssueFilters) {
+	repo: repository(owner: $owner, name: $name) {
+		description
+		issues(first: 10, filterBy: $filters) { ...IssueFields }
+	}
+}
+
+query Node($id: ID!) {
+	node(id: $id) {
+		__typename
+		... on Issue { number }
+	}
+}
+
+fragment IssueFields on Issue { id, number, state, createdAt }
+`)
+
+	var stdout bytes.Buffer
+	err := run([]string{"-schema", schemaFile, "-package", "github", opsFile}, &stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "// Code generated by graphqlgen. DO NOT EDIT.\n\npackage github\n\nimport \"github.com/merico-dev/graphql\"\n" + `
+// GetRepositoryQuery is the query GetRepository of ops.graphql.
+type GetRepositoryQuery struct {
+	Repo *struct {
+		Description *graphql.String
+		Issues      []struct {
+			ID        graphql.ID
+			Number    graphql.Int
+			State     IssueState
+			CreatedAt DateTime
+		} ` + "`" + `graphql:"issues(first: 10, filterBy: $filters)"` + "`" + `
+	} ` + "`" + `graphql:"repo: repository(owner: $owner, name: $name)"` + "`" + `
+}
+
+// GetRepositoryVariables returns the variables of GetRepositoryQuery.
+func GetRepositoryVariables(owner graphql.String, name graphql.String, filters *IssueFilters) map[string]interface{} {
+	return map[string]interface{}{
+		"owner":   owner,
+		"name":    name,
+		"filters": filters,
+	}
+}
+
+// NodeQuery is the query Node of ops.graphql.
+type NodeQuery struct {
+	Node *struct {
+		Typename      graphql.String ` + "`" + `graphql:"__typename"` + "`" + `
+		IssueFragment struct {
+			Number graphql.Int
+		} ` + "`" + `graphql:"... on Issue"` + "`" + `
+	} ` + "`" + `graphql:"node(id: $id)"` + "`" + `
+}
+
+// NodeVariables returns the variables of NodeQuery.
+func NodeVariables(id string) map[string]interface{} {
+	return map[string]interface{}{
+		"id": id,
+	}
+}
+
+// DateTime is the DateTime scalar.
+type DateTime string
+
+// IssueFilters is the IssueFilters input object.
+type IssueFilters struct {
+	States []IssueState ` + "`" + `json:"states,omitempty"` + "`" + `
+	Since  *DateTime    ` + "`" + `json:"since,omitempty"` + "`" + `
+}
+
+// IssueState is the IssueState enum.
+type IssueState string
+
+// Values of IssueState.
+const (
+	IssueStateOpen   IssueState = "OPEN"
+	IssueStateClosed IssueState = "CLOSED"
+)
+
+func init() {
+	graphql.RegisterEnum("IssueState!", map[IssueState]string{
+		IssueStateOpen:   "OPEN",
+		IssueStateClosed: "CLOSED",
+	})
+}
+`
+	if got := stdout.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRun_errors(t *testing.T) {
+	dir := t.TempDir()
+	schemaFile := filepath.Join(dir, "schema.graphql")
+	mustWriteFile(t, schemaFile, testSchema)
+	tests := []struct {
+		ops  string
+		want string
+	}{
+		{`query Q { repository(owner: "a", name: "b") { stars } }`, "operation Q: field repository: type Repository has no field stars"},
+		{`query Q { node(id: "1") }`, "operation Q: field node: field of type Node must have a selection set"},
+		{`query { node(id: "1") { id } }`, "query without a name"},
+		{`query Q($id: Issue!) { node(id: $id) { id } }`, "operation Q: variable $id: type Issue isn't an input type"},
+		{`query Q { node(id: "1") { ...Missing } }`, "operation Q: field node: fragment Missing isn't defined"},
+		{`query Q { node(id: "1") { id }`, `unexpected end of document: expected "}"`},
+	}
+	for _, tt := range tests {
+		opsFile := filepath.Join(dir, "ops.graphql")
+		mustWriteFile(t, opsFile, tt.ops)
+		err := run([]string{"-schema", schemaFile, opsFile}, new(bytes.Buffer))
+		if err == nil || !strings.HasSuffix(err.Error(), tt.want) {
+			t.Errorf("%s: got error: %v, want: %s", tt.ops, err, tt.want)
+		}
+	}
+}
+
+func TestRun_nullableLists(t *testing.T) {
+	dir := t.TempDir()
+	schemaFile, opsFile := filepath.Join(dir, "schema.graphql"), filepath.Join(dir, "ops.graphql")
+	mustWriteFile(t, schemaFile, testSchema)
+	mustWriteFile(t, opsFile, `
+query Q($ids: [ID!], $states: [[IssueState]]!) {
+	repository(owner: "a", name: "b") {
+		issues(first: 10, filterBy: {states: $states}) { id }
+	}
+}
+`)
+
+	var stdout bytes.Buffer
+	err := run([]string{"-schema", schemaFile, opsFile}, &stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nullable lists are pointers to slices, as the graphql package
+	// declares them as nullable.
+	if want := "func QVariables(ids *[]string, states []*[]*IssueState) map[string]interface{} {"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("got:\n%s\nwant it to contain: %s", stdout.String(), want)
+	}
+}
+
+func mustWriteFile(t *testing.T, name, content string) {
+	t.Helper()
+	err := os.WriteFile(name, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
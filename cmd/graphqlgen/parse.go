@@ -0,0 +1,489 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// document is a parsed GraphQL executable document.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is an operation definition of a document.
+type operation struct {
+	typ        string // "query", "mutation" or "subscription".
+	name       string
+	variables  []variable
+	selections []selection
+}
+
+// variable is a variable definition of an operation.
+type variable struct {
+	name string
+	typ  typeRef
+}
+
+// typeRef is a type reference, as written in a variable definition.
+type typeRef struct {
+	name    string   // Named type, if not a list.
+	elem    *typeRef // Element type, if a list.
+	nonNull bool
+}
+
+// fragment is a fragment definition of a document.
+type fragment struct {
+	name       string
+	on         string
+	selections []selection
+}
+
+// selection is a field, fragment spread, or inline fragment.
+type selection struct {
+	// Fields.
+	alias      string
+	name       string
+	arguments  string // E.g., "(owner: $owner, name: $name)", or "".
+	directives string // E.g., " @include(if: $withComments)", or "".
+	selections []selection
+
+	// Fragment spreads and inline fragments.
+	spread string // Name of the spread fragment, if a fragment spread.
+	inline bool   // Whether an inline fragment.
+	on     string // Type condition of an inline fragment, if any.
+}
+
+// token is a lexical token of a document.
+type token struct {
+	kind byte // 'n' for names, 's' for strings, 'v' for numbers, 'p' for punctuators.
+	text string
+	line int
+}
+
+// lex splits the document src into tokens.
+func lex(src string) ([]token, error) {
+	src = strings.TrimPrefix(src, "\ufeff")
+	var tokens []token
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '"':
+			start := i
+			if strings.HasPrefix(src[i:], `"""`) {
+				end := strings.Index(src[i+3:], `"""`)
+				for end != -1 && src[i+3+end-1] == '\\' {
+					next := strings.Index(src[i+3+end+1:], `"""`)
+					if next == -1 {
+						end = -1
+						break
+					}
+					end += 1 + next
+				}
+				if end == -1 {
+					return nil, fmt.Errorf("line %d: unterminated block string", line)
+				}
+				i += 3 + end + 3
+			} else {
+				i++
+				for i < len(src) && src[i] != '"' {
+					if src[i] == '\\' {
+						i++
+					}
+					if i < len(src) && src[i] == '\n' {
+						return nil, fmt.Errorf("line %d: unterminated string", line)
+					}
+					i++
+				}
+				if i >= len(src) {
+					return nil, fmt.Errorf("line %d: unterminated string", line)
+				}
+				i++
+			}
+			tokens = append(tokens, token{kind: 's', text: src[start:i], line: line})
+			line += strings.Count(src[start:i], "\n")
+		case c == '.':
+			if !strings.HasPrefix(src[i:], "...") {
+				return nil, fmt.Errorf("line %d: unexpected %q", line, c)
+			}
+			tokens = append(tokens, token{kind: 'p', text: "...", line: line})
+			i += 3
+		case strings.IndexByte("!$&()=:@[]{}|", c) != -1:
+			tokens = append(tokens, token{kind: 'p', text: string(c), line: line})
+			i++
+		case c == '-' || c >= '0' && c <= '9':
+			start := i
+			i++
+			for i < len(src) && (isNameByte(src[i]) || src[i] == '.' || (src[i] == '-' || src[i] == '+') && (src[i-1] == 'e' || src[i-1] == 'E')) {
+				i++
+			}
+			tokens = append(tokens, token{kind: 'v', text: src[start:i], line: line})
+		case isNameByte(c):
+			start := i
+			for i < len(src) && isNameByte(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: 'n', text: src[start:i], line: line})
+		default:
+			return nil, fmt.Errorf("line %d: unexpected %q", line, c)
+		}
+	}
+	return tokens, nil
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// parser parses a document from its tokens.
+type parser struct {
+	tokens []token
+	i      int
+}
+
+// parseDocument parses the executable document src.
+func parseDocument(src string) (*document, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	doc := &document{fragments: make(map[string]*fragment)}
+	for !p.done() {
+		switch t := p.peek(); {
+		case t.text == "fragment":
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if doc.fragments[f.name] != nil {
+				return nil, fmt.Errorf("fragment %s is defined more than once", f.name)
+			}
+			doc.fragments[f.name] = f
+		case t.text == "query" || t.text == "mutation" || t.text == "subscription" || t.text == "{":
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		default:
+			return nil, p.errorf("expected an operation or fragment definition")
+		}
+	}
+	return doc, nil
+}
+
+func (p *parser) done() bool { return p.i >= len(p.tokens) }
+
+// peek returns the next token, or a token with no text at the end.
+func (p *parser) peek() token {
+	if p.done() {
+		return token{}
+	}
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	p.i++
+	return t
+}
+
+// errorf returns an error about the next token.
+func (p *parser) errorf(format string, args ...interface{}) error {
+	if p.done() {
+		return fmt.Errorf("unexpected end of document: "+format, args...)
+	}
+	t := p.peek()
+	return fmt.Errorf("line %d: unexpected %q: "+format, append([]interface{}{t.line, t.text}, args...)...)
+}
+
+// expect consumes the punctuator text.
+func (p *parser) expect(text string) error {
+	if t := p.peek(); t.kind != 'p' || t.text != text {
+		return p.errorf("expected %q", text)
+	}
+	p.i++
+	return nil
+}
+
+// name consumes a name.
+func (p *parser) name() (string, error) {
+	if p.peek().kind != 'n' {
+		return "", p.errorf("expected a name")
+	}
+	return p.next().text, nil
+}
+
+// accept consumes the punctuator text, if it's next, and reports whether it was.
+func (p *parser) accept(text string) bool {
+	if t := p.peek(); t.kind == 'p' && t.text == text {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{typ: "query"}
+	if p.peek().kind == 'n' {
+		op.typ = p.next().text
+		if p.peek().kind == 'n' {
+			op.name = p.next().text
+		}
+		if p.accept("(") {
+			for !p.accept(")") {
+				v, err := p.variable()
+				if err != nil {
+					return nil, err
+				}
+				op.variables = append(op.variables, v)
+			}
+		}
+		_, err := p.directives()
+		if err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	op.selections, err = p.selectionSet()
+	return op, err
+}
+
+func (p *parser) variable() (variable, error) {
+	err := p.expect("$")
+	if err != nil {
+		return variable{}, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return variable{}, err
+	}
+	err = p.expect(":")
+	if err != nil {
+		return variable{}, err
+	}
+	typ, err := p.typeRef()
+	if err != nil {
+		return variable{}, err
+	}
+	if p.accept("=") {
+		_, err := p.value()
+		if err != nil {
+			return variable{}, err
+		}
+	}
+	_, err = p.directives()
+	return variable{name: name, typ: typ}, err
+}
+
+func (p *parser) typeRef() (typeRef, error) {
+	var t typeRef
+	if p.accept("[") {
+		elem, err := p.typeRef()
+		if err != nil {
+			return typeRef{}, err
+		}
+		err = p.expect("]")
+		if err != nil {
+			return typeRef{}, err
+		}
+		t.elem = &elem
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return typeRef{}, err
+		}
+		t.name = name
+	}
+	t.nonNull = p.accept("!")
+	return t, nil
+}
+
+func (p *parser) fragment() (*fragment, error) {
+	p.next() // "fragment".
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if p.next().text != "on" {
+		p.i--
+		return nil, p.errorf(`expected "on"`)
+	}
+	on, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.directives()
+	if err != nil {
+		return nil, err
+	}
+	selections, err := p.selectionSet()
+	return &fragment{name: name, on: on, selections: selections}, err
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	err := p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+	var selections []selection
+	for !p.accept("}") {
+		if p.done() {
+			return nil, p.errorf(`expected "}"`)
+		}
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+	return selections, nil
+}
+
+func (p *parser) selection() (selection, error) {
+	var s selection
+	var err error
+	if p.accept("...") {
+		if t := p.peek(); t.kind == 'n' && t.text != "on" {
+			s.spread = p.next().text
+			s.directives, err = p.directives()
+			return s, err
+		}
+		s.inline = true
+		if p.peek().text == "on" {
+			p.next()
+			s.on, err = p.name()
+			if err != nil {
+				return s, err
+			}
+		}
+		s.directives, err = p.directives()
+		if err != nil {
+			return s, err
+		}
+		s.selections, err = p.selectionSet()
+		return s, err
+	}
+	s.name, err = p.name()
+	if err != nil {
+		return s, err
+	}
+	if p.accept(":") {
+		s.alias = s.name
+		s.name, err = p.name()
+		if err != nil {
+			return s, err
+		}
+	}
+	if p.peek().kind == 'p' && p.peek().text == "(" {
+		s.arguments, err = p.arguments()
+		if err != nil {
+			return s, err
+		}
+	}
+	s.directives, err = p.directives()
+	if err != nil {
+		return s, err
+	}
+	if p.peek().kind == 'p' && p.peek().text == "{" {
+		s.selections, err = p.selectionSet()
+	}
+	return s, err
+}
+
+// arguments consumes arguments, and returns them as written in graphql
+// tags, e.g. "(owner: $owner, name: $name)".
+func (p *parser) arguments() (string, error) {
+	p.next() // "(".
+	var args []string
+	for !p.accept(")") {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		err = p.expect(":")
+		if err != nil {
+			return "", err
+		}
+		value, err := p.value()
+		if err != nil {
+			return "", err
+		}
+		args = append(args, name+": "+value)
+	}
+	return "(" + strings.Join(args, ", ") + ")", nil
+}
+
+// directives consumes directives, and returns them as written in graphql
+// tags, each preceded by a space, e.g. " @include(if: $withComments)".
+func (p *parser) directives() (string, error) {
+	var b strings.Builder
+	for p.accept("@") {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(" @" + name)
+		if p.peek().kind == 'p' && p.peek().text == "(" {
+			args, err := p.arguments()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(args)
+		}
+	}
+	return b.String(), nil
+}
+
+// value consumes a value, and returns it as written in graphql tags.
+func (p *parser) value() (string, error) {
+	t := p.next()
+	switch {
+	case t.kind == 'p' && t.text == "$":
+		name, err := p.name()
+		return "$" + name, err
+	case t.kind == 'p' && t.text == "[":
+		var values []string
+		for !p.accept("]") {
+			v, err := p.value()
+			if err != nil {
+				return "", err
+			}
+			values = append(values, v)
+		}
+		return "[" + strings.Join(values, ", ") + "]", nil
+	case t.kind == 'p' && t.text == "{":
+		var fields []string
+		for !p.accept("}") {
+			name, err := p.name()
+			if err != nil {
+				return "", err
+			}
+			err = p.expect(":")
+			if err != nil {
+				return "", err
+			}
+			v, err := p.value()
+			if err != nil {
+				return "", err
+			}
+			fields = append(fields, name+": "+v)
+		}
+		return "{" + strings.Join(fields, ", ") + "}", nil
+	case t.kind != 'p' && t.kind != 0:
+		return t.text, nil
+	}
+	p.i--
+	return "", p.errorf("expected a value")
+}
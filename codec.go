@@ -0,0 +1,175 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+
+	"github.com/merico-dev/graphql/internal/jsonutil"
+)
+
+// Codec encodes and decodes JSON. It allows replacing "encoding/json" with
+// a faster, API-compatible implementation, such as jsoniter or go-json.
+//
+// A Codec is used to encode the request body, to decode the response body,
+// and to decode scalar values into the fields of query data structures.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// WithCodec makes the client use codec for encoding requests and decoding
+// responses, instead of "encoding/json".
+func WithCodec(codec Codec) ClientOption {
+	return func(c *Client) {
+		c.codec = codec
+		c.decodeOptions = append(c.decodeOptions, jsonutil.UnmarshalFunc(codec.Unmarshal))
+	}
+}
+
+// marshal returns the JSON encoding of v, using the client's codec if set.
+func (c *Client) marshal(v interface{}) ([]byte, error) {
+	if c.codec != nil {
+		return c.codec.Marshal(v)
+	}
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+// unmarshal decodes the JSON value read from r into v,
+// using the client's codec if set.
+func (c *Client) unmarshal(r io.Reader, v interface{}) error {
+	if c.codec != nil {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return c.codec.Unmarshal(b, v)
+	}
+	return json.NewDecoder(r).Decode(v)
+}
+
+// WithWireCodec registers codec as the encoding of the media type
+// mediaType, such as "application/msgpack" or "application/cbor", for
+// servers and gateways that support binary encodings to save bandwidth.
+//
+// Requests sent as a POST body are encoded with the first registered codec,
+// and the registered media types are preferred over JSON in the Accept
+// header of requests. Responses are decoded with the codec registered for
+// their Content-Type, or as JSON if there's none.
+//
+// Since a wire codec isn't given the Go types of variables, variables are
+// converted to their JSON representation (objects, arrays, strings,
+// numbers, booleans and null) before being encoded with it.
+func WithWireCodec(mediaType string, codec Codec) ClientOption {
+	return func(c *Client) {
+		c.wireCodecs = append(c.wireCodecs, wireCodec{mediaType: mediaType, codec: codec})
+	}
+}
+
+// wireCodec is a codec registered for a media type.
+type wireCodec struct {
+	mediaType string
+	codec     Codec
+}
+
+// wireCodec returns the codec registered for the media type of
+// the Content-Type header value ct, or nil if there's none.
+func (c *Client) wireCodec(ct string) Codec {
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return nil
+	}
+	for _, wc := range c.wireCodecs {
+		if wc.mediaType == mediaType {
+			return wc.codec
+		}
+	}
+	return nil
+}
+
+// marshalWire encodes the GraphQL request in with codec.
+func marshalWire(codec Codec, in interface{}) ([]byte, error) {
+	// Convert the request to its JSON representation, which any codec
+	// can encode without knowing about json tags and json.Marshalers.
+	b, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	err = dec.Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+	return codec.Marshal(fromJSONNumbers(v))
+}
+
+// fromJSONNumbers replaces the json.Number values in v, as decoded into
+// an interface{}, with int64 values if they're integers, or float64 values.
+func fromJSONNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = fromJSONNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = fromJSONNumbers(e)
+		}
+	}
+	return v
+}
+
+// unmarshalWire decodes the response body read from r with codec into v,
+// which must be a pointer to a value that "encoding/json" can decode into.
+func unmarshalWire(codec Codec, r io.Reader, v interface{}) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var generic interface{}
+	err = codec.Unmarshal(b, &generic)
+	if err != nil {
+		return err
+	}
+	// Convert the response to JSON, which the response data is decoded from.
+	b, err = json.Marshal(toJSONValue(generic))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// toJSONValue replaces the maps with non-string keys in v, as decoded
+// into an interface{} by some codecs, with maps that JSON can encode.
+func toJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = toJSONValue(e)
+		}
+		return m
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = toJSONValue(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = toJSONValue(e)
+		}
+	}
+	return v
+}
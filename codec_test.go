@@ -0,0 +1,157 @@
+package graphql_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+// countingCodec is a graphql.Codec that counts its calls.
+type countingCodec struct {
+	marshals, unmarshals int
+}
+
+func (c *countingCodec) Marshal(v interface{}) ([]byte, error) {
+	c.marshals++
+	return json.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	c.unmarshals++
+	return json.Unmarshal(data, v)
+}
+
+func TestWithCodec(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"{viewer{login,name}}"}`; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher", "name": "Gopher"}}}`)
+	})
+	codec := new(countingCodec)
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithCodec(codec))
+
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+			Name  graphql.String
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Viewer.Login != "gopher" || q.Viewer.Name != "Gopher" {
+		t.Errorf("got q.Viewer: %+v", q.Viewer)
+	}
+	if got, want := codec.marshals, 1; got != want {
+		t.Errorf("got %d Marshal calls, want: %d", got, want)
+	}
+	// One for the response envelope, and one for each scalar value.
+	if got, want := codec.unmarshals, 3; got != want {
+		t.Errorf("got %d Unmarshal calls, want: %d", got, want)
+	}
+}
+
+func TestWithCodec_queryBatch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `[{"data": {"viewer": {"login": "a"}}}, {"data": {"viewer": {"login": "b"}}}]`)
+	})
+	codec := new(countingCodec)
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithCodec(codec))
+
+	var q1, q2 struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	_, err := client.QueryBatch(context.Background(), []graphql.BatchOperation{{Query: &q1}, {Query: &q2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q1.Viewer.Login != "a" || q2.Viewer.Login != "b" {
+		t.Errorf("got logins: %q, %q, want: a, b", q1.Viewer.Login, q2.Viewer.Login)
+	}
+	if got, want := codec.marshals, 1; got != want {
+		t.Errorf("got %d Marshal calls, want: %d", got, want)
+	}
+	// One for the response envelopes, and one for each scalar value.
+	if got, want := codec.unmarshals, 3; got != want {
+		t.Errorf("got %d Unmarshal calls, want: %d", got, want)
+	}
+}
+
+// prefixCodec is a graphql.Codec standing in for a binary encoding.
+// It's JSON prefixed with "BIN", and decodes maps with interface{} keys,
+// as some binary codecs do.
+type prefixCodec struct{}
+
+func (prefixCodec) Marshal(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	return append([]byte("BIN"), b...), err
+}
+
+func (prefixCodec) Unmarshal(data []byte, v interface{}) error {
+	if !bytes.HasPrefix(data, []byte("BIN")) {
+		return fmt.Errorf("missing BIN prefix: %q", data)
+	}
+	var m map[string]interface{}
+	err := json.Unmarshal(data[3:], &m)
+	if err != nil {
+		return err
+	}
+	generic := make(map[interface{}]interface{})
+	for k, e := range m {
+		generic[k] = e
+	}
+	*v.(*interface{}) = generic
+	return nil
+}
+
+func TestWithWireCodec(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("Content-Type"), "application/x-bin"; got != want {
+			t.Errorf("got Content-Type: %q, want: %q", got, want)
+		}
+		if got, want := req.Header.Get("Accept"), "application/x-bin, application/graphql-response+json, application/json"; got != want {
+			t.Errorf("got Accept: %q, want: %q", got, want)
+		}
+		body := mustRead(req.Body)
+		if got, want := body, `BIN{"query":"query($first:Int!){viewer{login}}","variables":{"first":5}}`; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/x-bin")
+		mustWrite(w, `BIN{"data": {"viewer": {"login": "gopher"}}, "errors": [{"message": "partial"}]}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithWireCodec("application/x-bin", prefixCodec{}))
+
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	dataErrors, err := client.Query(context.Background(), &q, map[string]interface{}{
+		"first": graphql.Int(5),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Viewer.Login, graphql.String("gopher"); got != want {
+		t.Errorf("got login: %q, want: %q", got, want)
+	}
+	if got, want := fmt.Sprint(dataErrors), "[partial]"; got != want {
+		t.Errorf("got errors: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,160 @@
+package graphql
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Compressor compresses and decompresses HTTP bodies in a content coding,
+// such as zstd.
+type Compressor interface {
+	NewReader(r io.Reader) (io.ReadCloser, error)
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// WithCompression registers c as the implementation of the content coding
+// encoding, such as "zstd", to negotiate compression with servers and
+// gateways. Registered codings are preferred over gzip and deflate, which
+// are built in, in the Accept-Encoding header of requests, and responses
+// are decompressed according to their Content-Encoding header. E.g., with
+// the zstd package of github.com/klauspost/compress:
+//
+//	type zstdCompressor struct{}
+//
+//	func (zstdCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+//		d, err := zstd.NewReader(r)
+//		return d.IOReadCloser(), err
+//	}
+//
+//	func (zstdCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+//		return zstd.NewWriter(w)
+//	}
+//
+//	client := graphql.NewClient(url, nil, graphql.WithCompression("zstd", zstdCompressor{}))
+func WithCompression(encoding string, c Compressor) ClientOption {
+	return func(cl *Client) {
+		cl.compressors = append(cl.compressors, compressor{encoding: encoding, Compressor: c})
+	}
+}
+
+// WithRequestCompression makes the client compress the bodies of requests
+// in the content coding encoding, which is "gzip", "deflate" or a coding
+// registered with WithCompression. Only use it with servers known to accept it, since
+// unlike response compression, it can't be negotiated.
+func WithRequestCompression(encoding string) ClientOption {
+	return func(c *Client) {
+		c.requestEncoding = encoding
+	}
+}
+
+// compressor is a Compressor registered for a content coding.
+type compressor struct {
+	encoding string
+	Compressor
+}
+
+// gzipCompressor is the built-in Compressor of the gzip content coding.
+type gzipCompressor struct{}
+
+func (gzipCompressor) NewReader(r io.Reader) (io.ReadCloser, error)  { return gzip.NewReader(r) }
+func (gzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }
+
+// deflateCompressor is the built-in Compressor of the deflate content
+// coding, which is the zlib format.
+type deflateCompressor struct{}
+
+func (deflateCompressor) NewReader(r io.Reader) (io.ReadCloser, error) { return zlib.NewReader(r) }
+func (deflateCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriter(w), nil
+}
+
+// compressor returns the Compressor of the content coding encoding,
+// or nil if it's not supported.
+func (c *Client) compressor(encoding string) Compressor {
+	for _, comp := range c.compressors {
+		if strings.EqualFold(comp.encoding, encoding) {
+			return comp.Compressor
+		}
+	}
+	switch strings.ToLower(encoding) {
+	case "gzip":
+		return gzipCompressor{}
+	case "deflate":
+		return deflateCompressor{}
+	}
+	return nil
+}
+
+// acceptEncoding returns the Accept-Encoding header to send,
+// or "" to leave it to the transport.
+func (c *Client) acceptEncoding() string {
+	if len(c.compressors) == 0 {
+		return ""
+	}
+	var encodings []string
+	registered := make(map[string]bool)
+	for _, comp := range c.compressors {
+		encodings = append(encodings, comp.encoding)
+		registered[strings.ToLower(comp.encoding)] = true
+	}
+	for _, builtin := range []string{"gzip", "deflate"} {
+		if !registered[builtin] {
+			encodings = append(encodings, builtin)
+		}
+	}
+	return strings.Join(encodings, ", ")
+}
+
+// compressRequest compresses the body of req, if any,
+// in the client's request content coding.
+func (c *Client) compressRequest(req *http.Request) error {
+	if req.Body == nil {
+		return nil
+	}
+	comp := c.compressor(c.requestEncoding)
+	if comp == nil {
+		return fmt.Errorf("unsupported request content coding %q", c.requestEncoding)
+	}
+	var buf bytes.Buffer
+	w, err := comp.NewWriter(&buf)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, req.Body)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	body := buf.Bytes()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Encoding", c.requestEncoding)
+	return nil
+}
+
+// decompressResponse returns a reader of the body r of resp, decompressed
+// according to its Content-Encoding header. The transport decompresses
+// gzip responses itself unless the client set Accept-Encoding.
+func (c *Client) decompressResponse(resp *http.Response, r io.Reader) (io.ReadCloser, error) {
+	encoding := strings.TrimSpace(resp.Header.Get("Content-Encoding"))
+	if encoding == "" || strings.EqualFold(encoding, "identity") || resp.Uncompressed {
+		return ioutil.NopCloser(r), nil
+	}
+	comp := c.compressor(encoding)
+	if comp == nil {
+		return nil, fmt.Errorf("unsupported response content coding %q", encoding)
+	}
+	return comp.NewReader(r)
+}
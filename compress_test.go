@@ -0,0 +1,103 @@
+package graphql_test
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+// flateCompressor stands in for a Compressor
+// of a content coding such as zstd.
+type flateCompressor struct{}
+
+func (flateCompressor) NewReader(r io.Reader) (io.ReadCloser, error) { return flate.NewReader(r), nil }
+func (flateCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return flate.NewWriter(w, flate.BestSpeed)
+}
+
+func TestWithCompression(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("Accept-Encoding"), "deflate, gzip"; got != want {
+			t.Errorf("got Accept-Encoding: %q, want: %q", got, want)
+		}
+		if got, want := req.Header.Get("Content-Encoding"), "deflate"; got != want {
+			t.Errorf("got Content-Encoding: %q, want: %q", got, want)
+		}
+		body, err := ioutil.ReadAll(flate.NewReader(req.Body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(body), `{"query":"{viewer{login}}"}`+"\n"; got != want {
+			t.Errorf("got body: %q, want: %q", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		var zw io.WriteCloser
+		if req.URL.Query().Get("gzip") != "" {
+			w.Header().Set("Content-Encoding", "gzip")
+			zw = gzip.NewWriter(w)
+		} else {
+			w.Header().Set("Content-Encoding", "deflate")
+			zw, _ = flate.NewWriter(w, flate.BestSpeed)
+		}
+		mustWrite(zw, `{"data": {"viewer": {"login": "gopher"}}}`)
+		zw.Close()
+	})
+	for _, url := range []string{"/graphql", "/graphql?gzip=1"} {
+		client := graphql.NewClient(url, &http.Client{Transport: localRoundTripper{handler: mux}},
+			graphql.WithCompression("deflate", flateCompressor{}), graphql.WithRequestCompression("deflate"))
+		var q struct {
+			Viewer struct {
+				Login graphql.String
+			}
+		}
+		_, err := client.Query(context.Background(), &q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := q.Viewer.Login, graphql.String("gopher"); got != want {
+			t.Errorf("%s: got q.Viewer.Login: %q, want: %q", url, got, want)
+		}
+	}
+}
+
+func TestWithRequestCompression_deflate(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("Content-Encoding"), "deflate"; got != want {
+			t.Errorf("got Content-Encoding: %q, want: %q", got, want)
+		}
+		zr, err := zlib.NewReader(req.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := mustRead(zr), `{"query":"{viewer{login}}"}`+"\n"; got != want {
+			t.Errorf("got body: %q, want: %q", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "deflate")
+		zw := zlib.NewWriter(w)
+		mustWrite(zw, `{"data": {"viewer": {"login": "gopher"}}}`)
+		zw.Close()
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithRequestCompression("deflate"))
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Viewer.Login, graphql.String("gopher"); got != want {
+		t.Errorf("got q.Viewer.Login: %q, want: %q", got, want)
+	}
+}
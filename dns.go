@@ -0,0 +1,150 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ResolveFunc resolves host to IP addresses,
+// such as with service discovery.
+type ResolveFunc func(ctx context.Context, host string) ([]string, error)
+
+// WithDNSRefresh makes the client re-resolve the host names of the servers
+// it talks to at most every interval, and close its idle connections when
+// their addresses change, so that the traffic of long-lived clients follows
+// DNS-based failover, rather than staying pinned to stale connections until
+// they fail. Connections in use at the time are closed once idle later on.
+//
+// If resolve is nil, host names are resolved with the system resolver.
+// Otherwise they're resolved with resolve, and if the transport of the
+// client's HTTP client is an *http.Transport, new connections are made to
+// the addresses it returns, tried in order.
+func WithDNSRefresh(interval time.Duration, resolve ResolveFunc) ClientOption {
+	return func(c *Client) {
+		hc := *c.httpClient
+		base := hc.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		t := &dnsRefreshTransport{
+			base:     base,
+			interval: interval,
+			resolve:  resolve,
+			hosts:    make(map[string]*resolvedHost),
+		}
+		if resolve == nil {
+			t.resolve = net.DefaultResolver.LookupHost
+		} else if tr, ok := base.(*http.Transport); ok {
+			tr = tr.Clone()
+			dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+			tr.DialContext = t.dialer(dialer.DialContext)
+			t.base = tr
+		}
+		hc.Transport = t
+		c.httpClient = &hc
+	}
+}
+
+// dnsRefreshTransport re-resolves the hosts requests are sent to,
+// and closes the idle connections of base when their addresses change.
+type dnsRefreshTransport struct {
+	base     http.RoundTripper
+	interval time.Duration
+	resolve  ResolveFunc
+
+	mu    sync.Mutex
+	hosts map[string]*resolvedHost
+}
+
+// resolvedHost is a host resolved by a dnsRefreshTransport.
+type resolvedHost struct {
+	addrs    []string // In the order they were resolved, which they're dialed in.
+	resolved time.Time
+}
+
+func (t *dnsRefreshTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Resolution failures are left to the transport to report.
+	_, _ = t.lookup(req.Context(), req.URL.Hostname())
+	return t.base.RoundTrip(req)
+}
+
+// lookup returns the addresses of host, resolving it again if it wasn't
+// for the refresh interval, and closing idle connections if they changed.
+func (t *dnsRefreshTransport) lookup(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	t.mu.Lock()
+	h, ok := t.hosts[host]
+	if ok && time.Since(h.resolved) < t.interval {
+		t.mu.Unlock()
+		return h.addrs, nil
+	}
+	t.mu.Unlock()
+
+	addrs, err := t.resolve(ctx, host)
+	if err != nil {
+		if ok {
+			// Keep using the last known addresses.
+			return h.addrs, nil
+		}
+		return nil, err
+	}
+	t.mu.Lock()
+	h, ok = t.hosts[host]
+	changed := ok && !sameAddrs(h.addrs, addrs)
+	t.hosts[host] = &resolvedHost{addrs: addrs, resolved: time.Now()}
+	t.mu.Unlock()
+	if changed {
+		if ci, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+			ci.CloseIdleConnections()
+		}
+	}
+	return addrs, nil
+}
+
+// dialer returns a dial function that connects to
+// the addresses t resolves hosts to, with dial.
+func (t *dnsRefreshTransport) dialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := t.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		err = errors.New("no addresses for " + host)
+		for _, a := range addrs {
+			var conn net.Conn
+			conn, err = dial(ctx, network, net.JoinHostPort(a, port))
+			if err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+// sameAddrs reports whether a and b are the same addresses, in any order.
+func sameAddrs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
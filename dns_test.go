@@ -0,0 +1,87 @@
+package graphql_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+// idleClosingTransport counts the calls to CloseIdleConnections.
+type idleClosingTransport struct {
+	localRoundTripper
+	closed int
+}
+
+func (t *idleClosingTransport) CloseIdleConnections() { t.closed++ }
+
+func TestWithDNSRefresh(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"__typename": "Query"}}`)
+	})
+	var mu sync.Mutex
+	addrs := []string{"10.0.0.1", "10.0.0.2"}
+	resolve := func(ctx context.Context, host string) ([]string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return addrs, nil
+	}
+	transport := &idleClosingTransport{localRoundTripper: localRoundTripper{handler: mux}}
+	client := graphql.NewClient("https://graphql.example.com/graphql", &http.Client{Transport: transport}, graphql.WithDNSRefresh(0, resolve))
+
+	do := func() {
+		_, err := client.Do(context.Background(), "{__typename}", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	do()
+	mu.Lock()
+	addrs = []string{"10.0.0.2", "10.0.0.1"} // Same addresses.
+	mu.Unlock()
+	do()
+	if transport.closed != 0 {
+		t.Errorf("got %v closings of idle connections, want: 0", transport.closed)
+	}
+	mu.Lock()
+	addrs = []string{"10.0.0.3"} // Failover.
+	mu.Unlock()
+	do()
+	if transport.closed != 1 {
+		t.Errorf("got %v closings of idle connections, want: 1", transport.closed)
+	}
+}
+
+func TestWithDNSRefresh_resolver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"__typename": "Query"}}`)
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolve := func(ctx context.Context, name string) ([]string, error) {
+		if name != "graphql.invalid" {
+			t.Errorf("got host: %q, want: %q", name, "graphql.invalid")
+		}
+		return []string{host}, nil
+	}
+	client := graphql.NewClient("http://graphql.invalid:"+port, &http.Client{Transport: &http.Transport{}}, graphql.WithDNSRefresh(0, resolve))
+	_, err = client.Do(context.Background(), "{__typename}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,107 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Operation is a named operation of a document with several operations.
+type Operation struct {
+	Type string // "query", "mutation" or "subscription". Empty means "query".
+	Name string
+
+	// V is a pointer to struct that corresponds to the GraphQL schema,
+	// which the operation is derived from, and which Client.Execute
+	// populates with the response. Variables are its variables.
+	V         interface{}
+	Variables map[string]interface{}
+}
+
+// ConstructDocument constructs a document with the operations ops, which
+// must have distinct names, such as the documents graphical clients produce
+// and persisted-document workflows register. Named fragments spread in
+// several operations are defined once, at the end of the document.
+//
+// Client.Execute executes one of the operations of the document.
+func ConstructDocument(ops ...Operation) (string, error) {
+	var buf bytes.Buffer
+	var fragments fragmentDefinitions
+	names := make(map[string]bool)
+	for _, op := range ops {
+		if op.Name == "" {
+			return "", fmt.Errorf("operation of type %T has no name", op.V)
+		}
+		if names[op.Name] {
+			return "", fmt.Errorf("operation name %s is used more than once", op.Name)
+		}
+		names[op.Name] = true
+		err := checkOperation(op.V, op.Variables)
+		if err != nil {
+			return "", fmt.Errorf("operation %s: %w", op.Name, err)
+		}
+		t := op.Type
+		if t == "" {
+			t = "query"
+		}
+		io.WriteString(&buf, t+" "+op.Name)
+		if len(op.Variables) > 0 {
+			io.WriteString(&buf, "("+queryArguments(op.Variables)+")")
+		}
+		writeQuery(&buf, reflect.TypeOf(op.V), false, op.Variables, &fragments, nil, "")
+	}
+	fragments.writeTo(&buf)
+	return buf.String(), nil
+}
+
+// Execute executes the operation op of the document doc, such as one
+// constructed with ConstructDocument, sending doc in its entirety along
+// with the name of op, and populates the response into op.V.
+func (c *Client) Execute(ctx context.Context, doc string, op Operation, opts ...RequestOption) ([]DataError, error) {
+	if c.readableDocuments {
+		doc = Format(doc)
+	}
+	ctx, done := c.trackStats(ctx, op.Name, doc, time.Time{})
+	resp, err := c.do(ctx, doc, op.Variables, append(opts[:len(opts):len(opts)], WithOperationName(op.Name)))
+	if err == nil {
+		err = c.decodeData(ctx, resp, op.V, c.documentConfig(opts))
+	}
+	done(resp, err)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Errors, nil
+}
+
+// namedOperationType returns the type of the operation name
+// in the GraphQL document doc, if it has one.
+func namedOperationType(doc, name string) (string, bool) {
+	for _, t := range [...]string{"query", "mutation", "subscription"} {
+		for i := 0; ; {
+			j := strings.Index(doc[i:], t)
+			if j == -1 {
+				break
+			}
+			j += i
+			i = j + len(t)
+			if j > 0 && isNameChar(rune(doc[j-1])) {
+				continue
+			}
+			rest := strings.TrimLeftFunc(doc[i:], unicode.IsSpace)
+			if len(rest) < len(doc[i:]) && strings.HasPrefix(rest, name) && (len(rest) == len(name) || !isNameChar(rune(rest[len(name)]))) {
+				return t, true
+			}
+		}
+	}
+	return "", false
+}
+
+// isNameChar reports whether r may be part of a GraphQL name.
+func isNameChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,79 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+type repositoryFields struct {
+	Name       graphql.String
+	StarsCount graphql.Int
+}
+
+func TestClient_Execute(t *testing.T) {
+	var getRepository struct {
+		Repository struct {
+			repositoryFields `graphql-fragment:"Repository"`
+		} `graphql:"repository(name: $name)"`
+	}
+	var addStar struct {
+		AddStar struct {
+			Repository struct {
+				repositoryFields `graphql-fragment:"Repository"`
+			}
+		} `graphql:"addStar(id: $id)"`
+	}
+	get := graphql.Operation{Name: "GetRepository", V: &getRepository, Variables: map[string]interface{}{"name": graphql.String("graphql")}}
+	add := graphql.Operation{Type: "mutation", Name: "AddStar", V: &addStar, Variables: map[string]interface{}{"id": graphql.ID("1")}}
+	doc, err := graphql.ConstructDocument(get, add)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `query GetRepository($name:String!){repository(name: $name){...repositoryFields}}mutation AddStar($id:ID!){addStar(id: $id){repository{...repositoryFields}}}fragment repositoryFields on Repository{name,starsCount}`; doc != want {
+		t.Errorf("got document:\n%v\nwant:\n%v", doc, want)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":`+quoteJSON(doc)+`,"operationName":"AddStar","variables":{"id":"1"}}`+"\n"; got != want {
+			t.Errorf("got body:\n%v\nwant:\n%v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"addStar": {"repository": {"name": "graphql", "starsCount": 42}}}}`)
+	})
+	// The operation executed is reported, rather than the first one of the document.
+	var routed []string
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithLatencyFunc(func(operation string, _ time.Duration, _ graphql.Outcome) {
+			routed = append(routed, operation)
+		}))
+	_, err = client.Execute(context.Background(), doc, add)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := addStar.AddStar.Repository.StarsCount, graphql.Int(42); got != want {
+		t.Errorf("got stars: %v, want: %v", got, want)
+	}
+	if len(routed) != 1 || routed[0] != "AddStar" {
+		t.Errorf("got latency reported for operations: %v, want: [AddStar]", routed)
+	}
+
+	_, err = graphql.ConstructDocument(get, get)
+	if err == nil {
+		t.Error("got no error for duplicate operation names")
+	}
+}
+
+func quoteJSON(s string) string {
+	b, err := json.Marshal(s)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,297 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// HTTPStatusError is returned when the server responds
+// with a non-200 OK status code.
+type HTTPStatusError struct {
+	StatusCode int         // E.g., 502.
+	Status     string      // E.g., "502 Bad Gateway".
+	Header     http.Header // Response header.
+
+	// Body is the response body. Bodies that aren't JSON, such as HTML error
+	// pages served by proxies, are truncated, which Truncated reports.
+	Body      []byte
+	Truncated bool
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("non-200 OK status code: %v body: %s", e.Status, quoteBody(e.Body, e.Truncated))
+}
+
+// ContentTypeError is returned when the server responds with a media type
+// other than JSON, which the response can't be decoded from.
+type ContentTypeError struct {
+	ContentType string // Content-Type header of the response.
+
+	// Body is the response body, truncated if it was too long,
+	// which Truncated reports.
+	Body      []byte
+	Truncated bool
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("unexpected response Content-Type %q, want a JSON media type; body: %s", e.ContentType, quoteBody(e.Body, e.Truncated))
+}
+
+// NetworkError is returned when a request can't be sent to the server,
+// or its response can't be received, such as when the connection is
+// refused or reset. It wraps the error of the transport.
+type NetworkError struct {
+	Err error
+}
+
+func (e *NetworkError) Error() string { return e.Err.Error() }
+func (e *NetworkError) Unwrap() error { return e.Err }
+
+// DecodeError is returned when a response is received but can't be
+// decoded, such as when its body is malformed JSON, or its data doesn't
+// match the query struct. It wraps the error of the decoder.
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string { return e.Err.Error() }
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// responseError returns the error to return for err, which occurred
+// reading and decoding a response body in ctx. readErr is the error that
+// reading the body failed with, if any, which makes err a *NetworkError
+// rather than a *DecodeError. Errors of the request's own limits and
+// cancellation are returned as is.
+func responseError(ctx context.Context, readErr, err error) error {
+	switch {
+	case readErr == nil:
+		return &DecodeError{Err: err}
+	case ctx.Err() != nil, errors.Is(readErr, ErrResponseTooLarge), errors.Is(readErr, ErrResponseStalled):
+		return err
+	}
+	return &NetworkError{Err: err}
+}
+
+// errRecorder is a reader recording the first error, other than io.EOF,
+// that reads from r fail with.
+type errRecorder struct {
+	r   io.Reader
+	err error
+}
+
+func (r *errRecorder) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// maxNonJSONBody is how much of a response body that isn't JSON is kept
+// in errors. It's enough to identify a proxy error page.
+const maxNonJSONBody = 512
+
+// readTruncated reads at most n bytes from r, and reports whether
+// there was more to read.
+func readTruncated(r io.Reader, n int64) (body []byte, truncated bool) {
+	body, _ = ioutil.ReadAll(io.LimitReader(r, n+1))
+	if int64(len(body)) > n {
+		return body[:n], true
+	}
+	return body, false
+}
+
+// quoteBody returns a quoted body for use in error messages.
+func quoteBody(body []byte, truncated bool) string {
+	if truncated {
+		return fmt.Sprintf("%q...", body)
+	}
+	return fmt.Sprintf("%q", body)
+}
+
+// dataErrors is the "errors" entry of a response. Unlike []DataError,
+// it also accepts the non-standard shapes some servers use for it:
+// a single error object, or a bare string.
+type dataErrors []DataError
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (es *dataErrors) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*es = nil
+		return nil
+	case len(data) > 0 && data[0] == '[':
+		var list []DataError
+		err := json.Unmarshal(data, &list)
+		if err != nil {
+			return err
+		}
+		*es = list
+		return nil
+	default:
+		var e DataError
+		err := e.UnmarshalJSON(data)
+		if err != nil {
+			return err
+		}
+		*es = dataErrors{e}
+		return nil
+	}
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// It salvages what it can from non-standard error shapes found in the wild,
+// rather than failing to decode the entire response: an error may be a bare
+// string, locations may hold numbers encoded as strings, and malformed
+// paths and extensions are dropped. If an error has no string message,
+// the error's JSON text is used as the message, so that it's not lost.
+func (e *DataError) UnmarshalJSON(data []byte) error {
+	var message string
+	if json.Unmarshal(data, &message) == nil {
+		*e = DataError{Message: message}
+		return nil
+	}
+	var raw struct {
+		Message    json.RawMessage
+		Locations  json.RawMessage
+		Path       []json.RawMessage
+		Extensions json.RawMessage
+	}
+	if json.Unmarshal(data, &raw) != nil || json.Unmarshal(raw.Message, &message) != nil {
+		*e = DataError{Message: string(bytes.TrimSpace(data))}
+		return nil
+	}
+	*e = DataError{Message: message}
+	json.Unmarshal(raw.Extensions, &e.Extensions) // Extensions that aren't an object are dropped.
+	var locations []struct {
+		Line   flexInt
+		Column flexInt
+	}
+	if json.Unmarshal(raw.Locations, &locations) == nil {
+		for _, l := range locations {
+			e.Locations = append(e.Locations, struct {
+				Line   int
+				Column int
+			}{int(l.Line), int(l.Column)})
+		}
+	}
+	for _, p := range raw.Path {
+		var key string
+		var index int
+		if json.Unmarshal(p, &key) == nil {
+			e.Path = append(e.Path, key)
+		} else if json.Unmarshal(p, &index) == nil {
+			e.Path = append(e.Path, index)
+		} else {
+			e.Path = nil
+			break
+		}
+	}
+	return nil
+}
+
+// DataErrors is a list of GraphQL errors, such as the errors Query returns,
+// as an error, for callers that return them along with other errors.
+// errors.As finds the first of its errors when the target is a *DataError.
+type DataErrors []DataError
+
+// Error implements error interface.
+func (es DataErrors) Error() string {
+	switch len(es) {
+	case 0:
+		return "no errors"
+	case 1:
+		return es[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", es[0].Error(), len(es)-1)
+}
+
+// As sets *target to the first error of es, if target is a *DataError
+// and es isn't empty.
+func (es DataErrors) As(target interface{}) bool {
+	t, ok := target.(*DataError)
+	if !ok || len(es) == 0 {
+		return false
+	}
+	*t = es[0]
+	return true
+}
+
+// Unwrap returns the errors of es, so that errors.Is and errors.As
+// consider each of them. It's only used by Go 1.20 and later; earlier
+// versions don't unwrap lists of errors, and errors.As finds only
+// the first error, through the As method.
+func (es DataErrors) Unwrap() []error {
+	errs := make([]error, len(es))
+	for i, e := range es {
+		errs[i] = e
+	}
+	return errs
+}
+
+// HasCode reports whether es has an error with the code code.
+func (es DataErrors) HasCode(code string) bool {
+	for _, e := range es {
+		if e.Code() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// WithFatalErrorCodes makes operations whose responses have GraphQL errors
+// with any of the codes codes, such as "UNAUTHENTICATED", fail with the
+// errors, as DataErrors, rather than returning them along with the data.
+//
+// By default, as the GraphQL specification allows, the data of responses
+// with errors is decoded even though it's partial, such as when some
+// fields failed or the server gave up on them at a deadline, and the
+// errors are returned. Failing operations aren't decoded into, unless
+// WithStreamingDecode had them decoded as they were received.
+func WithFatalErrorCodes(codes ...string) ClientOption {
+	return func(c *Client) {
+		if c.fatalErrorCodes == nil {
+			c.fatalErrorCodes = make(map[string]bool)
+		}
+		for _, code := range codes {
+			c.fatalErrorCodes[code] = true
+		}
+	}
+}
+
+// fatalErrors returns the errors of resp as DataErrors
+// if any of them has a code that fails operations.
+func (c *Client) fatalErrors(resp *Response) error {
+	for _, e := range resp.Errors {
+		if c.fatalErrorCodes[e.Code()] {
+			return DataErrors(resp.Errors)
+		}
+	}
+	return nil
+}
+
+// flexInt is an integer that may be encoded as a JSON number or string.
+type flexInt int
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (n *flexInt) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		data = []byte(s)
+	}
+	i, err := strconv.Atoi(string(data))
+	if err != nil {
+		return err
+	}
+	*n = flexInt(i)
+	return nil
+}
@@ -0,0 +1,261 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestClient_Do_nonStandardErrors(t *testing.T) {
+	type location = struct {
+		Line   int
+		Column int
+	}
+	tests := []struct {
+		in   string
+		want []graphql.DataError
+	}{
+		{
+			in:   `"errors": "rate limit exceeded"`,
+			want: []graphql.DataError{{Message: "rate limit exceeded"}},
+		},
+		{
+			in:   `"errors": {"message": "forbidden"}`,
+			want: []graphql.DataError{{Message: "forbidden"}},
+		},
+		{
+			in: `"errors": [{"message": "bad", "locations": [{"line": "3", "column": 7}]}, "worse"]`,
+			want: []graphql.DataError{
+				{Message: "bad", Locations: []location{{Line: 3, Column: 7}}},
+				{Message: "worse"},
+			},
+		},
+		{
+			in:   `"errors": [{"message": "bad", "locations": {"line": 1}}]`,
+			want: []graphql.DataError{{Message: "bad"}},
+		},
+		{
+			in:   `"errors": [{"message": "not found", "path": ["repository", "issues", 2]}]`,
+			want: []graphql.DataError{{Message: "not found", Path: []interface{}{"repository", "issues", 2}}},
+		},
+		{
+			in:   `"errors": [{"message": "not found", "path": [{"bad": true}]}]`,
+			want: []graphql.DataError{{Message: "not found"}},
+		},
+		{
+			in:   `"errors": [{"message": "slow down", "extensions": {"code": "RATE_LIMITED"}}]`,
+			want: []graphql.DataError{{Message: "slow down", Extensions: map[string]json.RawMessage{"code": json.RawMessage(`"RATE_LIMITED"`)}}},
+		},
+		{
+			in:   `"errors": [{"message": "slow down", "extensions": "RATE_LIMITED"}]`,
+			want: []graphql.DataError{{Message: "slow down"}},
+		},
+		{
+			in:   `"errors": [{"code": 500}]`,
+			want: []graphql.DataError{{Message: `{"code": 500}`}},
+		},
+		{
+			in:   `"errors": []`,
+			want: nil,
+		},
+		{
+			in:   `"errors": null`,
+			want: nil,
+		},
+	}
+	for _, tc := range tests {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"data": null, `+tc.in+`}`)
+		})
+		client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+		resp, err := client.Do(context.Background(), "{viewer{login}}", nil)
+		if err != nil {
+			t.Errorf("%s: got error: %v", tc.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(resp.Errors, tc.want) {
+			t.Errorf("%s:\ngot:  %+v\nwant: %+v", tc.in, resp.Errors, tc.want)
+		}
+	}
+}
+
+func TestClient_Query_nonJSONErrorBody(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusBadGateway)
+		mustWrite(w, "<html><body><h1>502 Bad Gateway</h1>"+strings.Repeat("<p>padding</p>", 1000)+"</body></html>")
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil)
+	var statusErr *graphql.HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("got error: %v, want: *graphql.HTTPStatusError", err)
+	}
+	if got, want := statusErr.StatusCode, http.StatusBadGateway; got != want {
+		t.Errorf("got StatusCode: %v, want: %v", got, want)
+	}
+	if !statusErr.Truncated {
+		t.Error("got Truncated: false, want: true")
+	}
+	if got, want := len(statusErr.Body), 512; got != want {
+		t.Errorf("got len(Body): %v, want: %v", got, want)
+	}
+	if !strings.HasPrefix(err.Error(), `non-200 OK status code: 502 Bad Gateway body: "<html><body><h1>502 Bad Gateway</h1>`) || !strings.HasSuffix(err.Error(), `"...`) {
+		t.Errorf("got error: %v", err)
+	}
+}
+
+func TestClient_Query_jsonErrorBody(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/graphql-response+json")
+		w.WriteHeader(http.StatusBadRequest)
+		mustWrite(w, `{"errors": [{"message": "Syntax Error"}]}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil)
+	var statusErr *graphql.HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("got error: %v, want: *graphql.HTTPStatusError", err)
+	}
+	if got, want := string(statusErr.Body), `{"errors": [{"message": "Syntax Error"}]}`; got != want || statusErr.Truncated {
+		t.Errorf("got Body: %s, Truncated: %v, want: %s, false", got, statusErr.Truncated, want)
+	}
+}
+
+func TestDataErrors(t *testing.T) {
+	errs := graphql.DataErrors{
+		{Message: "not found", Extensions: map[string]json.RawMessage{"code": json.RawMessage(`"NOT_FOUND"`)}},
+		{Message: "forbidden", Extensions: map[string]json.RawMessage{"code": json.RawMessage(`"FORBIDDEN"`)}},
+		{Message: "no code", Extensions: map[string]json.RawMessage{"code": json.RawMessage(`500`)}},
+	}
+	for i, want := range []string{"NOT_FOUND", "FORBIDDEN", ""} {
+		if got := errs[i].Code(); got != want {
+			t.Errorf("got errs[%d].Code(): %q, want: %q", i, got, want)
+		}
+	}
+	if !errs.HasCode("FORBIDDEN") || errs.HasCode("INTERNAL") {
+		t.Error("got wrong HasCode results")
+	}
+	if got, want := errs.Error(), "not found (and 2 more errors)"; got != want {
+		t.Errorf("got error: %q, want: %q", got, want)
+	}
+
+	err := fmt.Errorf("query failed: %w", errs)
+	var e graphql.DataError
+	if !errors.As(err, &e) {
+		t.Fatal("errors.As didn't find a DataError")
+	}
+	if got, want := e.Code(), "NOT_FOUND"; got != want {
+		t.Errorf("got code: %q, want: %q", got, want)
+	}
+}
+
+func TestWithFatalErrorCodes(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		code := "TIMEOUT"
+		if req.URL.Query().Get("fatal") != "" {
+			code = "UNAUTHENTICATED"
+		}
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher", "bio": null}}, "errors": [{"message": "failed", "path": ["viewer", "bio"], "extensions": {"code": "`+code+`"}}]}`)
+	})
+	type query struct {
+		Viewer struct {
+			Login graphql.String
+			Bio   *graphql.String
+		}
+	}
+
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithFatalErrorCodes("UNAUTHENTICATED", "FORBIDDEN"))
+	var q query
+	dataErrors, err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dataErrors) != 1 || dataErrors[0].Code() != "TIMEOUT" {
+		t.Errorf("got errors: %+v, want a TIMEOUT one", dataErrors)
+	}
+	if q.Viewer.Login != "gopher" || q.Viewer.Bio != nil {
+		t.Errorf("got viewer: %+v, want the partial data", q.Viewer)
+	}
+
+	client = graphql.NewClient("/graphql?fatal=1", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithFatalErrorCodes("UNAUTHENTICATED", "FORBIDDEN"))
+	q = query{}
+	dataErrors, err = client.Query(context.Background(), &q, nil)
+	var errs graphql.DataErrors
+	if !errors.As(err, &errs) || !errs.HasCode("UNAUTHENTICATED") || dataErrors != nil {
+		t.Fatalf("got errors: %+v, %v, want UNAUTHENTICATED DataErrors", dataErrors, err)
+	}
+	if q.Viewer.Login != "" {
+		t.Errorf("got viewer: %+v, want nothing decoded", q.Viewer)
+	}
+}
+
+// failingRoundTripper is an http.RoundTripper failing every request with err.
+type failingRoundTripper struct{ err error }
+
+func (f failingRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, f.err
+}
+
+func TestClient_Query_transportErrors(t *testing.T) {
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+
+	errRefused := errors.New("connection refused")
+	client := graphql.NewClient("/graphql", &http.Client{Transport: failingRoundTripper{err: errRefused}})
+	_, err := client.Query(context.Background(), &q, nil)
+	var networkErr *graphql.NetworkError
+	if !errors.As(err, &networkErr) || !errors.Is(err, errRefused) {
+		t.Errorf("got error: %#v, want a *graphql.NetworkError wrapping the transport's", err)
+	}
+
+	for _, body := range []string{
+		`{"data": {"viewer": {"login": "gopher"`,
+		`{"data": {"viewer": {"login": 42}}}`,
+	} {
+		body := body
+		mux := http.NewServeMux()
+		mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, body)
+		})
+		client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+		_, err := client.Query(context.Background(), &q, nil)
+		var decodeErr *graphql.DecodeError
+		if !errors.As(err, &decodeErr) {
+			t.Errorf("%s: got error: %#v, want a *graphql.DecodeError", body, err)
+		}
+		if errors.As(err, &networkErr) {
+			t.Errorf("%s: got a *graphql.NetworkError", body)
+		}
+	}
+}
@@ -73,7 +73,7 @@ func run() error {
 	variables := map[string]interface{}{
 		"characterID": graphql.ID("1003"),
 	}
-	err = client.Query(context.Background(), &q, variables)
+	_, err = client.Query(context.Background(), &q, variables)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,199 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// NewMultiClient creates a GraphQL client targeting several replicas of
+// a GraphQL server, at the URLs urls, which it distributes requests across
+// in turn. If a query to a replica fails because of a network error,
+// a 429 Too Many Requests status code, or a 5xx status code, it's sent to
+// the next replica right away, unless the response has a Retry-After
+// header or a retry hint, in which case it's left to the client's retry
+// policy. Failing over counts against the retry budget of WithRetryBudget
+// and is subject to WithBeforeRetry. Replicas whose requests failed several
+// times in a row are marked down, and aren't sent requests for a while
+// unless every replica is down; see WithFailover.
+//
+// Mutations fail over only if they couldn't be sent, such as because
+// the connection was refused, since a mutation that failed otherwise may
+// have been executed by the replica it was sent to; see
+// WithMutationFailover.
+//
+// Subscriptions, and the client's schema, use the first URL.
+// If httpClient is nil, then http.DefaultClient is used.
+// NewMultiClient panics if urls is empty.
+func NewMultiClient(urls []string, httpClient *http.Client, opts ...ClientOption) *Client {
+	if len(urls) == 0 {
+		panic("graphql: NewMultiClient: no URLs")
+	}
+	set := &endpointSet{maxFailures: 3, cooldown: 30 * time.Second}
+	for _, url := range urls {
+		set.endpoints = append(set.endpoints, endpointHealth{url: url})
+	}
+	return NewClient(urls[0], httpClient, append([]ClientOption{func(c *Client) { c.endpoints = set }}, opts...)...)
+}
+
+// WithFailover makes a client created with NewMultiClient mark a replica
+// down once maxFailures requests to it failed in a row, and send it
+// requests again after cooldown. The defaults are 3 and 30 seconds.
+func WithFailover(maxFailures int, cooldown time.Duration) ClientOption {
+	return func(c *Client) {
+		if c.endpoints != nil {
+			c.endpoints.maxFailures = maxFailures
+			c.endpoints.cooldown = cooldown
+		}
+	}
+}
+
+// WithMutationFailover makes a client created with NewMultiClient fail
+// over mutations like queries, for servers whose mutations are idempotent.
+func WithMutationFailover() ClientOption {
+	return func(c *Client) {
+		if c.endpoints != nil {
+			c.endpoints.mutations = true
+		}
+	}
+}
+
+// endpointSet holds the replicas of a client created with NewMultiClient.
+type endpointSet struct {
+	maxFailures int           // Failures in a row after which a replica is marked down.
+	cooldown    time.Duration // Time during which a replica marked down isn't sent requests.
+	mutations   bool          // Whether mutations fail over like queries.
+
+	mu        sync.Mutex
+	next      int // Index of the replica to send the next request to first.
+	endpoints []endpointHealth
+}
+
+// endpointHealth is the health of a replica.
+type endpointHealth struct {
+	url       string
+	failures  int       // Requests that failed in a row.
+	downUntil time.Time // When the replica is no longer considered down.
+}
+
+// order returns the indices of the replicas to try a request on, in order:
+// the ones that are up, in turn from the next one, then the ones that are
+// down.
+func (s *endpointSet) order() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	start := s.next
+	s.next = (s.next + 1) % len(s.endpoints)
+	var up, down []int
+	for i := range s.endpoints {
+		j := (start + i) % len(s.endpoints)
+		if now.Before(s.endpoints[j].downUntil) {
+			down = append(down, j)
+		} else {
+			up = append(up, j)
+		}
+	}
+	return append(up, down...)
+}
+
+// report records the outcome of a request to the replica i.
+func (s *endpointSet) report(i int, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := &s.endpoints[i]
+	if !failed {
+		e.failures = 0
+		e.downUntil = time.Time{}
+		return
+	}
+	e.failures++
+	if e.failures >= s.maxFailures {
+		e.downUntil = time.Now().Add(s.cooldown)
+	}
+}
+
+// failover calls send, which sends the request in made with cfg, once for
+// each replica of the client, in the order endpointSet.order returns, until
+// it doesn't fail transiently or failsOver reports it's not to be sent to
+// the next replica. It just calls send if the client has a single URL.
+func (c *Client) failover(ctx context.Context, in requestBody, cfg *requestConfig, send func() (*Response, error)) (*Response, error) {
+	if c.endpoints == nil {
+		return send()
+	}
+	var resp *Response
+	var err error
+	order := c.endpoints.order()
+	for n, i := range order {
+		cfg.endpointURL = c.endpoints.endpoints[i].url
+		resp, err = send()
+		failed := isTransient(ctx, err)
+		c.endpoints.report(i, failed)
+		if !failed || n == len(order)-1 || !c.failsOver(ctx, in, resp, err, n+1) {
+			break
+		}
+	}
+	cfg.endpointURL = ""
+	return resp, err
+}
+
+// failsOver reports whether the request in, whose attempt number n failed
+// transiently with resp and err, is to be sent to the next replica.
+// It waits for as long as the BeforeRetry func asks, if any.
+func (c *Client) failsOver(ctx context.Context, in requestBody, resp *Response, err error, n int) bool {
+	if in.operationType() != "query" && !c.endpoints.mutations && !unsent(err) {
+		return false
+	}
+	if _, hinted := c.retry.hint(resp, err); hinted {
+		return false
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		if _, ok := ParseRetryAfter(statusErr.Header); ok {
+			return false
+		}
+	}
+	var wait time.Duration
+	if c.retry.beforeRetry != nil {
+		var retry bool
+		wait, retry = c.retry.beforeRetry(RetryInfo{
+			Operation:     in.operationName(),
+			OperationType: in.operationType(),
+			Attempt:       n,
+			Response:      resp,
+			Err:           err,
+			Failover:      true,
+		})
+		if !retry {
+			return false
+		}
+	}
+	if c.retry.budget != nil && !c.retry.budget.take() {
+		return false
+	}
+	if wait <= 0 {
+		return true
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// unsent reports whether err is a failure to connect to the server,
+// which happens before the request is sent.
+func unsent(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
@@ -0,0 +1,191 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestNewMultiClient(t *testing.T) {
+	hits := make(map[string]int)
+	mux := http.NewServeMux()
+	for _, path := range []string{"/a", "/b", "/down"} {
+		path := path
+		mux.HandleFunc(path, func(w http.ResponseWriter, req *http.Request) {
+			hits[path]++
+			if path == "/down" {
+				http.Error(w, "bad gateway", http.StatusBadGateway)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+		})
+	}
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+
+	client := graphql.NewMultiClient([]string{"/a", "/b"}, &http.Client{Transport: localRoundTripper{handler: mux}})
+	for i := 0; i < 4; i++ {
+		_, err := client.Query(context.Background(), &q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if hits["/a"] != 2 || hits["/b"] != 2 {
+		t.Errorf("got hits: %v, want requests distributed evenly", hits)
+	}
+
+	hits = make(map[string]int)
+	client = graphql.NewMultiClient([]string{"/down", "/b"}, &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithFailover(2, time.Hour))
+	for i := 0; i < 6; i++ {
+		_, err := client.Query(context.Background(), &q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.Viewer.Login != "gopher" {
+			t.Errorf("got login: %q, want: gopher", q.Viewer.Login)
+		}
+	}
+	// The replica that's down is tried first by the first and third
+	// queries, and then marked down.
+	if hits["/down"] != 2 || hits["/b"] != 6 {
+		t.Errorf("got hits: %v, want 2 on /down and 6 on /b", hits)
+	}
+
+	client = graphql.NewMultiClient([]string{"/down"}, &http.Client{Transport: localRoundTripper{handler: mux}})
+	_, err := client.Query(context.Background(), &q, nil)
+	if err == nil {
+		t.Error("got no error when every replica is down")
+	}
+}
+
+// refusingRoundTripper refuses connections to /refused, and sends other
+// requests to handler.
+type refusingRoundTripper struct {
+	handler http.Handler
+}
+
+func (r refusingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path == "/refused" {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	}
+	return localRoundTripper{handler: r.handler}.RoundTrip(req)
+}
+
+func TestNewMultiClient_mutations(t *testing.T) {
+	hits := make(map[string]int)
+	mux := http.NewServeMux()
+	for _, path := range []string{"/ok", "/down"} {
+		path := path
+		mux.HandleFunc(path, func(w http.ResponseWriter, req *http.Request) {
+			hits[path]++
+			if path == "/down" {
+				http.Error(w, "bad gateway", http.StatusBadGateway)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"data": {"addStar": {"starrable": {"id": "1"}}}}`)
+		})
+	}
+	var m struct {
+		AddStar struct {
+			Starrable struct {
+				ID graphql.ID
+			}
+		} `graphql:"addStar(input: {starrableId: \"1\"})"`
+	}
+	httpClient := &http.Client{Transport: refusingRoundTripper{handler: mux}}
+
+	// A mutation that may have been executed isn't sent again.
+	client := graphql.NewMultiClient([]string{"/down", "/ok"}, httpClient)
+	_, err := client.Mutate(context.Background(), &m, nil)
+	if err == nil {
+		t.Error("got no error from a mutation failing with a 502")
+	}
+	if hits["/down"] != 1 || hits["/ok"] != 0 {
+		t.Errorf("got hits: %v, want 1 on /down only", hits)
+	}
+
+	// A mutation that couldn't be sent is.
+	hits = make(map[string]int)
+	client = graphql.NewMultiClient([]string{"/refused", "/ok"}, httpClient)
+	_, err = client.Mutate(context.Background(), &m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits["/ok"] != 1 {
+		t.Errorf("got hits: %v, want 1 on /ok", hits)
+	}
+
+	hits = make(map[string]int)
+	client = graphql.NewMultiClient([]string{"/down", "/ok"}, httpClient, graphql.WithMutationFailover())
+	_, err = client.Mutate(context.Background(), &m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits["/down"] != 1 || hits["/ok"] != 1 {
+		t.Errorf("got hits: %v, want 1 on /down and 1 on /ok", hits)
+	}
+}
+
+func TestNewMultiClient_retryPolicy(t *testing.T) {
+	hits := make(map[string]int)
+	mux := http.NewServeMux()
+	for _, path := range []string{"/ok", "/busy", "/down"} {
+		path := path
+		mux.HandleFunc(path, func(w http.ResponseWriter, req *http.Request) {
+			hits[path]++
+			switch path {
+			case "/busy":
+				w.Header().Set("Retry-After", "60")
+				http.Error(w, "too many requests", http.StatusTooManyRequests)
+			case "/down":
+				http.Error(w, "bad gateway", http.StatusBadGateway)
+			default:
+				w.Header().Set("Content-Type", "application/json")
+				mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+			}
+		})
+	}
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	httpClient := &http.Client{Transport: localRoundTripper{handler: mux}}
+
+	// A response asking to retry later is left to the retry policy,
+	// which doesn't retry by default.
+	client := graphql.NewMultiClient([]string{"/busy", "/ok"}, httpClient)
+	_, err := client.Query(context.Background(), &q, nil)
+	if err == nil {
+		t.Error("got no error from a query failing with a 429 and Retry-After")
+	}
+	if hits["/ok"] != 0 {
+		t.Errorf("got hits: %v, want none on /ok", hits)
+	}
+
+	// Failing over is subject to WithBeforeRetry.
+	var infos []graphql.RetryInfo
+	client = graphql.NewMultiClient([]string{"/down", "/ok"}, httpClient,
+		graphql.WithBeforeRetry(func(info graphql.RetryInfo) (time.Duration, bool) {
+			infos = append(infos, info)
+			return 0, false
+		}))
+	_, err = client.Query(context.Background(), &q, nil)
+	if err == nil {
+		t.Error("got no error from a query whose failover was vetoed")
+	}
+	if len(infos) != 1 || !infos[0].Failover || infos[0].Attempt != 1 {
+		t.Errorf("got retry infos: %+v, want one failover", infos)
+	}
+}
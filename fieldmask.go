@@ -0,0 +1,140 @@
+package graphql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WithFieldMask makes Query, Mutate and Prepare select only the fields of
+// the query struct at the response paths paths, so that a single struct
+// can serve several uses without selecting the fields each of them doesn't
+// need. Fields that aren't selected are left zero-valued.
+//
+// Response paths are the response keys leading to fields, separated by
+// dots, as for WithDecodeHook. A path selects the field at it with all its
+// subfields, along with the fields leading to it. E.g., given:
+//
+//	var q struct {
+//		Repository struct {
+//			Name   graphql.String
+//			Issues struct {
+//				Nodes []struct {
+//					Title graphql.String
+//					Body  graphql.String
+//				}
+//			} `graphql:"issues(first: 10)"`
+//		} `graphql:"repository(owner: \"octocat\", name: \"Hello-World\")"`
+//	}
+//
+// the mask "repository.name", "repository.issues.nodes.title" selects:
+//
+//	{repository(owner: "octocat", name: "Hello-World"){name,issues(first: 10){nodes{title}}}}
+//
+// The fields of inline fragments and embedded structs are addressed as
+// fields of the struct they're in. It's an error for a path not to match
+// any field, or for the mask to leave out a required field.
+func WithFieldMask(paths ...string) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.fieldMask = append(cfg.fieldMask[:len(cfg.fieldMask):len(cfg.fieldMask)], paths...)
+	}
+}
+
+// fieldMask is the field mask of a document being constructed,
+// at the response path of a selection set.
+type fieldMask struct {
+	*maskPaths
+	path string // Response path of the selection set, "" for the operation.
+}
+
+// maskPaths is the set of response paths of a field mask, and
+// what constructing a document with it found out about them.
+type maskPaths struct {
+	paths     map[string]bool // Paths selected with their subfields.
+	ancestors map[string]bool // Paths leading to paths.
+	matched   map[string]bool // Paths of paths that matched a field.
+	required  []string        // Paths of required fields left out.
+}
+
+// newFieldMask returns the field mask selecting paths,
+// or nil if paths is nil, which selects every field.
+func newFieldMask(paths []string) *fieldMask {
+	if paths == nil {
+		return nil
+	}
+	m := &maskPaths{
+		paths:     make(map[string]bool),
+		ancestors: make(map[string]bool),
+		matched:   make(map[string]bool),
+	}
+	for _, path := range paths {
+		m.paths[path] = true
+		for i := strings.LastIndex(path, "."); i != -1; i = strings.LastIndex(path[:i], ".") {
+			m.ancestors[path[:i]] = true
+		}
+	}
+	return &fieldMask{maskPaths: m}
+}
+
+// field reports whether the mask selects the field with the response key
+// key, which is required if required is true, and returns the mask of its
+// subfields if so. The returned mask is nil if they're all selected.
+func (m *fieldMask) field(key string, required bool) (*fieldMask, bool) {
+	path := joinPath(m.path, key)
+	switch {
+	case m.paths[path]:
+		m.matched[path] = true
+		return nil, true
+	case m.ancestors[path]:
+		return &fieldMask{maskPaths: m.maskPaths, path: path}, true
+	}
+	if required {
+		m.required = append(m.required, path)
+	}
+	return nil, false
+}
+
+// key returns a key identifying the paths of the mask,
+// for reusing documents constructed with it.
+func (m *fieldMask) key() string {
+	paths := make([]string, 0, len(m.paths))
+	for path := range m.paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return "mask:" + strings.Join(paths, ",")
+}
+
+// err returns an error if a path of the mask didn't match any field once
+// a document was constructed with it, or it left out a required field.
+// It returns nil for a nil mask.
+func (m *fieldMask) err() error {
+	if m == nil {
+		return nil
+	}
+	if len(m.required) > 0 {
+		return fmt.Errorf("field mask leaves out required field %s", m.required[0])
+	}
+	for _, path := range sortedNames(m.paths) {
+		if !m.matchedWithin(path) {
+			return fmt.Errorf("field mask path %s matches no field", path)
+		}
+	}
+	return nil
+}
+
+// matchedWithin reports whether path, or a path it's within, matched a
+// field. Paths within matched ones aren't walked, since their fields are
+// selected whole.
+func (m *fieldMask) matchedWithin(path string) bool {
+	for {
+		if m.matched[path] {
+			return true
+		}
+		i := strings.LastIndex(path, ".")
+		if i == -1 {
+			return false
+		}
+		path = path[:i]
+	}
+}
@@ -0,0 +1,136 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestWithFieldMask(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query($owner:String!){repository(owner: $owner){name,issues(first: 10){nodes{title}}}}","variables":{"owner":"octocat"}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"repository": {"name": "Hello-World", "issues": {"nodes": [{"title": "Bug"}]}}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type author struct {
+		Login graphql.String
+	}
+	var q struct {
+		Repository struct {
+			Name   graphql.String
+			Issues struct {
+				Nodes []struct {
+					Title  graphql.String
+					Body   graphql.String
+					Author author
+				}
+			} `graphql:"issues(first: 10)"`
+			Viewer author `graphql:"viewer(as: $viewer)"`
+		} `graphql:"repository(owner: $owner)"`
+	}
+	variables := map[string]interface{}{
+		"owner":  graphql.String("octocat"),
+		"viewer": graphql.String("gopher"),
+	}
+	_, err := client.Query(context.Background(), &q, variables, graphql.WithFieldMask("repository.name", "repository.issues.nodes.title"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Repository.Name != "Hello-World" || len(q.Repository.Issues.Nodes) != 1 || q.Repository.Issues.Nodes[0].Title != "Bug" {
+		t.Errorf("got repository: %+v", q.Repository)
+	}
+
+	op, err := client.Prepare(context.Background(), graphql.Operation{V: &q, Variables: variables},
+		graphql.WithFieldMask("repository.issues.nodes.author", "repository.viewer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := op.Query, `query($owner:String!$viewer:String!){repository(owner: $owner){issues(first: 10){nodes{author{login}}},viewer(as: $viewer){login}}}`; got != want {
+		t.Errorf("got query: %q, want: %q", got, want)
+	}
+}
+
+func TestWithFieldMask_fragments(t *testing.T) {
+	type ActorFields struct {
+		Login graphql.String
+		URL   graphql.String
+	}
+	var q struct {
+		Node struct {
+			Typename graphql.String `graphql:"__typename"`
+			User     struct {
+				Bio graphql.String
+			} `graphql:"... on User"`
+			ActorFields `graphql-fragment:"User"`
+		} `graphql:"node(id: \"1\")"`
+	}
+	client := graphql.NewClient("/graphql", nil)
+	op, err := client.Prepare(context.Background(), graphql.Operation{V: &q}, graphql.WithFieldMask("node.login"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := op.Query, `{node(id: "1"){... on User{login}}}`; got != want {
+		t.Errorf("got query: %q, want: %q", got, want)
+	}
+
+	// Documents constructed without a mask are unaffected.
+	op, err = client.Prepare(context.Background(), graphql.Operation{V: &q})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := op.Query, `{node(id: "1"){__typename,... on User{bio},...ActorFields}}fragment ActorFields on User{login,url}`; got != want {
+		t.Errorf("got query: %q, want: %q", got, want)
+	}
+}
+
+func TestWithFieldMask_emptyObjectArguments(t *testing.T) {
+	var q struct {
+		Repository struct {
+			IssueCount graphql.Int    `graphql:"issueCount(filter: {})"`
+			Name       graphql.String `graphql:"name @include(if: true)"`
+			Issues     struct {
+				TotalCount graphql.Int
+			} `graphql:"issues(filterBy: {})"`
+		}
+	}
+	client := graphql.NewClient("/graphql", nil)
+	op, err := client.Prepare(context.Background(), graphql.Operation{V: &q},
+		graphql.WithFieldMask("repository.issueCount", "repository.name", "repository.issues.totalCount"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := op.Query, `{repository{issueCount(filter: {}),name @include(if: true),issues(filterBy: {}){totalCount}}}`; got != want {
+		t.Errorf("got query: %q, want: %q", got, want)
+	}
+}
+
+func TestWithFieldMask_errors(t *testing.T) {
+	var q struct {
+		Viewer struct {
+			Login graphql.String `graphql:"login,required"`
+			Bio   graphql.String
+		}
+	}
+	client := graphql.NewClient("/graphql", nil)
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{[]string{"viewer.bio"}, "field mask leaves out required field viewer.login"},
+		{[]string{"viewer.login", "viewer.name"}, "field mask path viewer.name matches no field"},
+	}
+	for _, tc := range tests {
+		_, err := client.Prepare(context.Background(), graphql.Operation{V: &q}, graphql.WithFieldMask(tc.paths...))
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("%v: got error: %v, want: %v", tc.paths, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package graphql
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/merico-dev/graphql/internal/jsonutil"
+)
+
+// WithSpecialFloats makes the client accept the special float values NaN,
+// Infinity and -Infinity, which some servers emit even though JSON can't
+// represent them, rather than failing to decode the entire response.
+// They're decoded into float fields whether they're written as bare
+// tokens, as JavaScript would, or as the strings "NaN", "Infinity"
+// and "-Infinity".
+//
+// Integers written with an exponent, such as 1e3, are decoded into
+// integer fields regardless of this option.
+func WithSpecialFloats() ClientOption {
+	return func(c *Client) {
+		c.specialFloats = true
+		c.decodeOptions = append(c.decodeOptions, jsonutil.SpecialFloats())
+	}
+}
+
+// specialFloatTokens are the bare tokens some servers emit
+// for special float values.
+var specialFloatTokens = [...][]byte{[]byte("NaN"), []byte("Infinity"), []byte("-Infinity")}
+
+// quoteSpecialFloats reads the JSON text from r, and returns it with
+// the bare special float tokens outside strings quoted.
+func quoteSpecialFloats(r io.Reader) (io.Reader, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var out []byte // Allocated once a token is quoted.
+	last := 0      // End of the text copied to out.
+	inString := false
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case !inString && (c == 'N' || c == 'I' || c == '-'):
+			for _, tok := range specialFloatTokens {
+				if bytes.HasPrefix(b[i:], tok) {
+					out = append(out, b[last:i]...)
+					out = append(out, '"')
+					out = append(out, tok...)
+					out = append(out, '"')
+					i += len(tok) - 1
+					last = i + 1
+					break
+				}
+			}
+		}
+	}
+	if out == nil {
+		return bytes.NewReader(b), nil
+	}
+	return bytes.NewReader(append(out, b[last:]...)), nil
+}
@@ -0,0 +1,307 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WithReadableDocuments makes the client send the documents constructed by
+// Client.Query, Client.Mutate and Subscribe formatted with Format, rather
+// than minified. It's meant for humans reading documents in proxies,
+// HAR files and server logs, at the cost of larger requests.
+func WithReadableDocuments() ClientOption {
+	return func(c *Client) {
+		c.readableDocuments = true
+	}
+}
+
+// WithFieldPathComments makes the client send the documents constructed by
+// Client.Query and Client.Mutate formatted as WithReadableDocuments does,
+// with each field preceded by a comment holding the path of the Go struct
+// field it's selected for, such as "# Repository.Issues.Nodes.Title".
+// It's meant for tracing selections in logs and persisted document
+// registries back to the query structs they came from.
+func WithFieldPathComments() ClientOption {
+	return func(c *Client) {
+		c.readableDocuments = true
+		c.fieldPathComments = true
+	}
+}
+
+// Format returns the GraphQL document doc formatted for humans,
+// with a selection per line and nested selection sets indented.
+// The result is equivalent to doc.
+//
+// E.g., "query($id:ID!){node(id:$id){id}}" ->
+//
+//	query($id: ID!) {
+//	  node(id: $id) {
+//	    id
+//	  }
+//	}
+func Format(doc string) string {
+	var f formatter
+	for i := 0; i < len(doc); i++ {
+		c := doc[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			f.space = true
+			continue
+		case c == '"':
+			end := stringEnd(doc, i)
+			f.token(doc[i:end])
+			i = end - 1
+		case c == '#':
+			end := strings.IndexAny(doc[i:], "\r\n")
+			if end == -1 {
+				end = len(doc) - i
+			}
+			f.comment(strings.TrimSpace(doc[i+1 : i+end]))
+			i += end - 1
+		case c == '{' && f.parens == 0:
+			f.spaced("{")
+			f.indent++
+			f.newline()
+		case c == '}' && f.parens == 0:
+			f.indent--
+			f.newline()
+			f.write("}")
+			if f.indent == 0 {
+				f.definitionEnd = true
+			}
+		case c == ',' && f.parens == 0:
+			f.newline()
+		case c == ',':
+			f.write(", ")
+		case c == '(':
+			f.parens++
+			f.write("(")
+		case c == ')':
+			f.parens--
+			f.write(")")
+		case c == ':':
+			f.write(": ")
+		case c == '=':
+			f.spaced("= ")
+		case c == '@':
+			f.spaced("@")
+		case c == '$' && f.parens > 0 && !strings.ContainsAny(f.last(), "(:[{ "):
+			// Next variable definition. E.g., "$a:Int!$b:Int".
+			f.write(", $")
+		default:
+			f.token(doc[i : i+1])
+		}
+		f.space = false
+	}
+	return f.b.String()
+}
+
+// formatter accumulates a formatted document.
+type formatter struct {
+	b             strings.Builder
+	indent        int  // Depth of selection sets.
+	parens        int  // Depth of argument and variable definition lists.
+	space         bool // Whether whitespace precedes the next token.
+	definitionEnd bool // Whether a definition just ended.
+	commentEnd    bool // Whether a comment just ended, so a line must be started.
+}
+
+// last returns the last byte written, as a string.
+func (f *formatter) last() string {
+	s := f.b.String()
+	if s == "" {
+		return ""
+	}
+	return s[len(s)-1:]
+}
+
+// write writes s, separating it from a previous definition or
+// comment if needed.
+func (f *formatter) write(s string) {
+	if f.commentEnd {
+		f.newline()
+	}
+	if f.definitionEnd {
+		f.b.WriteString("\n\n")
+		f.definitionEnd = false
+	}
+	f.b.WriteString(s)
+}
+
+// token writes s, preceded by a space if whitespace separated it
+// from the previous token in the source.
+func (f *formatter) token(s string) {
+	if f.space && !f.definitionEnd && !f.commentEnd && !strings.ContainsAny(f.last(), " \n([{") && !strings.ContainsAny(s[:1], ")]}:,") {
+		f.write(" ")
+	}
+	f.write(s)
+}
+
+// spaced writes s, preceded by a space.
+func (f *formatter) spaced(s string) {
+	if l := f.last(); l != "" && l != " " && l != "\n" && l != "(" && !f.definitionEnd && !f.commentEnd {
+		f.write(" ")
+	}
+	f.write(s)
+}
+
+func (f *formatter) newline() {
+	f.commentEnd = false
+	f.b.WriteString("\n" + strings.Repeat("  ", f.indent))
+}
+
+// comment writes the comment text, on a line of its own if it
+// starts one, or else at the end of the current line.
+func (f *formatter) comment(text string) {
+	if l := f.last(); l != "" && l != " " && l != "\n" {
+		f.write(" ")
+	}
+	f.write("# " + text)
+	f.commentEnd = true
+}
+
+// stringEnd returns the index just past the string or block string
+// starting at doc[i].
+func stringEnd(doc string, i int) int {
+	if strings.HasPrefix(doc[i:], `"""`) {
+		for j := i + 3; j < len(doc); j++ {
+			if doc[j] == '\\' && strings.HasPrefix(doc[j:], `\"""`) {
+				j += 3
+				continue
+			}
+			if strings.HasPrefix(doc[j:], `"""`) {
+				return j + 3
+			}
+		}
+		return len(doc)
+	}
+	for j := i + 1; j < len(doc); j++ {
+		switch doc[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1
+		}
+	}
+	return len(doc)
+}
+
+// DebugString returns the GraphQL document query formatted with Format,
+// with the variables referenced in it replaced by their values from
+// variables as literals. It answers "what exactly did we ask the server"
+// when debugging, and isn't meant to be sent.
+//
+// Values of variables and input object fields whose names look secret,
+// such as "token" or "password", are masked.
+func DebugString(query string, variables map[string]interface{}) string {
+	if open, brace := strings.IndexByte(query, '('), strings.IndexByte(query, '{'); open != -1 && open < brace {
+		// Drop the variable definitions, which no longer apply.
+		if end := strings.IndexByte(query[open:], ')'); end != -1 {
+			query = query[:open] + query[open+end+1:]
+		}
+	}
+	var b strings.Builder
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '"':
+			end := stringEnd(query, i)
+			b.WriteString(query[i:end])
+			i = end - 1
+		case c == '$':
+			end := i + 1
+			for end < len(query) && isNameByte(query[end]) {
+				end++
+			}
+			name := query[i+1 : end]
+			v, ok := variables[name]
+			if !ok {
+				b.WriteByte(c)
+				continue
+			}
+			writeDebugLiteral(&b, name, v)
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return Format(b.String())
+}
+
+// writeDebugLiteral writes the value v of the variable or input object
+// field name to b as a GraphQL literal, masking it if name looks secret.
+func writeDebugLiteral(b *strings.Builder, name string, v interface{}) {
+	if isSecretName(name) {
+		b.WriteString(`"***"`)
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		fmt.Fprintf(b, "<%v>", err)
+		return
+	}
+	var value interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		fmt.Fprintf(b, "<%v>", err)
+		return
+	}
+	writeLiteral(b, value)
+}
+
+// writeLiteral writes the decoded JSON value v to b as a GraphQL literal.
+// Strings are written quoted, including enum values.
+func writeLiteral(b *strings.Builder, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteString("{")
+		for i, k := range keys {
+			if i != 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(k + ":")
+			if isSecretName(k) {
+				b.WriteString(`"***"`)
+			} else {
+				writeLiteral(b, v[k])
+			}
+		}
+		b.WriteString("}")
+	case []interface{}:
+		b.WriteString("[")
+		for i, e := range v {
+			if i != 0 {
+				b.WriteString(",")
+			}
+			writeLiteral(b, e)
+		}
+		b.WriteString("]")
+	case string:
+		s, _ := json.Marshal(v)
+		b.Write(s)
+	case nil:
+		b.WriteString("null")
+	default:
+		fmt.Fprint(b, v)
+	}
+}
+
+// isSecretName reports whether the variable or field name looks like it
+// holds a secret.
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range [...]string{"password", "passwd", "secret", "token", "apikey", "api_key", "credential", "authorization"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{
+			in: `{viewer{login}}`,
+			want: `{
+  viewer {
+    login
+  }
+}`,
+		},
+		{
+			in: `query GetNode($id:ID!$first:Int){node(id: $id){id,...on Repository{issues(first:$first,filterBy:{labels:["a,b","c"]})@include(if:true){totalCount}},...ActorFields}}fragment ActorFields on User{login}`,
+			want: `query GetNode($id: ID!, $first: Int) {
+  node(id: $id) {
+    id
+    ...on Repository {
+      issues(first: $first, filterBy: {labels: ["a,b", "c"]}) @include(if: true) {
+        totalCount
+      }
+    }
+    ...ActorFields
+  }
+}
+
+fragment ActorFields on User {
+  login
+}`,
+		},
+		{
+			in: `mutation{addStar(input:{starrableId:"1",note:"\"{quoted}\""}){clientMutationId}}`,
+			want: `mutation {
+  addStar(input: {starrableId: "1", note: "\"{quoted}\""}) {
+    clientMutationId
+  }
+}`,
+		},
+		{
+			in: "{#Viewer\nviewer{login # the handle\nname}}",
+			want: `{
+  # Viewer
+  viewer {
+    login # the handle
+    name
+  }
+}`,
+		},
+	}
+	for _, tc := range tests {
+		if got := graphql.Format(tc.in); got != tc.want {
+			t.Errorf("Format(%q):\ngot:\n%s\nwant:\n%s", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestWithReadableDocuments(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query($login: String!) {\n  user(login: $login) {\n    name\n  }\n}","variables":{"login":"gopher"}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithReadableDocuments())
+
+	var q struct {
+		User struct {
+			Name graphql.String
+		} `graphql:"user(login:$login)"`
+	}
+	_, err := client.Query(context.Background(), &q, map[string]interface{}{"login": graphql.String("gopher")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.User.Name, graphql.String("Gopher"); got != want {
+		t.Errorf("got name: %q, want: %q", got, want)
+	}
+}
+
+func TestWithFieldPathComments(t *testing.T) {
+	client := graphql.NewClient("/graphql", nil, graphql.WithFieldPathComments())
+	var q struct {
+		Repository struct {
+			Name   graphql.String
+			Issues struct {
+				Nodes []struct {
+					Title graphql.String
+				}
+			} `graphql:"issues(first: 10)"`
+		} `graphql:"repository(owner: \"octocat\")"`
+	}
+	op, err := client.Prepare(context.Background(), graphql.Operation{V: &q})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  # Repository
+  repository(owner: "octocat") {
+    # Repository.Name
+    name
+    # Repository.Issues
+    issues(first: 10) {
+      # Repository.Issues.Nodes
+      nodes {
+        # Repository.Issues.Nodes.Title
+        title
+      }
+    }
+  }
+}`
+	if op.Query != want {
+		t.Errorf("got query:\n%s\nwant:\n%s", op.Query, want)
+	}
+}
+
+func TestDebugString(t *testing.T) {
+	query := `mutation Login($login:String!$password:String!$input:LoginInput!$n:Int){login(login:$login,password:$password,input:$input){token,first(n:$n,missing:$missing)}}`
+	variables := map[string]interface{}{
+		"login":    graphql.String("gopher"),
+		"password": graphql.String("hunter2"),
+		"input": map[string]interface{}{
+			"apiToken": "abc",
+			"scopes":   []string{"repo", "user"},
+			"expires":  nil,
+		},
+		"n": graphql.Int(3),
+	}
+	want := `mutation Login {
+  login(login: "gopher", password: "***", input: {apiToken: "***", expires: null, scopes: ["repo", "user"]}) {
+    token
+    first(n: 3, missing: $missing)
+  }
+}`
+	if got := graphql.DebugString(query, variables); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
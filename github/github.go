@@ -0,0 +1,235 @@
+// Package github configures GraphQL clients for GitHub's GraphQL API,
+// which has quirks of its own: a points-based rate limit reported in the
+// rateLimit field and in response headers, secondary rate limits, and
+// schema previews enabled through the Accept header.
+//
+// Documentation: https://docs.github.com/en/graphql.
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+// URL is the URL of GitHub's GraphQL API.
+const URL = "https://api.github.com/graphql"
+
+// NewClient creates a GraphQL client for GitHub's GraphQL API.
+// httpClient should authenticate requests, e.g., with an OAuth2 token.
+// If httpClient is nil, then http.DefaultClient is used.
+//
+// Unless budget is nil, the client waits for it before each request,
+// and keeps it up to date with the rate limit headers of responses. It retries requests that
+// failed because of the primary or secondary rate limit once they're lifted,
+// and transient failures with exponential backoff. opts are applied after
+// these options, so they take precedence; e.g., graphql.WithRetry(1, nil)
+// disables retries for clients making mutations that aren't idempotent.
+func NewClient(httpClient *http.Client, budget *Budget, opts ...graphql.ClientOption) *graphql.Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	defaults := []graphql.ClientOption{
+		graphql.WithRetry(3, graphql.ExponentialBackoff(time.Second, time.Minute)),
+		graphql.WithRetryHints(RetryHints()),
+	}
+	if budget != nil {
+		hc := *httpClient
+		hc.Transport = budget.Transport(httpClient.Transport)
+		httpClient = &hc
+		defaults = append(defaults, graphql.WithRateLimiter(budget))
+	}
+	return graphql.NewClient(URL, httpClient, append(defaults, opts...)...)
+}
+
+// WithPreviews makes the client enable the schema previews names,
+// such as "merge-info", with the Accept header of every request.
+func WithPreviews(names ...string) graphql.ClientOption {
+	mediaTypes := make([]string, 0, len(names)+1)
+	for _, name := range names {
+		mediaTypes = append(mediaTypes, "application/vnd.github."+name+"-preview+json")
+	}
+	mediaTypes = append(mediaTypes, "application/json")
+	return graphql.WithRequestOptions(graphql.WithAccept(mediaTypes...))
+}
+
+// RateLimit is GitHub's rateLimit object, which describes the client's
+// rate limit status.
+type RateLimit struct {
+	Cost      int       // Points the query cost.
+	Limit     int       // Points allowed per hour.
+	Remaining int       // Points remaining until ResetAt.
+	ResetAt   time.Time // When Remaining is reset to Limit.
+}
+
+// Query is like graphql.Client.Query, except that it also selects the
+// rateLimit field, without q having to declare it, and returns its value.
+// q must be a pointer to struct.
+func Query(ctx context.Context, c *graphql.Client, q interface{}, variables map[string]interface{}, opts ...graphql.RequestOption) (RateLimit, []graphql.DataError, error) {
+	v := reflect.ValueOf(q)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return RateLimit{}, nil, fmt.Errorf("github: got %T, want a pointer to struct", q)
+	}
+	// Select rateLimit alongside the fields of q, which are spread with
+	// an inline fragment on the root type. q's type isn't embedded, since
+	// reflect.StructOf doesn't support embedding types with methods.
+	w := reflect.New(reflect.StructOf([]reflect.StructField{
+		{Name: "RateLimit", Type: reflect.TypeOf(RateLimit{}), Tag: `graphql:"rateLimit"`},
+		{Name: "Query", Type: v.Elem().Type(), Tag: `graphql:"... on Query"`},
+	})).Elem()
+	w.Field(1).Set(v.Elem())
+	dataErrors, err := c.Query(ctx, w.Addr().Interface(), variables, opts...)
+	v.Elem().Set(w.Field(1))
+	return w.Field(0).Interface().(RateLimit), dataErrors, err
+}
+
+// Budget tracks how much of the primary rate limit remains, and pauses
+// requests once it's nearly exhausted until it's reset, rather than letting
+// them fail. It implements graphql.Limiter. Its zero value isn't usable;
+// use NewBudget.
+type Budget struct {
+	reserve int
+
+	mu        sync.Mutex
+	remaining int // Points remaining, or -1 if unknown.
+	resetAt   time.Time
+}
+
+// NewBudget returns a Budget that pauses requests once reserve points
+// or fewer remain.
+func NewBudget(reserve int) *Budget {
+	return &Budget{reserve: reserve, remaining: -1}
+}
+
+// Wait implements graphql.Limiter. It blocks until the rate limit is reset
+// if reserve points or fewer remain, or ctx is done.
+func (b *Budget) Wait(ctx context.Context) error {
+	b.mu.Lock()
+	wait := time.Duration(0)
+	if b.remaining >= 0 && b.remaining <= b.reserve {
+		wait = time.Until(b.resetAt)
+	}
+	b.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Observe updates b with the rate limit status rl, e.g., as returned by Query.
+func (b *Budget) Observe(rl RateLimit) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remaining = rl.Remaining
+	b.resetAt = rl.ResetAt
+}
+
+// Remaining returns the points remaining until the rate limit is reset,
+// as last observed, and when it's reset. It reports false if b hasn't
+// observed the rate limit status yet.
+func (b *Budget) Remaining() (int, time.Time, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.remaining, b.resetAt, b.remaining >= 0
+}
+
+// Transport returns an http.RoundTripper that sends requests with base,
+// or with http.DefaultTransport if base is nil, and updates b with the
+// rate limit headers of responses.
+func (b *Budget) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return budgetTransport{base: base, budget: b}
+}
+
+type budgetTransport struct {
+	base   http.RoundTripper
+	budget *Budget
+}
+
+func (t budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if remaining, resetAt, ok := rateLimitHeaders(resp.Header); ok {
+		t.budget.Observe(RateLimit{Remaining: remaining, ResetAt: resetAt})
+	}
+	return resp, nil
+}
+
+// rateLimitHeaders returns the points remaining and the reset time
+// reported by the rate limit headers h, if present.
+func rateLimitHeaders(h http.Header) (int, time.Time, bool) {
+	remaining, err := strconv.Atoi(h.Get("X-Ratelimit-Remaining"))
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	reset, err := strconv.ParseInt(h.Get("X-Ratelimit-Reset"), 10, 64)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	return remaining, time.Unix(reset, 0), true
+}
+
+// secondaryRateLimitWait is how long to wait after hitting a secondary
+// rate limit without a Retry-After header, as GitHub recommends.
+const secondaryRateLimitWait = time.Minute
+
+// RetryHints returns a graphql.RetryHintParser for GitHub's rate limits.
+// It finds hints in:
+//
+//   - the Retry-After header, which secondary rate limit responses carry;
+//   - responses with errors once the primary rate limit is exhausted,
+//     such as RATE_LIMITED errors, which can be retried when it's reset;
+//   - 403 Forbidden and 429 Too Many Requests responses mentioning
+//     a secondary rate limit, which can be retried after a minute.
+func RetryHints() graphql.RetryHintParser {
+	return retryHints{}
+}
+
+type retryHints struct{}
+
+func (retryHints) RetryAfter(resp *graphql.Response, err error) (time.Duration, bool) {
+	var header http.Header
+	var statusErr *graphql.HTTPStatusError
+	switch {
+	case errors.As(err, &statusErr):
+		header = statusErr.Header
+	case resp != nil && len(resp.Errors) > 0:
+		header = resp.Header
+	default:
+		return 0, false
+	}
+	if d, ok := graphql.ParseRetryAfter(header); ok {
+		return d, true
+	}
+	if remaining, resetAt, ok := rateLimitHeaders(header); ok && remaining == 0 {
+		d := time.Until(resetAt)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	if statusErr != nil && (statusErr.StatusCode == http.StatusForbidden || statusErr.StatusCode == http.StatusTooManyRequests) &&
+		strings.Contains(strings.ToLower(string(statusErr.Body)), "secondary rate limit") {
+		return secondaryRateLimitWait, true
+	}
+	return 0, false
+}
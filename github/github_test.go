@@ -0,0 +1,149 @@
+package github_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+	"github.com/merico-dev/graphql/github"
+)
+
+// viewerQuery is a query type with methods, which Query supports.
+type viewerQuery struct {
+	Viewer struct {
+		Login graphql.String
+	}
+}
+
+func (q viewerQuery) String() string {
+	return string(q.Viewer.Login)
+}
+
+func TestQuery(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		if got, want := string(body), `{"query":"{rateLimit{cost,limit,remaining,resetAt},... on Query{viewer{login}}}"}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		if got, want := req.Header.Get("Accept"), "application/vnd.github.merge-info-preview+json, application/json"; got != want {
+			t.Errorf("got Accept: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data": {"rateLimit": {"cost": 1, "limit": 5000, "remaining": 4999, "resetAt": "2030-01-01T00:00:00Z"}, "viewer": {"login": "gopher"}}}`)
+	})
+	client := github.NewClient(&http.Client{Transport: localRoundTripper{handler: handler}}, nil, github.WithPreviews("merge-info"))
+
+	var q viewerQuery
+	rl, dataErrors, err := github.Query(context.Background(), client, &q, nil)
+	if err != nil || dataErrors != nil {
+		t.Fatal(err, dataErrors)
+	}
+	if got, want := q.Viewer.Login, graphql.String("gopher"); got != want {
+		t.Errorf("got login: %q, want: %q", got, want)
+	}
+	want := github.RateLimit{Cost: 1, Limit: 5000, Remaining: 4999, ResetAt: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if rl != want {
+		t.Errorf("got rate limit: %+v, want: %+v", rl, want)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	reset := time.Now().Add(time.Hour)
+	var requests int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		w.Header().Set("X-Ratelimit-Remaining", strconv.Itoa(int(11-n)))
+		w.Header().Set("X-Ratelimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	budget := github.NewBudget(10)
+	client := github.NewClient(&http.Client{Transport: localRoundTripper{handler: handler}}, budget)
+
+	_, err := client.Do(context.Background(), "{viewer{login}}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining, _, ok := budget.Remaining(); !ok || remaining != 10 {
+		t.Errorf("got remaining: %v, %v, want 10", remaining, ok)
+	}
+	// The budget is exhausted, so the next request waits for the reset.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err = client.Do(ctx, "{viewer{login}}", nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("got error: %v, want: %v", err, context.DeadlineExceeded)
+	}
+	if requests != 1 {
+		t.Errorf("got %v requests, want 1", requests)
+	}
+}
+
+func TestRetryHints(t *testing.T) {
+	tests := []struct {
+		name   string
+		resp   *graphql.Response
+		err    error
+		want   time.Duration
+		wantOK bool
+	}{
+		{
+			name: "retry after",
+			err: &graphql.HTTPStatusError{StatusCode: http.StatusForbidden, Header: http.Header{
+				"Retry-After": {"30"},
+			}},
+			want: 30 * time.Second, wantOK: true,
+		},
+		{
+			name: "rate limited",
+			resp: &graphql.Response{
+				Errors: []graphql.DataError{{Message: "API rate limit exceeded"}},
+				Header: http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"946684800"}},
+			},
+			want: 0, wantOK: true,
+		},
+		{
+			name: "secondary rate limit",
+			err: &graphql.HTTPStatusError{StatusCode: http.StatusForbidden, Body: []byte(
+				`{"message": "You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`,
+			)},
+			want: time.Minute, wantOK: true,
+		},
+		{
+			name: "budget left",
+			resp: &graphql.Response{
+				Errors: []graphql.DataError{{Message: "not found"}},
+				Header: http.Header{"X-Ratelimit-Remaining": {"10"}, "X-Ratelimit-Reset": {"946684800"}},
+			},
+		},
+		{
+			name: "forbidden",
+			err:  &graphql.HTTPStatusError{StatusCode: http.StatusForbidden, Body: []byte(`{"message": "Bad credentials"}`)},
+		},
+	}
+	for _, tc := range tests {
+		got, ok := github.RetryHints().RetryAfter(tc.resp, tc.err)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("%s: got %v, %v, want %v, %v", tc.name, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+// localRoundTripper is an http.RoundTripper that executes HTTP transactions
+// by using handler directly, instead of going over an HTTP connection.
+type localRoundTripper struct {
+	handler http.Handler
+}
+
+func (l localRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	l.handler.ServeHTTP(w, req)
+	return w.Result(), nil
+}
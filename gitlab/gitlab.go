@@ -0,0 +1,106 @@
+// Package gitlab configures GraphQL clients for GitLab's GraphQL API,
+// which paginates connections with keyset cursors, caps the number of
+// items per page, and rejects queries above a maximum complexity, which
+// grows with the number of items requested.
+//
+// Documentation: https://docs.gitlab.com/ee/api/graphql/.
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"regexp"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+// URL is the URL of GitLab.com's GraphQL API. Self-managed instances
+// serve it at /api/graphql too.
+const URL = "https://gitlab.com/api/graphql"
+
+// MaxPageSize is the maximum number of items GitLab returns per page.
+const MaxPageSize = 100
+
+// NewClient creates a GraphQL client for the GitLab GraphQL API at url,
+// such as URL. httpClient should authenticate requests, e.g., with a
+// personal access token. If httpClient is nil, then http.DefaultClient is used.
+//
+// The client retries transient failures with exponential backoff.
+// opts are applied after this option, so they take precedence.
+func NewClient(url string, httpClient *http.Client, opts ...graphql.ClientOption) *graphql.Client {
+	opts = append([]graphql.ClientOption{
+		graphql.WithRetry(3, graphql.ExponentialBackoff(time.Second, time.Minute)),
+	}, opts...)
+	return graphql.NewClient(url, httpClient, opts...)
+}
+
+// complexityError matches the message of the error GitLab reports
+// for queries above its maximum complexity.
+var complexityError = regexp.MustCompile(`^Query has complexity of \d+, which exceeds max complexity of \d+`)
+
+// IsComplexityError reports whether e is the error GitLab reports
+// for a query above its maximum complexity.
+func IsComplexityError(e graphql.DataError) bool {
+	return complexityError.MatchString(e.Message)
+}
+
+// QueryPaginated is like graphql.Client.QueryPaginated, except that it
+// tunes the page size to GitLab's complexity limit. The page size is the
+// value of the variable sizeVariable, such as "first", which must be an
+// integer. When a page is rejected because the query is too complex,
+// it's requested again with half the page size, down to 1 item; the
+// following pages are requested with the reduced size.
+func QueryPaginated(ctx context.Context, c *graphql.Client, q interface{}, variables map[string]interface{}, sizeVariable string, onPage func() error, opts ...graphql.PageOption) ([]graphql.DataError, error) {
+	opts = append([]graphql.PageOption{
+		graphql.WithPageRetry(func(vars map[string]interface{}, dataErrors []graphql.DataError) bool {
+			for _, e := range dataErrors {
+				if !IsComplexityError(e) {
+					return false
+				}
+			}
+			size, ok := halve(vars[sizeVariable])
+			if ok {
+				vars[sizeVariable] = size
+			}
+			return ok
+		}),
+	}, opts...)
+	return c.QueryPaginated(ctx, q, variables, onPage, opts...)
+}
+
+// halve returns half the integer size, of the same type, or a pointer to it
+// if size is a pointer. It reports false if size isn't an integer greater
+// than 1.
+func halve(size interface{}) (interface{}, bool) {
+	v := reflect.ValueOf(size)
+	ptr := v.Kind() == reflect.Ptr
+	if ptr {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	var half reflect.Value
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() <= 1 {
+			return nil, false
+		}
+		half = reflect.New(v.Type())
+		half.Elem().SetInt(v.Int() / 2)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() <= 1 {
+			return nil, false
+		}
+		half = reflect.New(v.Type())
+		half.Elem().SetUint(v.Uint() / 2)
+	default:
+		return nil, false
+	}
+	if ptr {
+		return half.Interface(), true
+	}
+	return half.Elem().Interface(), true
+}
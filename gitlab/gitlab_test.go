@@ -0,0 +1,121 @@
+package gitlab_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+	"github.com/merico-dev/graphql/gitlab"
+)
+
+func TestQueryPaginated(t *testing.T) {
+	const query = `query($after:String$first:Int!){project{issues(first: $first, after: $after){nodes{iid},pageInfo{hasNextPage,endCursor}}}}`
+	var sizes []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		w.Header().Set("Content-Type", "application/json")
+		switch string(body) {
+		case `{"query":"` + query + `","variables":{"after":null,"first":100}}` + "\n":
+			sizes = append(sizes, "100")
+			io.WriteString(w, `{"errors": [{"message": "Query has complexity of 300, which exceeds max complexity of 250"}]}`)
+		case `{"query":"` + query + `","variables":{"after":null,"first":50}}` + "\n":
+			sizes = append(sizes, "50")
+			io.WriteString(w, `{"data": {"project": {"issues": {"nodes": [{"iid": "1"}], "pageInfo": {"hasNextPage": true, "endCursor": "c1"}}}}}`)
+		case `{"query":"` + query + `","variables":{"after":"c1","first":50}}` + "\n":
+			sizes = append(sizes, "50")
+			io.WriteString(w, `{"data": {"project": {"issues": {"nodes": [{"iid": "2"}], "pageInfo": {"hasNextPage": false, "endCursor": "c2"}}}}}`)
+		default:
+			t.Errorf("unexpected body: %s", body)
+			http.Error(w, "unexpected body", http.StatusBadRequest)
+		}
+	})
+	client := gitlab.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+
+	var q struct {
+		Project struct {
+			Issues struct {
+				Nodes []struct {
+					IID graphql.String `graphql:"iid"`
+				}
+				PageInfo struct {
+					HasNextPage graphql.Boolean
+					EndCursor   graphql.String
+				}
+			} `graphql:"issues(first: $first, after: $after)"`
+		}
+	}
+	var got []string
+	dataErrors, err := gitlab.QueryPaginated(context.Background(), client, &q, map[string]interface{}{
+		"first": graphql.Int(gitlab.MaxPageSize),
+		"after": (*graphql.String)(nil),
+	}, "first", func() error {
+		for _, n := range q.Project.Issues.Nodes {
+			got = append(got, string(n.IID))
+		}
+		return nil
+	})
+	if err != nil || dataErrors != nil {
+		t.Fatal(dataErrors, err)
+	}
+	if want := []string{"1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got nodes: %v, want: %v", got, want)
+	}
+	if want := []string{"100", "50", "50"}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("got page sizes: %v, want: %v", sizes, want)
+	}
+}
+
+func TestQueryPaginated_minimumSize(t *testing.T) {
+	var requests int
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"errors": [{"message": "Query has complexity of 300, which exceeds max complexity of 250"}]}`)
+	})
+	client := gitlab.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+
+	var q struct {
+		Project struct {
+			Issues struct {
+				PageInfo struct {
+					HasNextPage graphql.Boolean
+					EndCursor   graphql.String
+				}
+			} `graphql:"issues(first: $first, after: $after)"`
+		}
+	}
+	first := graphql.Int(4)
+	dataErrors, err := gitlab.QueryPaginated(context.Background(), client, &q, map[string]interface{}{
+		"first": &first,
+		"after": (*graphql.String)(nil),
+	}, "first", func() error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dataErrors) != 1 || !gitlab.IsComplexityError(dataErrors[0]) {
+		t.Errorf("got errors: %v, want a complexity error", dataErrors)
+	}
+	if got, want := requests, 3; got != want {
+		t.Errorf("got %v requests, want: %v", got, want)
+	}
+	if got, want := first, graphql.Int(4); got != want {
+		t.Errorf("got first: %v, want the variable unchanged: %v", got, want)
+	}
+}
+
+// localRoundTripper is an http.RoundTripper that executes HTTP transactions
+// by using handler directly, instead of going over an HTTP connection.
+type localRoundTripper struct {
+	handler http.Handler
+}
+
+func (l localRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	l.handler.ServeHTTP(w, req)
+	return w.Result(), nil
+}
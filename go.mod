@@ -1,8 +1,8 @@
 module github.com/merico-dev/graphql
 
-go 1.17
+go 1.18
 
 require (
 	github.com/graph-gophers/graphql-go v1.4.0
-	golang.org/x/net v0.0.0-20220728211354-c7608f3a8462
+	golang.org/x/net v0.25.0
 )
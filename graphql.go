@@ -5,8 +5,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/merico-dev/graphql/internal/jsonutil"
 	"golang.org/x/net/context/ctxhttp"
@@ -16,92 +20,711 @@ import (
 type Client struct {
 	url        string // GraphQL server URL.
 	httpClient *http.Client
+	endpoints  *endpointSet // Replicas of the server, if created with NewMultiClient.
+
+	formPOST          bool                   // Send requests as application/x-www-form-urlencoded.
+	getQueries        bool                   // Send queries as GET requests.
+	persistedQueries  persistedQueriesMode   // Whether and how persisted query hashes are sent.
+	allowList         map[string]bool        // Allowed operation hashes and names, if non-nil.
+	maxQuerySize      int                    // Maximum document size in bytes, if positive.
+	maxExtendAliases  int                    // Maximum aliases per graphql-extend field, if positive.
+	maxResponseBytes  int64                  // Maximum decompressed response body size in bytes, if positive.
+	unusedVariables   UnusedVariablePolicy   // What to do with variables the document doesn't reference.
+	readableDocuments bool                   // Send constructed documents formatted rather than minified.
+	fieldPathComments bool                   // Precede fields of constructed documents by comments with their Go paths.
+	specialFloats     bool                   // Accept NaN and Infinity tokens in responses.
+	enumValidation    bool                   // Validate enum values of variables against the schema.
+	schemaValidation  bool                   // Validate operation structs against the schema.
+	fatalErrorCodes   map[string]bool        // Codes of GraphQL errors that fail operations, if non-nil.
+	latencyFunc       LatencyFunc            // Called with the latency of each operation, if non-nil.
+	statsFunc         StatsFunc              // Called with the Stats of each operation, if non-nil.
+	operationHooks    []OperationHook        // Called for each operation.
+	streamingDecode   bool                   // Decode response data while it's received. See WithStreamingDecode.
+	spill             *spill                 // Spool large response bodies to disk, if non-nil.
+	retry             retryPolicy            // How failed operations are retried.
+	limiter           Limiter                // Limits the rate of requests, if non-nil.
+	concurrency       chan struct{}          // Slots of the requests being sent, if limited.
+	responseFunc      func(*Response, error) // Called with the outcome of each attempt, if non-nil.
+	requestLogger     RequestLogger          // Called before each request, if non-nil.
+	responseLogger    ResponseLogger         // Called with each response, if non-nil.
+	cache             *responseCache         // Caches responses to queries, if set.
+	queue             *offlineQueue          // Queues mutations while the server is unreachable, if non-nil.
+	wsKeepalive       wsKeepalive            // Keepalive configuration of subscriptions.
+	sseSubscriptions  bool                   // Carry subscriptions over Server-Sent Events rather than WebSocket.
+	codec             Codec                  // Codec used instead of "encoding/json", if non-nil.
+	wireCodecs        []wireCodec            // Codecs of binary encodings, in order of preference.
+	compressors       []compressor           // Content codings besides gzip, in order of preference.
+	requestEncoding   string                 // Content coding of request bodies, if non-empty.
+	preconnect        preconnectMode         // Whether and how to warm up the connection in NewClient.
+	decodeOptions     []jsonutil.Option      // Options used when unmarshaling response data.
+	requestOptions    []RequestOption        // Options applied to every request, before per-request ones.
+	headerProvider    HeaderProvider         // Provides headers of every operation, if non-nil.
+	middleware        []Middleware           // Middleware operations go through, outermost first.
+	transformers      []VariableTransformer  // Transformers variables go through before they're sent, in order.
+	signer            Signer                 // Signs HTTP requests right before they're sent, if non-nil.
+	schema            *schemaCache           // Schema introspected when needed.
+	requestStats      *requestStats          // Counts of the requests sent. See Stats.
+	subscriptions     *subscriptionSet       // Active subscriptions, ended by Close and Drain.
+
+	// connectionInit, if non-nil, returns the connection_init payload of subscriptions.
+	connectionInit func(ctx context.Context) (interface{}, error)
 }
 
 // NewClient creates a GraphQL client targeting the specified GraphQL server URL.
 // If httpClient is nil, then http.DefaultClient is used.
-func NewClient(url string, httpClient *http.Client) *Client {
+func NewClient(url string, httpClient *http.Client, opts ...ClientOption) *Client {
 	if httpClient == nil {
 		httpClient = http.DefaultClient
 	}
-	return &Client{
-		url:        url,
-		httpClient: httpClient,
+	c := &Client{
+		url:          url,
+		httpClient:   httpClient,
+		schema:       new(schemaCache),
+		requestStats: new(requestStats),
+		wsKeepalive:  defaultWSKeepalive,
+
+		subscriptions: new(subscriptionSet),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	if c.preconnect != preconnectOff {
+		go c.warmUp()
+	}
+	return c
 }
 
 // Query executes a single GraphQL query request,
 // with a query derived from q, populating the response into it.
 // q should be a pointer to struct that corresponds to the GraphQL schema.
-func (c *Client) Query(ctx context.Context, q interface{}, variables map[string]interface{}) ([]DataError, error) {
-	query, variables := ConstructQuery(q, variables)
-	data, dataErrors, err := c.do(ctx, query, q, variables)
+func (c *Client) Query(ctx context.Context, q interface{}, variables map[string]interface{}, opts ...RequestOption) ([]DataError, error) {
+	start := time.Now()
+	opts = c.streamInto(q, variables, opts)
+	query, variables, err := c.construct(ctx, "query", q, variables, opts)
 	if err != nil {
 		return nil, err
 	}
-	if data != nil {
-		err = jsonutil.UnmarshalGraphQL(*data, q)
-		if err != nil {
-			return nil, err
-		}
+	ctx, done := c.trackStats(ctx, operationName(query), query, start)
+	resp, err := c.do(ctx, query, variables, opts)
+	if err == nil {
+		err = c.decodeData(ctx, resp, q, c.documentConfig(opts))
+	}
+	done(resp, err)
+	if err != nil {
+		return nil, err
 	}
-	return dataErrors, nil
+	return resp.Errors, nil
 }
 
 // Mutate executes a single GraphQL mutation request,
 // with a mutation derived from m, populating the response into it.
 // m should be a pointer to struct that corresponds to the GraphQL schema.
-func (c *Client) Mutate(ctx context.Context, m interface{}, variables map[string]interface{}) ([]DataError, error) {
-	query := ConstructMutation(m, variables)
-	data, dataErrors, err := c.do(ctx, query, m, variables)
+func (c *Client) Mutate(ctx context.Context, m interface{}, variables map[string]interface{}, opts ...RequestOption) ([]DataError, error) {
+	start := time.Now()
+	opts = c.streamInto(m, variables, opts)
+	query, variables, err := c.construct(ctx, "mutation", m, variables, opts)
+	if err != nil {
+		return nil, err
+	}
+	ctx, done := c.trackStats(ctx, operationName(query), query, start)
+	resp, err := c.do(ctx, query, variables, opts)
+	if err == nil {
+		err = c.decodeData(ctx, resp, m, c.documentConfig(opts))
+	}
+	done(resp, err)
 	if err != nil {
 		return nil, err
 	}
-	if data != nil {
-		err = jsonutil.UnmarshalGraphQL(*data, m)
+	return resp.Errors, nil
+}
+
+// Exec executes a single GraphQL operation with the hand-written query
+// document query, populating the response into result, as Query does with
+// a query derived from a struct. It's for operations that query structs
+// can't express. result should be a pointer to struct or map, whose fields
+// match the selections of query, including their aliases.
+func (c *Client) Exec(ctx context.Context, query string, result interface{}, variables map[string]interface{}, opts ...RequestOption) ([]DataError, error) {
+	opts = c.streamInto(result, variables, opts)
+	if c.readableDocuments {
+		query = Format(query)
+	}
+	if name := c.documentConfig(opts).operationName; name != "" {
+		query = nameOperation(query, name)
+	}
+	ctx, done := c.trackStats(ctx, operationName(query), query, time.Time{})
+	resp, err := c.do(ctx, query, variables, opts)
+	if err == nil {
+		err = c.decodeData(ctx, resp, result, c.documentConfig(opts))
+	}
+	done(resp, err)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Errors, nil
+}
+
+// decodeData decodes the data of resp, if any, into v,
+// unless resp has fatal errors, which it returns.
+// cfg is the configuration of the request.
+func (c *Client) decodeData(ctx context.Context, resp *Response, v interface{}, cfg *requestConfig) error {
+	if err := c.fatalErrors(resp); err != nil {
+		return err
+	}
+	if resp.Data == nil {
+		return nil
+	}
+	if s := trackedStats(ctx); s != nil {
+		start := time.Now()
+		defer func() { s.Decode += time.Since(start) }()
+	}
+	err := jsonutil.UnmarshalGraphQL(resp.Data, v, c.dataDecodeOptions(cfg)...)
+	if err != nil {
+		return &DecodeError{Err: err}
+	}
+	return nil
+}
+
+// dataDecodeOptions returns the options for decoding the response data
+// of a request with cfg.
+func (c *Client) dataDecodeOptions(cfg *requestConfig) []jsonutil.Option {
+	if !cfg.autoTypename {
+		return c.decodeOptions
+	}
+	return append(c.decodeOptions[:len(c.decodeOptions):len(c.decodeOptions)], jsonutil.AutoTypenames())
+}
+
+// construct constructs the document of the operation of type operation,
+// "query" or "mutation", derived from v, and prepares it for sending with
+// opts. It returns the document and variables to send.
+func (c *Client) construct(ctx context.Context, operation string, v interface{}, variables map[string]interface{}, opts []RequestOption) (string, map[string]interface{}, error) {
+	if operation == "query" {
+		err := c.checkExtendAliases(variables)
 		if err != nil {
-			return nil, err
+			return "", nil, err
+		}
+	}
+	err := c.validateOperation(ctx, operation, v, variables)
+	if err != nil {
+		return "", nil, err
+	}
+	cfg := c.documentConfig(opts)
+	copts := constructOptions{
+		mask:      newFieldMask(cfg.fieldMask),
+		comments:  c.fieldPathComments,
+		typenames: cfg.autoTypename,
+	}
+	var query string
+	if operation == "mutation" {
+		query, err = constructMutation(v, variables, copts)
+	} else {
+		query, variables, err = constructQuery(v, variables, copts)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if copts.mask != nil && len(variables) > 0 {
+		// Variables referenced only by fields the mask leaves out
+		// aren't the caller's mistake, so they're dropped regardless
+		// of the client's policy.
+		body := selectionSet(query)
+		if unused := unusedVariables(body, variables); len(unused) > 0 {
+			query, variables = stripVariables(operation, body, variables, unused)
 		}
 	}
-	return dataErrors, nil
+	query, variables, err = c.prepareDocument(ctx, operation, query, variables)
+	if err != nil {
+		return "", nil, err
+	}
+	if cfg.operationName != "" {
+		query = nameOperation(query, cfg.operationName)
+	}
+	return query, variables, nil
+}
+
+// prepareDocument prepares the constructed document doc, which is an
+// operation of type operation, for sending according to the client's
+// options. It returns the document and variables to send.
+func (c *Client) prepareDocument(ctx context.Context, operation, doc string, variables map[string]interface{}) (string, map[string]interface{}, error) {
+	doc, variables, err := c.handleUnusedVariables(operation, doc, variables)
+	if err != nil {
+		return "", nil, err
+	}
+	err = c.validateEnums(ctx, variables)
+	if err != nil {
+		return "", nil, err
+	}
+	if c.readableDocuments {
+		doc = Format(doc)
+	}
+	return doc, variables, nil
+}
+
+// Do executes a single GraphQL operation with the given query document
+// and variables, and returns the server's response without decoding its data.
+// It's a lower-level alternative to Query and Mutate for callers that need
+// access to the complete response.
+//
+// If the server responded with a non-200 OK status code, Do returns
+// an *HTTPStatusError along with a Response holding the status and header.
+// If the request couldn't be sent or the response received, the error is
+// a *NetworkError, and if the response couldn't be decoded, a *DecodeError.
+func (c *Client) Do(ctx context.Context, query string, variables map[string]interface{}, opts ...RequestOption) (*Response, error) {
+	return c.do(ctx, query, variables, opts)
 }
 
 // do executes a single GraphQL operation.
-func (c *Client) do(ctx context.Context, query string, v interface{}, variables map[string]interface{}) (*json.RawMessage, []DataError, error) {
-	in := struct {
-		Query     string                 `json:"query"`
-		Variables map[string]interface{} `json:"variables,omitempty"`
-	}{
-		Query:     query,
-		Variables: variables,
+func (c *Client) do(ctx context.Context, query string, variables map[string]interface{}, opts []RequestOption) (*Response, error) {
+	cfg, err := c.requestConfig(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.operationName != "" {
+		query = nameOperation(query, cfg.operationName)
 	}
-	var buf bytes.Buffer
-	err := json.NewEncoder(&buf).Encode(in)
+	err = c.checkQuery(query)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	variables, err = c.transformVariables(ctx, query, variables)
+	if err != nil {
+		return nil, err
 	}
-	resp, err := ctxhttp.Post(ctx, c.httpClient, c.url, "application/json", &buf)
+	variables, err = sentVariables(variables)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	in := requestBody{
+		Query:         query,
+		OperationName: cfg.operationName,
+		Variables:     variables,
+		Extensions:    cfg.extensions,
+	}
+	ctx, done := c.trackStats(ctx, in.operationName(), query, time.Time{})
+	var resp *Response
+	if len(c.middleware) > 0 {
+		resp, err = c.doMiddleware(ctx, in, cfg, c.dispatch)
+	} else {
+		resp, err = c.dispatch(ctx, in, cfg)
+	}
+	if cfg.responseExtensions != nil && resp != nil {
+		*cfg.responseExtensions = resp.Extensions
+	}
+	done(resp, err)
+	return resp, err
+}
+
+// dispatch executes the GraphQL request in, made with cfg, through the
+// client's response cache or offline queue if they apply to it.
+func (c *Client) dispatch(ctx context.Context, in requestBody, cfg *requestConfig) (*Response, error) {
+	switch in.operationType() {
+	case "query":
+		if c.cache != nil && c.cache.cache != nil && !cfg.noCache {
+			cfg.into = nil // Cached responses need their data.
+			return c.doCached(ctx, in, cfg)
+		}
+	case "mutation":
+		if c.queue != nil {
+			cfg.into = nil // Queued mutations are decoded later.
+			return c.doQueued(ctx, in, cfg)
+		}
+	}
+	return c.execute(ctx, in, cfg)
+}
+
+// execute sends the GraphQL request in, made with cfg,
+// retrying it if needed.
+func (c *Client) execute(ctx context.Context, in requestBody, cfg *requestConfig) (*Response, error) {
+	start := time.Now()
+	resp, err := c.doRetrying(ctx, in, func() (*Response, error) {
+		return c.failover(ctx, in, cfg, func() (*Response, error) {
+			var resp *Response
+			var err error
+			done := c.requestStats.start()
+			if c.persistedQueries != persistedQueriesOff {
+				resp, err = c.doPersisted(ctx, in, cfg)
+			} else {
+				resp, err = c.send(ctx, in, c.getQueries && in.operationType() == "query", cfg)
+			}
+			done(err)
+			if c.responseFunc != nil {
+				c.responseFunc(resp, err)
+			}
+			return resp, err
+		})
+	})
+	if c.latencyFunc != nil {
+		c.latencyFunc(in.operationName(), time.Since(start), outcomeOf(resp, err))
+	}
+	return resp, err
+}
+
+// send sends the GraphQL request in to the server, and decodes its response.
+// If get is true, in is sent as a GET request.
+func (c *Client) send(ctx context.Context, in requestBody, get bool, cfg *requestConfig) (*Response, error) {
+	if c.limiter != nil {
+		err := c.limiter.Wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	req, err := c.newRequest(in, get, cfg)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range cfg.header {
+		req.Header[k] = vs
+	}
+	if hasIncrementalDirectives(in.Query) {
+		req.Header.Set("Accept", incrementalAccept+", "+req.Header.Get("Accept"))
+	}
+	if c.requestEncoding != "" {
+		err := c.compressRequest(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+	stats := trackedStats(ctx)
+	if stats != nil {
+		stats.Requests++
+		if req.ContentLength > 0 {
+			stats.BytesSent += req.ContentLength
+		} else {
+			stats.BytesSent += int64(len(req.URL.RawQuery))
+		}
+		var trace *requestTrace
+		ctx, trace = traceRequest(ctx)
+		defer trace.addTo(stats)
+	}
+	req, err = c.sign(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if c.requestLogger != nil {
+		logged := []requestBody{in}
+		if cfg.batch != nil {
+			logged = cfg.batch
+		}
+		for _, in := range logged {
+			c.requestLogger(ctx, in.operationName(), in.Query, in.Variables)
+		}
+	}
+	sent := time.Now()
+	abort := func() {}
+	if cfg.stallTimeout > 0 {
+		ctx, abort = context.WithCancel(ctx)
+		defer abort()
+	}
+	if in.operationType() == "query" {
+		ctx = context.WithValue(ctx, replayableKey{}, true)
+	}
+	resp, err := ctxhttp.Do(ctx, c.httpClient, req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, &NetworkError{Err: err}
 	}
 	defer resp.Body.Close()
+	var respBody io.Reader = resp.Body
+	if stats != nil {
+		respBody = countingReader{r: resp.Body, n: &stats.BytesReceived}
+		stats.Server += serverDuration(ParseServerTiming(resp.Header))
+	}
+	if cfg.progress != nil || cfg.stallTimeout > 0 {
+		pr, stop := newProgressReader(respBody, resp.ContentLength, cfg, abort)
+		defer stop()
+		respBody = pr
+	}
+	decompressed, err := c.decompressResponse(resp, respBody)
+	if err != nil {
+		return nil, err
+	}
+	defer decompressed.Close()
+	respBody = decompressed
+	if c.responseLogger != nil {
+		var body bytes.Buffer
+		respBody = io.TeeReader(respBody, &body)
+		defer func() {
+			c.responseLogger(ctx, ResponseLog{
+				Operation: in.operationName(),
+				Status:    resp.StatusCode,
+				Header:    resp.Header,
+				Duration:  time.Since(sent),
+				Body:      body.Bytes(),
+			})
+		}()
+	}
+	out := &Response{
+		Header: resp.Header,
+		Status: resp.StatusCode,
+	}
+	ct := resp.Header.Get("Content-Type")
 	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, nil, fmt.Errorf("non-200 OK status code: %v body: %q", resp.Status, body)
+		err := &HTTPStatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Header:     resp.Header,
+		}
+		if isJSONContentType(ct) {
+			err.Body, err.Truncated = c.readErrorBody(respBody)
+		} else {
+			err.Body, err.Truncated = readTruncated(respBody, maxNonJSONBody)
+		}
+		return out, err
+	}
+	respBody = c.limitResponse(respBody)
+	if c.spill != nil {
+		body, cleanup, err := c.spill.spool(respBody)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		respBody = body
+	}
+	recorder := &errRecorder{r: respBody}
+	respBody = recorder
+	if cfg.batch != nil {
+		cfg.responses, err = c.readBatch(respBody, ct, out, len(cfg.batch))
+		if err != nil && recorder.err != nil {
+			return nil, responseError(ctx, recorder.err, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+	var envelope struct {
+		Data       json.RawMessage
+		Errors     dataErrors
+		Extensions map[string]json.RawMessage
+	}
+	if boundary, ok := isMultipartMixed(ct); ok {
+		err = c.readIncremental(respBody, boundary, out, cfg)
+		if err != nil {
+			return nil, responseError(ctx, recorder.err, err)
+		}
+		return out, nil
+	} else if codec := c.wireCodec(ct); codec != nil {
+		err = unmarshalWire(codec, respBody, &envelope)
+	} else if isJSONContentType(ct) {
+		body := respBody
+		if c.specialFloats {
+			body, err = quoteSpecialFloats(respBody)
+			if err != nil {
+				return nil, responseError(ctx, recorder.err, err)
+			}
+		}
+		if cfg.into != nil && c.streamingDecode {
+			err = c.decodeStreaming(body, out, cfg)
+			if err != nil {
+				return nil, responseError(ctx, recorder.err, err)
+			}
+			return out, nil
+		}
+		err = c.unmarshal(body, &envelope)
+	} else {
+		err := &ContentTypeError{ContentType: ct}
+		err.Body, err.Truncated = readTruncated(respBody, maxNonJSONBody)
+		return out, err
+	}
+	if err != nil {
+		return nil, responseError(ctx, recorder.err, err)
+	}
+	if string(envelope.Data) != "null" {
+		out.Data = envelope.Data
+	}
+	if len(envelope.Errors) > 0 {
+		out.Errors = envelope.Errors
+	}
+	out.Extensions = envelope.Extensions
+	return out, nil
+}
+
+// isJSONContentType reports whether the Content-Type header value ct
+// is a JSON media type the response decoder understands. A missing
+// Content-Type is given the benefit of the doubt.
+func isJSONContentType(ct string) bool {
+	if ct == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// requestBody is the body of a GraphQL request.
+type requestBody struct {
+	Query         string                 `json:"query,omitempty"` // Empty when only a persisted query hash is sent.
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	Extensions    map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// operationName returns the name of the operation to execute,
+// or "" if it's anonymous.
+func (in requestBody) operationName() string {
+	if in.OperationName != "" {
+		return in.OperationName
+	}
+	return operationName(in.Query)
+}
+
+// operationType returns the type of the operation to execute.
+func (in requestBody) operationType() string {
+	if in.OperationName != "" {
+		if t, ok := namedOperationType(in.Query, in.OperationName); ok {
+			return t
+		}
+	}
+	return operationType(in.Query)
+}
+
+// newRequest returns an HTTP request for the GraphQL request in, made with
+// cfg, encoded in the client's wire format. If get is true, in is encoded
+// in the URL query of a GET request instead. If the variables of in hold
+// Uploads, in is sent as a multipart request regardless. If cfg holds
+// a batch, the request is for the batch instead of in.
+func (c *Client) newRequest(in requestBody, get bool, cfg *requestConfig) (*http.Request, error) {
+	endpoint, err := c.endpoint(cfg)
+	if err != nil {
+		return nil, err
 	}
-	var out struct {
-		Data   *json.RawMessage
-		Errors []DataError
-		//Extensions interface{} // Unused.
+	if cfg.batch != nil {
+		return c.newBatchRequest(endpoint, cfg.batch)
 	}
-	err = json.NewDecoder(resp.Body).Decode(&out)
+	if uploads := findUploads(in.Variables); len(uploads) > 0 {
+		return c.newUploadRequest(endpoint, in, uploads)
+	}
+	if get {
+		params, err := c.formValues(in)
+		if err != nil {
+			return nil, err
+		}
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		q := u.Query()
+		for k, vs := range params {
+			q[k] = vs
+		}
+		u.RawQuery = q.Encode()
+		return http.NewRequest(http.MethodGet, u.String(), nil)
+	}
+	if c.formPOST {
+		form, err := c.formValues(in)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	}
+	if len(c.wireCodecs) > 0 {
+		wc := c.wireCodecs[0]
+		body, err := marshalWire(wc.codec, in)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", wc.mediaType)
+		return req, nil
+	}
+	body, err := c.marshal(in)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	if len(out.Errors) > 0 {
-		return out.Data, out.Errors, nil
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
 	}
-	return out.Data, nil, nil
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// endpoint returns the GraphQL server URL for a request made with cfg,
+// with the parameters of the client's URL template substituted.
+func (c *Client) endpoint(cfg *requestConfig) (string, error) {
+	base := c.url
+	if cfg.endpointURL != "" {
+		base = cfg.endpointURL
+	}
+	if !strings.Contains(base, "{") {
+		return base, nil
+	}
+	rest := base
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(rest, '{')
+		if i == -1 {
+			break
+		}
+		j := strings.IndexByte(rest[i:], '}')
+		if j == -1 {
+			break
+		}
+		name := rest[i+1 : i+j]
+		value, ok := cfg.urlParams[name]
+		if !ok {
+			return "", fmt.Errorf("no value for parameter %q of GraphQL server URL %q; set it with WithURLParam", name, base)
+		}
+		b.WriteString(rest[:i])
+		b.WriteString(url.PathEscape(value))
+		rest = rest[i+j+1:]
+	}
+	b.WriteString(rest)
+	return b.String(), nil
+}
+
+// formValues encodes the GraphQL request in as form values,
+// with the variables and extensions JSON-encoded.
+func (c *Client) formValues(in requestBody) (url.Values, error) {
+	form := url.Values{}
+	if in.Query != "" {
+		form.Set("query", in.Query)
+	}
+	if in.OperationName != "" {
+		form.Set("operationName", in.OperationName)
+	}
+	if len(in.Variables) > 0 {
+		b, err := c.marshal(in.Variables)
+		if err != nil {
+			return nil, err
+		}
+		form.Set("variables", string(bytes.TrimSpace(b)))
+	}
+	if len(in.Extensions) > 0 {
+		b, err := c.marshal(in.Extensions)
+		if err != nil {
+			return nil, err
+		}
+		form.Set("extensions", string(bytes.TrimSpace(b)))
+	}
+	return form, nil
+}
+
+// Response is a response from a GraphQL server.
+// Specification: https://spec.graphql.org/October2021/#sec-Response.
+type Response struct {
+	Data       json.RawMessage            // Data is nil if absent or null.
+	Errors     []DataError                // Errors is nil if absent or empty.
+	Extensions map[string]json.RawMessage // Extensions is nil if absent.
+
+	Header http.Header // HTTP response header.
+	Status int         // HTTP response status code.
 }
 
 // DataError represents the "errors" in a response from a GraphQL server.
@@ -112,9 +735,25 @@ type DataError struct {
 		Line   int
 		Column int
 	}
+	// Path is the path of the response field that the error is about,
+	// made of field names (string) and list indices (int). It's nil if
+	// the error isn't about a particular field.
+	Path []interface{}
+	// Extensions holds additional information about the error,
+	// such as a machine-readable code. It's nil if absent.
+	Extensions map[string]json.RawMessage
 }
 
 // Error implements error interface.
 func (e DataError) Error() string {
 	return e.Message
 }
+
+// Code returns the machine-readable code of the error, such as "NOT_FOUND"
+// or "FORBIDDEN", which servers report as the "code" extension. It returns
+// "" if the error has no string code.
+func (e DataError) Code() string {
+	var code string
+	json.Unmarshal(e.Extensions["code"], &code)
+	return code
+}
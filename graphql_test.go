@@ -2,10 +2,15 @@ package graphql_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/merico-dev/graphql"
@@ -48,6 +53,39 @@ func TestClient_Query_MergeItems(t *testing.T) {
 	}
 }
 
+func TestClient_Query_union(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"{issueOrPullRequest{__typename,... on Issue{title},... on PullRequest{title,merged}}}"}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"issueOrPullRequest": {"__typename": "PullRequest", "title": "Add fragments", "merged": true}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		IssueOrPullRequest struct {
+			Typename graphql.String `graphql:"__typename"`
+			Issue    struct {
+				Title graphql.String
+			} `graphql:"... on Issue"`
+			PullRequest struct {
+				Title  graphql.String
+				Merged graphql.Boolean
+			} `graphql:"... on PullRequest"`
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := q.IssueOrPullRequest; got.Typename != "PullRequest" || got.PullRequest.Title != "Add fragments" || !got.PullRequest.Merged {
+		t.Errorf("got: %+v", got)
+	}
+}
+
 func TestClient_Query_partialDataWithErrorResponse(t *testing.T) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
@@ -202,6 +240,501 @@ func TestClient_Query_emptyVariables(t *testing.T) {
 	}
 }
 
+func TestClient_Query_caseInsensitiveFields(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"databaseID": 1}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithCaseInsensitiveFields())
+
+	var q struct {
+		User struct {
+			DatabaseID graphql.Int `graphql:"databaseId"`
+		}
+	}
+	dataErrors, err := client.Query(context.Background(), &q, nil)
+	if dataErrors != nil {
+		t.Fatal(dataErrors)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.User.DatabaseID, graphql.Int(1); got != want {
+		t.Errorf("got q.User.DatabaseID: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Query_preciseNumbers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"node": {"id": 9007199254740993, "databaseId": 9007199254740993}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithPreciseNumbers())
+
+	var q struct {
+		Node struct {
+			ID         graphql.ID
+			DatabaseID graphql.String `graphql:"databaseId"`
+		}
+	}
+	dataErrors, err := client.Query(context.Background(), &q, nil)
+	if dataErrors != nil {
+		t.Fatal(dataErrors)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Node.ID, json.Number("9007199254740993"); got != want {
+		t.Errorf("got q.Node.ID: %v, want: %v", got, want)
+	}
+	if got, want := q.Node.DatabaseID, graphql.String("9007199254740993"); got != want {
+		t.Errorf("got q.Node.DatabaseID: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Query_decodeHooks(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "Gopher", "email": " Gopher@Example.com "}}}`)
+	})
+	lower := func(v interface{}) error {
+		s := v.(*graphql.String)
+		*s = graphql.String(strings.ToLower(string(*s)))
+		return nil
+	}
+	trim := func(v interface{}) error {
+		s := v.(*graphql.String)
+		*s = graphql.String(strings.TrimSpace(string(*s)))
+		return nil
+	}
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithTaggedDecodeHook("lower", lower), graphql.WithDecodeHook("viewer.email", trim))
+
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+			Email graphql.String `graphql-hook:"lower"`
+		}
+	}
+	dataErrors, err := client.Query(context.Background(), &q, nil)
+	if dataErrors != nil {
+		t.Fatal(dataErrors)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Viewer.Login, graphql.String("Gopher"); got != want {
+		t.Errorf("got q.Viewer.Login: %q, want: %q", got, want)
+	}
+	if got, want := q.Viewer.Email, graphql.String("gopher@example.com"); got != want {
+		t.Errorf("got q.Viewer.Email: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_Query_specialFloats(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"stats": {"min": -Infinity, "max": Infinity, "mean": NaN, "name": "NaN \" Infinity", "count": 1e2}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithSpecialFloats())
+
+	var q struct {
+		Stats struct {
+			Min   graphql.Float
+			Max   graphql.Float
+			Mean  graphql.Float
+			Name  graphql.String
+			Count graphql.Int
+		}
+	}
+	dataErrors, err := client.Query(context.Background(), &q, nil)
+	if dataErrors != nil {
+		t.Fatal(dataErrors)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(float64(q.Stats.Min), -1) || !math.IsInf(float64(q.Stats.Max), 1) || !math.IsNaN(float64(q.Stats.Mean)) {
+		t.Errorf("got stats: %+v, want -Inf, +Inf and NaN", q.Stats)
+	}
+	if got, want := q.Stats.Name, graphql.String(`NaN " Infinity`); got != want {
+		t.Errorf("got name: %q, want: %q", got, want)
+	}
+	if got, want := q.Stats.Count, graphql.Int(100); got != want {
+		t.Errorf("got count: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Do(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query($id:ID!){node(id:$id){id}}","variables":{"id":"1"}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		mustWrite(w, `{
+			"data": {"node": null},
+			"errors": [{"message": "not found"}],
+			"extensions": {"cost": {"requestedQueryCost": 1}}
+		}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	resp, err := client.Do(context.Background(), "query($id:ID!){node(id:$id){id}}", map[string]interface{}{"id": graphql.ID("1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(resp.Data), `{"node": null}`; got != want {
+		t.Errorf("got resp.Data: %s, want: %s", got, want)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Message != "not found" {
+		t.Errorf("got resp.Errors: %v, want: [not found]", resp.Errors)
+	}
+	if got, want := string(resp.Extensions["cost"]), `{"requestedQueryCost": 1}`; got != want {
+		t.Errorf("got resp.Extensions[cost]: %s, want: %s", got, want)
+	}
+	if got, want := resp.Header.Get("X-RateLimit-Remaining"), "4999"; got != want {
+		t.Errorf("got X-RateLimit-Remaining header: %q, want: %q", got, want)
+	}
+	if got, want := resp.Status, http.StatusOK; got != want {
+		t.Errorf("got resp.Status: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Exec(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query Me($size:Int!){me: viewer{login,avatarUrl(size: $size) @include(if: true)}}","variables":{"size":64}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{
+			"data": {"me": {"login": "gopher", "avatarUrl": "https://example.org/gopher.png"}},
+			"errors": [{"message": "deprecated", "path": ["me", "avatarUrl"]}]
+		}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var result struct {
+		Me struct {
+			Login     graphql.String
+			AvatarURL graphql.String
+		}
+	}
+	errs, err := client.Exec(context.Background(), "query Me($size:Int!){me: viewer{login,avatarUrl(size: $size) @include(if: true)}}", &result, map[string]interface{}{"size": graphql.Int(64)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := result.Me.Login, graphql.String("gopher"); got != want {
+		t.Errorf("got login: %q, want: %q", got, want)
+	}
+	if got, want := result.Me.AvatarURL, graphql.String("https://example.org/gopher.png"); got != want {
+		t.Errorf("got avatarUrl: %q, want: %q", got, want)
+	}
+	if len(errs) != 1 || errs[0].Message != "deprecated" {
+		t.Errorf("got errors: %v, want: [deprecated]", errs)
+	}
+}
+
+func TestClient_Do_errorStatusCode(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	resp, err := client.Do(context.Background(), "{viewer{login}}", nil)
+	if err == nil {
+		t.Fatal("got error: nil, want: non-nil")
+	}
+	if resp == nil {
+		t.Fatal("got resp: nil, want: non-nil")
+	}
+	if got, want := resp.Status, http.StatusTooManyRequests; got != want {
+		t.Errorf("got resp.Status: %v, want: %v", got, want)
+	}
+	if got, want := resp.Header.Get("Retry-After"), "60"; got != want {
+		t.Errorf("got Retry-After header: %q, want: %q", got, want)
+	}
+	if resp.Data != nil {
+		t.Errorf("got resp.Data: %s, want: nil", resp.Data)
+	}
+}
+
+func TestClient_Query_accept(t *testing.T) {
+	var wantAccept string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got := req.Header.Get("Accept"); got != wantAccept {
+			t.Errorf("got Accept header: %q, want: %q", got, wantAccept)
+		}
+		w.Header().Set("Content-Type", "application/vnd.github.v4+json; charset=utf-8")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+	wantAccept = "application/graphql-response+json, application/json"
+	if _, err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+	client = graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRequestOptions(graphql.WithAccept("application/vnd.github.v4+json")))
+	wantAccept = "application/vnd.github.v4+json"
+	if _, err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+	wantAccept = "application/vnd.github.merge-info-preview+json, application/json"
+	if _, err := client.Query(context.Background(), &q, nil, graphql.WithAccept("application/vnd.github.merge-info-preview+json", "application/json")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_Query_unexpectedContentType(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		mustWrite(w, `<html>Sign in</html>`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil)
+	if err == nil {
+		t.Fatal("got error: nil, want: non-nil")
+	}
+	if got, want := err.Error(), `unexpected response Content-Type "text/html", want a JSON media type; body: "<html>Sign in</html>"`; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Query_formEncodedPOST(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("Content-Type"), "application/x-www-form-urlencoded"; got != want {
+			t.Errorf("got Content-Type header: %q, want: %q", got, want)
+		}
+		if got, want := req.PostFormValue("query"), `query($login:String!){user(login: $login){name}}`; got != want {
+			t.Errorf("got query: %q, want: %q", got, want)
+		}
+		if got, want := req.PostFormValue("variables"), `{"login":"gopher"}`; got != want {
+			t.Errorf("got variables: %q, want: %q", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithFormEncodedPOST())
+
+	var q struct {
+		User struct {
+			Name graphql.String
+		} `graphql:"user(login: $login)"`
+	}
+	_, err := client.Query(context.Background(), &q, map[string]interface{}{"login": graphql.String("gopher")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.User.Name, graphql.String("Gopher"); got != want {
+		t.Errorf("got q.User.Name: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_Query_get(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			if got, want := req.URL.Query().Get("query"), `query($login:String!){user(login: $login){name}}`; got != want {
+				t.Errorf("got query: %q, want: %q", got, want)
+			}
+			if got, want := req.URL.Query().Get("variables"), `{"login":"gopher"}`; got != want {
+				t.Errorf("got variables: %q, want: %q", got, want)
+			}
+			if got, want := req.URL.Query().Get("tenant"), "acme"; got != want {
+				t.Errorf("got tenant: %q, want: %q", got, want)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+		case http.MethodPost:
+			if got, want := mustRead(req.Body), `{"query":"mutation{follow{ok}}"}`+"\n"; got != want {
+				t.Errorf("got body: %q, want: %q", got, want)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"data": {"follow": {"ok": true}}}`)
+		}
+	})
+	client := graphql.NewClient("/graphql?tenant=acme", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithGET())
+
+	var q struct {
+		User struct {
+			Name graphql.String
+		} `graphql:"user(login: $login)"`
+	}
+	_, err := client.Query(context.Background(), &q, map[string]interface{}{"login": graphql.String("gopher")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.User.Name, graphql.String("Gopher"); got != want {
+		t.Errorf("got q.User.Name: %q, want: %q", got, want)
+	}
+
+	var m struct {
+		Follow struct {
+			OK graphql.Boolean
+		}
+	}
+	_, err = client.Mutate(context.Background(), &m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Follow.OK {
+		t.Error("got not ok")
+	}
+}
+
+// The same field can be selected twice under different aliases,
+// which responses are decoded by.
+func TestClient_Query_aliases(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query($closed:IssueState!$open:IssueState!){repository{openIssues: issues(states: $open){totalCount},closedIssues : issues(states: $closed){totalCount}}}","variables":{"closed":"CLOSED","open":"OPEN"}}`+"\n"; got != want {
+			t.Errorf("got body:\n%v\nwant:\n%v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"repository": {"openIssues": {"totalCount": 3}, "closedIssues": {"totalCount": 7}}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type IssueState string
+	type issues struct {
+		TotalCount graphql.Int
+	}
+	var q struct {
+		Repository struct {
+			OpenIssues   issues `graphql:"openIssues: issues(states: $open)"`
+			ClosedIssues issues `graphql:"closedIssues : issues(states: $closed)"`
+		}
+	}
+	_, err := client.Query(context.Background(), &q, map[string]interface{}{
+		"open":   IssueState("OPEN"),
+		"closed": IssueState("CLOSED"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Repository.OpenIssues.TotalCount, graphql.Int(3); got != want {
+		t.Errorf("got open issues: %v, want: %v", got, want)
+	}
+	if got, want := q.Repository.ClosedIssues.TotalCount, graphql.Int(7); got != want {
+		t.Errorf("got closed issues: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Query_userAgent(t *testing.T) {
+	var want http.Header
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		for k := range want {
+			if got, want := req.Header.Get(k), want.Get(k); got != want {
+				t.Errorf("got %s header: %q, want: %q", k, got, want)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+	want = http.Header{"User-Agent": {"merico-graphql/" + graphql.Version}}
+	if _, err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+	client = graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithUserAgent("collector/2.0"),
+		graphql.WithClientName("collector", "2.0.1"))
+	want = http.Header{
+		"User-Agent":                   {"collector/2.0"},
+		"Apollographql-Client-Name":    {"collector"},
+		"Apollographql-Client-Version": {"2.0.1"},
+	}
+	if _, err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_Query_headers(t *testing.T) {
+	var want http.Header
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		for k := range want {
+			if got, want := req.Header.Get(k), want.Get(k); got != want {
+				t.Errorf("got %s header: %q, want: %q", k, got, want)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+
+	type tenantKey struct{}
+	tokens := 0
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRequestOptions(graphql.WithHeader("X-Tenant", "default")),
+		graphql.WithHeaderProvider(func(ctx context.Context) (http.Header, error) {
+			tenant, ok := ctx.Value(tenantKey{}).(string)
+			if !ok {
+				return nil, errors.New("no tenant")
+			}
+			tokens++
+			return http.Header{
+				"authorization": {fmt.Sprintf("Bearer token%d", tokens)},
+				"X-Tenant":      {tenant},
+			}, nil
+		}))
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	want = http.Header{"Authorization": {"Bearer token1"}, "X-Tenant": {"acme"}}
+	if _, err := client.Query(ctx, &q, nil); err != nil {
+		t.Fatal(err)
+	}
+	want = http.Header{"Authorization": {"Bearer token2"}, "X-Tenant": {"acme"}, "X-Trace-Id": {"abc"}}
+	if _, err := client.Query(ctx, &q, nil, graphql.WithRequestHeaders(http.Header{"x-trace-id": {"abc"}})); err != nil {
+		t.Fatal(err)
+	}
+	want = http.Header{"Authorization": {"Bearer token3"}, "X-Tenant": {"other"}}
+	if _, err := client.Query(ctx, &q, nil, graphql.WithRequestHeaders(http.Header{"X-Tenant": {"other"}})); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.Query(context.Background(), &q, nil)
+	if err == nil || err.Error() != "no tenant" {
+		t.Errorf("got error: %v, want: no tenant", err)
+	}
+}
+
 // localRoundTripper is an http.RoundTripper that executes HTTP transactions
 // by using handler directly, instead of going over an HTTP connection.
 type localRoundTripper struct {
@@ -228,3 +761,120 @@ func mustWrite(w io.Writer, s string) {
 		panic(err)
 	}
 }
+
+func TestClient_Query_urlParam(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.URL.EscapedPath(), "/eu/projects/a%2Fb/graphql"; got != want {
+			t.Errorf("got path: %v, want: %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	client := graphql.NewClient("/{region}/projects/{project}/graphql", &http.Client{Transport: localRoundTripper{handler: handler}},
+		graphql.WithRequestOptions(graphql.WithURLParam("region", "eu")))
+
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil, graphql.WithURLParam("project", "a/b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Viewer.Login, graphql.String("gopher"); got != want {
+		t.Errorf("got login: %q, want: %q", got, want)
+	}
+
+	_, err = client.Query(context.Background(), &q, nil)
+	if got, want := fmt.Sprint(err), `no value for parameter "project" of GraphQL server URL "/{region}/projects/{project}/graphql"; set it with WithURLParam`; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Query_extensions(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"{viewer{login}}","extensions":{"cost":{"dryRun":true},"tracing":true}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRequestOptions(graphql.WithExtension("tracing", true)))
+
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil, graphql.WithExtension("cost", map[string]bool{"dryRun": true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_Query_responseExtensions(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}, "extensions": {"cost": {"requestedQueryCost": 1}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	var extensions map[string]json.RawMessage
+	_, err := client.Query(context.Background(), &q, nil, graphql.WithResponseExtensions(&extensions))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(extensions["cost"]), `{"requestedQueryCost": 1}`; got != want {
+		t.Errorf("got extensions[cost]: %s, want: %s", got, want)
+	}
+	if got, want := q.Viewer.Login, graphql.String("gopher"); got != want {
+		t.Errorf("got login: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_Query_operationName(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		w.Header().Set("Content-Type", "application/json")
+		switch body {
+		case `{"query":"query GetViewer{viewer{login}}","operationName":"GetViewer"}` + "\n":
+			mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+		case `{"query":"mutation AddStar($id:ID!){addStar(id: $id){count}}","operationName":"AddStar","variables":{"id":"1"}}` + "\n":
+			mustWrite(w, `{"data": {"addStar": {"count": 1}}}`)
+		default:
+			t.Errorf("unexpected body: %s", body)
+			http.Error(w, "unexpected body", http.StatusBadRequest)
+		}
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil, graphql.WithOperationName("GetViewer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m struct {
+		AddStar struct {
+			Count graphql.Int
+		} `graphql:"addStar(id: $id)"`
+	}
+	_, err = client.Mutate(context.Background(), &m, map[string]interface{}{"id": graphql.ID("1")}, graphql.WithOperationName("AddStar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
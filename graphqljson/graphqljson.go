@@ -0,0 +1,116 @@
+// Package graphqljson decodes the JSON-encoded data of GraphQL responses
+// into query structs, the way graphql.Client does, for tools that receive
+// responses by other means, such as from files, caches or other clients.
+//
+// Query structs are decoded field by field according to their graphql
+// struct field tags, inline fragments and embedded structs, rather than
+// their json tags.
+package graphqljson
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/merico-dev/graphql/internal/jsonutil"
+)
+
+// Unmarshal parses the JSON-encoded GraphQL response data and stores
+// the result in the query struct pointed to by v.
+func Unmarshal(data []byte, v interface{}, opts ...Option) error {
+	return jsonutil.UnmarshalGraphQL(data, v, opts...)
+}
+
+// Decode decodes the next JSON-encoded GraphQL response data read from
+// dec into the query struct pointed to by v, without reading the rest of
+// the input, such as when the data is part of a larger JSON document.
+// dec must be set to decode numbers as json.Number, with UseNumber.
+//
+// If the data is null, v is left unchanged.
+func Decode(dec *json.Decoder, v interface{}, opts ...Option) error {
+	return jsonutil.DecodeGraphQL(dec, v, opts...)
+}
+
+// Unmarshaler is implemented by types that decode their values themselves,
+// such as custom scalars. UnmarshalGraphQL is passed the JSON encoding of
+// a non-null value as a whole, even if it's an object or array.
+//
+// Unlike implementing json.Unmarshaler, implementing Unmarshaler doesn't
+// change how documents are constructed: the fields of struct types are
+// still selected, and only decoding the result is up to the type.
+type Unmarshaler interface {
+	UnmarshalGraphQL(data []byte) error
+}
+
+// Option configures the behavior of Unmarshal and Decode.
+type Option = jsonutil.Option
+
+// CaseInsensitive returns an Option that matches response keys against
+// graphql tag names case-insensitively, the same way "encoding/json"
+// matches keys against struct field names.
+func CaseInsensitive() Option {
+	return jsonutil.CaseInsensitive()
+}
+
+// PreciseNumbers returns an Option that decodes numbers into interface
+// values as json.Number rather than float64, into string values as their
+// text, and into values that unmarshal from text, such as *big.Float ones,
+// with their UnmarshalText method.
+func PreciseNumbers() Option {
+	return jsonutil.PreciseNumbers()
+}
+
+// SpecialFloats returns an Option that decodes the strings "NaN",
+// "Infinity" and "-Infinity" into float fields.
+func SpecialFloats() Option {
+	return jsonutil.SpecialFloats()
+}
+
+// Hook is called with a pointer to a struct field once its value is
+// decoded, such as a *string. It may modify the value, e.g., to normalize
+// it. If it returns an error, decoding fails with it.
+type Hook = jsonutil.Hook
+
+// PathHook returns an Option that calls hook for the fields at the
+// response path, which is the response keys leading to them, separated
+// by dots. List elements don't add to the path.
+func PathHook(path string, hook Hook) Option {
+	return jsonutil.PathHook(path, hook)
+}
+
+// TagHook returns an Option that calls hook for the fields whose
+// graphql-hook tag lists name among comma-separated names.
+func TagHook(name string, hook Hook) Option {
+	return jsonutil.TagHook(name, hook)
+}
+
+// ScalarFunc returns an Option that decodes the values of the type of v
+// with unmarshal, which is passed the JSON encoding of a non-null value
+// and a pointer to a value of the type, instead of with json.Unmarshal.
+//
+// E.g., ScalarFunc(money{}, unmarshalMoney).
+func ScalarFunc(v interface{}, unmarshal func(data []byte, v interface{}) error) Option {
+	return jsonutil.ScalarFunc(reflect.TypeOf(v), unmarshal)
+}
+
+// TimeLayouts returns an Option that decodes strings into time.Time values
+// with the first of layouts they parse with, such as "2006-01-02" for
+// a Date scalar, instead of as RFC 3339 times only.
+func TimeLayouts(layouts ...string) Option {
+	return ScalarFunc(time.Time{}, func(data []byte, v interface{}) error {
+		var s string
+		err := json.Unmarshal(data, &s)
+		if err != nil {
+			return err
+		}
+		for _, layout := range layouts {
+			t, e := time.Parse(layout, s)
+			if e == nil {
+				*v.(*time.Time) = t
+				return nil
+			}
+			err = e
+		}
+		return err
+	})
+}
@@ -0,0 +1,126 @@
+package graphqljson_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+	"github.com/merico-dev/graphql/graphqljson"
+)
+
+// money decodes itself from an object with a number of cents.
+type money struct {
+	Cents    int64
+	Currency string
+}
+
+func (m *money) UnmarshalGraphQL(data []byte) error {
+	var v struct {
+		Amount   float64
+		Currency string
+	}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	*m = money{Cents: int64(v.Amount * 100), Currency: strings.ToUpper(v.Currency)}
+	return nil
+}
+
+func TestUnmarshal(t *testing.T) {
+	var q struct {
+		Order struct {
+			Total    money `graphql:"total{amount,currency}"`
+			PlacedOn time.Time
+			Note     graphql.String
+		}
+	}
+	data := `{"order": {"total": {"amount": 12.5, "currency": "eur"}, "placedOn": "2024-03-01", "note": " fragile "}}`
+	err := graphqljson.Unmarshal([]byte(data), &q,
+		graphqljson.TimeLayouts(time.RFC3339, "2006-01-02"),
+		graphqljson.PathHook("order.note", func(v interface{}) error {
+			s := v.(*graphql.String)
+			*s = graphql.String(strings.TrimSpace(string(*s)))
+			return nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Order.Total, (money{Cents: 1250, Currency: "EUR"}); got != want {
+		t.Errorf("got total: %+v, want: %+v", got, want)
+	}
+	if got, want := q.Order.PlacedOn, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got placed on: %v, want: %v", got, want)
+	}
+	if got, want := q.Order.Note, graphql.String("fragile"); got != want {
+		t.Errorf("got note: %q, want: %q", got, want)
+	}
+
+	err = graphqljson.Unmarshal([]byte(`{"order": {"placedOn": "March 1st"}}`), &q, graphqljson.TimeLayouts("2006-01-02"))
+	if err == nil {
+		t.Error("got no error decoding a time matching none of the layouts")
+	}
+}
+
+// price decodes itself, rounding its amount to cents.
+type price struct {
+	Amount   float64
+	Currency string
+}
+
+func (p *price) UnmarshalGraphQL(data []byte) error {
+	var v struct {
+		Amount   float64
+		Currency string
+	}
+	err := json.Unmarshal(data, &v)
+	*p = price{Amount: float64(int64(v.Amount*100+0.5)) / 100, Currency: v.Currency}
+	return err
+}
+
+func TestUnmarshaler_selection(t *testing.T) {
+	// The fields of structs implementing Unmarshaler are still selected.
+	var q struct {
+		Price price
+	}
+	query, _, err := graphql.ConstructQuery(&q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := query, "{price{amount,currency}}"; got != want {
+		t.Errorf("got query: %q, want: %q", got, want)
+	}
+	err = graphqljson.Unmarshal([]byte(`{"price": {"amount": 9.999, "currency": "USD"}}`), &q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Price, (price{Amount: 10, Currency: "USD"}); got != want {
+		t.Errorf("got price: %+v, want: %+v", got, want)
+	}
+}
+
+func TestScalarFunc(t *testing.T) {
+	type cents int64
+	var q struct {
+		Price cents
+	}
+	err := graphqljson.Unmarshal([]byte(`{"price": "1.25"}`), &q, graphqljson.ScalarFunc(cents(0), func(data []byte, v interface{}) error {
+		var s string
+		err := json.Unmarshal(data, &s)
+		if err != nil {
+			return err
+		}
+		var f float64
+		err = json.Unmarshal([]byte(s), &f)
+		*v.(*cents) = cents(f * 100)
+		return err
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Price != 125 {
+		t.Errorf("got price: %v, want: 125", q.Price)
+	}
+}
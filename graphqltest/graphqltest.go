@@ -0,0 +1,345 @@
+// Package graphqltest provides a fake GraphQL server for tests of code
+// that uses graphql clients, so that they needn't hand-roll HTTP servers
+// that pick operations out of minified query documents.
+//
+// Tests register stubs, which match operations by name or by a pattern of
+// their query document, with canned responses:
+//
+//	s := graphqltest.NewServer(t)
+//	s.On("GetViewer").Respond(`{"viewer": {"login": "gopher"}}`)
+//	s.OnQuery(`addStar\(`).RespondErrors(graphql.DataError{Message: "forbidden"})
+//	client := s.Client()
+//
+// Operations that no stub matches fail the test when it ends.
+package graphqltest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+// Server is a fake GraphQL server. It serves the operations it's sent
+// with the responses of the first registered stub that matches them.
+// It accepts operations sent as JSON, form values and query parameters,
+// and batches of operations.
+type Server struct {
+	t testing.TB
+
+	mu        sync.Mutex // Guards the fields below, and those of stubs.
+	stubs     []*Stub
+	requests  []Request
+	unmatched []Request // Requests that no stub matched.
+}
+
+// NewServer returns a fake GraphQL server that fails the test t, when it
+// ends, if it was sent operations that no stub matches. Failures are
+// reported from the test's goroutine rather than the server's, as
+// testing.TB requires.
+func NewServer(t testing.TB) *Server {
+	s := &Server{t: t}
+	t.Cleanup(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, r := range s.unmatched {
+			t.Errorf("graphqltest: no stub matches operation %q: %s", r.OperationName, r.Query)
+		}
+	})
+	return s
+}
+
+// Request is an operation sent to a Server.
+type Request struct {
+	OperationName string // Name of the operation, if it's named. E.g., "GetViewer".
+	Query         string
+	Variables     map[string]interface{}
+	Header        http.Header
+}
+
+// On registers a stub that matches the operations named name.
+func (s *Server) On(name string) *Stub {
+	return s.add(func(r Request) bool { return r.OperationName == name })
+}
+
+// OnQuery registers a stub that matches the operations whose query
+// document matches the regular expression pattern. E.g., `^mutation`,
+// or `repository\(`.
+func (s *Server) OnQuery(pattern string) *Stub {
+	re := regexp.MustCompile(pattern)
+	return s.add(func(r Request) bool { return re.MatchString(r.Query) })
+}
+
+// OnFunc registers a stub that matches the operations for which match
+// returns true, such as those with some variables.
+func (s *Server) OnFunc(match func(Request) bool) *Stub {
+	return s.add(match)
+}
+
+func (s *Server) add(match func(Request) bool) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stub := &Stub{server: s, match: match, status: http.StatusOK, data: json.RawMessage("null")}
+	s.stubs = append(s.stubs, stub)
+	return stub
+}
+
+// Requests returns the operations sent to s so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Client returns a GraphQL client that sends its operations to s,
+// in memory, created with opts.
+func (s *Server) Client(opts ...graphql.ClientOption) *graphql.Client {
+	return graphql.NewClient("http://graphqltest/graphql", &http.Client{Transport: s.Transport()}, opts...)
+}
+
+// Transport returns an http.RoundTripper that serves requests with s,
+// in memory, for code that creates its clients from an *http.Client.
+func (s *Server) Transport() http.RoundTripper {
+	return roundTripper{handler: s}
+}
+
+// Start starts an HTTP server serving s, for code that needs a URL rather
+// than a client. It's closed when the test ends.
+func (s *Server) Start() *httptest.Server {
+	hs := httptest.NewServer(s)
+	s.t.Cleanup(hs.Close)
+	return hs
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	reqs, batch, err := parseRequest(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	responses := make([]interface{}, len(reqs))
+	for i, r := range reqs {
+		resp, ok := s.match(r)
+		if !ok {
+			responses[i] = response{Errors: []dataError{{Message: "graphqltest: no stub matches the operation"}}}
+			continue
+		}
+		if resp.status != http.StatusOK && !batch {
+			w.WriteHeader(resp.status)
+			io.WriteString(w, resp.body)
+			return
+		}
+		responses[i] = resp.response
+	}
+	var body interface{} = responses
+	if !batch {
+		body = responses[0]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// match records the request r, and returns the response of the first
+// stub that matches it, and whether there's one.
+func (s *Server) match(r Request) (stubResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+	for _, stub := range s.stubs {
+		if stub.match(r) {
+			stub.calls++
+			return stub.response(), true
+		}
+	}
+	s.unmatched = append(s.unmatched, r)
+	return stubResponse{}, false
+}
+
+// Stub is a canned response to the operations it matches.
+// By default, it responds with null data.
+type Stub struct {
+	server *Server
+	match  func(Request) bool
+
+	// Guarded by the server's mutex, as stubs are set up
+	// while the server may be serving requests.
+	calls      int
+	status     int
+	body       string // Body of non-200 OK responses.
+	data       json.RawMessage
+	errors     []dataError
+	extensions map[string]interface{}
+}
+
+// Respond sets the data that st responds with, as JSON.
+// E.g., `{"viewer": {"login": "gopher"}}`.
+func (st *Stub) Respond(data string) *Stub {
+	st.server.mu.Lock()
+	defer st.server.mu.Unlock()
+	st.data = json.RawMessage(data)
+	return st
+}
+
+// RespondErrors sets the GraphQL errors that st responds with,
+// along with its data.
+func (st *Stub) RespondErrors(errs ...graphql.DataError) *Stub {
+	st.server.mu.Lock()
+	defer st.server.mu.Unlock()
+	st.errors = make([]dataError, 0, len(errs))
+	for _, e := range errs {
+		st.errors = append(st.errors, newDataError(e))
+	}
+	return st
+}
+
+// RespondExtensions sets the extensions that st responds with.
+func (st *Stub) RespondExtensions(extensions map[string]interface{}) *Stub {
+	st.server.mu.Lock()
+	defer st.server.mu.Unlock()
+	st.extensions = extensions
+	return st
+}
+
+// RespondStatus makes st respond with the HTTP status code status and
+// body, instead of a GraphQL response, such as to test how failures of
+// proxies are handled. Operations in batches get their GraphQL response.
+func (st *Stub) RespondStatus(status int, body string) *Stub {
+	st.server.mu.Lock()
+	defer st.server.mu.Unlock()
+	st.status = status
+	st.body = body
+	return st
+}
+
+// Calls returns the number of operations that st has matched.
+func (st *Stub) Calls() int {
+	st.server.mu.Lock()
+	defer st.server.mu.Unlock()
+	return st.calls
+}
+
+// stubResponse is what a stub responds with.
+type stubResponse struct {
+	status   int
+	body     string
+	response response
+}
+
+// response returns what st responds with. It must be called with
+// the server's mutex held.
+func (st *Stub) response() stubResponse {
+	return stubResponse{
+		status:   st.status,
+		body:     st.body,
+		response: response{Data: st.data, Errors: st.errors, Extensions: st.extensions},
+	}
+}
+
+// response is a GraphQL response.
+type response struct {
+	Data       json.RawMessage        `json:"data"`
+	Errors     []dataError            `json:"errors,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// dataError is the JSON encoding of a graphql.DataError.
+type dataError struct {
+	Message    string                     `json:"message"`
+	Locations  []location                 `json:"locations,omitempty"`
+	Path       []interface{}              `json:"path,omitempty"`
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
+}
+
+type location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func newDataError(e graphql.DataError) dataError {
+	de := dataError{Message: e.Message, Path: e.Path, Extensions: e.Extensions}
+	for _, l := range e.Locations {
+		de.Locations = append(de.Locations, location{Line: l.Line, Column: l.Column})
+	}
+	return de
+}
+
+// parseRequest returns the operations of req, and whether they're
+// a batch.
+func parseRequest(req *http.Request) (_ []Request, batch bool, err error) {
+	if req.Method == http.MethodGet || strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		r := Request{Query: req.FormValue("query"), OperationName: req.FormValue("operationName"), Header: req.Header}
+		if v := req.FormValue("variables"); v != "" {
+			err := json.Unmarshal([]byte(v), &r.Variables)
+			if err != nil {
+				return nil, false, fmt.Errorf("decoding variables: %v", err)
+			}
+		}
+		return []Request{named(r)}, false, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	var reqs []Request
+	if body = bytes.TrimSpace(body); len(body) > 0 && body[0] == '[' {
+		err = json.Unmarshal(body, &reqs)
+		if len(reqs) == 0 && err == nil {
+			err = fmt.Errorf("empty batch")
+		}
+		batch = true
+	} else {
+		reqs = make([]Request, 1)
+		err = json.Unmarshal(body, &reqs[0])
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("decoding request: %v", err)
+	}
+	for i := range reqs {
+		reqs[i].Header = req.Header
+		reqs[i] = named(reqs[i])
+	}
+	return reqs, batch, nil
+}
+
+// named returns r with its operation name taken from its query document,
+// if it wasn't given.
+func named(r Request) Request {
+	if r.OperationName == "" {
+		r.OperationName = operationName(r.Query)
+	}
+	return r
+}
+
+// operationName matches the name of the first operation of a document.
+var operationNameRE = regexp.MustCompile(`^\s*(?:query|mutation|subscription)\s+([_A-Za-z][_0-9A-Za-z]*)`)
+
+// operationName returns the name of the first operation of the document
+// query, or "" if it's anonymous.
+func operationName(query string) string {
+	m := operationNameRE.FindStringSubmatch(query)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// roundTripper is an http.RoundTripper that executes HTTP transactions
+// by using handler directly, instead of going over an HTTP connection.
+type roundTripper struct {
+	handler http.Handler
+}
+
+func (rt roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	rt.handler.ServeHTTP(w, req)
+	return w.Result(), nil
+}
@@ -0,0 +1,184 @@
+package graphqltest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+	"github.com/merico-dev/graphql/graphqltest"
+)
+
+func TestServer(t *testing.T) {
+	s := graphqltest.NewServer(t)
+	viewer := s.On("GetViewer").Respond(`{"viewer": {"login": "gopher"}}`)
+	s.OnQuery(`^mutation`).RespondErrors(graphql.DataError{Message: "forbidden", Path: []interface{}{"addStar"}})
+	s.OnQuery(`repository\(`).RespondStatus(http.StatusBadGateway, "bad gateway")
+	client := s.Client()
+
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil, graphql.WithOperationName("GetViewer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Viewer.Login, graphql.String("gopher"); got != want {
+		t.Errorf("got login: %q, want: %q", got, want)
+	}
+
+	var m struct {
+		AddStar struct {
+			Starred graphql.Boolean
+		} `graphql:"addStar(id: $id)"`
+	}
+	dataErrors, err := client.Mutate(context.Background(), &m, map[string]interface{}{"id": graphql.ID("R_1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dataErrors) != 1 || dataErrors[0].Message != "forbidden" || !reflect.DeepEqual(dataErrors[0].Path, []interface{}{"addStar"}) {
+		t.Errorf("got errors: %+v, want forbidden at addStar", dataErrors)
+	}
+
+	var r struct {
+		Repository struct {
+			Name graphql.String
+		} `graphql:"repository(name: \"graphql\")"`
+	}
+	_, err = client.Query(context.Background(), &r, nil)
+	var statusErr *graphql.HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("got error: %v, want a 502 HTTPStatusError", err)
+	}
+
+	requests := s.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(requests))
+	}
+	if got, want := requests[0].OperationName, "GetViewer"; got != want {
+		t.Errorf("got operation name: %q, want: %q", got, want)
+	}
+	if got, want := requests[1].Variables, map[string]interface{}{"id": "R_1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got variables: %v, want: %v", got, want)
+	}
+	if got, want := viewer.Calls(), 1; got != want {
+		t.Errorf("got %d calls, want %d", got, want)
+	}
+}
+
+func TestServer_getAndBatch(t *testing.T) {
+	s := graphqltest.NewServer(t)
+	s.OnFunc(func(r graphqltest.Request) bool { return r.Variables["login"] == "a" }).Respond(`{"user": {"login": "a"}}`)
+	s.OnFunc(func(r graphqltest.Request) bool { return r.Variables["login"] == "b" }).Respond(`{"user": {"login": "b"}}`)
+
+	type user struct {
+		User struct {
+			Login graphql.String
+		} `graphql:"user(login: $login)"`
+	}
+	var a user
+	_, err := s.Client(graphql.WithGET()).Query(context.Background(), &a, map[string]interface{}{"login": graphql.String("a")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.User.Login != "a" {
+		t.Errorf("got login: %q, want: a", a.User.Login)
+	}
+
+	var b1, b2 user
+	_, err = s.Client().QueryBatch(context.Background(), []graphql.BatchOperation{
+		{Query: &b1, Variables: map[string]interface{}{"login": graphql.String("b")}},
+		{Query: &b2, Variables: map[string]interface{}{"login": graphql.String("a")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b1.User.Login != "b" || b2.User.Login != "a" {
+		t.Errorf("got logins: %q, %q, want: b, a", b1.User.Login, b2.User.Login)
+	}
+}
+
+func TestServer_unmatched(t *testing.T) {
+	rt := &recordingT{TB: t}
+	s := graphqltest.NewServer(rt)
+	hs := s.Start()
+	client := graphql.NewClient(hs.URL, nil)
+
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	dataErrors, err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dataErrors) != 1 {
+		t.Errorf("got errors: %+v, want one", dataErrors)
+	}
+	if len(rt.errors) != 0 {
+		t.Errorf("got test errors before the test ended: %q", rt.errors)
+	}
+	rt.end()
+	if got, want := rt.errors, []string{`graphqltest: no stub matches operation "": {viewer{login}}`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got test errors: %q, want: %q", got, want)
+	}
+}
+
+func TestServer_concurrent(t *testing.T) {
+	s := graphqltest.NewServer(t)
+	stub := s.On("GetViewer")
+	client := graphql.NewClient(s.Start().URL, nil)
+
+	// Stubs may be set up while the server is serving requests.
+	done := make(chan error)
+	go func() {
+		var q struct {
+			Viewer struct {
+				Login graphql.String
+			}
+		}
+		var err error
+		for i := 0; i < 10 && err == nil; i++ {
+			_, err = client.Query(context.Background(), &q, nil, graphql.WithOperationName("GetViewer"))
+		}
+		done <- err
+	}()
+	for i := 0; i < 10; i++ {
+		stub.Respond(fmt.Sprintf(`{"viewer": {"login": "gopher-%d"}}`, i))
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stub.Calls(), 10; got != want {
+		t.Errorf("got %d calls, want %d", got, want)
+	}
+}
+
+// recordingT is a testing.TB that records the errors reported with Errorf,
+// and the functions registered with Cleanup, which end calls.
+type recordingT struct {
+	testing.TB
+	errors  []string
+	cleanup []func()
+}
+
+func (rt *recordingT) Errorf(format string, args ...interface{}) {
+	rt.errors = append(rt.errors, fmt.Sprintf(format, args...))
+}
+
+func (rt *recordingT) Cleanup(f func()) {
+	rt.cleanup = append(rt.cleanup, f)
+}
+
+// end calls the functions registered with Cleanup, as if the test ended.
+func (rt *recordingT) end() {
+	for i := len(rt.cleanup) - 1; i >= 0; i-- {
+		rt.cleanup[i]()
+	}
+}
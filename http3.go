@@ -0,0 +1,167 @@
+package graphql
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithHTTP3 makes the client send requests with rt, an HTTP/3-capable
+// RoundTripper such as the one of github.com/quic-go/quic-go/http3, to
+// endpoints that advertise HTTP/3 support in an Alt-Svc response header.
+// Requests to other endpoints, and the requests that discover support,
+// are sent with the client's HTTP client as usual. If a request over
+// HTTP/3 fails, HTTP/3 isn't used for its endpoint for a while, and the
+// request is sent again as usual if it's a query, or a GET request, and
+// its body can be replayed. Other requests, such as those of mutations,
+// fail instead, since they may have reached the server before failing.
+//
+// It's experimental: HTTP/3 can improve latency on lossy networks, but
+// is often blocked by firewalls that drop UDP traffic.
+func WithHTTP3(rt http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		hc := *c.httpClient
+		base := hc.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		hc.Transport = &altSvcTransport{
+			base:   base,
+			h3:     rt,
+			hosts:  make(map[string]time.Time),
+			broken: make(map[string]time.Time),
+		}
+		c.httpClient = &hc
+	}
+}
+
+// http3Backoff is how long HTTP/3 isn't used
+// for an endpoint after a request over it failed.
+const http3Backoff = 5 * time.Minute
+
+// altSvcTransport sends requests with h3 to the hosts that advertised
+// HTTP/3 support in an Alt-Svc header, and with base otherwise.
+type altSvcTransport struct {
+	base http.RoundTripper
+	h3   http.RoundTripper
+
+	mu     sync.Mutex
+	hosts  map[string]time.Time // Hosts supporting HTTP/3, with when their support expires.
+	broken map[string]time.Time // Hosts HTTP/3 failed for, with when to try it again.
+}
+
+func (t *altSvcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if t.useHTTP3(host) {
+		resp, err := t.h3.RoundTrip(req)
+		if err == nil {
+			return resp, nil
+		}
+		if req.Context().Err() != nil {
+			return nil, err
+		}
+		t.markBroken(host)
+		if req.Method != http.MethodGet && req.Context().Value(replayableKey{}) == nil {
+			return nil, err
+		}
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		t.observe(host, req.URL.Port(), req.URL.Scheme, resp.Header.Values("Alt-Svc"))
+	}
+	return resp, err
+}
+
+// replayableKey is the context key of requests that may be sent again
+// over TCP once sending them over HTTP/3 failed, because they're queries,
+// which have no side effects.
+type replayableKey struct{}
+
+// useHTTP3 reports whether to send a request to host with HTTP/3.
+func (t *altSvcTransport) useHTTP3(host string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	if until, ok := t.broken[host]; ok {
+		if now.Before(until) {
+			return false
+		}
+		delete(t.broken, host)
+	}
+	expires, ok := t.hosts[host]
+	if ok && now.After(expires) {
+		delete(t.hosts, host)
+		return false
+	}
+	return ok
+}
+
+func (t *altSvcTransport) markBroken(host string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.broken[host] = time.Now().Add(http3Backoff)
+}
+
+// observe records the HTTP/3 support host advertised in the Alt-Svc
+// header values altSvc of a response to a request to port and scheme.
+// Only alternatives on the same host and port are used.
+func (t *altSvcTransport) observe(host, port, scheme string, altSvc []string) {
+	if len(altSvc) == 0 {
+		return
+	}
+	if port == "" {
+		port = "443"
+		if scheme == "http" {
+			port = "80"
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, v := range altSvc {
+		if strings.TrimSpace(v) == "clear" {
+			delete(t.hosts, host)
+			continue
+		}
+		for _, alt := range strings.Split(v, ",") {
+			maxAge, ok := parseHTTP3Alternative(alt, port)
+			if ok {
+				t.hosts[host] = time.Now().Add(maxAge)
+			}
+		}
+	}
+}
+
+// parseHTTP3Alternative parses an alternative service of an Alt-Svc header,
+// such as `h3=":443"; ma=3600`, and returns for how long it's valid if it's
+// HTTP/3 on port of the same host.
+func parseHTTP3Alternative(alt, port string) (time.Duration, bool) {
+	params := strings.Split(alt, ";")
+	protocol, authority, ok := strings.Cut(strings.TrimSpace(params[0]), "=")
+	if !ok || protocol != "h3" || strings.Trim(authority, `"`) != ":"+port {
+		return 0, false
+	}
+	maxAge := 24 * time.Hour // Default of RFC 7838.
+	for _, p := range params[1:] {
+		name, value, _ := strings.Cut(strings.TrimSpace(p), "=")
+		if name == "ma" {
+			seconds, err := strconv.Atoi(value)
+			if err != nil {
+				return 0, false
+			}
+			maxAge = time.Duration(seconds) * time.Second
+		}
+	}
+	return maxAge, true
+}
@@ -0,0 +1,90 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+// fakeHTTP3 is a stand-in for an HTTP/3 RoundTripper.
+type fakeHTTP3 struct {
+	rt   http.RoundTripper
+	fail bool
+}
+
+func (f *fakeHTTP3) RoundTrip(req *http.Request) (*http.Response, error) {
+	if f.fail {
+		return nil, errors.New("udp blocked")
+	}
+	req.Header.Set("X-Protocol", "h3")
+	return f.rt.RoundTrip(req)
+}
+
+func TestWithHTTP3(t *testing.T) {
+	var protocols []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		protocol := req.Header.Get("X-Protocol")
+		if protocol == "" {
+			protocol = "h1"
+		}
+		protocols = append(protocols, protocol)
+		w.Header().Set("Alt-Svc", `h2=":443"; ma=60, h3=":443"; ma=3600`)
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	local := localRoundTripper{handler: mux}
+	h3 := &fakeHTTP3{rt: local}
+	client := graphql.NewClient("https://example.com/graphql", &http.Client{Transport: local}, graphql.WithHTTP3(h3))
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Do(context.Background(), "{viewer{login}}", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Once HTTP/3 fails, requests fall back to the client's transport.
+	h3.fail = true
+	for i := 0; i < 2; i++ {
+		_, err := client.Do(context.Background(), "{viewer{login}}", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"h1", "h3", "h1", "h1"}; !reflect.DeepEqual(protocols, want) {
+		t.Errorf("got protocols: %v, want: %v", protocols, want)
+	}
+}
+
+func TestWithHTTP3_mutations(t *testing.T) {
+	var requests int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Header().Set("Alt-Svc", `h3=":443"`)
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"addStar": {"id": "1"}}}`)
+	})
+	local := localRoundTripper{handler: mux}
+	h3 := &fakeHTTP3{rt: local}
+	client := graphql.NewClient("https://example.com/graphql", &http.Client{Transport: local}, graphql.WithHTTP3(h3))
+
+	_, err := client.Do(context.Background(), "{viewer{login}}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A mutation that failed over HTTP/3 may have been executed,
+	// so it isn't sent again.
+	h3.fail = true
+	_, err = client.Do(context.Background(), `mutation{addStar(id: "1"){id}}`, nil)
+	if err == nil {
+		t.Error("got no error from a mutation failing over HTTP/3")
+	}
+	if requests != 1 {
+		t.Errorf("got %v requests, want 1", requests)
+	}
+}
@@ -0,0 +1,368 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"reflect"
+	"strings"
+
+	"github.com/merico-dev/graphql/internal/jsonutil"
+)
+
+// incrementalAccept is the media type of incrementally delivered responses,
+// which is accepted for operations with @defer or @stream directives.
+const incrementalAccept = "multipart/mixed;deferSpec=20220824"
+
+// Increment is a payload of a response delivered incrementally, for an
+// operation with @defer or @stream directives.
+type Increment struct {
+	Label   string        // Label of the @defer or @stream directive, if any.
+	Path    []interface{} // Path of the deferred fragment, or of the first streamed item. nil for the initial payload.
+	Errors  []DataError   // Errors of the payload.
+	HasNext bool          // Whether more payloads follow.
+
+	// Data is the data of the payload: the initial data, or the data of
+	// the deferred fragment at Path. Items are the streamed list items,
+	// the first of which is at Path.
+	Data  json.RawMessage
+	Items []json.RawMessage
+}
+
+// WithIncrementFunc makes the client call f with each payload of a response
+// delivered incrementally, once it's applied, so that deferred fragments
+// and streamed list items can be shown as they arrive. Query and Mutate
+// decode each payload into the query struct as it's received, so that f
+// can read the struct with the payload applied; responses decoded that way
+// have a nil Data, as seen by functions such as the one set with
+// WithResponseFunc. Responses that need their data, such as cached ones,
+// are decoded once the last payload is received instead.
+//
+// Operations with @defer or @stream directives accept incremental delivery
+// as multipart/mixed responses regardless; WithIncrementFunc only observes
+// the payloads.
+func WithIncrementFunc(f func(Increment)) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.incrementFunc = f
+	}
+}
+
+// hasIncrementalDirectives reports whether the document query
+// uses the @defer or @stream directives.
+func hasIncrementalDirectives(query string) bool {
+	return strings.Contains(query, "@defer") || strings.Contains(query, "@stream")
+}
+
+// isMultipartMixed reports whether the Content-Type header value ct
+// is multipart/mixed, and returns its boundary if so.
+func isMultipartMixed(ct string) (boundary string, ok bool) {
+	mediaType, params, err := mime.ParseMediaType(ct)
+	if err != nil || mediaType != "multipart/mixed" {
+		return "", false
+	}
+	return params["boundary"], true
+}
+
+// incrementalPayload is a part of an incrementally delivered response.
+// Payloads of the current version of the specification refer to the
+// deferred fragments and streams announced as pending by id, rather than
+// by path.
+type incrementalPayload struct {
+	Data        json.RawMessage
+	Items       []json.RawMessage
+	Path        []interface{}
+	Label       string
+	Errors      dataErrors
+	Extensions  map[string]json.RawMessage
+	HasNext     bool
+	Incremental []incrementalPayload
+
+	ID        string
+	SubPath   []interface{}
+	Pending   []pendingResult
+	Completed []completedResult
+}
+
+// pendingResult is a deferred fragment or stream announced by the server.
+type pendingResult struct {
+	ID    string
+	Path  []interface{}
+	Label string
+}
+
+// completedResult is a pending result the server is done with.
+type completedResult struct {
+	ID     string
+	Errors dataErrors
+}
+
+// readIncremental reads the multipart/mixed response r, with the parts
+// separated by boundary, applying its payloads to the data of out, or
+// decoding them into cfg.into as they're received if it's set.
+func (c *Client) readIncremental(r io.Reader, boundary string, out *Response, cfg *requestConfig) error {
+	mr := multipart.NewReader(r, boundary)
+	var data interface{}
+	pending := make(map[string]pendingResult)
+	first := true
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		dec := json.NewDecoder(part)
+		dec.UseNumber()
+		var p incrementalPayload
+		err = dec.Decode(&p)
+		if err == io.EOF {
+			continue // An empty part, such as a heartbeat.
+		} else if err != nil {
+			return err
+		}
+		var increments []Increment
+		switch {
+		case first:
+			first = false
+			err = unmarshalNumbers(p.Data, &data)
+			if err != nil {
+				return err
+			}
+			if cfg.into != nil {
+				err = c.decodeInitial(p.Data, cfg)
+				if err != nil {
+					return err
+				}
+			}
+			increments = append(increments, Increment{Errors: p.Errors, Data: p.Data})
+		case p.Path != nil:
+			// A payload of an earlier version of the specification,
+			// without the incremental list.
+			p.Incremental = []incrementalPayload{p}
+			p.Errors = nil
+		}
+		out.Errors = append(out.Errors, p.Errors...)
+		for k, v := range p.Extensions {
+			if out.Extensions == nil {
+				out.Extensions = make(map[string]json.RawMessage)
+			}
+			out.Extensions[k] = v
+		}
+		for _, pend := range p.Pending {
+			pend.Path = pathIndices(pend.Path)
+			pending[pend.ID] = pend
+		}
+		for _, inc := range p.Incremental {
+			inc.Path = pathIndices(inc.Path)
+			if inc.ID != "" {
+				pend, ok := pending[inc.ID]
+				if !ok {
+					return fmt.Errorf("payload of unknown pending result %q", inc.ID)
+				}
+				inc.Label = pend.Label
+				inc.Path = append(pend.Path[:len(pend.Path):len(pend.Path)], pathIndices(inc.SubPath)...)
+				if inc.Items != nil {
+					// Streamed items are appended to the list at the path.
+					n, err := listLen(data, inc.Path)
+					if err != nil {
+						return fmt.Errorf("applying payload at path %v: %w", inc.Path, err)
+					}
+					inc.Path = append(inc.Path, n)
+				}
+			}
+			data, err = applyIncrement(data, inc)
+			if err == nil && cfg.into != nil {
+				err = c.decodeIncrement(inc, cfg)
+			}
+			if err != nil {
+				return fmt.Errorf("applying payload at path %v: %w", inc.Path, err)
+			}
+			out.Errors = append(out.Errors, inc.Errors...)
+			increments = append(increments, Increment{Label: inc.Label, Path: inc.Path, Errors: inc.Errors, Data: inc.Data, Items: inc.Items})
+		}
+		for _, done := range p.Completed {
+			pend := pending[done.ID]
+			delete(pending, done.ID)
+			if len(done.Errors) == 0 {
+				continue
+			}
+			// The deferred fragment or stream failed.
+			out.Errors = append(out.Errors, done.Errors...)
+			increments = append(increments, Increment{Label: pend.Label, Path: pend.Path, Errors: done.Errors})
+		}
+		if cfg.incrementFunc == nil {
+			continue
+		}
+		for i, inc := range increments {
+			inc.HasNext = p.HasNext || i < len(increments)-1
+			cfg.incrementFunc(inc)
+		}
+	}
+	if len(out.Errors) == 0 {
+		out.Errors = nil
+	}
+	if data != nil && cfg.into == nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		out.Data = b
+	}
+	return nil
+}
+
+// decodeInitial decodes the initial data of an incrementally delivered
+// response into cfg.into.
+func (c *Client) decodeInitial(data json.RawMessage, cfg *requestConfig) error {
+	if cfg.dataDecoded {
+		// Reset what an earlier attempt decoded.
+		v := reflect.ValueOf(cfg.into).Elem()
+		v.Set(reflect.Zero(v.Type()))
+	}
+	cfg.dataDecoded = true
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	return jsonutil.UnmarshalGraphQL(data, cfg.into, c.dataDecodeOptions(cfg)...)
+}
+
+// decodeIncrement decodes the deferred data or streamed items of inc
+// into cfg.into, at the path of inc.
+func (c *Client) decodeIncrement(inc incrementalPayload, cfg *requestConfig) error {
+	if inc.Items != nil {
+		list, start := inc.Path[:len(inc.Path)-1], inc.Path[len(inc.Path)-1].(int)
+		for i, item := range inc.Items {
+			err := jsonutil.UnmarshalGraphQLAt(item, cfg.into, append(list[:len(list):len(list)], start+i), c.dataDecodeOptions(cfg)...)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(inc.Data) == 0 || string(inc.Data) == "null" {
+		return nil
+	}
+	return jsonutil.UnmarshalGraphQLAt(inc.Data, cfg.into, inc.Path, c.dataDecodeOptions(cfg)...)
+}
+
+// listLen returns the length of the list at path in data.
+func listLen(data interface{}, path []interface{}) (int, error) {
+	var n int
+	_, err := updateAt(data, path, func(v interface{}) (interface{}, error) {
+		items, ok := v.([]interface{})
+		if !ok {
+			return nil, errors.New("streamed items into a non-list")
+		}
+		n = len(items)
+		return v, nil
+	})
+	return n, err
+}
+
+// applyIncrement applies the deferred data or streamed items of inc
+// to data, at the path of inc, and returns the result.
+func applyIncrement(data interface{}, inc incrementalPayload) (interface{}, error) {
+	if inc.Items != nil {
+		if len(inc.Path) == 0 {
+			return nil, errors.New("streamed items without a list index")
+		}
+		return updateAt(data, inc.Path[:len(inc.Path)-1], func(v interface{}) (interface{}, error) {
+			items, ok := v.([]interface{})
+			if !ok {
+				return nil, errors.New("streamed items into a non-list")
+			}
+			for _, raw := range inc.Items {
+				var item interface{}
+				err := unmarshalNumbers(raw, &item)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+			return items, nil
+		})
+	}
+	var patch interface{}
+	err := unmarshalNumbers(inc.Data, &patch)
+	if err != nil || patch == nil {
+		return data, err
+	}
+	return updateAt(data, inc.Path, func(v interface{}) (interface{}, error) {
+		return mergeObjects(v, patch), nil
+	})
+}
+
+// updateAt replaces the value at path in v, which is made of field names
+// and list indices, with the one f returns for it, and returns the result.
+func updateAt(v interface{}, path []interface{}, f func(v interface{}) (interface{}, error)) (interface{}, error) {
+	if len(path) == 0 {
+		return f(v)
+	}
+	switch node := v.(type) {
+	case map[string]interface{}:
+		name, ok := path[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("index %v into an object", path[0])
+		}
+		child, err := updateAt(node[name], path[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		node[name] = child
+		return node, nil
+	case []interface{}:
+		i, ok := path[0].(int)
+		if !ok || i < 0 || i >= len(node) {
+			return nil, fmt.Errorf("index %v out of range of a list of %d items", path[0], len(node))
+		}
+		child, err := updateAt(node[i], path[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = child
+		return node, nil
+	}
+	return nil, fmt.Errorf("key %v into a scalar or null", path[0])
+}
+
+// pathIndices returns path with its list indices,
+// decoded as json.Number, converted to int.
+func pathIndices(path []interface{}) []interface{} {
+	for i, key := range path {
+		if n, ok := key.(json.Number); ok {
+			if index, err := n.Int64(); err == nil {
+				path[i] = int(index)
+			}
+		}
+	}
+	return path
+}
+
+// mergeObjects merges the object patch into the object v, recursively,
+// and returns the result. Values other than objects are replaced.
+func mergeObjects(v, patch interface{}) interface{} {
+	dst, ok1 := v.(map[string]interface{})
+	src, ok2 := patch.(map[string]interface{})
+	if !ok1 || !ok2 {
+		return patch
+	}
+	for k, s := range src {
+		dst[k] = mergeObjects(dst[k], s)
+	}
+	return dst
+}
+
+// unmarshalNumbers unmarshals the JSON data into v,
+// keeping numbers as json.Number.
+func unmarshalNumbers(data []byte, v *interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -0,0 +1,148 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestIncrementalDelivery(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("Accept"), "multipart/mixed;deferSpec=20220824, application/graphql-response+json, application/json"; got != want {
+			t.Errorf("got Accept: %q, want: %q", got, want)
+		}
+		w.Header().Set("Content-Type", `multipart/mixed; boundary="-"; deferSpec=20220824`)
+		mustWrite(w, strings.Join([]string{
+			"\r\n---\r\n",
+			"Content-Type: application/json; charset=utf-8\r\n\r\n" +
+				`{"data": {"viewer": {"login": "gopher", "repositories": [{"name": "a"}]}}, "hasNext": true}` + "\r\n---\r\n",
+			"Content-Type: application/json; charset=utf-8\r\n\r\n" +
+				`{"incremental": [{"data": {"bio": "Gopher", "followers": 12345678901}, "path": ["viewer"], "label": "details"}, {"items": [{"name": "b"}, {"name": "c"}], "path": ["viewer", "repositories", 1]}], "hasNext": true}` + "\r\n---\r\n",
+			"Content-Type: application/json; charset=utf-8\r\n\r\n" +
+				`{"incremental": [{"items": [{"name": "d"}], "path": ["viewer", "repositories", 3], "errors": [{"message": "slow", "path": ["viewer", "repositories", 3]}]}], "hasNext": false}` + "\r\n-----\r\n",
+		}, ""))
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Viewer struct {
+			Login   graphql.String
+			Details struct {
+				Bio       graphql.String
+				Followers int64
+			} `graphql:"... @defer(label: \"details\")"`
+			Repositories []struct {
+				Name graphql.String
+			} `graphql:"repositories @stream(initialCount: 1)"`
+		}
+	}
+	var increments []graphql.Increment
+	var bios []string
+	dataErrors, err := client.Query(context.Background(), &q, nil, graphql.WithIncrementFunc(func(inc graphql.Increment) {
+		increments = append(increments, inc)
+		bios = append(bios, string(q.Viewer.Details.Bio))
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dataErrors) != 1 || dataErrors[0].Message != "slow" {
+		t.Errorf("got errors: %+v, want slow", dataErrors)
+	}
+	if q.Viewer.Login != "gopher" || q.Viewer.Details.Bio != "Gopher" || q.Viewer.Details.Followers != 12345678901 {
+		t.Errorf("got viewer: %+v, want the deferred details applied", q.Viewer)
+	}
+	var names []string
+	for _, r := range q.Viewer.Repositories {
+		names = append(names, string(r.Name))
+	}
+	if want := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got repositories: %q, want: %q", names, want)
+	}
+
+	if len(increments) != 4 {
+		t.Fatalf("got %d increments, want 4", len(increments))
+	}
+	if inc := increments[1]; inc.Label != "details" || !reflect.DeepEqual(inc.Path, []interface{}{"viewer"}) || !inc.HasNext {
+		t.Errorf("got second increment: %+v, want the details", inc)
+	}
+	if got, want := string(increments[0].Data), `{"viewer": {"login": "gopher", "repositories": [{"name": "a"}]}}`; got != want {
+		t.Errorf("got initial data: %s, want: %s", got, want)
+	}
+	if got, want := string(increments[1].Data), `{"bio": "Gopher", "followers": 12345678901}`; got != want {
+		t.Errorf("got deferred data: %s, want only the increment: %s", got, want)
+	}
+	if got, want := len(increments[2].Items), 2; got != want {
+		t.Errorf("got %d streamed items, want: %d", got, want)
+	}
+	// The query struct is decoded as payloads are received.
+	if got, want := bios, []string{"", "Gopher", "Gopher", "Gopher"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got bios seen by the increment func: %q, want: %q", got, want)
+	}
+	if inc := increments[3]; inc.HasNext || len(inc.Errors) != 1 || !reflect.DeepEqual(inc.Path, []interface{}{"viewer", "repositories", 3}) {
+		t.Errorf("got last increment: %+v, want the last item with its error", inc)
+	}
+}
+
+func TestIncrementalDelivery_pending(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", `multipart/mixed; boundary="-"`)
+		mustWrite(w, strings.Join([]string{
+			"\r\n---\r\n",
+			"Content-Type: application/json\r\n\r\n" +
+				`{"data": {"viewer": {"login": "gopher", "repositories": [{"name": "a"}]}}, "pending": [{"id": "0", "path": ["viewer"], "label": "details"}, {"id": "1", "path": ["viewer", "repositories"]}], "hasNext": true}` + "\r\n---\r\n",
+			"Content-Type: application/json\r\n\r\n" +
+				`{"incremental": [{"id": "0", "data": {"bio": "Gopher"}}, {"id": "1", "items": [{"name": "b"}]}], "completed": [{"id": "0"}], "hasNext": true}` + "\r\n---\r\n",
+			"Content-Type: application/json\r\n\r\n" +
+				`{"incremental": [{"id": "1", "items": [{"name": "c"}]}], "completed": [{"id": "1", "errors": [{"message": "slow", "path": ["viewer", "repositories"]}]}], "hasNext": false}` + "\r\n-----\r\n",
+		}, ""))
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Viewer struct {
+			Login   graphql.String
+			Details struct {
+				Bio graphql.String
+			} `graphql:"... @defer(label: \"details\")"`
+			Repositories []struct {
+				Name graphql.String
+			} `graphql:"repositories @stream(initialCount: 1)"`
+		}
+	}
+	var increments []graphql.Increment
+	dataErrors, err := client.Query(context.Background(), &q, nil, graphql.WithIncrementFunc(func(inc graphql.Increment) {
+		increments = append(increments, inc)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dataErrors) != 1 || dataErrors[0].Message != "slow" {
+		t.Errorf("got errors: %+v, want slow", dataErrors)
+	}
+	if q.Viewer.Details.Bio != "Gopher" || len(q.Viewer.Repositories) != 3 || q.Viewer.Repositories[2].Name != "c" {
+		t.Errorf("got viewer: %+v, want the deferred details and streamed items applied", q.Viewer)
+	}
+	var paths []string
+	for _, inc := range increments {
+		paths = append(paths, fmt.Sprint(inc.Label, inc.Path, len(inc.Errors), inc.HasNext))
+	}
+	if got, want := paths, []string{"[] 0 true", "details[viewer] 0 true", "[viewer repositories 1] 0 true", "[viewer repositories 2] 0 true", "[viewer repositories] 1 false"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got increments: %q, want: %q", got, want)
+	}
+
+	// Data is merged for callers reading it rather than a query struct.
+	resp, err := client.Do(context.Background(), "{viewer{login ... @defer(label: \"details\"){bio} repositories @stream(initialCount: 1){name}}}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(resp.Data), `{"viewer":{"bio":"Gopher","login":"gopher","repositories":[{"name":"a"},{"name":"b"},{"name":"c"}]}}`; got != want {
+		t.Errorf("got data: %s, want: %s", got, want)
+	}
+}
@@ -4,12 +4,16 @@ package jsonutil
 
 import (
 	"bytes"
+	"encoding"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/big"
 	"reflect"
 	"strings"
+	"sync"
 )
 
 // UnmarshalGraphQL parses the JSON-encoded GraphQL response data and stores
@@ -17,10 +21,14 @@ import (
 //
 // The implementation is created on top of the JSON tokenizer available
 // in "encoding/json".Decoder.
-func UnmarshalGraphQL(data []byte, v interface{}) error {
+func UnmarshalGraphQL(data []byte, v interface{}, opts ...Option) error {
 	dec := json.NewDecoder(bytes.NewReader(data))
 	dec.UseNumber()
-	err := (&decoder{tokenizer: dec}).Decode(v)
+	d := &decoder{tokenizer: dec}
+	for _, opt := range opts {
+		opt(d)
+	}
+	err := d.Decode(v)
 	if err != nil {
 		return err
 	}
@@ -37,6 +45,128 @@ func UnmarshalGraphQL(data []byte, v interface{}) error {
 	}
 }
 
+// DecodeGraphQL decodes the next JSON-encoded GraphQL response data read
+// from dec into the GraphQL query data structure pointed to by v, without
+// reading the rest of the input. It's for decoding data while it's being
+// received, as part of a larger JSON document. dec must be set to decode
+// numbers as json.Number, with UseNumber.
+//
+// If the data is null, v is left unchanged.
+func DecodeGraphQL(dec *json.Decoder, v interface{}, opts ...Option) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	d := &decoder{tokenizer: &unreadTokenizer{tok: tok, dec: dec}}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d.Decode(v)
+}
+
+// UnmarshalGraphQLAt parses the JSON-encoded GraphQL response data and
+// stores the result in the value at path within the GraphQL query data
+// structure pointed to by v, leaving the rest of v unchanged. path is made
+// of field names and list indices, as the paths of GraphQL responses;
+// an index one past the end of a slice appends an element to it.
+// It's for applying the payloads of incrementally delivered responses:
+// objects are merged into the structs they're decoded into.
+func UnmarshalGraphQLAt(data []byte, v interface{}, path []interface{}, opts ...Option) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return fmt.Errorf("cannot decode into non-pointer %T", v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	d := &decoder{tokenizer: dec}
+	for _, opt := range opts {
+		opt(d)
+	}
+	targets := []reflect.Value{rv.Elem()}
+	for _, key := range path {
+		var next []reflect.Value
+		for _, t := range targets {
+			if t.Kind() == reflect.Ptr {
+				if t.IsNil() {
+					t.Set(reflect.New(t.Type().Elem())) // t = new(T).
+				}
+				t = t.Elem()
+			}
+			switch key := key.(type) {
+			case string:
+				for _, s := range structsOf(t) {
+					if f, _ := d.fieldByGraphQLName(s, key); f.IsValid() {
+						next = append(next, f)
+					}
+				}
+			case int:
+				switch {
+				case t.Kind() == reflect.Slice && key == t.Len():
+					t.Set(reflect.Append(t, reflect.Zero(t.Type().Elem()))) // t = append(t, T).
+					fallthrough
+				case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && key >= 0 && key < t.Len():
+					next = append(next, t.Index(key))
+				}
+			}
+		}
+		if len(next) == 0 {
+			return fmt.Errorf("struct field or list element for %v doesn't exist in any of %v places to unmarshal", key, len(targets))
+		}
+		targets = next
+	}
+	for _, t := range targets {
+		d.vs = append(d.vs, []reflect.Value{t})
+	}
+	err := d.decode()
+	if err != nil {
+		return err
+	}
+	tok, err := dec.Token()
+	switch err {
+	case io.EOF:
+		return nil
+	case nil:
+		return fmt.Errorf("invalid token '%v' after top-level value", tok)
+	default:
+		return err
+	}
+}
+
+// structsOf returns the struct v, if it's a struct, along with the GraphQL
+// fragments and embedded structs within it, recursively.
+func structsOf(v reflect.Value) []reflect.Value {
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	structs := []reflect.Value{v}
+	for _, fragment := range structInfoOf(v.Type()).fragments {
+		f := v.Field(fragment.index)
+		if f.Kind() == reflect.Ptr {
+			f = f.Elem()
+		}
+		structs = append(structs, structsOf(f)...)
+	}
+	return structs
+}
+
+// unreadTokenizer returns tok, which was already read from dec,
+// before the rest of the tokens of dec.
+type unreadTokenizer struct {
+	tok json.Token
+	dec *json.Decoder
+}
+
+func (t *unreadTokenizer) Token() (json.Token, error) {
+	if tok := t.tok; tok != nil {
+		t.tok = nil
+		return tok, nil
+	}
+	return t.dec.Token()
+}
+
 // decoder is a JSON decoder that performs custom unmarshaling behavior
 // for GraphQL query data structures. It's implemented on top of a JSON tokenizer.
 type decoder struct {
@@ -54,6 +184,391 @@ type decoder struct {
 	// a single JSON value into multiple GraphQL fragments or embedded structs, so
 	// we keep track of them all.
 	vs [][]reflect.Value
+
+	// Stack of the JSON objects being decoded, innermost last.
+	objects []object
+
+	// Stack of the structs of d.objects, which hold slices of it.
+	structs []objectStruct
+
+	// Stack of the number of elements of the JSON arrays
+	// being decoded so far, innermost last.
+	arrayLens []int
+
+	// hooks, if non-nil, are called with fields once they're decoded.
+	hooks *hooks
+
+	// Stack of the keys of the JSON objects being decoded, innermost
+	// last, with the fields they're decoded into. Maintained if hooks
+	// are set.
+	keys []keyFrame
+
+	// caseInsensitive controls whether graphql tag names are matched
+	// against response keys case-insensitively.
+	caseInsensitive bool
+
+	// unmarshal, if non-nil, is used instead of json.Unmarshal
+	// to unmarshal scalar values.
+	unmarshal func(data []byte, v interface{}) error
+
+	// preciseNumbers controls whether numbers are unmarshaled into
+	// interfaces as json.Number, and into strings as their text.
+	preciseNumbers bool
+
+	// specialFloats controls whether the strings "NaN", "Infinity"
+	// and "-Infinity" are unmarshaled into floats.
+	specialFloats bool
+
+	// autoTypenames controls whether __typename keys that no field
+	// is decoded from are skipped.
+	autoTypenames bool
+
+	// scalars, if non-nil, maps types to the functions that unmarshal
+	// them, taking precedence over the ones registered with RegisterScalar.
+	scalars map[reflect.Type]func(data []byte, v interface{}) error
+}
+
+// object is a JSON object being decoded.
+type object struct {
+	structs []objectStruct // Structs the object is decoded into.
+
+	// Fields of structs present in the object, and whether they're
+	// non-null. It's nil unless one of the structs has required or
+	// typename fields, which need it.
+	seen map[fieldKey]bool
+}
+
+// objectStruct is a struct that an object is decoded into.
+type objectStruct struct {
+	v        reflect.Value
+	fragment bool // Whether it's within an inline fragment, which may not apply.
+
+	field  reflect.Value // Field of the parent struct holding it, if it's a fragment or embedded struct.
+	on     string        // Type condition, if it's a fragment with one.
+	parent int           // Index of the parent struct in object.structs, or -1 if none.
+}
+
+// fieldKey identifies a struct field being decoded into.
+type fieldKey struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+// popArray pops the length of the JSON array that was just decoded from
+// d.arrayLens, checking that the Go arrays it's decoded into, if any,
+// have that length.
+func (d *decoder) popArray() error {
+	n := d.arrayLens[len(d.arrayLens)-1]
+	d.arrayLens = d.arrayLens[:len(d.arrayLens)-1]
+	for i := range d.vs {
+		v := d.vs[i][len(d.vs[i])-1]
+		if v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		if v.Kind() == reflect.Array && v.Len() != n {
+			return fmt.Errorf("array %v has %d elements, but the JSON array has %d", v.Type(), v.Len(), n)
+		}
+	}
+	return nil
+}
+
+// popObject pops the object that was just decoded from d.objects,
+// checking that its required fields were present and non-null.
+// The fields of inline fragments are checked only if any of their
+// fields were present, since they may be on another type.
+//
+// If the object has a __typename decoded into a field of a type registered
+// with RegisterTypename, fragments on other types are reset to their zero
+// values, and their fields aren't checked.
+func (d *decoder) popObject() error {
+	obj := d.objects[len(d.objects)-1]
+	d.objects = d.objects[:len(d.objects)-1]
+	d.structs = d.structs[:len(d.structs)-len(obj.structs)]
+	excluded := obj.excludedFragments()
+	for i, s := range obj.structs {
+		if excluded != nil && excluded[i] {
+			continue
+		}
+		missing := -1
+		for _, i := range structInfoOf(s.v.Type()).required {
+			if f := s.v.Field(i); !obj.seen[fieldKey{f.UnsafeAddr(), f.Type()}] {
+				missing = i
+				break
+			}
+		}
+		if missing < 0 || s.fragment && !obj.matched(s.v) {
+			continue
+		}
+		field := s.v.Type().Field(missing).Name
+		if name := s.v.Type().Name(); name != "" {
+			return fmt.Errorf("required field %s.%s is null or missing", name, field)
+		}
+		return fmt.Errorf("required field %s is null or missing", field)
+	}
+	if excluded == nil {
+		return nil
+	}
+	for i, s := range obj.structs {
+		if excluded[i] && (s.parent < 0 || !excluded[s.parent]) {
+			s.field.Set(reflect.Zero(s.field.Type()))
+		}
+	}
+	return nil
+}
+
+// matched reports whether any of the exported fields of the struct v
+// was present in obj.
+func (obj object) matched(v reflect.Value) bool {
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).PkgPath != "" {
+			continue
+		}
+		f := v.Field(i)
+		if _, present := obj.seen[fieldKey{f.UnsafeAddr(), f.Type()}]; present {
+			return true
+		}
+	}
+	return false
+}
+
+// excludedFragments reports, for each struct of obj, whether it's within
+// a fragment whose type condition isn't the __typename of obj, if obj has
+// one decoded into a field of a type registered with RegisterTypename.
+// It returns nil if none is.
+func (obj object) excludedFragments() []bool {
+	gated := false
+	for _, s := range obj.structs {
+		gated = gated || s.on != ""
+	}
+	if !gated {
+		return nil
+	}
+	var typename string
+	for _, s := range obj.structs {
+		for _, i := range structInfoOf(s.v.Type()).typenames {
+			if f := s.v.Field(i); typename == "" && obj.seen[fieldKey{f.UnsafeAddr(), f.Type()}] {
+				typename = f.String()
+			}
+		}
+	}
+	if typename == "" {
+		return nil
+	}
+	excluded := make([]bool, len(obj.structs))
+	for i, s := range obj.structs {
+		excluded[i] = s.parent >= 0 && excluded[s.parent] || s.on != "" && s.on != typename
+	}
+	return excluded
+}
+
+// typenames holds the types registered with RegisterTypename.
+var typenames sync.Map // map[reflect.Type]struct{}
+
+// RegisterTypename makes fields of the string type t that hold the
+// __typename of an object select which of the fragments the object is
+// decoded into apply to it: fragments with a type condition other than
+// the __typename, such as "... on Issue" for a PullRequest, are reset to
+// their zero values once the object is decoded, so that only the matching
+// fragment holds the fields they have in common.
+func RegisterTypename(t reflect.Type) {
+	typenames.Store(t, struct{}{})
+	// Forget the typename fields of the structs seen so far.
+	structInfos.Range(func(t, _ interface{}) bool {
+		structInfos.Delete(t)
+		return true
+	})
+}
+
+// structInfo is what decoding needs to know of the fields of a struct
+// type, other than their names.
+type structInfo struct {
+	required  []int           // Indices of the exported fields tagged required.
+	typenames []int           // Indices of the exported fields of types registered with RegisterTypename.
+	fragments []fragmentField // GraphQL fragments and embedded structs.
+	gated     bool            // Whether any of fragments has a type condition.
+}
+
+// fragmentField is a field of a struct that's a GraphQL fragment
+// or an embedded struct.
+type fragmentField struct {
+	index  int
+	inline bool   // Whether it's an inline fragment.
+	on     string // Type condition, if any.
+}
+
+// structInfos caches the structInfo of struct types.
+var structInfos sync.Map // map[reflect.Type]*structInfo
+
+// structInfoOf returns the structInfo of the struct type t.
+func structInfoOf(t reflect.Type) *structInfo {
+	if info, ok := structInfos.Load(t); ok {
+		return info.(*structInfo)
+	}
+	info := &structInfo{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		if _, required, _ := LookupTag(f.Tag); required {
+			info.required = append(info.required, i)
+		}
+		if _, ok := typenames.Load(f.Type); ok {
+			info.typenames = append(info.typenames, i)
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); isGraphQLFragment(f) || isInlined(f) {
+			on := typeCondition(f)
+			info.fragments = append(info.fragments, fragmentField{index: i, inline: isGraphQLFragment(f), on: on})
+			info.gated = info.gated || on != ""
+		}
+	}
+	structInfos.Store(t, info)
+	return info
+}
+
+// Option configures the behavior of UnmarshalGraphQL.
+type Option func(*decoder)
+
+// CaseInsensitive returns an Option that matches response keys against
+// graphql tag names case-insensitively, the same way "encoding/json" matches
+// keys against struct field names. Struct fields without a graphql tag are
+// always matched case-insensitively.
+func CaseInsensitive() Option {
+	return func(d *decoder) { d.caseInsensitive = true }
+}
+
+// AutoTypenames returns an Option that skips __typename keys that no
+// struct field is decoded from, for documents that select __typename in
+// every selection set. Otherwise, they're skipped only in objects decoded
+// into structs with fragments on types or typename fields, which select
+// __typename to tell which fragments apply.
+func AutoTypenames() Option {
+	return func(d *decoder) { d.autoTypenames = true }
+}
+
+// UnmarshalFunc returns an Option that makes UnmarshalGraphQL use unmarshal
+// instead of json.Unmarshal to unmarshal scalar values into their fields.
+// The structure of the response is still tokenized by "encoding/json".
+func UnmarshalFunc(unmarshal func(data []byte, v interface{}) error) Option {
+	return func(d *decoder) { d.unmarshal = unmarshal }
+}
+
+// ScalarFunc returns an Option that makes values of the type t be
+// unmarshaled with unmarshal, as RegisterScalar does, for a single
+// UnmarshalGraphQL call.
+func ScalarFunc(t reflect.Type, unmarshal func(data []byte, v interface{}) error) Option {
+	return func(d *decoder) {
+		if d.scalars == nil {
+			d.scalars = make(map[reflect.Type]func(data []byte, v interface{}) error)
+		}
+		d.scalars[t] = unmarshal
+	}
+}
+
+// PreciseNumbers returns an Option that unmarshals numbers into interface
+// values, such as graphql.ID, as json.Number rather than float64, which
+// can't represent integers above 2^53 exactly, into string values, such
+// as json.Number ones, as their text, and into values that unmarshal from
+// text, such as big.Float ones, with their UnmarshalText method. Fields of
+// other types are unaffected; int64 and big.Int fields already hold their
+// numbers exactly.
+func PreciseNumbers() Option {
+	return func(d *decoder) { d.preciseNumbers = true }
+}
+
+// Hook is called with a pointer to a struct field once its value is
+// decoded, such as a *string. It may modify the value, e.g., to normalize
+// it. If it returns an error, unmarshaling fails with it.
+type Hook func(v interface{}) error
+
+// PathHook returns an Option that calls hook for the fields at the
+// response path, which is the response keys leading to them, separated
+// by dots. List elements don't add to the path. E.g., "viewer.email"
+// for the email of the viewer, and "repository.issues.title" for the
+// titles of all the issues of a repository.
+func PathHook(path string, hook Hook) Option {
+	return func(d *decoder) {
+		d.initHooks()
+		d.hooks.byPath[path] = append(d.hooks.byPath[path], hook)
+	}
+}
+
+// TagHook returns an Option that calls hook for the fields whose
+// graphql-hook tag lists name among comma-separated names. E.g., a field
+// tagged `graphql-hook:"trim,lower"` is passed to the hooks named "trim"
+// and "lower", in that order.
+func TagHook(name string, hook Hook) Option {
+	return func(d *decoder) {
+		d.initHooks()
+		d.hooks.byTag[name] = append(d.hooks.byTag[name], hook)
+	}
+}
+
+// hooks are the hooks of a decoder.
+type hooks struct {
+	byPath map[string][]Hook
+	byTag  map[string][]Hook
+}
+
+func (d *decoder) initHooks() {
+	if d.hooks == nil {
+		d.hooks = &hooks{byPath: make(map[string][]Hook), byTag: make(map[string][]Hook)}
+	}
+}
+
+// keyFrame is a key of a JSON object being decoded.
+type keyFrame struct {
+	key    string
+	fields []hookedField // Fields its value is decoded into.
+}
+
+// hookedField is a field being decoded, with its graphql-hook tag.
+type hookedField struct {
+	v   reflect.Value
+	tag string
+}
+
+// endValue is called once a JSON value is decoded. If it's the value of
+// a key of an object, it pops that key from d.keys, and calls the hooks
+// of the fields its value was decoded into.
+func (d *decoder) endValue() error {
+	if d.hooks == nil || d.state() != '{' || len(d.keys) == 0 {
+		return nil
+	}
+	var path strings.Builder
+	for i, k := range d.keys {
+		if i > 0 {
+			path.WriteByte('.')
+		}
+		path.WriteString(k.key)
+	}
+	frame := d.keys[len(d.keys)-1]
+	d.keys = d.keys[:len(d.keys)-1]
+	for _, f := range frame.fields {
+		hooks := d.hooks.byPath[path.String()]
+		if f.tag != "" {
+			for _, name := range strings.Split(f.tag, ",") {
+				hooks = append(hooks[:len(hooks):len(hooks)], d.hooks.byTag[strings.TrimSpace(name)]...)
+			}
+		}
+		for _, hook := range hooks {
+			err := hook(f.v.Addr().Interface())
+			if err != nil {
+				return fmt.Errorf("decoding %s: %w", path.String(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// SpecialFloats returns an Option that unmarshals the strings "NaN",
+// "Infinity" and "-Infinity", which some servers emit for special float
+// values that JSON can't represent, into float values. By default, they
+// fail to unmarshal into floats.
+func SpecialFloats() Option {
+	return func(d *decoder) { d.specialFloats = true }
 }
 
 // Decode decodes a single JSON value from d.tokenizer into v.
@@ -87,6 +602,10 @@ func (d *decoder) decode() error {
 				return errors.New("unexpected non-key in JSON input")
 			}
 			someFieldExist := false
+			skipTypename := d.autoTypenames
+			seen := d.objects[len(d.objects)-1].seen
+			var fields []reflect.Value
+			var hooked []hookedField
 			for i := range d.vs {
 				v := d.vs[i][len(d.vs[i])-1]
 				if v.Kind() == reflect.Ptr {
@@ -94,16 +613,33 @@ func (d *decoder) decode() error {
 				}
 				var f reflect.Value
 				if v.Kind() == reflect.Struct {
-					f = fieldByGraphQLName(v, key)
+					if key == "__typename" && !skipTypename {
+						info := structInfoOf(v.Type())
+						skipTypename = info.gated || len(info.typenames) > 0
+					}
+					var sf reflect.StructField
+					f, sf = d.fieldByGraphQLName(v, key)
 					if f.IsValid() {
 						someFieldExist = true
+						if seen != nil {
+							fields = append(fields, f)
+						}
+						if d.hooks != nil {
+							hooked = append(hooked, hookedField{v: f, tag: sf.Tag.Get("graphql-hook")})
+						}
 					}
 				}
 				d.vs[i] = append(d.vs[i], f)
 			}
-			if !someFieldExist {
+			// __typename is selected to tell which fragments apply, and in
+			// every selection set with AutoTypenames, so it needn't be
+			// decoded into a field then.
+			if !someFieldExist && !(key == "__typename" && skipTypename) {
 				return fmt.Errorf("struct field for %q doesn't exist in any of %v places to unmarshal", key, len(d.vs))
 			}
+			if d.hooks != nil {
+				d.keys = append(d.keys, keyFrame{key: key, fields: hooked})
+			}
 
 			// We've just consumed the current token, which was the key.
 			// Read the next token, which should be the value, and let the rest of code process it.
@@ -113,20 +649,32 @@ func (d *decoder) decode() error {
 			} else if err != nil {
 				return err
 			}
+			for _, f := range fields {
+				seen[fieldKey{f.UnsafeAddr(), f.Type()}] = tok != nil
+			}
 
 		// Are we inside an array and seeing next value (rather than end of array)?
 		case d.state() == '[' && tok != json.Delim(']'):
 			someSliceExist := false
+			index := d.arrayLens[len(d.arrayLens)-1]
+			d.arrayLens[len(d.arrayLens)-1]++
 			for i := range d.vs {
 				v := d.vs[i][len(d.vs[i])-1]
 				if v.Kind() == reflect.Ptr {
 					v = v.Elem()
 				}
 				var f reflect.Value
-				if v.Kind() == reflect.Slice {
+				switch v.Kind() {
+				case reflect.Slice:
 					v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem()))) // v = append(v, T).
 					f = v.Index(v.Len() - 1)
 					someSliceExist = true
+				case reflect.Array:
+					if index >= v.Len() {
+						return fmt.Errorf("array %v has %d elements, but the JSON array has more", v.Type(), v.Len())
+					}
+					f = v.Index(index)
+					someSliceExist = true
 				}
 				d.vs[i] = append(d.vs[i], f)
 			}
@@ -144,24 +692,51 @@ func (d *decoder) decode() error {
 				if !v.IsValid() {
 					continue
 				}
-				err := unmarshalValue(tok, v)
+				err := d.unmarshalValue(tok, v)
 				if err != nil {
 					return err
 				}
 			}
 			d.popAllVs()
+			err := d.endValue()
+			if err != nil {
+				return err
+			}
 
 		case json.Delim:
+			if (tok == '{' || tok == '[') && d.wholeValueTargets() {
+				// Object or array decoded as a whole.
+				raw, err := d.readValue(tok)
+				if err != nil {
+					return err
+				}
+				for i := range d.vs {
+					v := d.vs[i][len(d.vs[i])-1]
+					if !v.IsValid() {
+						continue
+					}
+					err := d.unmarshalJSON(raw, v)
+					if err != nil {
+						return err
+					}
+				}
+				d.popAllVs()
+				err = d.endValue()
+				if err != nil {
+					return err
+				}
+				continue
+			}
 			switch tok {
 			case '{':
 				// Start of object.
 
 				d.pushState(tok)
 
-				frontier := make([]reflect.Value, len(d.vs)) // Places to look for GraphQL fragments/embedded structs.
+				frontier := make([]objectStruct, len(d.vs)) // Places to look for GraphQL fragments/embedded structs.
 				for i := range d.vs {
 					v := d.vs[i][len(d.vs[i])-1]
-					frontier[i] = v
+					frontier[i] = objectStruct{v: v, parent: -1}
 					// TODO: Do this recursively or not? Add a test case if needed.
 					if v.Kind() == reflect.Ptr && v.IsNil() {
 						v.Set(reflect.New(v.Type().Elem())) // v = new(T).
@@ -169,48 +744,81 @@ func (d *decoder) decode() error {
 				}
 				// Find GraphQL fragments/embedded structs recursively, adding to frontier
 				// as new ones are discovered and exploring them further.
+				var obj object
+				start := len(d.structs)
 				for len(frontier) > 0 {
-					v := frontier[0]
+					s := frontier[0]
 					frontier = frontier[1:]
+					v := s.v
 					if v.Kind() == reflect.Ptr {
 						v = v.Elem()
 					}
 					if v.Kind() != reflect.Struct {
 						continue
 					}
-					for i := 0; i < v.NumField(); i++ {
-						if isGraphQLFragment(v.Type().Field(i)) || v.Type().Field(i).Anonymous {
-							// Add GraphQL fragment or embedded struct.
-							d.vs = append(d.vs, []reflect.Value{v.Field(i)})
-							frontier = append(frontier, v.Field(i))
-						}
+					s.v = v
+					d.structs = append(d.structs, s)
+					info := structInfoOf(v.Type())
+					if obj.seen == nil && (len(info.required) > 0 || len(info.typenames) > 0) {
+						obj.seen = make(map[fieldKey]bool)
+					}
+					for _, fragment := range info.fragments {
+						// Add GraphQL fragment or embedded struct.
+						f := v.Field(fragment.index)
+						d.vs = append(d.vs, []reflect.Value{f})
+						frontier = append(frontier, objectStruct{
+							v:        f,
+							fragment: s.fragment || fragment.inline,
+							field:    f,
+							on:       fragment.on,
+							parent:   len(d.structs) - 1 - start,
+						})
 					}
 				}
+				obj.structs = d.structs[start:len(d.structs):len(d.structs)]
+				d.objects = append(d.objects, obj)
 			case '[':
 				// Start of array.
 
 				d.pushState(tok)
+				d.arrayLens = append(d.arrayLens, 0)
 
 				for i := range d.vs {
 					v := d.vs[i][len(d.vs[i])-1]
-					// TODO: Confirm this is needed, write a test case.
-					//if v.Kind() == reflect.Ptr && v.IsNil() {
-					//	v.Set(reflect.New(v.Type().Elem())) // v = new(T).
-					//}
+					if v.Kind() == reflect.Ptr && v.IsNil() && (v.Type().Elem().Kind() == reflect.Slice || v.Type().Elem().Kind() == reflect.Array) {
+						v.Set(reflect.New(v.Type().Elem())) // v = new(T).
+					}
 
-					// Reset slice to empty (in case it had non-zero initial value).
+					// Reset slice or array to empty (in case it had non-zero initial value).
 					if v.Kind() == reflect.Ptr {
 						v = v.Elem()
 					}
-					if v.Kind() != reflect.Slice {
-						continue
+					switch v.Kind() {
+					case reflect.Slice:
+						v.Set(reflect.MakeSlice(v.Type(), 0, 0)) // v = make(T, 0, 0).
+					case reflect.Array:
+						v.Set(reflect.Zero(v.Type()))
 					}
-					v.Set(reflect.MakeSlice(v.Type(), 0, 0)) // v = make(T, 0, 0).
 				}
 			case '}', ']':
 				// End of object or array.
+				if tok == '}' {
+					err := d.popObject()
+					if err != nil {
+						return err
+					}
+				} else {
+					err := d.popArray()
+					if err != nil {
+						return err
+					}
+				}
 				d.popAllVs()
 				d.popState()
+				err := d.endValue()
+				if err != nil {
+					return err
+				}
 			default:
 				return errors.New("unexpected delimiter in JSON input")
 			}
@@ -253,38 +861,67 @@ func (d *decoder) popAllVs() {
 }
 
 // fieldByGraphQLName returns an exported struct field of struct v
-// that matches GraphQL name, or invalid reflect.Value if none found.
-func fieldByGraphQLName(v reflect.Value, name string) reflect.Value {
+// that matches GraphQL name, along with its description, or invalid
+// reflect.Value if none found.
+//
+// Response keys of the form "name__N" are aliased copies of the selection
+// "name", such as the ones produced for graphql-extend fields. Unless
+// a field matches them exactly, they match a slice field with GraphQL
+// name "name", and each one is appended to that slice as a new element.
+func (d *decoder) fieldByGraphQLName(v reflect.Value, name string) (reflect.Value, reflect.StructField) {
 	for i := 0; i < v.NumField(); i++ {
-		if v.Type().Field(i).PkgPath != "" {
+		typeField := v.Type().Field(i)
+		if typeField.PkgPath != "" {
 			// Skip unexported field.
 			continue
 		}
+		if d.hasGraphQLName(typeField, name) {
+			return v.Field(i), typeField
+		}
+	}
+	if base, ok := trimAliasIndex(name); ok {
+		return d.appendAliasedCopy(v, base)
+	}
+	return reflect.Value{}, reflect.StructField{}
+}
 
-		// Decode extended field
+// appendAliasedCopy appends an element to the exported slice field of
+// struct v that matches GraphQL name, and returns it along with the
+// description of the field, or invalid reflect.Value if none found.
+func (d *decoder) appendAliasedCopy(v reflect.Value, name string) (reflect.Value, reflect.StructField) {
+	for i := 0; i < v.NumField(); i++ {
 		typeField := v.Type().Field(i)
-		// Extended fields have a suffix that we need to remove.
-		extend, ok := typeField.Tag.Lookup("graphql-extend")
-		extended := ok && extend == "true"
-		if extended {
-			name = name[:strings.LastIndex(name, "__")]
-		}
-
-		if hasGraphQLName(typeField, name) {
-			f := v.Field(i)
-			if extended && f.Kind() == reflect.Slice {
-				f.Set(reflect.Append(f, reflect.Zero(f.Type().Elem()))) // f = append(f, T).
-				f = f.Index(f.Len() - 1)
-			}
-			return f
+		if typeField.PkgPath != "" {
+			continue
 		}
+		if f := v.Field(i); f.Kind() == reflect.Slice && d.hasGraphQLName(typeField, name) {
+			f.Set(reflect.Append(f, reflect.Zero(f.Type().Elem()))) // f = append(f, T).
+			return f.Index(f.Len() - 1), typeField
+		}
+	}
+	return reflect.Value{}, reflect.StructField{}
+}
+
+// trimAliasIndex trims the "__N" suffix from an aliased response key.
+// It reports whether name had such a suffix.
+//
+// E.g., "pullRequest__3" -> "pullRequest", true.
+func trimAliasIndex(name string) (string, bool) {
+	i := strings.LastIndex(name, "__")
+	if i <= 0 || i+2 == len(name) {
+		return name, false
 	}
-	return reflect.Value{}
+	for _, r := range name[i+2:] {
+		if r < '0' || r > '9' {
+			return name, false
+		}
+	}
+	return name[:i], true
 }
 
 // hasGraphQLName reports whether struct field f has GraphQL name.
-func hasGraphQLName(f reflect.StructField, name string) bool {
-	value, ok := f.Tag.Lookup("graphql")
+func (d *decoder) hasGraphQLName(f reflect.StructField, name string) bool {
+	value, _, ok := LookupTag(f.Tag)
 	if !ok {
 		// TODO: caseconv package is relatively slow. Optimize it, then consider using it here.
 		//return caseconv.MixedCapsToLowerCamelCase(f.Name) == name
@@ -297,10 +934,27 @@ func hasGraphQLName(f reflect.StructField, name string) bool {
 	}
 	// Cut off anything that follows the field name,
 	// such as field arguments, aliases, directives.
-	if i := strings.IndexAny(value, "(:@"); i != -1 {
+	if i := strings.IndexAny(value, "(:@{"); i != -1 {
 		value = value[:i]
 	}
-	return strings.TrimSpace(value) == name
+	value = strings.TrimSpace(value)
+	if d.caseInsensitive {
+		return strings.EqualFold(value, name)
+	}
+	return value == name
+}
+
+// LookupTag returns the value of the graphql tag in tag, without its
+// ",required" option, and reports whether the option is set, and whether
+// the tag is present.
+//
+// E.g., `graphql:"login,required"` -> "login", true, true.
+func LookupTag(tag reflect.StructTag) (value string, required, ok bool) {
+	value, ok = tag.Lookup("graphql")
+	if trimmed := strings.TrimSpace(value); strings.HasSuffix(trimmed, ",required") {
+		return strings.TrimSuffix(trimmed, ",required"), true, ok
+	}
+	return value, false, ok
 }
 
 // isGraphQLFragment reports whether struct field f is a GraphQL fragment.
@@ -313,13 +967,318 @@ func isGraphQLFragment(f reflect.StructField) bool {
 	return strings.HasPrefix(value, "...")
 }
 
+// typeCondition returns the type condition of struct field f, if it's
+// an inline fragment with one, or an embedded struct spread as a named
+// fragment. E.g., `graphql:"... on Issue"` -> "Issue".
+func typeCondition(f reflect.StructField) string {
+	if on, ok := f.Tag.Lookup("graphql-fragment"); ok && isInlined(f) {
+		return strings.TrimSpace(on)
+	}
+	value, ok := f.Tag.Lookup("graphql")
+	if !ok {
+		return ""
+	}
+	value = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "..."))
+	if !strings.HasPrefix(value, "on ") {
+		return ""
+	}
+	value = strings.TrimSpace(value[len("on "):])
+	if i := strings.IndexAny(value, " @{"); i != -1 {
+		value = value[:i]
+	}
+	return value
+}
+
+// isInlined reports whether struct field f is an embedded struct whose
+// fields are inlined. Embedded structs with a graphql tag are selected
+// by it instead, as a field or a fragment.
+func isInlined(f reflect.StructField) bool {
+	_, tagged := f.Tag.Lookup("graphql")
+	return f.Anonymous && !tagged
+}
+
 // unmarshalValue unmarshals JSON value into v.
 // v must be addressable and not obtained by the use of unexported
 // struct fields, otherwise unmarshalValue will panic.
-func unmarshalValue(value json.Token, v reflect.Value) error {
+func (d *decoder) unmarshalValue(value json.Token, v reflect.Value) error {
+	if d.scalarFunc(v.Type()) == nil {
+		if n, ok := value.(json.Number); ok && d.preciseNumbers {
+			if ok, err := setNumber(n, v); ok {
+				return err
+			}
+		}
+		if n, ok := value.(json.Number); ok && strings.ContainsAny(string(n), ".eE") {
+			if ok, err := setInteger(n, v); ok {
+				return err
+			}
+		}
+		if s, ok := value.(string); ok && d.specialFloats {
+			if ok := setSpecialFloat(s, v); ok {
+				return nil
+			}
+		}
+	}
 	b, err := json.Marshal(value) // TODO: Short-circuit (if profiling says it's worth it).
 	if err != nil {
 		return err
 	}
+	return d.unmarshalJSON(b, v)
+}
+
+// unmarshalJSON unmarshals the JSON encoding b into v.
+func (d *decoder) unmarshalJSON(b []byte, v reflect.Value) error {
+	if ok, err := d.unmarshalScalar(b, v); ok {
+		return err
+	}
+	if d.unmarshal != nil {
+		return d.unmarshal(b, v.Addr().Interface())
+	}
 	return json.Unmarshal(b, v.Addr().Interface())
 }
+
+// wholeValueTargets reports whether the places to unmarshal the next
+// JSON value into all take objects and arrays as a whole, rather than
+// field by field or element by element. Those are types with custom
+// JSON unmarshaling, such as json.RawMessage, maps, and interfaces.
+func (d *decoder) wholeValueTargets() bool {
+	some := false
+	for i := range d.vs {
+		v := d.vs[i][len(d.vs[i])-1]
+		if !v.IsValid() {
+			continue
+		}
+		t := v.Type()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if !reflect.PtrTo(t).Implements(jsonUnmarshaler) && d.scalarFunc(t) == nil && t.Kind() != reflect.Map && t.Kind() != reflect.Interface {
+			return false
+		}
+		some = true
+	}
+	return some
+}
+
+// scalars maps the Go types registered with RegisterScalar
+// to the functions that unmarshal them.
+var scalars sync.Map // map[reflect.Type]func(data []byte, v interface{}) error
+
+// RegisterScalar makes values of the type t be unmarshaled with unmarshal,
+// which is passed the JSON encoding of a non-null value, and a pointer to
+// a value of t, instead of with json.Unmarshal. Values of t are decoded as
+// a whole, even if they're JSON objects or arrays.
+func RegisterScalar(t reflect.Type, unmarshal func(data []byte, v interface{}) error) {
+	scalars.Store(t, unmarshal)
+}
+
+// IsScalar reports whether the type t was registered with RegisterScalar.
+func IsScalar(t reflect.Type) bool {
+	_, ok := scalars.Load(t)
+	return ok
+}
+
+// graphQLUnmarshaler is implemented by types that unmarshal the JSON
+// encoding of their non-null values themselves, as a whole.
+type graphQLUnmarshaler interface {
+	UnmarshalGraphQL(data []byte) error
+}
+
+var graphQLUnmarshalerType = reflect.TypeOf((*graphQLUnmarshaler)(nil)).Elem()
+
+// scalarFunc returns the function that unmarshals values of the type t,
+// possibly through pointers, as a whole, if any: the one set with the
+// ScalarFunc option, the one registered with RegisterScalar, or the
+// UnmarshalGraphQL method of t.
+func (d *decoder) scalarFunc(t reflect.Type) func(data []byte, v interface{}) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if f, ok := d.scalars[t]; ok {
+		return f
+	}
+	if f, ok := scalars.Load(t); ok {
+		return f.(func(data []byte, v interface{}) error)
+	}
+	if reflect.PtrTo(t).Implements(graphQLUnmarshalerType) {
+		return func(data []byte, v interface{}) error {
+			return v.(graphQLUnmarshaler).UnmarshalGraphQL(data)
+		}
+	}
+	return nil
+}
+
+// unmarshalScalar unmarshals the JSON encoding b into v, allocating
+// pointers as needed, if the type v holds has a scalarFunc.
+// It reports whether it has.
+func (d *decoder) unmarshalScalar(b []byte, v reflect.Value) (bool, error) {
+	f := d.scalarFunc(v.Type())
+	if f == nil || bytes.Equal(b, []byte("null")) {
+		return false, nil
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return true, f(b, v.Addr().Interface())
+}
+
+// readValue reads the rest of the JSON object or array that starts with
+// the delimiter first from d.tokenizer, and returns its JSON encoding.
+func (d *decoder) readValue(first json.Delim) ([]byte, error) {
+	var buf bytes.Buffer
+	type level struct {
+		object bool // Whether it's an object rather than an array.
+		n      int  // Tokens written in it so far, keys included.
+	}
+	var levels []level
+	tok := json.Token(first)
+	for {
+		end := tok == json.Delim('}') || tok == json.Delim(']')
+		if len(levels) > 0 && !end {
+			l := &levels[len(levels)-1]
+			switch {
+			case l.object && l.n%2 == 1:
+				buf.WriteByte(':')
+			case l.n > 0:
+				buf.WriteByte(',')
+			}
+			l.n++
+		}
+		switch tok := tok.(type) {
+		case json.Delim:
+			buf.WriteRune(rune(tok))
+			if end {
+				levels = levels[:len(levels)-1]
+			} else {
+				levels = append(levels, level{object: tok == '{'})
+			}
+		default:
+			b, err := json.Marshal(tok)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(b)
+		}
+		if len(levels) == 0 {
+			return buf.Bytes(), nil
+		}
+		var err error
+		tok, err = d.tokenizer.Token()
+		if err == io.EOF {
+			return nil, errors.New("unexpected end of JSON input")
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+var (
+	jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// setNumber sets v, possibly through pointers, to n if it's an empty
+// interface, a string, or a type that unmarshals from text, such as
+// *big.Float, without custom JSON unmarshaling. It reports whether it did.
+func setNumber(n json.Number, v reflect.Value) (bool, error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	t := reflect.PtrTo(v.Type())
+	switch {
+	case t.Implements(jsonUnmarshaler) || IsScalar(v.Type()):
+		return false, nil
+	case t.Implements(textUnmarshaler):
+		return true, v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(n))
+	case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+		v.Set(reflect.ValueOf(n))
+		return true, nil
+	case v.Kind() == reflect.String:
+		v.SetString(string(n))
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// setInteger sets v, possibly through pointers, to n if it's an integer
+// without custom JSON unmarshaling, and n is an integer written with a
+// fraction or exponent, such as 1e3 or 2.0. It reports whether it did.
+func setInteger(n json.Number, v reflect.Value) (bool, error) {
+	t := v.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(jsonUnmarshaler) || IsScalar(t) {
+		return false, nil
+	}
+	var signed bool
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		signed = true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return false, nil
+	}
+	f, _, err := big.ParseFloat(string(n), 10, 0, big.ToNearestEven)
+	if err != nil || !f.IsInt() {
+		return false, nil // Left for json.Unmarshal to report.
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if signed {
+		i, acc := f.Int64()
+		if acc != big.Exact || v.OverflowInt(i) {
+			return true, fmt.Errorf("number %s overflows %v", n, v.Type())
+		}
+		v.SetInt(i)
+		return true, nil
+	}
+	u, acc := f.Uint64()
+	if acc != big.Exact || v.OverflowUint(u) {
+		return true, fmt.Errorf("number %s overflows %v", n, v.Type())
+	}
+	v.SetUint(u)
+	return true, nil
+}
+
+// setSpecialFloat sets v, possibly through pointers, to the special float
+// value s names if it's a float without custom JSON unmarshaling.
+// It reports whether it did.
+func setSpecialFloat(s string, v reflect.Value) bool {
+	var f float64
+	switch s {
+	case "NaN":
+		f = math.NaN()
+	case "Infinity":
+		f = math.Inf(1)
+	case "-Infinity":
+		f = math.Inf(-1)
+	default:
+		return false
+	}
+	t := v.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Float32 && t.Kind() != reflect.Float64 || reflect.PtrTo(t).Implements(jsonUnmarshaler) || IsScalar(t) {
+		return false
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	v.SetFloat(f)
+	return true
+}
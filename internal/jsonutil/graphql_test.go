@@ -1,7 +1,13 @@
 package jsonutil_test
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -61,6 +67,33 @@ func TestUnmarshalGraphQL_graphqlTag(t *testing.T) {
 	}
 }
 
+func TestUnmarshalGraphQL_taggedEmbeddedStruct(t *testing.T) {
+	type Actor struct {
+		Login graphql.String
+	}
+	type query struct {
+		Login graphql.String
+		Actor `graphql:"viewer"`
+	}
+	var got query
+	err := jsonutil.UnmarshalGraphQL([]byte(`{
+		"viewer": {
+			"login": "inner"
+		},
+		"login": "outer"
+	}`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := query{
+		Login: "outer",
+		Actor: Actor{Login: "inner"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+}
+
 func TestUnmarshalGraphQL_jsonTag(t *testing.T) {
 	type query struct {
 		Foo graphql.String `json:"baz"`
@@ -254,6 +287,44 @@ func TestUnmarshalGraphQL_unexportedField(t *testing.T) {
 	}
 }
 
+func TestUnmarshalGraphQL_typenameWithoutField(t *testing.T) {
+	type query struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	data := []byte(`{"viewer": {"__typename": "User", "login": "gopher"}}`)
+	err := jsonutil.UnmarshalGraphQL(data, new(query))
+	if got, want := fmt.Sprint(err), `struct field for "__typename" doesn't exist in any of 1 places to unmarshal`; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+
+	var got query
+	err = jsonutil.UnmarshalGraphQL(data, &got, jsonutil.AutoTypenames())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Viewer.Login != "gopher" {
+		t.Errorf("got login: %q, want: gopher", got.Viewer.Login)
+	}
+
+	// Objects with fragments on types select __typename to tell which apply.
+	var fragments struct {
+		Node struct {
+			Issue struct {
+				Title graphql.String
+			} `graphql:"... on Issue"`
+		}
+	}
+	err = jsonutil.UnmarshalGraphQL([]byte(`{"node": {"__typename": "Issue", "title": "Bug"}}`), &fragments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fragments.Node.Issue.Title != "Bug" {
+		t.Errorf("got title: %q, want: Bug", fragments.Node.Issue.Title)
+	}
+}
+
 func TestUnmarshalGraphQL_multipleValues(t *testing.T) {
 	type query struct {
 		Foo graphql.String
@@ -466,3 +537,452 @@ func TestUnmarshalGraphQL_extended(t *testing.T) {
 		t.Error("not equal")
 	}
 }
+
+func TestUnmarshalGraphQL_caseInsensitive(t *testing.T) {
+	type query struct {
+		DatabaseID graphql.Int    `graphql:"databaseId"`
+		Login      graphql.String `graphql:"login"`
+	}
+	data := []byte(`{
+		"databaseID": 42,
+		"LOGIN": "gopher"
+	}`)
+	err := jsonutil.UnmarshalGraphQL(data, new(query))
+	if err == nil {
+		t.Fatal("got error: nil, want: non-nil")
+	}
+	var got query
+	err = jsonutil.UnmarshalGraphQL(data, &got, jsonutil.CaseInsensitive())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := query{
+		DatabaseID: 42,
+		Login:      "gopher",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Error("not equal")
+	}
+}
+
+func TestUnmarshalGraphQL_preciseNumbers(t *testing.T) {
+	type query struct {
+		ID         graphql.ID
+		DatabaseID int64
+		Number     string
+		Total      *graphql.String
+		Amount     json.Number
+		Big        *big.Float
+		Bigger     *big.Int
+	}
+	data := []byte(`{
+		"id": 9007199254740993,
+		"databaseId": 9007199254740993,
+		"number": 9007199254740993,
+		"total": 12,
+		"amount": 1.50e3,
+		"big": 1234567890123456789.5,
+		"bigger": 123456789012345678901234567890
+	}`)
+	var got query
+	err := jsonutil.UnmarshalGraphQL(data, &got, jsonutil.PreciseNumbers())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := query{
+		ID:         json.Number("9007199254740993"),
+		DatabaseID: 9007199254740993,
+		Number:     "9007199254740993",
+		Total:      graphql.NewString("12"),
+		Amount:     "1.50e3",
+	}
+	if got, want := got.Big.Text('f', 1), "1234567890123456789.5"; got != want {
+		t.Errorf("got Big: %v, want: %v", got, want)
+	}
+	if got, want := got.Bigger.String(), "123456789012345678901234567890"; got != want {
+		t.Errorf("got Bigger: %v, want: %v", got, want)
+	}
+	got.Big, got.Bigger = nil, nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+
+	var imprecise struct {
+		ID graphql.ID
+	}
+	err = jsonutil.UnmarshalGraphQL([]byte(`{"id": 9007199254740993}`), &imprecise)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := imprecise.ID, float64(9007199254740992); got != want {
+		t.Errorf("got ID without PreciseNumbers: %v, want it rounded: %v", got, want)
+	}
+}
+
+func TestUnmarshalGraphQL_exponentIntegers(t *testing.T) {
+	type query struct {
+		Count   graphql.Int
+		Size    *uint64
+		Average graphql.Float
+	}
+	var got query
+	err := jsonutil.UnmarshalGraphQL([]byte(`{"count": 1.5e3, "size": 2E+2, "average": 2.5e-1}`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := uint64(200)
+	want := query{Count: 1500, Size: &size, Average: 0.25}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+
+	for _, data := range []string{`{"count": 1.5}`, `{"count": 1e10}`} {
+		err = jsonutil.UnmarshalGraphQL([]byte(data), new(query))
+		if err == nil {
+			t.Errorf("%s: got error: nil, want: non-nil", data)
+		}
+	}
+}
+
+func TestUnmarshalGraphQL_specialFloats(t *testing.T) {
+	type query struct {
+		Min   graphql.Float
+		Max   *graphql.Float
+		Mean  float32
+		Label graphql.String
+	}
+	data := []byte(`{"min": "-Infinity", "max": "Infinity", "mean": "NaN", "label": "NaN"}`)
+	err := jsonutil.UnmarshalGraphQL(data, new(query))
+	if err == nil {
+		t.Fatal("got error: nil, want: non-nil")
+	}
+	var got query
+	err = jsonutil.UnmarshalGraphQL(data, &got, jsonutil.SpecialFloats())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(float64(got.Min), -1) || got.Max == nil || !math.IsInf(float64(*got.Max), 1) || !math.IsNaN(float64(got.Mean)) || got.Label != "NaN" {
+		t.Errorf("got: %+v", got)
+	}
+}
+
+func TestUnmarshalGraphQL_required(t *testing.T) {
+	type Author struct {
+		Login graphql.String `graphql:"login,required"`
+		Email *graphql.String
+	}
+	type query struct {
+		Issues []struct {
+			Number graphql.Int `graphql:"number,required"`
+			Author *Author     `graphql:"author"`
+			Bot    struct {
+				Name graphql.String `graphql:"name,required"`
+			} `graphql:"... on Bot"`
+		}
+	}
+	tests := []struct {
+		data string
+		want string // Error, if any.
+	}{
+		{data: `{"issues": [{"number": 1, "author": {"login": "gopher", "email": null}}]}`},
+		{data: `{"issues": [{"number": 1, "author": null}]}`},
+		{data: `{"issues": [{"number": 1, "name": "dependabot"}]}`},
+		{
+			data: `{"issues": [{"number": 1}, {"author": {"login": "gopher"}}]}`,
+			want: "required field Number is null or missing",
+		},
+		{
+			data: `{"issues": [{"number": 1, "author": {"login": null}}]}`,
+			want: "required field Author.Login is null or missing",
+		},
+		{
+			data: `{"issues": [{"number": 1, "name": null}]}`,
+			want: "required field Name is null or missing",
+		},
+	}
+	for _, tc := range tests {
+		err := jsonutil.UnmarshalGraphQL([]byte(tc.data), new(query))
+		if tc.want == "" && err != nil || tc.want != "" && (err == nil || err.Error() != tc.want) {
+			t.Errorf("%s: got error: %v, want: %q", tc.data, err, tc.want)
+		}
+	}
+}
+
+func TestUnmarshalGraphQL_pointerSlices(t *testing.T) {
+	type node struct {
+		Name graphql.String
+	}
+	type query struct {
+		Nodes    []*node
+		Tags     []*graphql.String
+		Pages    *[]*node
+		Matrix   [][]*node
+		NilNodes *[]*node
+	}
+	var got query
+	err := jsonutil.UnmarshalGraphQL([]byte(`{
+		"nodes": [{"name": "a"}, null, {"name": "b"}],
+		"tags": ["t", null],
+		"pages": [{"name": "c"}],
+		"matrix": [[{"name": "d"}], []],
+		"nilNodes": null
+	}`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := query{
+		Nodes:  []*node{{Name: "a"}, nil, {Name: "b"}},
+		Tags:   []*graphql.String{graphql.NewString("t"), nil},
+		Pages:  &[]*node{{Name: "c"}},
+		Matrix: [][]*node{{{Name: "d"}}, {}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Error("not equal")
+	}
+}
+
+func TestUnmarshalGraphQL_arrays(t *testing.T) {
+	type query struct {
+		Nodes [2]struct {
+			Name graphql.String
+		}
+		Scores *[3]graphql.Int
+	}
+	var got query
+	got.Nodes[1].Name = "stale"
+	err := jsonutil.UnmarshalGraphQL([]byte(`{"nodes": [{"name": "a"}, {}], "scores": [1, 2, 3]}`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want query
+	want.Nodes[0].Name = "a"
+	want.Scores = &[3]graphql.Int{1, 2, 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`{"nodes": [{"name": "a"}]}`, "array [2]struct { Name graphql.String } has 2 elements, but the JSON array has 1"},
+		{`{"scores": [1, 2, 3, 4]}`, "array [3]graphql.Int has 3 elements, but the JSON array has more"},
+	}
+	for _, tc := range tests {
+		err := jsonutil.UnmarshalGraphQL([]byte(tc.data), new(query))
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("%s: got error: %v, want: %v", tc.data, err, tc.want)
+		}
+	}
+}
+
+func TestUnmarshalGraphQL_wholeValues(t *testing.T) {
+	type query struct {
+		Map   map[string]interface{}
+		Raw   json.RawMessage
+		Any   interface{}
+		Other struct {
+			Login graphql.String
+		}
+	}
+	var got query
+	err := jsonutil.UnmarshalGraphQL([]byte(`{
+		"map": {"b": 1, "a": [true, {"x": null}]},
+		"raw": [{"z": "<"}, []],
+		"any": {"k": "v"},
+		"other": {"login": "gopher"}
+	}`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := query{
+		Map: map[string]interface{}{"b": float64(1), "a": []interface{}{true, map[string]interface{}{"x": nil}}},
+		Raw: json.RawMessage(`[{"z":"\u003c"},[]]`),
+		Any: map[string]interface{}{"k": "v"},
+	}
+	want.Other.Login = "gopher"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+}
+
+func TestUnmarshalGraphQL_mergedAliases(t *testing.T) {
+	type query struct {
+		Nodes []struct {
+			ID graphql.ID
+		} `graphql:"node(id: \"1\")"`
+		Other__x graphql.String
+	}
+	var got query
+	err := jsonutil.UnmarshalGraphQL([]byte(`{
+		"node__1": {"id": "1"},
+		"node__2": {"id": "2"},
+		"other__x": "x"
+	}`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Nodes) != 2 || got.Nodes[0].ID != "1" || got.Nodes[1].ID != "2" {
+		t.Errorf("got wrong Nodes: %v", got.Nodes)
+	}
+	if got.Other__x != "x" {
+		t.Errorf("got Other__x: %q, want: %q", got.Other__x, "x")
+	}
+}
+
+func TestUnmarshalGraphQL_mergedAliasesExactName(t *testing.T) {
+	// A field matching an aliased key exactly is decoded into, wherever
+	// it is among the fields, rather than the slice field of its selection.
+	type query struct {
+		Foos   []graphql.String `graphql:"foo"`
+		Foo__2 graphql.String   `graphql:"foo__2"`
+	}
+	var got query
+	err := jsonutil.UnmarshalGraphQL([]byte(`{"foo__1": "a", "foo__2": "b", "foo__3": "c"}`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := query{Foos: []graphql.String{"a", "c"}, Foo__2: "b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+}
+
+func TestUnmarshalGraphQL_hooks(t *testing.T) {
+	type query struct {
+		Viewer struct {
+			Login string
+			Email string `graphql-hook:"trim,lower"`
+		}
+		Users []struct {
+			Email string `graphql-hook:"lower"`
+			Name  string
+		}
+	}
+	trim := func(v interface{}) error {
+		s := v.(*string)
+		*s = strings.TrimSpace(*s)
+		return nil
+	}
+	lower := func(v interface{}) error {
+		s := v.(*string)
+		*s = strings.ToLower(*s)
+		return nil
+	}
+	var names []string
+	name := func(v interface{}) error {
+		names = append(names, *v.(*string))
+		return nil
+	}
+	var got query
+	err := jsonutil.UnmarshalGraphQL([]byte(`{
+		"viewer": {"login": " Gopher ", "email": " Gopher@Example.com "},
+		"users": [
+			{"email": "A@Example.com", "name": "A"},
+			{"email": "B@Example.com", "name": "B"}
+		]
+	}`), &got, jsonutil.TagHook("trim", trim), jsonutil.TagHook("lower", lower), jsonutil.PathHook("users.name", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Viewer.Login != " Gopher " || got.Viewer.Email != "gopher@example.com" {
+		t.Errorf("got Viewer: %+v", got.Viewer)
+	}
+	if len(got.Users) != 2 || got.Users[0].Email != "a@example.com" || got.Users[1].Email != "b@example.com" {
+		t.Errorf("got Users: %+v", got.Users)
+	}
+	if want := []string{"A", "B"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got names: %v, want: %v", names, want)
+	}
+
+	errInvalid := errors.New("invalid")
+	err = jsonutil.UnmarshalGraphQL([]byte(`{"viewer": {"login": "gopher", "email": "x"}}`), &got, jsonutil.PathHook("viewer", func(v interface{}) error {
+		return errInvalid
+	}))
+	if !errors.Is(err, errInvalid) {
+		t.Errorf("got error: %v, want: %v", err, errInvalid)
+	}
+}
+
+func TestDecodeGraphQL(t *testing.T) {
+	type query struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	dec := json.NewDecoder(strings.NewReader(`{"data": {"viewer": {"login": "gopher"}}, "next": null, "rest": 1}`))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		t.Fatalf("got token: %v, %v", tok, err)
+	}
+	if tok, err := dec.Token(); err != nil || tok != "data" {
+		t.Fatalf("got token: %v, %v", tok, err)
+	}
+	var got query
+	err := jsonutil.DecodeGraphQL(dec, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Viewer.Login != "gopher" {
+		t.Errorf("got Login: %q, want: %q", got.Viewer.Login, "gopher")
+	}
+	// Null data leaves v unchanged.
+	if tok, err := dec.Token(); err != nil || tok != "next" {
+		t.Fatalf("got token: %v, %v", tok, err)
+	}
+	err = jsonutil.DecodeGraphQL(dec, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Viewer.Login != "gopher" {
+		t.Errorf("got Login after null: %q, want: %q", got.Viewer.Login, "gopher")
+	}
+	// The rest of the input is left to be read.
+	if tok, err := dec.Token(); err != nil || tok != "rest" {
+		t.Errorf("got token: %v, %v, want: rest", tok, err)
+	}
+}
+
+func TestUnmarshalGraphQLAt(t *testing.T) {
+	type query struct {
+		Viewer struct {
+			Login string
+			Repos []struct {
+				Name  string
+				Stars int `graphql:"stargazerCount"`
+			}
+			OnUser struct {
+				Bio string
+			} `graphql:"... on User"`
+		}
+	}
+	var got query
+	err := jsonutil.UnmarshalGraphQL([]byte(`{"viewer": {"login": "gopher", "repos": [{"name": "graphql"}]}}`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		path []interface{}
+		data string
+	}{
+		{[]interface{}{"viewer", "repos", 0}, `{"stargazerCount": 3}`},
+		{[]interface{}{"viewer", "repos", 1}, `{"name": "ident", "stargazerCount": 1}`},
+		{[]interface{}{"viewer"}, `{"bio": "Go"}`},
+	} {
+		err := jsonutil.UnmarshalGraphQLAt([]byte(tc.data), &got, tc.path)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.path, err)
+		}
+	}
+	if got, want := fmt.Sprintf("%+v %+v", got.Viewer.Repos, got.Viewer.OnUser), "[{Name:graphql Stars:3} {Name:ident Stars:1}] {Bio:Go}"; got != want {
+		t.Errorf("got repos and bio: %v, want: %v", got, want)
+	}
+	if got.Viewer.Login != "gopher" {
+		t.Errorf("got Login: %q, want it unchanged", got.Viewer.Login)
+	}
+
+	err = jsonutil.UnmarshalGraphQLAt([]byte(`{"name": "x"}`), &got, []interface{}{"viewer", "repos", 3})
+	if got, want := fmt.Sprint(err), "struct field or list element for 3 doesn't exist in any of 1 places to unmarshal"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,323 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/merico-dev/graphql/ident"
+	"github.com/merico-dev/graphql/internal/jsonutil"
+	"github.com/merico-dev/graphql/introspection"
+)
+
+// Introspect queries the server for its schema with the introspection query.
+func (c *Client) Introspect(ctx context.Context, opts ...RequestOption) (*introspection.Schema, error) {
+	resp, err := c.do(ctx, introspection.Query, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Errors) > 0 {
+		return nil, resp.Errors[0]
+	}
+	if resp.Data == nil {
+		return nil, errors.New("introspection response has no data")
+	}
+	var r introspection.Response
+	err = json.Unmarshal(resp.Data, &r)
+	if err != nil {
+		return nil, err
+	}
+	return &r.Schema, nil
+}
+
+// IntrospectType queries the server for the type named name with the
+// introspection query introspection.TypeQuery. It returns nil if the
+// schema has no such type.
+func (c *Client) IntrospectType(ctx context.Context, name string, opts ...RequestOption) (*introspection.Type, error) {
+	resp, err := c.do(ctx, introspection.TypeQuery, map[string]interface{}{"name": name}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Errors) > 0 {
+		return nil, resp.Errors[0]
+	}
+	if resp.Data == nil {
+		return nil, errors.New("introspection response has no data")
+	}
+	var r introspection.TypeResponse
+	err = json.Unmarshal(resp.Data, &r)
+	if err != nil {
+		return nil, err
+	}
+	return r.Type, nil
+}
+
+// CheckCompatibility gets the server's schema with Schema, and checks that the
+// query structs ops still match it: that the fields they select exist, and
+// that their Go types line up with the types of the fields. Each element of
+// ops should be a query, mutation or subscription struct, or a pointer to one.
+//
+// It's meant to run at startup, so that services fail fast after a schema
+// change breaks them. Mismatches are reported by a *CompatibilityError.
+func (c *Client) CheckCompatibility(ctx context.Context, ops ...interface{}) error {
+	schema, err := c.Schema(ctx)
+	if err != nil {
+		return fmt.Errorf("introspecting schema: %w", err)
+	}
+	return checkCompatibility(schema, ops)
+}
+
+// CompatibilityError reports where query structs don't match a schema.
+type CompatibilityError struct {
+	// Problems describes each mismatch, prefixed by the Go path
+	// of the mismatched struct field.
+	Problems []string
+}
+
+func (e *CompatibilityError) Error() string {
+	return "query structs don't match the schema:\n\t" + strings.Join(e.Problems, "\n\t")
+}
+
+// checkCompatibility checks that the query structs ops match schema.
+func checkCompatibility(schema *introspection.Schema, ops []interface{}) error {
+	cc := compatibilityChecker{schema: schema}
+	for i, op := range ops {
+		cc.checkOperation(fmt.Sprintf("ops[%d]", i), op, nil)
+	}
+	if len(cc.problems) > 0 {
+		return &CompatibilityError{Problems: cc.problems}
+	}
+	return nil
+}
+
+// compatibilityChecker checks query structs against a schema,
+// collecting the problems it finds.
+type compatibilityChecker struct {
+	schema   *introspection.Schema
+	problems []string
+
+	// If validate is true, the arguments of fields, and the variables
+	// they're bound to, are checked too. See Validate.
+	validate  bool
+	variables map[string]interface{}
+}
+
+func (cc *compatibilityChecker) problemf(path, format string, args ...interface{}) {
+	if path != "" {
+		path += ": "
+	}
+	cc.problems = append(cc.problems, path+fmt.Sprintf(format, args...))
+}
+
+// checkOperation checks the operation struct op, at path unless its type
+// is named, against the root operation type root, or the one rootType
+// returns if root is nil.
+func (cc *compatibilityChecker) checkOperation(path string, op interface{}, root *introspection.Type) {
+	t := reflect.TypeOf(op)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != nil && t.Name() != "" {
+		path = t.Name()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		cc.problemf(path, "got %T, want a struct", op)
+		return
+	}
+	if root == nil {
+		root = cc.rootType(t)
+	}
+	if root == nil {
+		cc.problemf(path, "schema has no root operation type for it")
+		return
+	}
+	cc.checkStruct(path, t, root)
+}
+
+// rootType returns the root operation type that the operation struct t
+// selects from: the query type, unless t's first field is only found
+// on the mutation or subscription type.
+func (cc *compatibilityChecker) rootType(t reflect.Type) *introspection.Type {
+	var roots []*introspection.Type
+	for _, name := range []*introspection.TypeName{cc.schema.QueryType, cc.schema.MutationType, cc.schema.SubscriptionType} {
+		if name != nil {
+			if root := cc.schema.Type(name.Name); root != nil {
+				roots = append(roots, root)
+			}
+		}
+	}
+	if len(roots) == 0 {
+		return nil
+	}
+	if t.NumField() > 0 {
+		if name, ok := selectedField(t.Field(0)); ok {
+			for _, root := range roots {
+				if root.Field(name) != nil {
+					return root
+				}
+			}
+		}
+	}
+	return roots[0]
+}
+
+// checkStruct checks the fields of struct t, at path,
+// against the fields of the schema type typ.
+func (cc *compatibilityChecker) checkStruct(path string, t reflect.Type, typ *introspection.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fieldPath := joinPath(path, f.Name)
+		if on, ok := fragmentSpread(f); ok {
+			fragmentType := cc.schema.Type(on)
+			if fragmentType == nil {
+				cc.problemf(fieldPath, "schema has no type %q", on)
+				continue
+			}
+			cc.checkStruct(fieldPath, derefType(f.Type), fragmentType)
+			continue
+		}
+		value, _, ok := jsonutil.LookupTag(f.Tag)
+		if !ok && f.Anonymous {
+			cc.checkStruct(path, derefType(f.Type), typ)
+			continue
+		}
+		if value = strings.TrimSpace(value); strings.HasPrefix(value, "...") {
+			// Inline fragment, optionally on another type.
+			fragmentType := typ
+			if on := strings.TrimSpace(strings.TrimPrefix(value, "...")); strings.HasPrefix(on, "on ") {
+				name := strings.TrimSpace(on[len("on "):])
+				fragmentType = cc.schema.Type(name)
+				if fragmentType == nil {
+					cc.problemf(fieldPath, "schema has no type %q", name)
+					continue
+				}
+			}
+			cc.checkStruct(fieldPath, derefType(f.Type), fragmentType)
+			continue
+		}
+		name, _ := selectedField(f)
+		if name == "__typename" {
+			continue
+		}
+		field := typ.Field(name)
+		if field == nil {
+			cc.problemf(fieldPath, "type %s has no field %q", typ.Name, name)
+			continue
+		}
+		fieldType, variables, checkArguments := f.Type, cc.variables, cc.validate
+		if extend, _ := f.Tag.Lookup("graphql-extend"); extend == "true" {
+			// Selected once per element of the variable named after
+			// the field, with the variables of the element.
+			fieldType = derefType(fieldType)
+			if fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Array {
+				fieldType = fieldType.Elem()
+			}
+			elems, _ := cc.variables[name].([]map[string]interface{})
+			if len(elems) > 0 {
+				variables = elems[0]
+			}
+			checkArguments = checkArguments && len(elems) > 0
+		}
+		if checkArguments {
+			_, selection := splitAlias(value)
+			cc.checkArguments(fieldPath, selection, field, variables)
+		}
+		cc.checkType(fieldPath, fieldType, field.Type)
+	}
+}
+
+// checkType checks that the Go type t, at path, can hold values of the
+// schema type ref.
+func (cc *compatibilityChecker) checkType(path string, t reflect.Type, ref introspection.TypeRef) {
+	if ref.Kind == introspection.NonNull && ref.OfType != nil {
+		ref = *ref.OfType
+	}
+	t = derefType(t)
+	if t.Kind() == reflect.Interface || decodedWhole(t) {
+		// Decoded in a custom way.
+		return
+	}
+	if ref.Kind == introspection.List && ref.OfType != nil {
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			cc.problemf(path, "field has list type %v, but Go type %v isn't a slice", ref, t)
+			return
+		}
+		cc.checkType(path, t.Elem(), *ref.OfType)
+		return
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		cc.problemf(path, "field has type %v, but Go type %v is a slice", ref, t)
+		return
+	}
+	typ := cc.schema.Type(ref.Name)
+	if typ == nil {
+		cc.problemf(path, "schema has no type %q", ref.Name)
+		return
+	}
+	switch typ.Kind {
+	case introspection.Object, introspection.Interface, introspection.Union:
+		if t.Kind() != reflect.Struct {
+			cc.problemf(path, "field has %s type %s, but Go type %v isn't a struct", strings.ToLower(string(typ.Kind)), typ.Name, t)
+			return
+		}
+		cc.checkStruct(path, t, typ)
+	default:
+		if t.Kind() == reflect.Struct {
+			cc.problemf(path, "field has %s type %s, but Go type %v is a struct", strings.ToLower(string(typ.Kind)), typ.Name, t)
+			return
+		}
+		if !leafKindMatches(typ, t.Kind()) {
+			cc.problemf(path, "field has type %s, but Go type %v can't hold it", typ.Name, t)
+		}
+	}
+}
+
+// leafKindMatches reports whether Go values of kind can hold
+// values of the scalar or enum type typ.
+func leafKindMatches(typ *introspection.Type, kind reflect.Kind) bool {
+	if typ.Kind == introspection.Enum {
+		return kind == reflect.String
+	}
+	switch typ.Name {
+	case "Boolean":
+		return kind == reflect.Bool
+	case "Int", "Float":
+		return reflect.Int <= kind && kind <= reflect.Float64
+	case "String", "ID":
+		return kind == reflect.String
+	default:
+		// Custom scalars may be encoded in any way.
+		return true
+	}
+}
+
+// selectedField returns the name of the schema field that the struct field
+// f selects. It reports false if f is an inline fragment or embedded struct.
+func selectedField(f reflect.StructField) (string, bool) {
+	value, _, ok := jsonutil.LookupTag(f.Tag)
+	if !ok {
+		if f.Anonymous {
+			return "", false
+		}
+		return ident.ParseMixedCaps(f.Name).ToLowerCamelCase(), true
+	}
+	if strings.HasPrefix(strings.TrimSpace(value), "...") {
+		return "", false
+	}
+	_, field := splitAlias(value)
+	if i := strings.IndexAny(field, "(@{ "); i != -1 {
+		field = field[:i]
+	}
+	return field, true
+}
+
+// derefType returns t with any pointers dereferenced.
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,417 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+	"github.com/merico-dev/graphql/introspection"
+)
+
+// introspectionResponse is the response of a server with a small schema
+// to the introspection query.
+const introspectionResponse = `{"data": {"__schema": {
+	"queryType": {"name": "Query"},
+	"mutationType": {"name": "Mutation"},
+	"subscriptionType": null,
+	"types": [
+		{"kind": "OBJECT", "name": "Query", "fields": [
+			{"name": "viewer", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "OBJECT", "name": "User"}}},
+			{"name": "repository", "args": [{"name": "name", "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "String"}}}], "type": {"kind": "OBJECT", "name": "Repository"}}
+		]},
+		{"kind": "OBJECT", "name": "Mutation", "fields": [
+			{"name": "addStar", "args": [], "type": {"kind": "SCALAR", "name": "Int"}}
+		]},
+		{"kind": "OBJECT", "name": "User", "fields": [
+			{"name": "login", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "String"}}},
+			{"name": "age", "args": [], "type": {"kind": "SCALAR", "name": "Int"}, "isDeprecated": true, "deprecationReason": "Private."}
+		]},
+		{"kind": "OBJECT", "name": "Repository", "fields": [
+			{"name": "issues", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "LIST", "ofType": {"kind": "NON_NULL", "ofType": {"kind": "OBJECT", "name": "Issue"}}}}}
+		]},
+		{"kind": "OBJECT", "name": "Issue", "fields": [
+			{"name": "number", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "Int"}}},
+			{"name": "state", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "ENUM", "name": "IssueState"}}}
+		]},
+		{"kind": "ENUM", "name": "IssueState", "enumValues": [{"name": "OPEN"}, {"name": "CLOSED", "isDeprecated": false}]},
+		{"kind": "SCALAR", "name": "String"},
+		{"kind": "SCALAR", "name": "Int"},
+		{"kind": "SCALAR", "name": "Boolean"}
+	],
+	"directives": [
+		{"name": "include", "locations": ["FIELD"], "args": [{"name": "if", "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "Boolean"}}}]}
+	]
+}}}`
+
+func newIntrospectionClient(t *testing.T) *graphql.Client {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if body := mustRead(req.Body); !strings.Contains(body, "IntrospectionQuery") {
+			t.Errorf("got body: %v, want the introspection query", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, introspectionResponse)
+	})
+	return graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+}
+
+func TestClient_Introspect(t *testing.T) {
+	client := newIntrospectionClient(t)
+
+	schema, err := client.Introspect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := schema.QueryType.Name, "Query"; got != want {
+		t.Errorf("got query type: %v, want: %v", got, want)
+	}
+	if got, want := len(schema.Types), 9; got != want {
+		t.Errorf("got %v types, want %v", got, want)
+	}
+	if got, want := schema.Type("Repository").Field("issues").Type.String(), "[Issue!]!"; got != want {
+		t.Errorf("got issues type: %v, want: %v", got, want)
+	}
+	if age := schema.Type("User").Field("age"); !age.IsDeprecated || *age.DeprecationReason != "Private." {
+		t.Errorf("got age: %+v, want it deprecated", age)
+	}
+}
+
+func TestClient_IntrospectType(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var in struct {
+			Query     string
+			Variables map[string]string
+		}
+		err := json.Unmarshal([]byte(mustRead(req.Body)), &in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if in.Query != introspection.TypeQuery {
+			t.Errorf("got query: %q, want introspection.TypeQuery", in.Query)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch in.Variables["name"] {
+		case "Episode":
+			mustWrite(w, `{"data": {"__type": {"kind": "ENUM", "name": "Episode", "enumValues": [
+				{"name": "NEWHOPE", "isDeprecated": false, "deprecationReason": null},
+				{"name": "JEDI", "isDeprecated": true, "deprecationReason": "Use RETURN."}
+			]}}}`)
+		default:
+			mustWrite(w, `{"data": {"__type": null}}`)
+		}
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	typ, err := client.IntrospectType(context.Background(), "Episode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := typ.Kind, introspection.Enum; got != want {
+		t.Errorf("got kind: %v, want: %v", got, want)
+	}
+	if len(typ.EnumValues) != 2 || !typ.EnumValues[1].IsDeprecated || *typ.EnumValues[1].DeprecationReason != "Use RETURN." {
+		t.Errorf("got enum values: %+v", typ.EnumValues)
+	}
+	typ, err = client.IntrospectType(context.Background(), "Missing")
+	if err != nil || typ != nil {
+		t.Errorf("got %+v, %v, want nil, nil", typ, err)
+	}
+}
+
+func TestClient_CheckCompatibility(t *testing.T) {
+	client := newIntrospectionClient(t)
+
+	type Issues struct {
+		Repository struct {
+			Issues []struct {
+				Number graphql.Int
+				State  graphql.String
+			}
+		} `graphql:"repository(name: $name)"`
+	}
+	var viewer struct {
+		Viewer struct {
+			Typename graphql.String `graphql:"__typename"`
+			Name     graphql.String `graphql:"login"`
+		}
+	}
+	var addStar struct {
+		AddStar graphql.Int `graphql:"starred: addStar"`
+	}
+	err := client.CheckCompatibility(context.Background(), Issues{}, &viewer, &addStar)
+	if err != nil {
+		t.Errorf("got error: %v", err)
+	}
+
+	type Broken struct {
+		Viewer struct {
+			Name graphql.String
+			Age  graphql.String
+		}
+		Repository struct {
+			Issues struct {
+				Number graphql.Int
+			}
+		} `graphql:"repository(name: \"graphql\")"`
+	}
+	err = client.CheckCompatibility(context.Background(), Broken{})
+	var compatErr *graphql.CompatibilityError
+	if !errors.As(err, &compatErr) {
+		t.Fatalf("got error: %v, want a *graphql.CompatibilityError", err)
+	}
+	want := []string{
+		`Broken.Viewer.Name: type User has no field "name"`,
+		`Broken.Viewer.Age: field has type Int, but Go type graphql.String can't hold it`,
+		`Broken.Repository.Issues: field has list type [Issue!], but Go type struct { Number graphql.Int } isn't a slice`,
+	}
+	if !reflect.DeepEqual(compatErr.Problems, want) {
+		t.Errorf("got problems:\n%v\nwant:\n%v", strings.Join(compatErr.Problems, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestClient_Schema(t *testing.T) {
+	var requests int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, introspectionResponse)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	schema, err := client.Schema(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := schema.QueryType.Name, "Query"; got != want {
+		t.Errorf("got query type: %v, want: %v", got, want)
+	}
+	if _, err := client.Schema(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CheckCompatibility(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := requests, 1; got != want {
+		t.Errorf("got %v introspection requests, want: %v", got, want)
+	}
+	if _, err := client.RefreshSchema(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := requests, 2; got != want {
+		t.Errorf("got %v introspection requests after refresh, want: %v", got, want)
+	}
+}
+
+func TestClient_Schema_failure(t *testing.T) {
+	var introspections, queries int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(mustRead(req.Body), "IntrospectionQuery") {
+			atomic.AddInt32(&introspections, 1)
+			time.Sleep(10 * time.Millisecond)
+			mustWrite(w, `{"errors": [{"message": "introspection is disabled"}]}`)
+			return
+		}
+		atomic.AddInt32(&queries, 1)
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithSchemaValidation())
+
+	// Concurrent operations share a single introspection, and the failure
+	// isn't introspected again by later ones.
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var q struct {
+				Viewer struct {
+					Login graphql.String
+				}
+			}
+			_, err := client.Query(context.Background(), &q, nil)
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	_, err := client.Schema(context.Background())
+	if err == nil || err.Error() != "introspection is disabled" {
+		t.Errorf("got error: %v, want: introspection is disabled", err)
+	}
+	if introspections != 1 || queries != 5 {
+		t.Errorf("got %v introspections and %v queries, want 1 and 5", introspections, queries)
+	}
+}
+
+func TestWithSchemaTTL(t *testing.T) {
+	var requests int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, introspectionResponse)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithSchemaTTL(time.Millisecond))
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Schema(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	if got, want := requests, 2; got != want {
+		t.Errorf("got %v introspection requests, want: %v", got, want)
+	}
+}
+
+func TestWithSchemaCacheDir(t *testing.T) {
+	var requests int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, introspectionResponse)
+	})
+	dir := t.TempDir()
+	newClient := func(opts ...graphql.ClientOption) *graphql.Client {
+		opts = append(opts, graphql.WithSchemaCacheDir(dir))
+		return graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, opts...)
+	}
+
+	// The first client introspects, and later clients reuse its schema.
+	for i := 0; i < 2; i++ {
+		schema, err := newClient().Schema(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := schema.QueryType.Name, "Query"; got != want {
+			t.Errorf("got query type: %v, want: %v", got, want)
+		}
+	}
+	if got, want := requests, 1; got != want {
+		t.Errorf("got %v introspection requests, want: %v", got, want)
+	}
+
+	// A schema older than the TTL is introspected again.
+	time.Sleep(2 * time.Millisecond)
+	if _, err := newClient(graphql.WithSchemaTTL(time.Millisecond)).Schema(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := requests, 2; got != want {
+		t.Errorf("got %v introspection requests after expiry, want: %v", got, want)
+	}
+}
+
+func TestWithEnumValidation(t *testing.T) {
+	schema := strings.Replace(introspectionResponse, `"types": [`, `"types": [
+		{"kind": "INPUT_OBJECT", "name": "IssueFilters", "inputFields": [{"name": "states", "type": {"kind": "LIST", "ofType": {"kind": "NON_NULL", "ofType": {"kind": "ENUM", "name": "IssueState"}}}}]},`, 1)
+	var queries int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if body := mustRead(req.Body); strings.Contains(body, "IntrospectionQuery") {
+			mustWrite(w, schema)
+			return
+		}
+		queries++
+		mustWrite(w, `{"data": {"repository": null}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithEnumValidation())
+
+	type IssueState string
+	var q struct {
+		Repository struct {
+			Issues []struct {
+				Number graphql.Int
+			} `graphql:"issues(states: $states, filters: $filters)"`
+		} `graphql:"repository(name: \"graphql\")"`
+	}
+	tests := []struct {
+		variables map[string]interface{}
+		want      string
+	}{
+		{
+			variables: map[string]interface{}{
+				"states":  []IssueState{"OPEN", "CLOSED"},
+				"filters": graphql.InputObject{Type: "IssueFilters", Fields: map[string]interface{}{"states": []string{"OPEN"}}},
+			},
+		},
+		{
+			variables: map[string]interface{}{
+				"states":  []IssueState{"OPEN", "MERGED"},
+				"filters": graphql.InputObject{Type: "IssueFilters"},
+			},
+			want: `$states[1]: "MERGED" isn't a value of enum IssueState; allowed values: OPEN, CLOSED`,
+		},
+		{
+			variables: map[string]interface{}{
+				"states":  []IssueState{},
+				"filters": graphql.InputObject{Type: "IssueFilters", Fields: map[string]interface{}{"states": []string{"open"}}},
+			},
+			want: `$filters.states[0]: "open" isn't a value of enum IssueState; allowed values: OPEN, CLOSED`,
+		},
+	}
+	for _, tc := range tests {
+		queries = 0
+		_, err := client.Query(context.Background(), &q, tc.variables)
+		if tc.want == "" {
+			if err != nil || queries != 1 {
+				t.Errorf("%v: got error: %v, %v queries, want the query sent", tc.variables, err, queries)
+			}
+			continue
+		}
+		var enumErr *graphql.EnumValueError
+		if !errors.As(err, &enumErr) || err.Error() != tc.want {
+			t.Errorf("%v: got error: %v, want: %v", tc.variables, err, tc.want)
+		}
+		if queries != 0 {
+			t.Errorf("%v: got %v queries, want none sent", tc.variables, queries)
+		}
+	}
+}
+
+func TestWithEnumValidation_introspectionDisabled(t *testing.T) {
+	var introspections, queries int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(mustRead(req.Body), "IntrospectionQuery") {
+			introspections++
+			mustWrite(w, `{"errors": [{"message": "introspection is disabled"}]}`)
+			return
+		}
+		queries++
+		mustWrite(w, `{"data": {"repository": null}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithEnumValidation())
+
+	var q struct {
+		Repository struct {
+			Name graphql.String
+		} `graphql:"repository(state: $state)"`
+	}
+	for i := 0; i < 3; i++ {
+		_, err := client.Query(context.Background(), &q, map[string]interface{}{"state": graphql.String("OPEN")})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Variables aren't validated, and the server isn't introspected
+	// for every operation.
+	if introspections != 1 || queries != 3 {
+		t.Errorf("got %v introspections and %v queries, want 1 and 3", introspections, queries)
+	}
+}
@@ -0,0 +1,207 @@
+package introspection
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ChangeType is the type of a change between two schemas.
+type ChangeType string
+
+// Change types.
+const (
+	TypeAdded           ChangeType = "TYPE_ADDED"
+	TypeRemoved         ChangeType = "TYPE_REMOVED"
+	TypeKindChanged     ChangeType = "TYPE_KIND_CHANGED"
+	FieldAdded          ChangeType = "FIELD_ADDED"
+	FieldRemoved        ChangeType = "FIELD_REMOVED"
+	FieldTypeChanged    ChangeType = "FIELD_TYPE_CHANGED"
+	FieldDeprecated     ChangeType = "FIELD_DEPRECATED"
+	ArgAdded            ChangeType = "ARG_ADDED"
+	ArgRemoved          ChangeType = "ARG_REMOVED"
+	ArgTypeChanged      ChangeType = "ARG_TYPE_CHANGED"
+	EnumValueAdded      ChangeType = "ENUM_VALUE_ADDED"
+	EnumValueRemoved    ChangeType = "ENUM_VALUE_REMOVED"
+	EnumValueDeprecated ChangeType = "ENUM_VALUE_DEPRECATED"
+)
+
+// Change is a change between two schemas.
+type Change struct {
+	Type ChangeType
+	// Path is what changed: a type ("User"), a field or input field
+	// ("User.login"), an argument ("Query.user(id)"), or an enum value
+	// ("IssueState.OPEN").
+	Path string
+	// Old and New are the kinds of a type whose kind changed, or the types
+	// of a field or argument whose type changed. New is also the type of an
+	// added argument or input field, and the deprecation reason of a
+	// deprecated field or enum value.
+	Old, New string
+}
+
+func (c Change) String() string {
+	switch {
+	case c.Old != "":
+		return fmt.Sprintf("%s %s: %s -> %s", c.Type, c.Path, c.Old, c.New)
+	case c.New != "":
+		return fmt.Sprintf("%s %s: %s", c.Type, c.Path, c.New)
+	default:
+		return fmt.Sprintf("%s %s", c.Type, c.Path)
+	}
+}
+
+// Breaking reports whether the change may break existing operations:
+// it removes something, or changes the type of something.
+// Additions and deprecations aren't breaking, though adding a required
+// argument is.
+func (c Change) Breaking() bool {
+	switch c.Type {
+	case TypeRemoved, TypeKindChanged, FieldRemoved, FieldTypeChanged,
+		ArgRemoved, ArgTypeChanged, EnumValueRemoved:
+		return true
+	case ArgAdded:
+		return c.New != "" && c.New[len(c.New)-1] == '!'
+	default:
+		return false
+	}
+}
+
+// Diff returns the changes from schema old to schema new,
+// ordered by type name, and then by the order of members in new.
+func Diff(old, new *Schema) []Change {
+	var changes []Change
+	names := make(map[string]bool)
+	for _, t := range old.Types {
+		names[t.Name] = true
+	}
+	for _, t := range new.Types {
+		names[t.Name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		o, n := old.Type(name), new.Type(name)
+		switch {
+		case o == nil:
+			changes = append(changes, Change{Type: TypeAdded, Path: name})
+		case n == nil:
+			changes = append(changes, Change{Type: TypeRemoved, Path: name})
+		case o.Kind != n.Kind:
+			changes = append(changes, Change{Type: TypeKindChanged, Path: name, Old: string(o.Kind), New: string(n.Kind)})
+		default:
+			changes = append(changes, diffFields(name, o.Fields, n.Fields)...)
+			changes = append(changes, diffInputValues(name, ".", FieldAdded, FieldRemoved, FieldTypeChanged, o.InputFields, n.InputFields)...)
+			changes = append(changes, diffEnumValues(name, o.EnumValues, n.EnumValues)...)
+		}
+	}
+	return changes
+}
+
+func diffFields(typeName string, old, new []Field) []Change {
+	var changes []Change
+	for _, n := range new {
+		path := typeName + "." + n.Name
+		o := findField(old, n.Name)
+		if o == nil {
+			changes = append(changes, Change{Type: FieldAdded, Path: path})
+			continue
+		}
+		if ot, nt := o.Type.String(), n.Type.String(); ot != nt {
+			changes = append(changes, Change{Type: FieldTypeChanged, Path: path, Old: ot, New: nt})
+		}
+		changes = append(changes, diffInputValues(path, "(", ArgAdded, ArgRemoved, ArgTypeChanged, o.Args, n.Args)...)
+		if n.IsDeprecated && !o.IsDeprecated {
+			changes = append(changes, Change{Type: FieldDeprecated, Path: path, New: deref(n.DeprecationReason)})
+		}
+	}
+	for _, o := range old {
+		if findField(new, o.Name) == nil {
+			changes = append(changes, Change{Type: FieldRemoved, Path: typeName + "." + o.Name})
+		}
+	}
+	return changes
+}
+
+// diffInputValues diffs arguments or input fields. sep is "(" for
+// arguments, which are written as "field(arg)", and "." for input fields.
+func diffInputValues(parent, sep string, added, removed, typeChanged ChangeType, old, new []InputValue) []Change {
+	var changes []Change
+	path := func(name string) string {
+		if sep == "(" {
+			return parent + "(" + name + ")"
+		}
+		return parent + sep + name
+	}
+	for _, n := range new {
+		o := findInputValue(old, n.Name)
+		if o == nil {
+			changes = append(changes, Change{Type: added, Path: path(n.Name), New: n.Type.String()})
+			continue
+		}
+		if ot, nt := o.Type.String(), n.Type.String(); ot != nt {
+			changes = append(changes, Change{Type: typeChanged, Path: path(n.Name), Old: ot, New: nt})
+		}
+	}
+	for _, o := range old {
+		if findInputValue(new, o.Name) == nil {
+			changes = append(changes, Change{Type: removed, Path: path(o.Name)})
+		}
+	}
+	return changes
+}
+
+func diffEnumValues(typeName string, old, new []EnumValue) []Change {
+	var changes []Change
+	for _, n := range new {
+		path := typeName + "." + n.Name
+		o := findEnumValue(old, n.Name)
+		if o == nil {
+			changes = append(changes, Change{Type: EnumValueAdded, Path: path})
+		} else if n.IsDeprecated && !o.IsDeprecated {
+			changes = append(changes, Change{Type: EnumValueDeprecated, Path: path, New: deref(n.DeprecationReason)})
+		}
+	}
+	for _, o := range old {
+		if findEnumValue(new, o.Name) == nil {
+			changes = append(changes, Change{Type: EnumValueRemoved, Path: typeName + "." + o.Name})
+		}
+	}
+	return changes
+}
+
+func findField(fields []Field, name string) *Field {
+	for i := range fields {
+		if fields[i].Name == name {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+func findInputValue(values []InputValue, name string) *InputValue {
+	for i := range values {
+		if values[i].Name == name {
+			return &values[i]
+		}
+	}
+	return nil
+}
+
+func findEnumValue(values []EnumValue, name string) *EnumValue {
+	for i := range values {
+		if values[i].Name == name {
+			return &values[i]
+		}
+	}
+	return nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,70 @@
+package introspection_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/merico-dev/graphql/introspection"
+)
+
+func mustSchema(t *testing.T, s string) *introspection.Schema {
+	t.Helper()
+	var schema introspection.Schema
+	if err := json.Unmarshal([]byte(s), &schema); err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+func TestDiff(t *testing.T) {
+	old := mustSchema(t, `{"types": [
+		{"kind": "OBJECT", "name": "Query", "fields": [
+			{"name": "user", "args": [{"name": "id", "type": {"kind": "SCALAR", "name": "ID"}}], "type": {"kind": "OBJECT", "name": "User"}},
+			{"name": "node", "args": [], "type": {"kind": "SCALAR", "name": "String"}}
+		]},
+		{"kind": "OBJECT", "name": "User", "fields": [
+			{"name": "login", "args": [], "type": {"kind": "SCALAR", "name": "String"}},
+			{"name": "age", "args": [], "type": {"kind": "SCALAR", "name": "Int"}}
+		]},
+		{"kind": "ENUM", "name": "State", "enumValues": [{"name": "OPEN"}, {"name": "CLOSED"}]},
+		{"kind": "SCALAR", "name": "Gone"}
+	]}`)
+	new := mustSchema(t, `{"types": [
+		{"kind": "OBJECT", "name": "Query", "fields": [
+			{"name": "user", "args": [
+				{"name": "id", "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}},
+				{"name": "org", "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "String"}}}
+			], "type": {"kind": "OBJECT", "name": "User"}},
+			{"name": "node", "args": [], "type": {"kind": "SCALAR", "name": "String"}, "isDeprecated": true, "deprecationReason": "Use user."}
+		]},
+		{"kind": "OBJECT", "name": "User", "fields": [
+			{"name": "login", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "String"}}},
+			{"name": "email", "args": [], "type": {"kind": "SCALAR", "name": "String"}}
+		]},
+		{"kind": "ENUM", "name": "State", "enumValues": [{"name": "OPEN", "isDeprecated": true}, {"name": "MERGED"}]},
+		{"kind": "SCALAR", "name": "Added"}
+	]}`)
+
+	var got []string
+	for _, c := range introspection.Diff(old, new) {
+		got = append(got, fmt.Sprintf("%v breaking=%v", c, c.Breaking()))
+	}
+	want := []string{
+		"TYPE_ADDED Added breaking=false",
+		"TYPE_REMOVED Gone breaking=true",
+		"ARG_TYPE_CHANGED Query.user(id): ID -> ID! breaking=true",
+		"ARG_ADDED Query.user(org): String! breaking=true",
+		"FIELD_DEPRECATED Query.node: Use user. breaking=false",
+		"ENUM_VALUE_DEPRECATED State.OPEN breaking=false",
+		"ENUM_VALUE_ADDED State.MERGED breaking=false",
+		"ENUM_VALUE_REMOVED State.CLOSED breaking=true",
+		"FIELD_TYPE_CHANGED User.login: String -> String! breaking=true",
+		"FIELD_ADDED User.email breaking=false",
+		"FIELD_REMOVED User.age breaking=true",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,167 @@
+// Package introspection provides the GraphQL introspection query
+// and the types of its result, which describe a server's schema.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Introspection.
+package introspection
+
+import "strings"
+
+// Query is the introspection query that fetches the complete schema.
+// Its result is decoded into a Response.
+const Query = `query IntrospectionQuery{__schema{queryType{name},mutationType{name},subscriptionType{name},types{...FullType},directives{name,description,locations,args{...InputValue}}}}` +
+	`fragment FullType on __Type{kind,name,description,fields(includeDeprecated: true){name,description,args{...InputValue},type{...TypeRef},isDeprecated,deprecationReason},inputFields{...InputValue},interfaces{...TypeRef},enumValues(includeDeprecated: true){name,description,isDeprecated,deprecationReason},possibleTypes{...TypeRef}}` +
+	`fragment InputValue on __InputValue{name,description,type{...TypeRef},defaultValue}` +
+	`fragment TypeRef on __Type{kind,name,ofType{kind,name,ofType{kind,name,ofType{kind,name,ofType{kind,name,ofType{kind,name,ofType{kind,name,ofType{kind,name}}}}}}}}`
+
+// TypeQuery is the introspection query that fetches a single type,
+// named by its $name variable, which is cheaper than fetching the complete
+// schema when only some types are needed. Its result is decoded into
+// a TypeResponse.
+const TypeQuery = `query IntrospectionTypeQuery($name:String!){__type(name: $name){...FullType}}` +
+	`fragment FullType on __Type{kind,name,description,fields(includeDeprecated: true){name,description,args{...InputValue},type{...TypeRef},isDeprecated,deprecationReason},inputFields{...InputValue},interfaces{...TypeRef},enumValues(includeDeprecated: true){name,description,isDeprecated,deprecationReason},possibleTypes{...TypeRef}}` +
+	`fragment InputValue on __InputValue{name,description,type{...TypeRef},defaultValue}` +
+	`fragment TypeRef on __Type{kind,name,ofType{kind,name,ofType{kind,name,ofType{kind,name,ofType{kind,name,ofType{kind,name,ofType{kind,name,ofType{kind,name}}}}}}}}`
+
+// TypeResponse is the data of a response to TypeQuery.
+// Type is nil if the schema has no such type.
+type TypeResponse struct {
+	Type *Type `json:"__type"`
+}
+
+// Response is the data of a response to Query.
+type Response struct {
+	Schema Schema `json:"__schema"`
+}
+
+// Schema describes a GraphQL schema.
+type Schema struct {
+	QueryType        *TypeName   `json:"queryType"`
+	MutationType     *TypeName   `json:"mutationType"`
+	SubscriptionType *TypeName   `json:"subscriptionType"`
+	Types            []Type      `json:"types"`
+	Directives       []Directive `json:"directives"`
+}
+
+// TypeName is a reference to a named type.
+type TypeName struct {
+	Name string `json:"name"`
+}
+
+// Type returns the type named name, or nil if the schema has no such type.
+func (s *Schema) Type(name string) *Type {
+	for i := range s.Types {
+		if s.Types[i].Name == name {
+			return &s.Types[i]
+		}
+	}
+	return nil
+}
+
+// TypeKind is the kind of a type.
+type TypeKind string
+
+// Type kinds.
+const (
+	Scalar      TypeKind = "SCALAR"
+	Object      TypeKind = "OBJECT"
+	Interface   TypeKind = "INTERFACE"
+	Union       TypeKind = "UNION"
+	Enum        TypeKind = "ENUM"
+	InputObject TypeKind = "INPUT_OBJECT"
+	List        TypeKind = "LIST"
+	NonNull     TypeKind = "NON_NULL"
+)
+
+// Type describes a named type of a schema.
+type Type struct {
+	Kind          TypeKind     `json:"kind"`
+	Name          string       `json:"name"`
+	Description   string       `json:"description,omitempty"`
+	Fields        []Field      `json:"fields,omitempty"`        // OBJECT and INTERFACE only.
+	InputFields   []InputValue `json:"inputFields,omitempty"`   // INPUT_OBJECT only.
+	Interfaces    []TypeRef    `json:"interfaces,omitempty"`    // OBJECT and INTERFACE only.
+	EnumValues    []EnumValue  `json:"enumValues,omitempty"`    // ENUM only.
+	PossibleTypes []TypeRef    `json:"possibleTypes,omitempty"` // INTERFACE and UNION only.
+}
+
+// Field returns the field named name, or nil if t has no such field.
+func (t *Type) Field(name string) *Field {
+	for i := range t.Fields {
+		if t.Fields[i].Name == name {
+			return &t.Fields[i]
+		}
+	}
+	return nil
+}
+
+// Field describes a field of an object or interface type.
+type Field struct {
+	Name              string       `json:"name"`
+	Description       string       `json:"description,omitempty"`
+	Args              []InputValue `json:"args"`
+	Type              TypeRef      `json:"type"`
+	IsDeprecated      bool         `json:"isDeprecated"`
+	DeprecationReason *string      `json:"deprecationReason"`
+}
+
+// InputValue describes an argument, or a field of an input object type.
+type InputValue struct {
+	Name         string  `json:"name"`
+	Description  string  `json:"description,omitempty"`
+	Type         TypeRef `json:"type"`
+	DefaultValue *string `json:"defaultValue"` // GraphQL literal, if any.
+}
+
+// EnumValue describes a value of an enum type.
+type EnumValue struct {
+	Name              string  `json:"name"`
+	Description       string  `json:"description,omitempty"`
+	IsDeprecated      bool    `json:"isDeprecated"`
+	DeprecationReason *string `json:"deprecationReason"`
+}
+
+// Directive describes a directive supported by a schema.
+type Directive struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Locations   []string     `json:"locations"`
+	Args        []InputValue `json:"args"`
+}
+
+// TypeRef is a reference to a type, which is a named type,
+// or a list or non-null type wrapping another type in OfType.
+type TypeRef struct {
+	Kind   TypeKind `json:"kind"`
+	Name   string   `json:"name,omitempty"` // Empty for LIST and NON_NULL.
+	OfType *TypeRef `json:"ofType,omitempty"`
+}
+
+// NamedType returns the name of the named type that t refers to,
+// unwrapping any list and non-null types.
+func (t TypeRef) NamedType() string {
+	for t.OfType != nil && (t.Kind == List || t.Kind == NonNull) {
+		t = *t.OfType
+	}
+	return t.Name
+}
+
+// String returns t in GraphQL type syntax. E.g., "[String!]!".
+func (t TypeRef) String() string {
+	var b strings.Builder
+	t.write(&b)
+	return b.String()
+}
+
+func (t TypeRef) write(b *strings.Builder) {
+	switch {
+	case t.Kind == NonNull && t.OfType != nil:
+		t.OfType.write(b)
+		b.WriteString("!")
+	case t.Kind == List && t.OfType != nil:
+		b.WriteString("[")
+		t.OfType.write(b)
+		b.WriteString("]")
+	default:
+		b.WriteString(t.Name)
+	}
+}
@@ -0,0 +1,36 @@
+package introspection_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/merico-dev/graphql/introspection"
+)
+
+func TestTypeRef(t *testing.T) {
+	var ref introspection.TypeRef
+	err := json.Unmarshal([]byte(`{"kind": "NON_NULL", "ofType": {"kind": "LIST", "ofType": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "String"}}}}`), &ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ref.String(), "[String!]!"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if got, want := ref.NamedType(), "String"; got != want {
+		t.Errorf("got named type: %q, want: %q", got, want)
+	}
+}
+
+func TestSchema_Type(t *testing.T) {
+	schema := introspection.Schema{
+		Types: []introspection.Type{
+			{Kind: introspection.Object, Name: "Query", Fields: []introspection.Field{{Name: "viewer"}}},
+		},
+	}
+	if typ := schema.Type("Query"); typ == nil || typ.Field("viewer") == nil || typ.Field("nope") != nil {
+		t.Errorf("got type: %+v, want Query with field viewer", typ)
+	}
+	if typ := schema.Type("Mutation"); typ != nil {
+		t.Errorf("got type: %+v, want nil", typ)
+	}
+}
@@ -0,0 +1,228 @@
+package introspection
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+)
+
+// SDL returns the schema s in the GraphQL schema definition language,
+// e.g., to keep a snapshot of a remote schema in a repository.
+//
+// Types and directives are sorted by name, so the output is stable across
+// introspections of the same schema. Built-in scalars, introspection types,
+// and built-in directives are omitted.
+func SDL(s *Schema) string {
+	var b strings.Builder
+	writeSchemaDefinition(&b, s)
+
+	types := make([]Type, 0, len(s.Types))
+	for _, t := range s.Types {
+		if !builtinTypes[t.Name] && !strings.HasPrefix(t.Name, "__") {
+			types = append(types, t)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+
+	directives := make([]Directive, 0, len(s.Directives))
+	for _, d := range s.Directives {
+		if !builtinDirectives[d.Name] {
+			directives = append(directives, d)
+		}
+	}
+	sort.Slice(directives, func(i, j int) bool { return directives[i].Name < directives[j].Name })
+
+	for _, d := range directives {
+		separate(&b)
+		writeDescription(&b, "", d.Description)
+		b.WriteString("directive @" + d.Name)
+		writeArgs(&b, d.Args)
+		b.WriteString(" on " + strings.Join(d.Locations, " | ") + "\n")
+	}
+	for _, t := range types {
+		separate(&b)
+		writeType(&b, t)
+	}
+	return b.String()
+}
+
+// ParseSDL parses the schema sdl, written in the GraphQL schema definition
+// language, into the schema that introspecting a server with it returns.
+func ParseSDL(sdl string) (*Schema, error) {
+	s, err := graphqlgo.ParseSchema(sdl, nil)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.ToJSON()
+	if err != nil {
+		return nil, err
+	}
+	var r Response
+	err = json.Unmarshal(b, &r)
+	if err != nil {
+		return nil, err
+	}
+	return &r.Schema, nil
+}
+
+// Hash returns a hex-encoded SHA-256 hash of the SDL of the schema s.
+// It identifies the schema, e.g., to key work generated from it, and
+// doesn't change when the order of types in introspection results does.
+func Hash(s *Schema) string {
+	sum := sha256.Sum256([]byte(SDL(s)))
+	return hex.EncodeToString(sum[:])
+}
+
+var builtinTypes = map[string]bool{"String": true, "Int": true, "Float": true, "Boolean": true, "ID": true}
+
+var builtinDirectives = map[string]bool{"skip": true, "include": true, "deprecated": true, "specifiedBy": true}
+
+// writeSchemaDefinition writes the schema definition, unless the root
+// operation types have their default names and it can be omitted.
+func writeSchemaDefinition(b *strings.Builder, s *Schema) {
+	roots := []struct {
+		operation, defaultName string
+		typ                    *TypeName
+	}{
+		{"query", "Query", s.QueryType},
+		{"mutation", "Mutation", s.MutationType},
+		{"subscription", "Subscription", s.SubscriptionType},
+	}
+	custom := false
+	for _, r := range roots {
+		if r.typ != nil && r.typ.Name != r.defaultName {
+			custom = true
+		}
+	}
+	if !custom {
+		return
+	}
+	b.WriteString("schema {\n")
+	for _, r := range roots {
+		if r.typ != nil {
+			b.WriteString("  " + r.operation + ": " + r.typ.Name + "\n")
+		}
+	}
+	b.WriteString("}\n")
+}
+
+func writeType(b *strings.Builder, t Type) {
+	writeDescription(b, "", t.Description)
+	switch t.Kind {
+	case Scalar:
+		b.WriteString("scalar " + t.Name + "\n")
+	case Object, Interface:
+		if t.Kind == Object {
+			b.WriteString("type " + t.Name)
+		} else {
+			b.WriteString("interface " + t.Name)
+		}
+		if len(t.Interfaces) > 0 {
+			names := make([]string, len(t.Interfaces))
+			for i, ref := range t.Interfaces {
+				names[i] = ref.NamedType()
+			}
+			b.WriteString(" implements " + strings.Join(names, " & "))
+		}
+		b.WriteString(" {\n")
+		for _, f := range t.Fields {
+			writeDescription(b, "  ", f.Description)
+			b.WriteString("  " + f.Name)
+			writeArgs(b, f.Args)
+			b.WriteString(": " + f.Type.String())
+			writeDeprecated(b, f.IsDeprecated, f.DeprecationReason)
+			b.WriteString("\n")
+		}
+		b.WriteString("}\n")
+	case Union:
+		names := make([]string, len(t.PossibleTypes))
+		for i, ref := range t.PossibleTypes {
+			names[i] = ref.NamedType()
+		}
+		b.WriteString("union " + t.Name + " = " + strings.Join(names, " | ") + "\n")
+	case Enum:
+		b.WriteString("enum " + t.Name + " {\n")
+		for _, v := range t.EnumValues {
+			writeDescription(b, "  ", v.Description)
+			b.WriteString("  " + v.Name)
+			writeDeprecated(b, v.IsDeprecated, v.DeprecationReason)
+			b.WriteString("\n")
+		}
+		b.WriteString("}\n")
+	case InputObject:
+		b.WriteString("input " + t.Name + " {\n")
+		for _, f := range t.InputFields {
+			writeDescription(b, "  ", f.Description)
+			b.WriteString("  ")
+			writeInputValue(b, f)
+			b.WriteString("\n")
+		}
+		b.WriteString("}\n")
+	}
+}
+
+// writeArgs writes args in parentheses, or nothing if there are none.
+func writeArgs(b *strings.Builder, args []InputValue) {
+	if len(args) == 0 {
+		return
+	}
+	b.WriteString("(")
+	for i, arg := range args {
+		if i != 0 {
+			b.WriteString(", ")
+		}
+		writeInputValue(b, arg)
+	}
+	b.WriteString(")")
+}
+
+func writeInputValue(b *strings.Builder, v InputValue) {
+	b.WriteString(v.Name + ": " + v.Type.String())
+	if v.DefaultValue != nil {
+		b.WriteString(" = " + *v.DefaultValue)
+	}
+}
+
+func writeDeprecated(b *strings.Builder, deprecated bool, reason *string) {
+	if !deprecated {
+		return
+	}
+	b.WriteString(" @deprecated")
+	// "No longer supported" is the default reason, so it needn't be written.
+	if reason != nil && *reason != "No longer supported" {
+		b.WriteString("(reason: " + strconv.Quote(*reason) + ")")
+	}
+}
+
+// writeDescription writes description as a string, or as a block string
+// if it spans several lines, followed by a newline. indent is written
+// before each line.
+func writeDescription(b *strings.Builder, indent, description string) {
+	if description == "" {
+		return
+	}
+	if !strings.Contains(description, "\n") {
+		b.WriteString(indent + strconv.Quote(description) + "\n")
+		return
+	}
+	b.WriteString(indent + `"""` + "\n")
+	for _, line := range strings.Split(description, "\n") {
+		if line != "" {
+			b.WriteString(indent + strings.ReplaceAll(line, `"""`, `\"""`))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(indent + `"""` + "\n")
+}
+
+// separate writes a blank line between definitions.
+func separate(b *strings.Builder) {
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+}
@@ -0,0 +1,138 @@
+package introspection_test
+
+import (
+	"testing"
+
+	"github.com/merico-dev/graphql/introspection"
+)
+
+func TestSDL(t *testing.T) {
+	schema := mustSchema(t, `{
+		"queryType": {"name": "Query"},
+		"types": [
+			{"kind": "OBJECT", "name": "Query", "fields": [
+				{"name": "user", "args": [{"name": "id", "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}, "defaultValue": null}], "type": {"kind": "OBJECT", "name": "User"}},
+				{"name": "node", "args": [], "type": {"kind": "INTERFACE", "name": "Node"}, "isDeprecated": true, "deprecationReason": "Use user."}
+			]},
+			{"kind": "OBJECT", "name": "User", "description": "A user.", "interfaces": [{"kind": "INTERFACE", "name": "Node"}], "fields": [
+				{"name": "id", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}},
+				{"name": "tags", "description": "Tags,\nmost recent first.", "args": [{"name": "first", "type": {"kind": "SCALAR", "name": "Int"}, "defaultValue": "10"}], "type": {"kind": "LIST", "ofType": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "String"}}}}
+			]},
+			{"kind": "INTERFACE", "name": "Node", "fields": [{"name": "id", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}}]},
+			{"kind": "UNION", "name": "Result", "possibleTypes": [{"kind": "OBJECT", "name": "User"}, {"kind": "OBJECT", "name": "Query"}]},
+			{"kind": "ENUM", "name": "State", "enumValues": [{"name": "OPEN"}, {"name": "CLOSED", "isDeprecated": true, "deprecationReason": "No longer supported"}]},
+			{"kind": "INPUT_OBJECT", "name": "Filter", "inputFields": [{"name": "state", "type": {"kind": "ENUM", "name": "State"}, "defaultValue": "OPEN"}]},
+			{"kind": "SCALAR", "name": "DateTime"},
+			{"kind": "SCALAR", "name": "String"},
+			{"kind": "OBJECT", "name": "__Type", "fields": []}
+		],
+		"directives": [
+			{"name": "skip", "locations": ["FIELD"], "args": []},
+			{"name": "cost", "locations": ["FIELD_DEFINITION", "OBJECT"], "args": [{"name": "weight", "type": {"kind": "SCALAR", "name": "Int"}}]}
+		]
+	}`)
+	want := `directive @cost(weight: Int) on FIELD_DEFINITION | OBJECT
+
+scalar DateTime
+
+input Filter {
+  state: State = OPEN
+}
+
+interface Node {
+  id: ID!
+}
+
+type Query {
+  user(id: ID!): User
+  node: Node @deprecated(reason: "Use user.")
+}
+
+union Result = User | Query
+
+enum State {
+  OPEN
+  CLOSED @deprecated
+}
+
+"A user."
+type User implements Node {
+  id: ID!
+  """
+  Tags,
+  most recent first.
+  """
+  tags(first: Int = 10): [String!]
+}
+`
+	if got := introspection.SDL(schema); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSDL_schemaDefinition(t *testing.T) {
+	schema := mustSchema(t, `{
+		"queryType": {"name": "Root"},
+		"mutationType": {"name": "Mutation"},
+		"types": [{"kind": "SCALAR", "name": "DateTime"}]
+	}`)
+	want := `schema {
+  query: Root
+  mutation: Mutation
+}
+
+scalar DateTime
+`
+	if got := introspection.SDL(schema); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHash(t *testing.T) {
+	a := mustSchema(t, `{"queryType": {"name": "Query"}, "types": [
+		{"kind": "OBJECT", "name": "Query", "fields": [{"name": "user", "args": [], "type": {"kind": "OBJECT", "name": "User"}}]},
+		{"kind": "OBJECT", "name": "User", "fields": [{"name": "id", "args": [], "type": {"kind": "SCALAR", "name": "ID"}}]}
+	]}`)
+	b := mustSchema(t, `{"queryType": {"name": "Query"}, "types": [
+		{"kind": "OBJECT", "name": "User", "fields": [{"name": "id", "args": [], "type": {"kind": "SCALAR", "name": "ID"}}]},
+		{"kind": "OBJECT", "name": "Query", "fields": [{"name": "user", "args": [], "type": {"kind": "OBJECT", "name": "User"}}]}
+	]}`)
+	c := mustSchema(t, `{"queryType": {"name": "Query"}, "types": [
+		{"kind": "OBJECT", "name": "Query", "fields": [{"name": "user", "args": [], "type": {"kind": "OBJECT", "name": "User"}}]},
+		{"kind": "OBJECT", "name": "User", "fields": [{"name": "login", "args": [], "type": {"kind": "SCALAR", "name": "String"}}]}
+	]}`)
+	if introspection.Hash(a) != introspection.Hash(b) {
+		t.Error("got different hashes for schemas with types in a different order")
+	}
+	if introspection.Hash(a) == introspection.Hash(c) {
+		t.Error("got the same hash for different schemas")
+	}
+}
+
+func TestParseSDL(t *testing.T) {
+	schema, err := introspection.ParseSDL(`
+		type Query {
+			user(id: ID!, first: Int = 10): User
+		}
+		type User {
+			login: String!
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := schema.QueryType.Name, "Query"; got != want {
+		t.Errorf("got query type: %v, want: %v", got, want)
+	}
+	user := schema.Type("Query").Field("user")
+	if got, want := user.Type.String(), "User"; got != want {
+		t.Errorf("got user type: %v, want: %v", got, want)
+	}
+	if len(user.Args) != 2 || user.Args[0].Type.String() != "ID!" || user.Args[1].DefaultValue == nil || *user.Args[1].DefaultValue != "10" {
+		t.Errorf("got user arguments: %+v", user.Args)
+	}
+
+	if _, err := introspection.ParseSDL(`type Query {`); err == nil {
+		t.Error("got no error for malformed SDL")
+	}
+}
@@ -0,0 +1,55 @@
+package graphql
+
+import "time"
+
+// LatencyFunc is called after each operation a client executes, with the
+// name of the operation ("" if anonymous), the time it took, and its outcome.
+// It's a lightweight way to feed latency histograms.
+type LatencyFunc func(operation string, d time.Duration, outcome Outcome)
+
+// WithLatencyFunc makes the client call f after each operation it executes.
+// f may be called concurrently, and should return quickly.
+func WithLatencyFunc(f LatencyFunc) ClientOption {
+	return func(c *Client) {
+		c.latencyFunc = f
+	}
+}
+
+// Outcome is the outcome of an operation.
+type Outcome uint8
+
+const (
+	// OutcomeSuccess is a response without errors.
+	OutcomeSuccess Outcome = iota
+	// OutcomeDataErrors is a response with GraphQL errors,
+	// possibly along with partial data.
+	OutcomeDataErrors
+	// OutcomeFailure is the lack of a usable response, because of
+	// a network failure, a non-200 OK status code, or a malformed body.
+	OutcomeFailure
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "success"
+	case OutcomeDataErrors:
+		return "data_errors"
+	case OutcomeFailure:
+		return "failure"
+	default:
+		return "unknown"
+	}
+}
+
+// outcomeOf returns the outcome of an operation that returned resp and err.
+func outcomeOf(resp *Response, err error) Outcome {
+	switch {
+	case err != nil:
+		return OutcomeFailure
+	case len(resp.Errors) > 0:
+		return OutcomeDataErrors
+	default:
+		return OutcomeSuccess
+	}
+}
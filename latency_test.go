@@ -0,0 +1,43 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestWithLatencyFunc(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		switch body := mustRead(req.Body); body {
+		case `{"query":"query GetViewer{viewer{login}}"}` + "\n":
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+		case `{"query":"{viewer{login}}"}` + "\n":
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"data": null, "errors": [{"message": "unauthorized"}]}`)
+		default:
+			http.Error(w, "unexpected body", http.StatusBadRequest)
+		}
+	})
+	var got []string
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithLatencyFunc(func(operation string, d time.Duration, outcome graphql.Outcome) {
+			if d <= 0 {
+				t.Errorf("got non-positive duration %v", d)
+			}
+			got = append(got, fmt.Sprintf("%q %v", operation, outcome))
+		}))
+
+	client.Do(context.Background(), "query GetViewer{viewer{login}}", nil)
+	client.Do(context.Background(), "{viewer{login}}", nil)
+	client.Do(context.Background(), "{viewer{name}}", nil)
+	want := []string{`"GetViewer" success`, `"" data_errors`, `"" failure`}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
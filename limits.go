@@ -0,0 +1,116 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// ErrQueryTooLarge is returned, wrapped, when a constructed document exceeds
+// the limits set with WithMaxQuerySize or WithMaxExtendAliases.
+var ErrQueryTooLarge = errors.New("query too large")
+
+// ErrResponseTooLarge is returned, wrapped, when a response body exceeds
+// the limit set with WithMaxResponseBytes.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// WithMaxQuerySize limits the size of documents the client sends to n bytes.
+// Larger documents fail with an error wrapping ErrQueryTooLarge before being
+// sent, rather than being rejected by the server with an opaque status such
+// as 413 Request Entity Too Large.
+func WithMaxQuerySize(n int) ClientOption {
+	return func(c *Client) {
+		c.maxQuerySize = n
+	}
+}
+
+// WithMaxExtendAliases limits the number of aliased copies a graphql-extend
+// field may be expanded into by Client.Query to n. Queries with more fail
+// with an error wrapping ErrQueryTooLarge before being constructed.
+func WithMaxExtendAliases(n int) ClientOption {
+	return func(c *Client) {
+		c.maxExtendAliases = n
+	}
+}
+
+// WithMaxResponseBytes limits how much of a response body the client reads
+// to n bytes, after decompression. Operations whose responses are larger
+// fail with an error wrapping ErrResponseTooLarge once the limit is
+// reached, rather than exhausting memory, or disk with WithSpillToDisk.
+// The bodies of non-200 OK responses kept in HTTPStatusError are truncated
+// to n bytes.
+func WithMaxResponseBytes(n int64) ClientOption {
+	return func(c *Client) {
+		c.maxResponseBytes = n
+	}
+}
+
+// checkQuery checks that query may be sent by the client.
+func (c *Client) checkQuery(query string) error {
+	if c.maxQuerySize > 0 && len(query) > c.maxQuerySize {
+		return fmt.Errorf("%w: document is %d bytes, more than the limit of %d; split the query into smaller ones", ErrQueryTooLarge, len(query), c.maxQuerySize)
+	}
+	return c.checkAllowList(query)
+}
+
+// checkExtendAliases checks that none of the variables used for
+// graphql-extend fields expands into more aliases than the client allows.
+func (c *Client) checkExtendAliases(variables map[string]interface{}) error {
+	if c.maxExtendAliases <= 0 {
+		return nil
+	}
+	for k, v := range variables {
+		if v, ok := v.([]map[string]interface{}); ok && len(v) > c.maxExtendAliases {
+			return fmt.Errorf("%w: variable %q expands into %d aliases, more than the limit of %d; split it into chunks of at most %d", ErrQueryTooLarge, k, len(v), c.maxExtendAliases, c.maxExtendAliases)
+		}
+	}
+	return nil
+}
+
+// limitResponse returns a reader of the response body r that fails with
+// an error wrapping ErrResponseTooLarge once it has read more than the
+// client allows.
+func (c *Client) limitResponse(r io.Reader) io.Reader {
+	if c.maxResponseBytes <= 0 {
+		return r
+	}
+	return &limitedReader{r: r, limit: c.maxResponseBytes, remaining: c.maxResponseBytes + 1}
+}
+
+// readErrorBody reads the body r of a non-200 OK response whose media
+// type is JSON, truncating it to the limit of the client, if any.
+func (c *Client) readErrorBody(r io.Reader) (body []byte, truncated bool) {
+	if c.maxResponseBytes > 0 {
+		return readTruncated(r, c.maxResponseBytes)
+	}
+	body, _ = ioutil.ReadAll(r)
+	return body, false
+}
+
+// limitedReader reads from r until more than limit bytes are read,
+// and then fails with an error wrapping ErrResponseTooLarge.
+type limitedReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64 // Bytes to read before failing, one more than the bytes allowed.
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, l.err()
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining <= 0 {
+		return n - 1, l.err()
+	}
+	return n, err
+}
+
+func (l *limitedReader) err() error {
+	return fmt.Errorf("%w: body is more than the limit of %d bytes", ErrResponseTooLarge, l.limit)
+}
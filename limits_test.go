@@ -0,0 +1,109 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestWithMaxQuerySize(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		t.Error("unexpected request")
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithMaxQuerySize(10))
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil)
+	if !errors.Is(err, graphql.ErrQueryTooLarge) {
+		t.Fatalf("got error: %v, want: ErrQueryTooLarge", err)
+	}
+	if got, want := err.Error(), "query too large: document is 15 bytes, more than the limit of 10; split the query into smaller ones"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestWithMaxExtendAliases(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user__0": {"login": "a"}, "user__1": {"login": "b"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithMaxExtendAliases(2))
+
+	var q struct {
+		Users []struct {
+			Login string
+		} `graphql:"user(login: $login)" graphql-extend:"true"`
+	}
+	variables := map[string]interface{}{
+		"user": []map[string]interface{}{
+			{"login": graphql.String("a")},
+			{"login": graphql.String("b")},
+		},
+	}
+	if _, err := client.Query(context.Background(), &q, variables); err != nil {
+		t.Fatal(err)
+	}
+	variables["user"] = append(variables["user"].([]map[string]interface{}), map[string]interface{}{"login": graphql.String("c")})
+	_, err := client.Query(context.Background(), &q, variables)
+	if !errors.Is(err, graphql.ErrQueryTooLarge) {
+		t.Fatalf("got error: %v, want: ErrQueryTooLarge", err)
+	}
+	if got, want := err.Error(), `query too large: variable "user" expands into 3 aliases, more than the limit of 2; split it into chunks of at most 2`; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestWithMaxResponseBytes(t *testing.T) {
+	const body = `{"data": {"viewer": {"login": "gopher"}}}`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, body)
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		mustWrite(w, body)
+	})
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithMaxResponseBytes(int64(len(body))))
+	if _, err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Viewer.Login, "gopher"; got != want {
+		t.Errorf("got login: %q, want: %q", got, want)
+	}
+
+	client = graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithMaxResponseBytes(16))
+	_, err := client.Query(context.Background(), &q, nil)
+	if !errors.Is(err, graphql.ErrResponseTooLarge) {
+		t.Fatalf("got error: %v, want: ErrResponseTooLarge", err)
+	}
+	if got, want := err.Error(), "response too large: body is more than the limit of 16 bytes"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+
+	client = graphql.NewClient("/error", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithMaxResponseBytes(16))
+	_, err = client.Query(context.Background(), &q, nil)
+	var statusErr *graphql.HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("got error: %v, want an HTTPStatusError", err)
+	}
+	if got, want := string(statusErr.Body), body[:16]; got != want || !statusErr.Truncated {
+		t.Errorf("got body: %q (truncated: %v), want: %q truncated", got, statusErr.Truncated, want)
+	}
+}
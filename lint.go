@@ -0,0 +1,174 @@
+package graphql
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/merico-dev/graphql/ident"
+	"github.com/merico-dev/graphql/internal/jsonutil"
+)
+
+// Diagnostic is a problem that Lint found in a query struct.
+type Diagnostic struct {
+	Path    string // Go path of the struct field with the problem. E.g., "Repository.Issues".
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Path == "" {
+		return d.Message
+	}
+	return d.Path + ": " + d.Message
+}
+
+// Lint checks the query, mutation or subscription struct v and its
+// variables for common mistakes that would otherwise only surface as
+// errors from the server, or as silently zero-valued fields:
+// empty selection sets, fields with the same response key, graphql tags
+// with unbalanced parentheses, brackets or braces, variables referenced
+// in tags but missing from variables, and variables not referenced at all.
+//
+// It's meant to be called from tests. It returns nil if it finds no problems.
+func Lint(v interface{}, variables map[string]interface{}) []Diagnostic {
+	l := linter{variables: variables, referenced: make(map[string]bool), extended: make(map[string]bool)}
+	t := reflect.TypeOf(v)
+	if t == nil || derefType(t).Kind() != reflect.Struct {
+		return []Diagnostic{{Message: "not a struct"}}
+	}
+	l.lintStruct("", derefType(t), make(map[string]string))
+	var unused []string
+	for name := range variables {
+		if !l.referenced[name] && !l.extended[name] {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	for _, name := range unused {
+		l.problemf("", "variable $%s is not used", name)
+	}
+	return l.diagnostics
+}
+
+type linter struct {
+	variables   map[string]interface{}
+	referenced  map[string]bool // Variables referenced in tags.
+	extended    map[string]bool // Variables used by graphql-extend fields.
+	diagnostics []Diagnostic
+}
+
+func (l *linter) problemf(path, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// lintStruct lints the fields of struct t at path. keys maps the response
+// keys selected so far in the selection set to the paths selecting them.
+func (l *linter) lintStruct(path string, t reflect.Type, keys map[string]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fieldPath := joinPath(path, f.Name)
+		value, _, ok := jsonutil.LookupTag(f.Tag)
+		if !ok && f.Anonymous {
+			l.lintStruct(path, derefType(f.Type), keys)
+			continue
+		}
+		if extend, _ := f.Tag.Lookup("graphql-extend"); extend == "true" {
+			// Selected under generated aliases, with variables of its own.
+			// See writeQuery.
+			name := value
+			if i := strings.IndexAny(value, `(:[$!@{`); i != -1 {
+				name = value[:i]
+			}
+			l.extended[name] = true
+			l.lintTag(fieldPath, value, false)
+			continue
+		}
+		if ok {
+			l.lintTag(fieldPath, value, true)
+		}
+		if strings.HasPrefix(strings.TrimSpace(value), "...") {
+			l.lintSelectionSet(fieldPath, f.Type, true)
+			continue
+		}
+		key := ident.ParseMixedCaps(f.Name).ToLowerCamelCase()
+		if ok {
+			key, _ = splitAlias(value)
+		}
+		if other, dup := keys[key]; dup {
+			l.problemf(fieldPath, "response key %q is also selected by %s; give one of them an alias", key, other)
+		} else {
+			keys[key] = fieldPath
+		}
+		l.lintSelectionSet(fieldPath, f.Type, false)
+	}
+}
+
+// lintSelectionSet lints the selection set of a field of type t at path,
+// if it has one. fragment reports whether it's an inline fragment.
+func (l *linter) lintSelectionSet(path string, t reflect.Type, fragment bool) {
+	t = derefType(t)
+	for t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = derefType(t.Elem())
+	}
+	if t.Kind() != reflect.Struct || decodedWhole(t) {
+		if fragment {
+			l.problemf(path, "inline fragment needs a struct type, not %v", t)
+		}
+		return
+	}
+	if t.NumField() == 0 {
+		l.problemf(path, "empty selection set; select at least one field")
+		return
+	}
+	l.lintStruct(path, t, make(map[string]string))
+}
+
+// lintTag checks that the graphql tag value at path is balanced, and if
+// checkVariables is true, that the variables it references are present.
+func (l *linter) lintTag(path, value string, checkVariables bool) {
+	var stack []byte
+	closing := map[byte]byte{')': '(', ']': '[', '}': '{'}
+	inString := false
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '\\' && inString:
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '(' || c == '[' || c == '{':
+			stack = append(stack, c)
+		case closing[c] != 0:
+			if len(stack) == 0 || stack[len(stack)-1] != closing[c] {
+				l.problemf(path, "unbalanced %q in graphql tag %q", c, value)
+				return
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) > 0 || inString {
+		l.problemf(path, "unclosed parenthesis, bracket, brace or string in graphql tag %q", value)
+	}
+	if !checkVariables {
+		return
+	}
+	var missing []string
+	for name := range referencedVariables(value) {
+		l.referenced[name] = true
+		if _, ok := l.variables[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		l.problemf(path, "variable $%s is missing from the variables", name)
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,58 @@
+package graphql_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestLint(t *testing.T) {
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+			Name  graphql.String `graphql:"login"`
+		}
+		Repository struct {
+			Issues struct{} `graphql:"issues(first:$first)"`
+		} `graphql:"repository(owner:$owner,name:$name"`
+		Node struct {
+			OnUser struct {
+				Email graphql.String
+			} `graphql:"... on User"`
+		} `graphql:"node(id:$id)"`
+	}
+	variables := map[string]interface{}{
+		"owner": graphql.String("golang"),
+		"name":  graphql.String("go"),
+		"id":    graphql.ID("1"),
+		"since": graphql.String("2020-01-01"),
+	}
+	var got []string
+	for _, d := range graphql.Lint(q, variables) {
+		got = append(got, fmt.Sprint(d))
+	}
+	want := []string{
+		`Viewer.Name: response key "login" is also selected by Viewer.Login; give one of them an alias`,
+		`Repository: unclosed parenthesis, bracket, brace or string in graphql tag "repository(owner:$owner,name:$name"`,
+		`Repository.Issues: variable $first is missing from the variables`,
+		`Repository.Issues: empty selection set; select at least one field`,
+		`variable $since is not used`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLint_clean(t *testing.T) {
+	var q struct {
+		User struct {
+			Name  graphql.String
+			Login graphql.String `graphql:"handle: login"`
+		} `graphql:"user(login:$login, filter:{tags:[\"a)\"]})"`
+	}
+	if got := graphql.Lint(&q, map[string]interface{}{"login": graphql.String("gopher")}); got != nil {
+		t.Errorf("got diagnostics: %v, want none", got)
+	}
+}
@@ -0,0 +1,50 @@
+package graphql
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// RequestLogger is called before each request a client sends, including
+// retries, with the name of the operation ("" if anonymous), and the query
+// document and variables as they're sent, after any rewriting, such as of
+// graphql-extend fields and unused variables. query is empty when only
+// a persisted query hash is sent.
+type RequestLogger func(ctx context.Context, operation, query string, variables map[string]interface{})
+
+// WithRequestLogger makes the client call f before each request it sends,
+// so that the exact documents it sends can be inspected without a proxy.
+// f may be called concurrently, and should return quickly.
+func WithRequestLogger(f RequestLogger) ClientOption {
+	return func(c *Client) {
+		c.requestLogger = f
+	}
+}
+
+// ResponseLog describes a response to a request of a client.
+type ResponseLog struct {
+	Operation string        // Name of the operation, or "" if anonymous.
+	Status    int           // HTTP status code. E.g., 200.
+	Header    http.Header   // Response header.
+	Duration  time.Duration // Time from sending the request to reading the body.
+
+	// Body is the response body, decompressed, as far as the client read
+	// it. It's complete unless decoding it failed, or a limit, such as that
+	// of WithMaxResponseBytes, was reached.
+	Body []byte
+}
+
+// ResponseLogger is called with each response a client receives.
+type ResponseLogger func(ctx context.Context, r ResponseLog)
+
+// WithResponseLogger makes the client call f with each response it
+// receives, including responses to retried requests, once their bodies
+// have been read. Requests that fail without a response aren't logged.
+// Since bodies are kept in memory to be logged, it's meant for debugging.
+// f may be called concurrently, and should return quickly.
+func WithResponseLogger(f ResponseLogger) ClientOption {
+	return func(c *Client) {
+		c.responseLogger = f
+	}
+}
@@ -0,0 +1,56 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestWithRequestLogger(t *testing.T) {
+	const body = `{"data": {"user": {"login": "gopher"}}}`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, body)
+	})
+	type request struct {
+		operation, query string
+		variables        map[string]interface{}
+	}
+	var requests []request
+	var responses []graphql.ResponseLog
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRequestLogger(func(ctx context.Context, operation, query string, variables map[string]interface{}) {
+			requests = append(requests, request{operation, query, variables})
+		}),
+		graphql.WithResponseLogger(func(ctx context.Context, r graphql.ResponseLog) {
+			responses = append(responses, r)
+		}))
+
+	var q struct {
+		User struct {
+			Login graphql.String
+		} `graphql:"user(login: $login)"`
+	}
+	_, err := client.Query(context.Background(), &q, map[string]interface{}{"login": graphql.String("gopher")}, graphql.WithOperationName("GetUser"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []request{{
+		operation: "GetUser",
+		query:     "query GetUser($login:String!){user(login: $login){login}}",
+		variables: map[string]interface{}{"login": graphql.String("gopher")},
+	}}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("got requests: %+v, want: %+v", requests, want)
+	}
+	if len(responses) != 1 {
+		t.Fatalf("got %d responses, want 1", len(responses))
+	}
+	if r := responses[0]; r.Operation != "GetUser" || r.Status != http.StatusOK || string(r.Body) != body || r.Duration <= 0 {
+		t.Errorf("got response: %+v, want GetUser's 200 OK with its body", r)
+	}
+}
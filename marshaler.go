@@ -0,0 +1,242 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// GraphQLMarshaler is implemented by variable values that control both
+// the GraphQL type their variable is declared with, and how they're sent,
+// such as values of exotic custom scalars, without registering their type
+// with RegisterType.
+//
+// MarshalGraphQL returns the GraphQL type of the value, such as "Money!",
+// and the value to encode as JSON in its place. It may be called more
+// than once per operation.
+type GraphQLMarshaler interface {
+	MarshalGraphQL() (typeName string, value interface{}, err error)
+}
+
+// sentVariables returns variables as they're sent: without the entries
+// and input object fields holding absent Optional values, with the values implementing
+// GraphQLMarshaler replaced by the values they marshal to, and with the
+// values of types registered with RegisterScalar, including those within
+// lists, maps and structs, replaced by the values they marshal to.
+// It returns variables itself if there's nothing to change.
+func sentVariables(variables map[string]interface{}) (map[string]interface{}, error) {
+	variables = omitAbsent(variables)
+	var sent map[string]interface{}
+	for k, v := range variables {
+		var value interface{}
+		if m, ok := v.(GraphQLMarshaler); ok {
+			var err error
+			_, value, err = m.MarshalGraphQL()
+			if err != nil {
+				return nil, fmt.Errorf("variable $%s: %w", k, err)
+			}
+		} else {
+			var changed bool
+			var err error
+			value, changed, err = marshalScalars(reflect.ValueOf(v))
+			if err != nil {
+				return nil, fmt.Errorf("variable $%s: %w", k, err)
+			}
+			if !changed {
+				continue
+			}
+		}
+		if sent == nil {
+			sent = make(map[string]interface{}, len(variables))
+			for k, v := range variables {
+				sent[k] = v
+			}
+		}
+		sent[k] = value
+	}
+	if sent == nil {
+		return variables, nil
+	}
+	return sent, nil
+}
+
+// marshalScalars returns v with the values of types registered with
+// RegisterScalar replaced by the values they marshal to, and absent
+// Optional values of maps and structs left out. Lists, maps and structs
+// holding such values are replaced by []interface{} and
+// map[string]interface{} values that encode the same way. It reports
+// whether anything was replaced; if not, v is to be sent as is.
+func marshalScalars(v reflect.Value) (interface{}, bool, error) {
+	if !v.IsValid() {
+		return nil, false, nil
+	}
+	if marshal, ok := scalarMarshalers.Load(v.Type()); ok {
+		value, err := marshal.(func(interface{}) (interface{}, error))(v.Interface())
+		return value, true, err
+	}
+	if v.Type() == variableType {
+		// Encoded as its Value.
+		return marshalScalars(v.FieldByName("Value"))
+	}
+	if v.Type().Implements(jsonMarshaler) {
+		// Encoded by its own MarshalJSON method.
+		return nil, false, nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, false, nil
+		}
+		return marshalScalars(v.Elem())
+	case reflect.Slice, reflect.Array:
+		var values []interface{}
+		for i := 0; i < v.Len(); i++ {
+			value, changed, err := marshalScalars(v.Index(i))
+			if err != nil {
+				return nil, false, err
+			}
+			if !changed {
+				if values == nil {
+					continue
+				}
+				value = v.Index(i).Interface()
+			}
+			if values == nil {
+				values = make([]interface{}, i, v.Len())
+				for j := 0; j < i; j++ {
+					values[j] = v.Index(j).Interface()
+				}
+			}
+			values = append(values, value)
+		}
+		return values, values != nil, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false, nil
+		}
+		var changed bool
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			if isAbsentOptional(iter.Value()) {
+				changed = true
+				continue
+			}
+			value, ok, err := marshalScalars(iter.Value())
+			if err != nil {
+				return nil, false, err
+			}
+			if !ok {
+				value = iter.Value().Interface()
+			}
+			changed = changed || ok
+			m[iter.Key().String()] = value
+		}
+		return m, changed, nil
+	case reflect.Struct:
+		m := make(map[string]interface{}, v.NumField())
+		changed, err := marshalStructScalars(v, m)
+		return m, changed, err
+	default:
+		return nil, false, nil
+	}
+}
+
+// marshalStructScalars stores the fields of the struct v in m, under
+// the names that "encoding/json" encodes them with, after replacing the
+// values of types registered with RegisterScalar and leaving out absent
+// Optional values. It reports whether anything was replaced or left out.
+func marshalStructScalars(v reflect.Value, m map[string]interface{}) (bool, error) {
+	var changed bool
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+		if f.Anonymous && name == "" {
+			t := f.Type
+			if t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			if t.Kind() == reflect.Struct {
+				// Embedded struct, whose fields are promoted.
+				for fv.Kind() == reflect.Ptr {
+					if fv.IsNil() {
+						break
+					}
+					fv = fv.Elem()
+				}
+				if fv.Kind() == reflect.Ptr {
+					continue
+				}
+				ok, err := marshalStructScalars(fv, m)
+				if err != nil {
+					return false, err
+				}
+				changed = changed || ok
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyValue(fv) {
+			continue
+		}
+		if isAbsentOptional(fv) {
+			changed = true
+			continue
+		}
+		value, ok, err := marshalScalars(fv)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", name, err)
+		}
+		if !ok {
+			value = fv.Interface()
+		}
+		changed = changed || ok
+		m[name] = value
+	}
+	return changed, nil
+}
+
+// isAbsentOptional reports whether v holds an absent Optional value.
+func isAbsentOptional(v reflect.Value) bool {
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v.Type().Implements(optionalValueType) && v.Interface().(optionalValue).IsAbsent()
+}
+
+// isEmptyValue reports whether v is empty, as the omitempty
+// option of json tags defines it.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	default:
+		return false
+	}
+}
+
+var (
+	jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	variableType  = reflect.TypeOf(Variable{})
+)
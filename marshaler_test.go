@@ -0,0 +1,213 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+// money is a custom scalar sent as a string, such as "12.50 EUR".
+type money struct {
+	cents    int64
+	currency string
+}
+
+func (m money) MarshalGraphQL() (string, interface{}, error) {
+	if m.currency == "" {
+		return "", nil, errors.New("money has no currency")
+	}
+	return "Money!", fmt.Sprintf("%d.%02d %s", m.cents/100, m.cents%100, m.currency), nil
+}
+
+func TestGraphQLMarshaler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"mutation($price:Money!){setPrice(price: $price){ok}}","variables":{"price":"12.50 EUR"}}`+"\n"; got != want {
+			t.Errorf("got body:\n%v\nwant:\n%v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"setPrice": {"ok": true}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var m struct {
+		SetPrice struct {
+			OK graphql.Boolean
+		} `graphql:"setPrice(price: $price)"`
+	}
+	_, err := client.Mutate(context.Background(), &m, map[string]interface{}{"price": money{cents: 1250, currency: "EUR"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.SetPrice.OK {
+		t.Error("got not ok")
+	}
+
+	_, err = client.Mutate(context.Background(), &m, map[string]interface{}{"price": money{cents: 1250}})
+	if err == nil || err.Error() != "variable $price: money has no currency" {
+		t.Errorf("got error: %v, want: variable $price: money has no currency", err)
+	}
+}
+
+// date is a custom scalar registered with graphql.RegisterScalar,
+// sent and received as a string, such as "2024-02-29".
+type date struct {
+	year, month, day int
+}
+
+func init() {
+	graphql.RegisterScalar("Date!",
+		func(d date) (interface{}, error) {
+			if d.month == 0 {
+				return nil, errors.New("date has no month")
+			}
+			return fmt.Sprintf("%04d-%02d-%02d", d.year, d.month, d.day), nil
+		},
+		func(data []byte) (date, error) {
+			t, err := time.Parse(`"2006-01-02"`, string(data))
+			if err != nil {
+				return date{}, err
+			}
+			return date{t.Year(), int(t.Month()), t.Day()}, nil
+		})
+}
+
+func TestRegisterScalar(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"mutation($dates:[Date!]!$filter:EventFilter!$since:Date){cancelEvents(since: $since, dates: $dates, filter: $filter){cancelled{day,start}}}","variables":{"dates":["2024-02-29"],"filter":{"before":"2024-03-01","name":"standup"},"since":null}}`+"\n"; got != want {
+			t.Errorf("got body:\n%v\nwant:\n%v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"cancelEvents": {"cancelled": [{"day": "2024-02-29", "start": null}]}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type eventFilter struct {
+		Before date   `json:"before"`
+		Name   string `json:"name"`
+		After  *date  `json:"after,omitempty"`
+	}
+	graphql.RegisterType(eventFilter{}, "EventFilter!")
+	var m struct {
+		CancelEvents struct {
+			Cancelled []struct {
+				Day   date
+				Start *date
+			}
+		} `graphql:"cancelEvents(since: $since, dates: $dates, filter: $filter)"`
+	}
+	variables := map[string]interface{}{
+		"since":  (*date)(nil),
+		"dates":  []date{{2024, 2, 29}},
+		"filter": eventFilter{Before: date{2024, 3, 1}, Name: "standup"},
+	}
+	_, err := client.Mutate(context.Background(), &m, variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(m.CancelEvents.Cancelled), 1; got != want {
+		t.Fatalf("got %d cancelled events, want %d", got, want)
+	}
+	if got, want := m.CancelEvents.Cancelled[0].Day, (date{2024, 2, 29}); got != want {
+		t.Errorf("got day %v, want %v", got, want)
+	}
+	if got := m.CancelEvents.Cancelled[0].Start; got != nil {
+		t.Errorf("got start %v, want nil", got)
+	}
+
+	variables["dates"] = []date{{year: 2024}}
+	_, err = client.Mutate(context.Background(), &m, variables)
+	if err == nil || err.Error() != "variable $dates: date has no month" {
+		t.Errorf("got error: %v, want: variable $dates: date has no month", err)
+	}
+}
+
+func TestRegisterEnum(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query($orderBy:OrderDirection$states:[IssueState!]!){issues(states: $states, orderBy: $orderBy){state,direction}}","variables":{"orderBy":"DESC","states":["OPEN","CLOSED"]}}`+"\n"; got != want {
+			t.Errorf("got body:\n%v\nwant:\n%v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"issues": [
+			{"state": "CLOSED", "direction": "ASC"},
+			{"direction": "SIDEWAYS", "state": "MERGED"}
+		]}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type issueState int
+	const (
+		open issueState = iota
+		closed
+	)
+	graphql.RegisterEnum("IssueState!", map[issueState]string{open: "OPEN", closed: "CLOSED"})
+	type direction string
+	graphql.RegisterEnum("OrderDirection!", map[direction]string{"asc": "ASC", "desc": "DESC"})
+
+	var q struct {
+		Issues []struct {
+			State     *issueState
+			Direction *direction
+		} `graphql:"issues(states: $states, orderBy: $orderBy)"`
+	}
+	variables := map[string]interface{}{
+		"states":  []issueState{open, closed},
+		"orderBy": func() *direction { d := direction("desc"); return &d }(),
+	}
+	_, err := client.Query(context.Background(), &q, variables)
+	if err == nil || err.Error() != `"MERGED" isn't a value of enum IssueState` {
+		t.Errorf("got error: %v, want: \"MERGED\" isn't a value of enum IssueState", err)
+	}
+	if len(q.Issues) == 0 || q.Issues[0].State == nil || *q.Issues[0].State != closed || *q.Issues[0].Direction != "asc" {
+		t.Errorf("got issues: %+v, want the first closed and ascending", q.Issues)
+	}
+	if len(q.Issues) != 2 || q.Issues[1].Direction == nil || *q.Issues[1].Direction != "SIDEWAYS" {
+		t.Errorf("got issues: %+v, want the second one's unknown direction kept as is", q.Issues)
+	}
+
+	variables["states"] = []issueState{open, 2}
+	_, err = client.Query(context.Background(), &q, variables)
+	if want := "variable $states: 2 isn't a value of enum IssueState; allowed values: CLOSED, OPEN"; err == nil || err.Error() != want {
+		t.Errorf("got error: %v, want: %v", err, want)
+	}
+}
+
+func TestRegisterScalar_variable(t *testing.T) {
+	// Values wrapped in Variable are marshaled like unwrapped ones.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query($since:Date$state:EventState!=OPEN){events(since: $since, state: $state){day}}","variables":{"since":"2024-02-29","state":"CLOSED"}}`+"\n"; got != want {
+			t.Errorf("got body:\n%v\nwant:\n%v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"events": []}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type eventState int
+	graphql.RegisterEnum("EventState!", map[eventState]string{0: "OPEN", 1: "CLOSED"})
+	var q struct {
+		Events []struct {
+			Day date
+		} `graphql:"events(since: $since, state: $state)"`
+	}
+	variables := map[string]interface{}{
+		"since": graphql.Variable{Type: "Date", Value: date{2024, 2, 29}},
+		"state": graphql.Variable{Type: "EventState!", Default: "OPEN", Value: eventState(1)},
+	}
+	_, err := client.Query(context.Background(), &q, variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,70 @@
+package graphql
+
+import (
+	"context"
+	"net/http"
+)
+
+// Request is a GraphQL request, as seen by middleware.
+type Request struct {
+	Query         string                 // Document, as constructed.
+	OperationName string                 // Operation of the document to execute, if non-empty.
+	Variables     map[string]interface{} // Variables, as sent.
+	Extensions    map[string]interface{} // Request extensions, if any.
+	Header        http.Header            // HTTP headers to send.
+}
+
+// Doer executes GraphQL requests.
+type Doer interface {
+	Do(ctx context.Context, req *Request) (*Response, error)
+}
+
+// DoerFunc is an adapter to allow the use of ordinary functions as Doers.
+type DoerFunc func(ctx context.Context, req *Request) (*Response, error)
+
+// Do calls f(ctx, req).
+func (f DoerFunc) Do(ctx context.Context, req *Request) (*Response, error) {
+	return f(ctx, req)
+}
+
+// Middleware wraps the Doer next, which executes requests, with behavior
+// of its own, such as to refresh an auth token, log requests, record
+// metrics, or modify requests before they're executed.
+type Middleware func(next Doer) Doer
+
+// WithMiddleware makes the client pass each operation it executes through
+// the middleware mw, in order: the first one sees requests first and
+// responses last. Middleware sees requests once their document and
+// variables are constructed, and before the client's response cache,
+// offline queue and retries handle them, so that it sees every operation
+// once. Subscriptions don't go through middleware.
+func WithMiddleware(mw ...Middleware) ClientOption {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, mw...)
+	}
+}
+
+// doMiddleware executes the request in, made with cfg, through the
+// client's middleware, with dispatch executing the request the last
+// middleware passes on.
+func (c *Client) doMiddleware(ctx context.Context, in requestBody, cfg *requestConfig, dispatch func(ctx context.Context, in requestBody, cfg *requestConfig) (*Response, error)) (*Response, error) {
+	var d Doer = DoerFunc(func(ctx context.Context, req *Request) (*Response, error) {
+		cfg.header = req.Header
+		return dispatch(ctx, requestBody{
+			Query:         req.Query,
+			OperationName: req.OperationName,
+			Variables:     req.Variables,
+			Extensions:    req.Extensions,
+		}, cfg)
+	})
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		d = c.middleware[i](d)
+	}
+	return d.Do(ctx, &Request{
+		Query:         in.Query,
+		OperationName: in.OperationName,
+		Variables:     in.Variables,
+		Extensions:    in.Extensions,
+		Header:        cfg.header.Clone(),
+	})
+}
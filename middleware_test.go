@@ -0,0 +1,75 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestWithMiddleware(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("Authorization"), "Bearer fresh"; got != want {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if got, want := mustRead(req.Body), `{"query":"query($login:String!){user(login: $login){name}}","variables":{"login":"GOPHER"}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want: %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+
+	var calls []string
+	token := "stale"
+	logging := func(next graphql.Doer) graphql.Doer {
+		return graphql.DoerFunc(func(ctx context.Context, req *graphql.Request) (*graphql.Response, error) {
+			calls = append(calls, "log: "+req.Query)
+			resp, err := next.Do(ctx, req)
+			calls = append(calls, "logged")
+			return resp, err
+		})
+	}
+	auth := func(next graphql.Doer) graphql.Doer {
+		return graphql.DoerFunc(func(ctx context.Context, req *graphql.Request) (*graphql.Response, error) {
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := next.Do(ctx, req)
+			if resp != nil && resp.Status == http.StatusUnauthorized {
+				calls = append(calls, "refresh")
+				token = "fresh"
+				req.Header.Set("Authorization", "Bearer "+token)
+				return next.Do(ctx, req)
+			}
+			return resp, err
+		})
+	}
+	upper := func(next graphql.Doer) graphql.Doer {
+		return graphql.DoerFunc(func(ctx context.Context, req *graphql.Request) (*graphql.Response, error) {
+			req.Variables["login"] = graphql.String(strings.ToUpper(string(req.Variables["login"].(graphql.String))))
+			return next.Do(ctx, req)
+		})
+	}
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithMiddleware(logging, auth, upper))
+
+	var q struct {
+		User struct {
+			Name graphql.String
+		} `graphql:"user(login: $login)"`
+	}
+	_, err := client.Query(context.Background(), &q, map[string]interface{}{"login": graphql.String("gopher")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.User.Name != "Gopher" {
+		t.Errorf("got name: %q, want: %q", q.User.Name, "Gopher")
+	}
+	want := []string{"log: query($login:String!){user(login: $login){name}}", "refresh", "logged"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls: %q, want: %q", calls, want)
+	}
+}
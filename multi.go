@@ -0,0 +1,100 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// MultiResult is the result of one of the copies of a field selected by
+// QueryMulti.
+type MultiResult[T any] struct {
+	Value T // Result of the field, decoded like the field of a query struct.
+
+	// Errors are the GraphQL errors whose path is within the copy, with
+	// paths reported as if the field was selected on its own.
+	Errors []DataError
+}
+
+// QueryMulti selects field once per element of variables, in a single
+// query, and returns the result of each copy, in order. field is written
+// as in a graphql tag, with arguments bound to variables, e.g.,
+// "repository(owner: $owner, name: $name)", and each element of variables
+// holds the variables of a copy. It must not have an alias. T is the type
+// of the field.
+//
+// The copies are selected under aliases, as for a graphql-extend field,
+// with their variables renamed so they don't clash. QueryMulti returns
+// the GraphQL errors that aren't within a copy, such as validation errors,
+// separately from those of each copy. It returns an error, and no results,
+// if the query fails, such as because it has more copies than the limit of
+// WithMaxExtendAliases.
+//
+// E.g., to fetch several repositories at once:
+//
+//	type repository struct {
+//		StargazerCount graphql.Int
+//	}
+//	results, dataErrors, err := graphql.QueryMulti[*repository](ctx, client,
+//		"repository(owner: $owner, name: $name)", []map[string]interface{}{
+//			{"owner": graphql.String("golang"), "name": graphql.String("go")},
+//			{"owner": graphql.String("golang"), "name": graphql.String("tools")},
+//		})
+func QueryMulti[T any](ctx context.Context, c *Client, field string, variables []map[string]interface{}, opts ...RequestOption) ([]MultiResult[T], []DataError, error) {
+	if len(variables) == 0 {
+		return nil, nil, nil
+	}
+	for _, name := range sortedNames(referencedVariables(field)) {
+		for i, vars := range variables {
+			if _, ok := vars[name]; !ok {
+				return nil, nil, fmt.Errorf("field %s references variable $%s, which is missing from element %d of the variables", field, name, i)
+			}
+		}
+	}
+	variable := extendVariable(field)
+	q := reflect.New(extendQueryType(reflect.TypeOf([]T(nil)), field))
+	dataErrors, err := c.Query(ctx, q.Interface(), map[string]interface{}{variable: variables}, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	results := make([]MultiResult[T], len(variables))
+	values := q.Elem().Field(0)
+	for i := range results {
+		if i < values.Len() {
+			results[i].Value = values.Index(i).Interface().(T)
+		}
+	}
+	byIndex, other := SplitExtendErrors(variable, len(results), dataErrors)
+	for i := range results {
+		results[i].Errors = byIndex[i]
+	}
+	return results, other, nil
+}
+
+// SplitExtendErrors attributes the GraphQL errors of a query with
+// a graphql-extend field, whose variable named variable has n elements,
+// to the copies of the field. byIndex[i] holds the errors whose path is
+// within the copy for element i, with paths reported as if the field was
+// selected on its own, e.g., ["user", "name"] rather than
+// ["user__3", "name"]. The errors that aren't within a copy, such as
+// validation errors and errors about other fields, are returned in other.
+//
+// E.g., for a query struct with the field:
+//
+//	Users []User `graphql:"user(login: $login)" graphql-extend:"true"`
+//
+// SplitExtendErrors("user", len(q.Users), dataErrors) attributes the
+// errors to the elements of q.Users.
+func SplitExtendErrors(variable string, n int, errs []DataError) (byIndex [][]DataError, other []DataError) {
+	byIndex = make([][]DataError, n)
+	for _, e := range errs {
+		i, ok := extendIndex(variable, e.Path)
+		if !ok || i >= n {
+			other = append(other, e)
+			continue
+		}
+		e.Path = append([]interface{}{variable}, e.Path[1:]...)
+		byIndex[i] = append(byIndex[i], e)
+	}
+	return byIndex, other
+}
@@ -0,0 +1,85 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestQueryMulti(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query($repository__0__name:String!$repository__0__owner:String!$repository__1__name:String!$repository__1__owner:String!){repository__0:repository(owner: $repository__0__owner, name: $repository__0__name){stargazerCount},repository__1:repository(owner: $repository__1__owner, name: $repository__1__name){stargazerCount}}","variables":{"repository__0__name":"go","repository__0__owner":"golang","repository__1__name":"missing","repository__1__owner":"golang"}}`+"\n"; got != want {
+			t.Errorf("got body:\n%v\nwant:\n%v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"repository__0": {"stargazerCount": 120000}, "repository__1": null}, "errors": [
+			{"message": "Could not resolve to a Repository.", "path": ["repository__1"]},
+			{"message": "Query cost is high."}
+		]}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type repository struct {
+		StargazerCount graphql.Int
+	}
+	results, dataErrors, err := graphql.QueryMulti[*repository](context.Background(), client,
+		"repository(owner: $owner, name: $name)", []map[string]interface{}{
+			{"owner": graphql.String("golang"), "name": graphql.String("go")},
+			{"owner": graphql.String("golang"), "name": graphql.String("missing")},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if got := results[0]; got.Value == nil || got.Value.StargazerCount != 120000 || got.Errors != nil {
+		t.Errorf("got result 0: %+v, want 120000 stargazers and no errors", got)
+	}
+	want := []graphql.DataError{{Message: "Could not resolve to a Repository.", Path: []interface{}{"repository"}}}
+	if got := results[1]; got.Value != nil || !reflect.DeepEqual(got.Errors, want) {
+		t.Errorf("got result 1: %+v, want nil with errors %+v", got, want)
+	}
+	if len(dataErrors) != 1 || dataErrors[0].Message != "Query cost is high." {
+		t.Errorf("got errors: %+v, want the query cost error", dataErrors)
+	}
+
+	_, _, err = graphql.QueryMulti[*repository](context.Background(), client,
+		"repository(owner: $owner, name: $name)", []map[string]interface{}{
+			{"owner": graphql.String("golang"), "name": graphql.String("go")},
+			{"owner": graphql.String("golang")},
+		})
+	if got, want := err.Error(), "field repository(owner: $owner, name: $name) references variable $name, which is missing from element 1 of the variables"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestSplitExtendErrors(t *testing.T) {
+	errs := []graphql.DataError{
+		{Message: "not found", Path: []interface{}{"user__1"}},
+		{Message: "forbidden", Path: []interface{}{"user__0", "email"}},
+		{Message: "query cost is high"},
+		{Message: "out of range", Path: []interface{}{"user__5", "name"}},
+		{Message: "viewer unavailable", Path: []interface{}{"viewer"}},
+	}
+	byIndex, other := graphql.SplitExtendErrors("user", 3, errs)
+	want := [][]graphql.DataError{
+		{{Message: "forbidden", Path: []interface{}{"user", "email"}}},
+		{{Message: "not found", Path: []interface{}{"user"}}},
+		nil,
+	}
+	if !reflect.DeepEqual(byIndex, want) {
+		t.Errorf("got errors by index: %+v, want: %+v", byIndex, want)
+	}
+	if wantOther := []graphql.DataError{errs[2], errs[3], errs[4]}; !reflect.DeepEqual(other, wantOther) {
+		t.Errorf("got other errors: %+v, want: %+v", other, wantOther)
+	}
+	if got, want := errs[0].Path, []interface{}{"user__1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("errs was modified: got path %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,69 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// QueryNode executes a query for the object with the Relay global ID id,
+// through the node field of the root query type that Relay-compliant
+// servers have, and returns it decoded into a new T. T should be a struct
+// type that corresponds to the fields of the GraphQL object type typ,
+// such as "Issue", which is queried with an inline fragment; E.g.:
+//
+//	query($id:ID!){node(id: $id){__typename,... on Issue{number,title}}}
+//
+// The returned *T is nil if there's no object with the ID, or if it's not
+// of type typ. It saves declaring a wrapper struct for every node lookup.
+func QueryNode[T any](ctx context.Context, c *Client, typ string, id string, opts ...RequestOption) (*T, []DataError, error) {
+	if typ == "" || strings.IndexFunc(typ, func(r rune) bool { return r > 0x7f || !isNameByte(byte(r)) }) != -1 {
+		return nil, nil, fmt.Errorf("%q isn't a named type", typ)
+	}
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	node := reflect.StructOf([]reflect.StructField{
+		{Name: "Typename", Type: reflect.TypeOf(String("")), Tag: `graphql:"__typename"`},
+		{Name: "Fragment", Type: t, Tag: reflect.StructTag(fmt.Sprintf(`graphql:"... on %s"`, typ))},
+	})
+	q := reflect.New(reflect.StructOf([]reflect.StructField{
+		{Name: "Node", Type: reflect.PtrTo(node), Tag: `graphql:"node(id: $id)"`},
+	}))
+	dataErrors, err := c.Query(ctx, q.Interface(), map[string]interface{}{
+		"id": Variable{Type: "ID!", Value: id},
+	}, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	n := q.Elem().Field(0)
+	if n.IsNil() || n.Elem().Field(0).String() != typ {
+		return nil, dataErrors, nil
+	}
+	return n.Elem().Field(1).Addr().Interface().(*T), dataErrors, nil
+}
+
+// EncodeGlobalID returns the Relay global ID of the object of type typ
+// with the ID id, as the Relay reference implementation encodes it: the
+// base64 encoding of typ and id joined with a colon. E.g.,
+// EncodeGlobalID("User", "10") -> "VXNlcjoxMA==".
+//
+// Servers may encode global IDs differently, so it's only meant for those
+// known to encode them this way.
+func EncodeGlobalID(typ, id string) string {
+	return base64.StdEncoding.EncodeToString([]byte(typ + ":" + id))
+}
+
+// DecodeGlobalID returns the type and ID of the object with the Relay
+// global ID globalID, as encoded by EncodeGlobalID.
+func DecodeGlobalID(globalID string) (typ, id string, err error) {
+	b, err := base64.StdEncoding.DecodeString(globalID)
+	if err != nil {
+		return "", "", fmt.Errorf("global ID %q isn't base64 encoded: %w", globalID, err)
+	}
+	typ, id, ok := strings.Cut(string(b), ":")
+	if !ok || typ == "" {
+		return "", "", fmt.Errorf("global ID %q doesn't encode a type and an ID", globalID)
+	}
+	return typ, id, nil
+}
@@ -0,0 +1,68 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestQueryNode(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		w.Header().Set("Content-Type", "application/json")
+		switch body {
+		case `{"query":"query($id:ID!){node(id: $id){__typename,... on Issue{number,title}}}","variables":{"id":"SXNzdWU6MQ=="}}` + "\n":
+			mustWrite(w, `{"data": {"node": {"__typename": "Issue", "number": 1, "title": "Bug"}}}`)
+		case `{"query":"query($id:ID!){node(id: $id){__typename,... on Issue{number,title}}}","variables":{"id":"UFI6Mg=="}}` + "\n":
+			mustWrite(w, `{"data": {"node": {"__typename": "PullRequest"}}}`)
+		default:
+			mustWrite(w, `{"data": {"node": null}}`)
+		}
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type issue struct {
+		Number graphql.Int
+		Title  graphql.String
+	}
+	got, _, err := graphql.QueryNode[issue](context.Background(), client, "Issue", graphql.EncodeGlobalID("Issue", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.Number != 1 || got.Title != "Bug" {
+		t.Errorf("got issue: %+v, want #1", got)
+	}
+	for _, id := range []string{graphql.EncodeGlobalID("PR", "2"), "missing"} {
+		got, _, err = graphql.QueryNode[issue](context.Background(), client, "Issue", id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != nil {
+			t.Errorf("%s: got issue: %+v, want nil", id, got)
+		}
+	}
+	if _, _, err := graphql.QueryNode[issue](context.Background(), client, `Issue"`, "1"); err == nil {
+		t.Error("got no error for an invalid type")
+	}
+}
+
+func TestDecodeGlobalID(t *testing.T) {
+	typ, id, err := graphql.DecodeGlobalID(graphql.EncodeGlobalID("User", "10:a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != "User" || id != "10:a" {
+		t.Errorf("got %q, %q, want User, 10:a", typ, id)
+	}
+	if got, want := graphql.EncodeGlobalID("User", "10"), "VXNlcjoxMA=="; got != want {
+		t.Errorf("got global ID: %q, want: %q", got, want)
+	}
+	for _, globalID := range []string{"not base64!", "VXNlcg=="} {
+		if _, _, err := graphql.DecodeGlobalID(globalID); err == nil {
+			t.Errorf("%q: got no error", globalID)
+		}
+	}
+}
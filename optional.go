@@ -0,0 +1,140 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// Optional represents a value that is either present, explicitly null,
+// or absent altogether. The zero value of Optional is absent.
+//
+// As a variable value, an absent Optional is declared with a nullable type
+// but left out of the variables sent to the server, while a null one is sent
+// as an explicit null. This matters for mutations, where the two mean
+// "leave unchanged" and "clear" respectively. Absent Optional fields of
+// input objects, whether structs or maps, are left out of the variables
+// sent too.
+//
+// As a response field, Optional reports whether the field was returned
+// with a value, returned as null, or not returned at all.
+// It's meant for scalar values; object selections should use pointers.
+type Optional[T any] struct {
+	value T
+	state optionalState
+}
+
+type optionalState uint8
+
+const (
+	optionalAbsent optionalState = iota
+	optionalNull
+	optionalPresent
+)
+
+// Some returns an Optional holding the present value v.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{value: v, state: optionalPresent}
+}
+
+// Null returns an Optional holding an explicit null.
+func Null[T any]() Optional[T] {
+	return Optional[T]{state: optionalNull}
+}
+
+// Get returns the value of o, and reports whether it's present.
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.state == optionalPresent
+}
+
+// IsPresent reports whether o holds a value.
+func (o Optional[T]) IsPresent() bool { return o.state == optionalPresent }
+
+// IsNull reports whether o holds an explicit null.
+func (o Optional[T]) IsNull() bool { return o.state == optionalNull }
+
+// IsAbsent reports whether o holds neither a value nor a null.
+func (o Optional[T]) IsAbsent() bool { return o.state == optionalAbsent }
+
+// IsZero reports whether o is absent. It allows the "omitzero" json option
+// of Go 1.24 and later to leave absent fields out of values encoded with
+// "encoding/json" directly.
+func (o Optional[T]) IsZero() bool { return o.state == optionalAbsent }
+
+// MarshalJSON implements json.Marshaler.
+// Null and absent values are both encoded as null.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if o.state != optionalPresent {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		var zero T
+		*o = Optional[T]{value: zero, state: optionalNull}
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*o = Some(v)
+	return nil
+}
+
+// optionalType is the reflect.Type of the underlying value of the Optional.
+// It's used when declaring variables of Optional types.
+func (Optional[T]) optionalType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// optionalValue is implemented by all Optional types.
+type optionalValue interface {
+	optionalType() reflect.Type
+	IsAbsent() bool
+}
+
+var optionalValueType = reflect.TypeOf((*optionalValue)(nil)).Elem()
+
+// omitAbsent returns variables without the entries holding absent values.
+// It returns variables itself if there are none.
+func omitAbsent(variables map[string]interface{}) map[string]interface{} {
+	var present map[string]interface{}
+	for k, v := range variables {
+		if !isAbsent(v) {
+			continue
+		}
+		if present == nil {
+			present = make(map[string]interface{}, len(variables))
+			for k, v := range variables {
+				present[k] = v
+			}
+		}
+		delete(present, k)
+	}
+	if present == nil {
+		return variables
+	}
+	return present
+}
+
+// isAbsent reports whether the variable value v is to be left out of the
+// variables sent: an absent Optional value, or a Variable with a default
+// value whose Value is nil, a nil pointer or an absent Optional value.
+func isAbsent(v interface{}) bool {
+	if v, ok := v.(Variable); ok {
+		if v.Default == "" {
+			return false
+		}
+		rv := reflect.ValueOf(v.Value)
+		if !rv.IsValid() || rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return true
+		}
+		return isAbsent(v.Value)
+	}
+	o, ok := v.(optionalValue)
+	return ok && o.IsAbsent()
+}
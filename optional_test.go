@@ -0,0 +1,139 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestOptional_marshalJSON(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{in: graphql.Some(graphql.String("foo")), want: `"foo"`},
+		{in: graphql.Null[graphql.String](), want: `null`},
+		{in: graphql.Optional[graphql.String]{}, want: `null`},
+		{in: graphql.Some(graphql.NewInt(1)), want: `1`},
+	}
+	for i, tc := range tests {
+		b, err := json.Marshal(tc.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(b); got != tc.want {
+			t.Errorf("test case %d:\n got: %s\nwant: %s", i, got, tc.want)
+		}
+	}
+}
+
+func TestOptional_variableTypes(t *testing.T) {
+	var m struct {
+		Foo graphql.String `graphql:"foo(a: $a, b: $b, c: $c)"`
+	}
+	got, err := graphql.ConstructMutation(m, map[string]interface{}{
+		"a": graphql.Some(graphql.Int(1)),
+		"b": graphql.Null[graphql.String](),
+		"c": graphql.Optional[[]graphql.ID]{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "mutation($a:Int$b:String$c:[ID!]){foo(a: $a, b: $b, c: $c)}"; got != want {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestClient_Mutate_optional(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var in struct {
+			Query     string
+			Variables map[string]interface{}
+		}
+		if err := json.NewDecoder(req.Body).Decode(&in); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := in.Query, `mutation($description:String$name:String$topic:String){updateRepository(name: $name, description: $description, topic: $topic){name,description,topic}}`; got != want {
+			t.Errorf("\ngot:  %q\nwant: %q", got, want)
+		}
+		if _, ok := in.Variables["topic"]; ok {
+			t.Error("absent variable topic was sent")
+		}
+		if v, ok := in.Variables["description"]; !ok || v != nil {
+			t.Errorf("got description: %v, want: null", v)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"updateRepository": {"name": "graphql", "description": null}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var m struct {
+		UpdateRepository struct {
+			Name        graphql.Optional[graphql.String]
+			Description graphql.Optional[graphql.String]
+			Topic       graphql.Optional[graphql.String]
+		} `graphql:"updateRepository(name: $name, description: $description, topic: $topic)"`
+	}
+	variables := map[string]interface{}{
+		"name":        graphql.Some(graphql.String("graphql")),
+		"description": graphql.Null[graphql.String](),
+		"topic":       graphql.Optional[graphql.String]{},
+	}
+	_, err := client.Mutate(context.Background(), &m, variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, ok := m.UpdateRepository.Name.Get(); !ok || name != "graphql" {
+		t.Errorf("got Name: %v, %v, want: %q, true", name, ok, "graphql")
+	}
+	if !m.UpdateRepository.Description.IsNull() {
+		t.Error("got Description not null, want null")
+	}
+	if !m.UpdateRepository.Topic.IsAbsent() {
+		t.Error("got Topic not absent, want absent")
+	}
+}
+
+func TestClient_Mutate_optionalInputFields(t *testing.T) {
+	type UpdateRepositoryInput struct {
+		Name        graphql.String                   `json:"name"`
+		Description graphql.Optional[graphql.String] `json:"description"`
+		Topic       graphql.Optional[graphql.String] `json:"topic"`
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := mustRead(req.Body), `{"query":"mutation($input:UpdateRepositoryInput!$labels:LabelsInput!){updateRepository(input: $input){name},setLabels(input: $labels){name}}","variables":{"input":{"description":null,"name":"graphql"},"labels":{"color":null}}}`+"\n"; got != want {
+			t.Errorf("\ngot:  %s\nwant: %s", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"updateRepository": {"name": "graphql"}, "setLabels": {"name": "bug"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var m struct {
+		UpdateRepository struct {
+			Name graphql.String
+		} `graphql:"updateRepository(input: $input)"`
+		SetLabels struct {
+			Name graphql.String
+		} `graphql:"setLabels(input: $labels)"`
+	}
+	variables := map[string]interface{}{
+		"input": UpdateRepositoryInput{
+			Name:        "graphql",
+			Description: graphql.Null[graphql.String](),
+		},
+		"labels": graphql.Variable{Type: "LabelsInput!", Value: map[string]interface{}{
+			"color": graphql.Null[graphql.String](),
+			"name":  graphql.Optional[graphql.String]{},
+		}},
+	}
+	_, err := client.Mutate(context.Background(), &m, variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,327 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/merico-dev/graphql/graphqljson"
+	"github.com/merico-dev/graphql/internal/jsonutil"
+)
+
+// ClientOption configures a Client. It's passed to NewClient.
+type ClientOption func(*Client)
+
+// WithCaseInsensitiveFields makes the client match response keys against
+// graphql struct field tags case-insensitively, the same way "encoding/json"
+// matches keys against struct field names.
+//
+// It's useful for servers whose response key casing doesn't round-trip
+// through the lowerCamelCase names used in the query, which would otherwise
+// leave the corresponding fields silently zero-valued.
+func WithCaseInsensitiveFields() ClientOption {
+	return func(c *Client) {
+		c.decodeOptions = append(c.decodeOptions, jsonutil.CaseInsensitive())
+	}
+}
+
+// WithPreciseNumbers makes the client decode numbers in responses into
+// interface fields, such as graphql.ID, as json.Number rather than float64,
+// into string fields, including json.Number ones, as their exact text, and
+// into big-number fields, such as *big.Float ones, from their text. Integers above 2^53, such
+// as large numeric IDs, are otherwise silently rounded by float64.
+// Integer fields, such as int64 ones, decode them exactly either way.
+func WithPreciseNumbers() ClientOption {
+	return func(c *Client) {
+		c.decodeOptions = append(c.decodeOptions, jsonutil.PreciseNumbers())
+	}
+}
+
+// DecodeHook is called with a pointer to a field of a response struct
+// once its value is decoded, such as a *graphql.String. It may modify the
+// value in place, e.g., to trim whitespace, normalize time zones or
+// lowercase emails. If it returns an error, decoding the response fails
+// with it.
+type DecodeHook func(v interface{}) error
+
+// WithDecodeHook makes the client call hook for the fields at the response
+// path, which is the response keys leading to them, separated by dots.
+// List elements don't add to the path; e.g., "repository.issues.nodes.title"
+// names the titles of all the issues selected.
+func WithDecodeHook(path string, hook DecodeHook) ClientOption {
+	return func(c *Client) {
+		c.decodeOptions = append(c.decodeOptions, jsonutil.PathHook(path, jsonutil.Hook(hook)))
+	}
+}
+
+// WithTaggedDecodeHook makes the client call hook for the fields whose
+// graphql-hook tag lists name, such as the Email field in:
+//
+//	Email graphql.String `graphql-hook:"trim,lower"`
+//
+// Hooks named in the tag are called in the order they're listed.
+func WithTaggedDecodeHook(name string, hook DecodeHook) ClientOption {
+	return func(c *Client) {
+		c.decodeOptions = append(c.decodeOptions, jsonutil.TagHook(name, jsonutil.Hook(hook)))
+	}
+}
+
+// WithDecodeOptions makes the client decode response data with opts,
+// such as graphqljson.TimeLayouts, as graphqljson.Unmarshal does.
+func WithDecodeOptions(opts ...graphqljson.Option) ClientOption {
+	return func(c *Client) {
+		c.decodeOptions = append(c.decodeOptions, opts...)
+	}
+}
+
+// WithFormEncodedPOST makes the client send requests as
+// application/x-www-form-urlencoded POST bodies, with the "query" field
+// holding the document and the "variables" field holding the JSON-encoded
+// variables. It's meant for legacy servers that don't accept JSON bodies.
+func WithFormEncodedPOST() ClientOption {
+	return func(c *Client) {
+		c.formPOST = true
+	}
+}
+
+// WithGET makes the client send queries as GET requests, with the
+// "query", "operationName", "variables" and "extensions" fields in the URL
+// query, so that CDNs and gateways that only cache GET requests can cache
+// their responses. Mutations are still sent as POST requests.
+//
+// Requests carrying Uploads are sent as multipart POST requests regardless,
+// and persisted queries are sent as WithAutomaticPersistedQueries and the
+// like specify.
+func WithGET() ClientOption {
+	return func(c *Client) {
+		c.getQueries = true
+	}
+}
+
+// WithUserAgent makes the client identify itself with the User-Agent
+// header ua, instead of the default "merico-graphql/<version>".
+func WithUserAgent(ua string) ClientOption {
+	return WithRequestOptions(WithHeader("User-Agent", ua))
+}
+
+// WithClientName makes the client identify the application using it
+// with the apollographql-client-name and apollographql-client-version
+// headers, which some gateways require to attribute traffic.
+func WithClientName(name, version string) ClientOption {
+	return WithRequestOptions(
+		WithHeader("Apollographql-Client-Name", name),
+		WithHeader("Apollographql-Client-Version", version),
+	)
+}
+
+// WithRequestOptions makes the client apply opts to every request it makes.
+// Options passed to an individual request are applied after them,
+// so they take precedence.
+func WithRequestOptions(opts ...RequestOption) ClientOption {
+	return func(c *Client) {
+		c.requestOptions = append(c.requestOptions, opts...)
+	}
+}
+
+// RequestOption configures a single request.
+// It's passed to Client.Query, Client.Mutate and the like.
+type RequestOption func(*requestConfig)
+
+// requestConfig is the configuration of a single request,
+// assembled from the client's and the request's options.
+type requestConfig struct {
+	header      http.Header            // Additional HTTP headers to send.
+	urlParams   map[string]string      // Parameters of the GraphQL server URL template.
+	endpointURL string                 // URL to send to instead of the client's, if non-empty. See NewMultiClient.
+	noCache     bool                   // Bypass the client's response cache.
+	extensions  map[string]interface{} // Request extensions to send, if non-nil.
+
+	responseExtensions *map[string]json.RawMessage // Where to store the extensions of the response, if non-nil.
+
+	operationName string   // Operation of the document to execute, if non-empty.
+	fieldMask     []string // Response paths of the fields to select, if non-nil. See WithFieldMask.
+	autoTypename  bool     // Select __typename in every nested selection set. See WithAutoTypename.
+
+	// Progress of receiving responses.
+	progress     ProgressFunc  // Called as response bodies are received, if non-nil.
+	stallTimeout time.Duration // Time without receiving bytes after which requests fail, if positive.
+
+	// Streaming decoding of response data. See WithStreamingDecode.
+	into        interface{} // Where to decode streamed or incrementally delivered response data while it's received, if non-nil.
+	dataDecoded bool        // Whether data was decoded into into by an earlier attempt.
+
+	incrementFunc func(Increment) // Called with each payload of incrementally delivered responses, if non-nil.
+
+	// Batching. See QueryBatch.
+	batch     []requestBody // Requests sent as a single batch request instead, if non-nil.
+	responses []*Response   // Responses to the batch, in order, once received.
+
+	// Event delivery of subscriptions.
+	eventBuffer  int            // Capacity of the events channel.
+	overflow     OverflowPolicy // What to do with events when the buffer is full.
+	droppedEvent func()         // Called for each dropped event, if non-nil.
+}
+
+// requestConfig returns the configuration for a request made with opts
+// in ctx. It returns the error of the client's HeaderProvider, if any.
+func (c *Client) requestConfig(ctx context.Context, opts []RequestOption) (*requestConfig, error) {
+	accept := defaultAccept
+	for i := len(c.wireCodecs) - 1; i >= 0; i-- {
+		accept = c.wireCodecs[i].mediaType + ", " + accept
+	}
+	cfg := &requestConfig{
+		header: http.Header{
+			"Accept":     {accept},
+			"User-Agent": {defaultUserAgent},
+		},
+	}
+	if ae := c.acceptEncoding(); ae != "" {
+		cfg.header.Set("Accept-Encoding", ae)
+	}
+	for _, opt := range c.requestOptions {
+		opt(cfg)
+	}
+	if c.headerProvider != nil {
+		header, err := c.headerProvider(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for k, vs := range header {
+			cfg.header[http.CanonicalHeaderKey(k)] = vs
+		}
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg, nil
+}
+
+// WithHeader sets the HTTP header key to value on the request,
+// replacing any values set by earlier options.
+func WithHeader(key, value string) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.header.Set(key, value)
+	}
+}
+
+// WithRequestHeaders sets the HTTP headers in header on the request,
+// replacing any values set by earlier options for the same keys.
+// It's meant for headers that vary per request, such as Authorization,
+// tenant and trace headers.
+func WithRequestHeaders(header http.Header) RequestOption {
+	return func(cfg *requestConfig) {
+		for k, vs := range header {
+			cfg.header[http.CanonicalHeaderKey(k)] = vs
+		}
+	}
+}
+
+// HeaderProvider returns HTTP headers to send with a request made in ctx,
+// such as an Authorization header with a short-lived OAuth token, or
+// headers derived from values of ctx. If it returns an error, the request
+// fails with it.
+type HeaderProvider func(ctx context.Context) (http.Header, error)
+
+// WithHeaderProvider makes the client call provider for each operation,
+// and set the headers it returns on its requests, replacing any values
+// set by WithRequestOptions for the same keys. Options passed to
+// individual requests take precedence over them.
+//
+// Headers are part of the keys of cached responses, so responses cached
+// for one set of provided headers aren't used for another.
+func WithHeaderProvider(provider HeaderProvider) ClientOption {
+	return func(c *Client) {
+		c.headerProvider = provider
+	}
+}
+
+// WithURLParam sets the parameter name of the GraphQL server URL to value,
+// for clients whose URL is a template with parameters in braces, such as
+// "https://{region}.api.example.com/graphql". It allows a single client to
+// target region-sharded or per-project endpoints. value is escaped.
+//
+// Requests fail if the URL has a parameter that isn't set.
+func WithURLParam(name, value string) RequestOption {
+	return func(cfg *requestConfig) {
+		if cfg.urlParams == nil {
+			cfg.urlParams = make(map[string]string)
+		}
+		cfg.urlParams[name] = value
+	}
+}
+
+// WithExtension sets the request extension name to value, which is sent in
+// the "extensions" field of the request alongside the query and variables.
+// Gateways and servers use extensions for opt-ins such as tracing.
+// To set it for every request of a client, use it with WithRequestOptions.
+func WithExtension(name string, value interface{}) RequestOption {
+	return func(cfg *requestConfig) {
+		if cfg.extensions == nil {
+			cfg.extensions = make(map[string]interface{})
+		}
+		cfg.extensions[name] = value
+	}
+}
+
+// WithResponseExtensions makes the request store the "extensions" object
+// of the response, such as query cost, rate limit, or tracing data, in
+// *dst, for callers of Query and Mutate, which only return the response
+// data. *dst is set to nil if the response has no extensions.
+func WithResponseExtensions(dst *map[string]json.RawMessage) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.responseExtensions = dst
+	}
+}
+
+// WithOperationName makes the request execute the operation name of a
+// document with several named operations, such as one constructed with
+// ConstructDocument, by sending it as the "operationName" of the request.
+//
+// If the document has a single anonymous operation, such as one constructed
+// by Query and Mutate, the operation is named name, e.g. "query GetViewer{...}",
+// so that servers can tell operations apart in logs, metrics and allow lists.
+func WithOperationName(name string) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.operationName = name
+	}
+}
+
+// documentConfig returns the configuration the client's and opts' options
+// set, without assembling the rest of the request configuration, for the
+// options that apply to constructing documents, such as WithOperationName
+// and WithFieldMask.
+func (c *Client) documentConfig(opts []RequestOption) *requestConfig {
+	cfg := &requestConfig{header: make(http.Header)}
+	for _, opt := range c.requestOptions {
+		opt(cfg)
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// Version is the version of this package.
+// It's part of the default User-Agent header sent by clients.
+const Version = "0.1.0"
+
+// defaultUserAgent is the User-Agent header sent unless overridden.
+const defaultUserAgent = "merico-graphql/" + Version
+
+// defaultAccept is the Accept header sent unless overridden.
+// It prefers the GraphQL-over-HTTP media type, falling back to plain JSON
+// for servers that predate it.
+const defaultAccept = "application/graphql-response+json, application/json"
+
+// WithAccept sets the Accept header of the request to mediaTypes, in order
+// of preference. It's needed for servers that gate features behind vendor
+// media types, such as GitHub's API previews.
+// To set it for every request of a client, use it with WithRequestOptions.
+//
+// The response must still have a JSON media type (application/json or any
+// type with a "+json" suffix) for the client to decode it.
+func WithAccept(mediaTypes ...string) RequestOption {
+	return WithHeader("Accept", strings.Join(mediaTypes, ", "))
+}
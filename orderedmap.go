@@ -0,0 +1,157 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// OrderedMap is a JSON object that preserves the order of its keys. It's
+// a response type for dynamic selections, whose fields aren't known in
+// advance, for tools that re-render results faithfully. Its selection set
+// is written in the graphql tag of its field:
+//
+//	var q struct {
+//		Viewer graphql.OrderedMap `graphql:"viewer{login,name}"`
+//	}
+//
+// Nested objects are decoded as OrderedMap values, arrays as []interface{}
+// values, and numbers as json.Number values, which keep their exact text.
+type OrderedMap []MapEntry
+
+// MapEntry is a key and its value in an OrderedMap.
+type MapEntry struct {
+	Key   string
+	Value interface{}
+}
+
+// Get returns the value of key, and reports whether m has key.
+func (m OrderedMap) Get(key string) (interface{}, bool) {
+	for _, e := range m {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Keys returns the keys of m, in order.
+func (m OrderedMap) Keys() []string {
+	keys := make([]string, len(m))
+	for i, e := range m {
+		keys[i] = e.Key
+	}
+	return keys
+}
+
+// MarshalJSON implements json.Marshaler. Keys are encoded in order.
+func (m OrderedMap) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, e := range m {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *OrderedMap) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		*m = nil
+		return nil
+	}
+	if tok != json.Delim('{') {
+		return errors.New("graphql: OrderedMap: JSON value isn't an object")
+	}
+	v, err := decodeOrderedObject(dec)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// decodeOrderedObject decodes the rest of the JSON object whose opening
+// delimiter was read from dec.
+func decodeOrderedObject(dec *json.Decoder) (OrderedMap, error) {
+	m := OrderedMap{}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if tok == json.Delim('}') {
+			return m, nil
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, errors.New("graphql: OrderedMap: unexpected non-key in JSON input")
+		}
+		value, err := decodeOrderedValue(dec)
+		if err != nil {
+			return nil, err
+		}
+		m = append(m, MapEntry{Key: key, Value: value})
+	}
+}
+
+// decodeOrderedValue decodes the next JSON value from dec,
+// with objects decoded as OrderedMap values.
+func decodeOrderedValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	switch tok {
+	case json.Delim('{'):
+		return decodeOrderedObject(dec)
+	case json.Delim('['):
+		list := []interface{}{}
+		for dec.More() {
+			v, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		_, err := dec.Token() // Closing ']'.
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		return list, nil
+	default:
+		return tok, nil
+	}
+}
+
+// unexpectedEOF returns io.ErrUnexpectedEOF for io.EOF, and err otherwise.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestOrderedMap(t *testing.T) {
+	const data = `{"zeta":1.50,"alpha":{"b":"x","a":null},"list":[{"y":true,"x":false},2]}`
+	var m graphql.OrderedMap
+	err := json.Unmarshal([]byte(data), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Keys(), []string{"zeta", "alpha", "list"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got keys: %v, want: %v", got, want)
+	}
+	alpha, ok := m.Get("alpha")
+	if !ok {
+		t.Fatal("got no alpha")
+	}
+	if got, want := alpha.(graphql.OrderedMap).Keys(), []string{"b", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got alpha keys: %v, want: %v", got, want)
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != data {
+		t.Errorf("got re-encoded: %s, want: %s", got, data)
+	}
+}
+
+func TestClient_Query_orderedMap(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := mustRead(req.Body), `{"query":"{viewer{name,login},node: repository(name: \"graphql\"){name}}"}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"name": "Gopher", "login": "gopher"}, "node": {"name": "graphql"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Viewer graphql.OrderedMap  `graphql:"viewer{name,login}"`
+		Node   *graphql.OrderedMap `graphql:"node: repository(name: \"graphql\"){name}"`
+	}
+	dataErrors, err := client.Query(context.Background(), &q, nil)
+	if err != nil || dataErrors != nil {
+		t.Fatal(dataErrors, err)
+	}
+	want := graphql.OrderedMap{{Key: "name", Value: "Gopher"}, {Key: "login", Value: "gopher"}}
+	if !reflect.DeepEqual(q.Viewer, want) {
+		t.Errorf("got viewer: %v, want: %v", q.Viewer, want)
+	}
+	if q.Node == nil || !reflect.DeepEqual(*q.Node, graphql.OrderedMap{{Key: "name", Value: "graphql"}}) {
+		t.Errorf("got node: %v", q.Node)
+	}
+}
@@ -0,0 +1,161 @@
+// Package otelgraphql instruments GraphQL clients of the graphql package
+// with OpenTelemetry traces and metrics.
+//
+// It's a module of its own, so that programs that don't use OpenTelemetry
+// don't depend on it.
+//
+// E.g.:
+//
+//	client := graphql.NewClient(url, httpClient,
+//		otelgraphql.WithTracerProvider(otel.GetTracerProvider()),
+//		otelgraphql.WithMeterProvider(otel.GetMeterProvider()))
+//
+// To trace the HTTP requests of operations as children of their spans,
+// use an instrumented http.Client, such as one whose transport is
+// otelhttp.NewTransport.
+package otelgraphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/merico-dev/graphql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the instrumentation scope
+// of the spans and metrics.
+const instrumentationName = "github.com/merico-dev/graphql/otelgraphql"
+
+// Attribute keys.
+const (
+	operationNameKey = attribute.Key("graphql.operation.name")
+	operationTypeKey = attribute.Key("graphql.operation.type")
+	documentHashKey  = attribute.Key("graphql.document.sha256")
+	errorsCountKey   = attribute.Key("graphql.errors.count")
+	outcomeKey       = attribute.Key("graphql.client.outcome")
+	requestsKey      = attribute.Key("graphql.client.requests")
+	requestSizeKey   = attribute.Key("graphql.client.request.size")
+	responseSizeKey  = attribute.Key("graphql.client.response.size")
+	constructKey     = attribute.Key("graphql.client.construct.duration")
+	ttfbKey          = attribute.Key("graphql.client.ttfb.duration")
+	serverKey        = attribute.Key("graphql.client.server.duration")
+	decodeKey        = attribute.Key("graphql.client.decode.duration")
+)
+
+// WithTracerProvider makes the client record a span for each operation it
+// executes, other than subscriptions, with tracers from tp. Spans are named
+// after the type and name of their operation, such as "query GetViewer",
+// start when the construction of the document starts, and carry the hash
+// of the document, the number of GraphQL errors in the response, and the
+// time spent constructing the document, waiting for the server, and
+// decoding the response, in seconds.
+//
+// Spans are in the contexts of the HTTP requests of their operations.
+func WithTracerProvider(tp trace.TracerProvider) graphql.ClientOption {
+	tracer := tp.Tracer(instrumentationName, trace.WithInstrumentationVersion(graphql.Version))
+	return graphql.WithOperationHook(func(ctx context.Context, op graphql.OperationInfo) (context.Context, func(graphql.Stats, *graphql.Response, error)) {
+		name := op.Type
+		if op.Name != "" {
+			name += " " + op.Name
+		}
+		ctx, span := tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithTimestamp(op.Start),
+			trace.WithAttributes(operationAttributes(op)...),
+			trace.WithAttributes(documentHashKey.String(documentHash(op.Query))))
+		return ctx, func(stats graphql.Stats, resp *graphql.Response, err error) {
+			span.SetAttributes(
+				requestsKey.Int(stats.Requests),
+				requestSizeKey.Int64(stats.BytesSent),
+				responseSizeKey.Int64(stats.BytesReceived),
+				constructKey.Float64(stats.Construct.Seconds()),
+				ttfbKey.Float64(stats.TTFB.Seconds()),
+				serverKey.Float64(stats.Server.Seconds()),
+				decodeKey.Float64(stats.Decode.Seconds()),
+			)
+			switch {
+			case err != nil:
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			case resp != nil && len(resp.Errors) > 0:
+				span.SetAttributes(errorsCountKey.Int(len(resp.Errors)))
+				span.SetStatus(codes.Error, resp.Errors[0].Message)
+			default:
+				span.SetAttributes(errorsCountKey.Int(0))
+			}
+			span.End()
+		}
+	})
+}
+
+// WithMeterProvider makes the client record metrics of the operations it
+// executes, other than subscriptions, with meters from mp:
+//
+//   - graphql.client.operation.duration, a histogram of the time
+//     operations take, in seconds, including constructing their documents
+//     and decoding their responses;
+//   - graphql.client.response.size, a histogram of the size of response
+//     bodies, in bytes, for operations that weren't responded to from
+//     the client's cache.
+//
+// Measurements have the name and type of their operation, and its outcome,
+// such as "success", "data_errors" or "failure", as attributes.
+func WithMeterProvider(mp metric.MeterProvider) graphql.ClientOption {
+	meter := mp.Meter(instrumentationName, metric.WithInstrumentationVersion(graphql.Version))
+	duration, err := meter.Float64Histogram("graphql.client.operation.duration",
+		instrument.WithDescription("Duration of GraphQL operations."),
+		instrument.WithUnit("s"))
+	if err != nil {
+		otel.Handle(err)
+	}
+	size, err := meter.Int64Histogram("graphql.client.response.size",
+		instrument.WithDescription("Size of GraphQL response bodies."),
+		instrument.WithUnit("By"))
+	if err != nil {
+		otel.Handle(err)
+	}
+	return graphql.WithOperationHook(func(ctx context.Context, op graphql.OperationInfo) (context.Context, func(graphql.Stats, *graphql.Response, error)) {
+		return ctx, func(stats graphql.Stats, resp *graphql.Response, err error) {
+			attrs := append(operationAttributes(op), outcomeKey.String(outcome(resp, err).String()))
+			duration.Record(ctx, stats.Total.Seconds(), attrs...)
+			if stats.Requests > 0 {
+				size.Record(ctx, stats.BytesReceived, attrs...)
+			}
+		}
+	})
+}
+
+// operationAttributes returns the attributes describing op.
+func operationAttributes(op graphql.OperationInfo) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{operationTypeKey.String(op.Type)}
+	if op.Name != "" {
+		attrs = append(attrs, operationNameKey.String(op.Name))
+	}
+	return attrs
+}
+
+// documentHash returns the hex-encoded SHA-256 hash of the document query,
+// the same hash that persisted queries are sent with.
+func documentHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// outcome returns the outcome of an operation that returned resp and err.
+func outcome(resp *graphql.Response, err error) graphql.Outcome {
+	switch {
+	case err != nil:
+		return graphql.OutcomeFailure
+	case resp != nil && len(resp.Errors) > 0:
+		return graphql.OutcomeDataErrors
+	default:
+		return graphql.OutcomeSuccess
+	}
+}
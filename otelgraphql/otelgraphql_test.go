@@ -0,0 +1,111 @@
+package otelgraphql_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+	"github.com/merico-dev/graphql/otelgraphql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInstrumentation(t *testing.T) {
+	var requestSpan trace.SpanContext
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		requestSpan = trace.SpanContextFromContext(req.Context())
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data": {"viewer": null}, "errors": [{"message": "not logged in"}]}`)
+	})
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		otelgraphql.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		otelgraphql.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil, graphql.WithOperationName("GetViewer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("got %d spans, want 1", len(ended))
+	}
+	span := ended[0]
+	if got, want := span.Name(), "query GetViewer"; got != want {
+		t.Errorf("got span name %q, want %q", got, want)
+	}
+	if got, want := span.Status().Code, codes.Error; got != want {
+		t.Errorf("got span status %v, want %v", got, want)
+	}
+	if requestSpan.SpanID() != span.SpanContext().SpanID() {
+		t.Error("request wasn't made in the context of the span")
+	}
+	attrs := attribute.NewSet(span.Attributes()...)
+	for key, want := range map[attribute.Key]attribute.Value{
+		"graphql.operation.name":  attribute.StringValue("GetViewer"),
+		"graphql.operation.type":  attribute.StringValue("query"),
+		"graphql.document.sha256": attribute.StringValue(fmt.Sprintf("%x", sha256.Sum256([]byte("query GetViewer{viewer{login}}")))),
+		"graphql.errors.count":    attribute.IntValue(1),
+		"graphql.client.requests": attribute.IntValue(1),
+	} {
+		if got, _ := attrs.Value(key); got != want {
+			t.Errorf("got %s attribute %q, want %q", key, got.Emit(), want.Emit())
+		}
+	}
+
+	var rm metricdata.ResourceMetrics
+	err = reader.Collect(context.Background(), &rm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]uint64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			data, ok := m.Data.(metricdata.Histogram)
+			if !ok {
+				continue
+			}
+			for _, dp := range data.DataPoints {
+				if outcome, _ := dp.Attributes.Value("graphql.client.outcome"); outcome.AsString() != "data_errors" {
+					t.Errorf("got %s outcome %q, want data_errors", m.Name, outcome.AsString())
+				}
+				counts[m.Name] += dp.Count
+			}
+		}
+	}
+	for _, name := range []string{"graphql.client.operation.duration", "graphql.client.response.size"} {
+		if counts[name] != 1 {
+			t.Errorf("got %d %s measurements, want 1", counts[name], name)
+		}
+	}
+}
+
+// localRoundTripper is an http.RoundTripper that executes HTTP transactions
+// by using handler directly, instead of going over an HTTP connection.
+type localRoundTripper struct {
+	handler http.Handler
+}
+
+func (l localRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	l.handler.ServeHTTP(w, req)
+	return w.Result(), nil
+}
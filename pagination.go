@@ -0,0 +1,225 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+)
+
+// QueryPaginated executes the query q once per page of the Relay-style
+// connection it selects, calling onPage after each page is decoded into q.
+// q should be a pointer to struct that corresponds to the GraphQL schema.
+//
+// The connection is the first struct reachable from q through struct fields
+// that has a PageInfo field with HasNextPage and EndCursor fields.
+// Its arguments should include a cursor variable, "after" by default, with
+// a nullable type; E.g., `graphql:"issues(first: 100, after: $after)"`.
+// QueryPaginated sets it to the end cursor of each page to get the next one,
+// until a page has no next page or onPage returns an error.
+//
+// Pages are requested back to back unless pacing options such as
+// WithPageDelay, WithPageLimiter, or WithPageBudget are given.
+//
+// If a page has GraphQL errors, QueryPaginated stops and returns them,
+// unless WithPageRetry has it request the page again.
+//
+// onPage may be nil, such as when WithAccumulatedItems collects the items
+// of every page into q.
+func (c *Client) QueryPaginated(ctx context.Context, q interface{}, variables map[string]interface{}, onPage func() error, opts ...PageOption) ([]DataError, error) {
+	cfg := pageConfig{cursorVariable: "after"}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	vars := make(map[string]interface{}, len(variables)+1)
+	for k, v := range variables {
+		vars[k] = v
+	}
+	var items reflect.Value // Items of the pages so far, if accumulating.
+	for page := 0; ; page++ {
+		if page > 0 {
+			err := cfg.pace(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+		dataErrors, err := c.Query(ctx, q, vars, cfg.requestOptions...)
+		if err == nil && len(dataErrors) > 0 && cfg.retryPage != nil && cfg.retryPage(vars, dataErrors) {
+			page--
+			continue
+		}
+		if err != nil || len(dataErrors) > 0 {
+			return dataErrors, err
+		}
+		if onPage != nil {
+			err = onPage()
+			if err != nil {
+				return nil, err
+			}
+		}
+		conn, ok := findConnection(reflect.ValueOf(q))
+		if !ok {
+			return nil, errors.New("graphql: no connection with PageInfo{HasNextPage, EndCursor} found in query")
+		}
+		var pageItems reflect.Value
+		if cfg.accumulate {
+			pageItems, ok = connectionItems(conn)
+			if !ok {
+				return nil, errors.New("graphql: no Nodes or Edges slice found in connection to accumulate")
+			}
+			if !items.IsValid() {
+				items = reflect.MakeSlice(pageItems.Type(), 0, pageItems.Len())
+			}
+			items = reflect.AppendSlice(items, pageItems)
+		}
+		pageInfo := conn.FieldByName("PageInfo")
+		cursor := pageInfo.FieldByName("EndCursor")
+		if !pageInfo.FieldByName("HasNextPage").Bool() || cursor.Kind() == reflect.Ptr && cursor.IsNil() {
+			if cfg.accumulate {
+				pageItems.Set(items)
+			}
+			return nil, nil
+		}
+		if cursor.Kind() != reflect.Ptr {
+			// Pass the cursor as a pointer, so that its variable is declared nullable.
+			p := reflect.New(cursor.Type())
+			p.Elem().Set(cursor)
+			cursor = p
+		}
+		vars[cfg.cursorVariable] = cursor.Interface()
+	}
+}
+
+// PageOption configures QueryPaginated.
+type PageOption func(*pageConfig)
+
+// pageConfig is the configuration of QueryPaginated.
+type pageConfig struct {
+	cursorVariable string
+	requestOptions []RequestOption
+	delay          time.Duration
+	limiter        Limiter
+	budget         func() time.Duration
+	retryPage      func(variables map[string]interface{}, dataErrors []DataError) bool
+	accumulate     bool
+}
+
+// WithCursorVariable sets the name of the variable holding the cursor
+// of the page to get, instead of "after".
+func WithCursorVariable(name string) PageOption {
+	return func(cfg *pageConfig) { cfg.cursorVariable = name }
+}
+
+// WithPageRequestOptions applies opts to the request for each page.
+func WithPageRequestOptions(opts ...RequestOption) PageOption {
+	return func(cfg *pageConfig) { cfg.requestOptions = append(cfg.requestOptions, opts...) }
+}
+
+// WithPageDelay waits d between consecutive pages.
+func WithPageDelay(d time.Duration) PageOption {
+	return func(cfg *pageConfig) { cfg.delay = d }
+}
+
+// WithPageLimiter waits for l before requesting each page after the first,
+// so that pages are requested at the rate l allows.
+func WithPageLimiter(l Limiter) PageOption {
+	return func(cfg *pageConfig) { cfg.limiter = l }
+}
+
+// WithPageBudget calls f after each page, and waits for the duration it
+// returns before requesting the next page. It makes pagination aware of
+// cost budgets that servers report with each page, such as the rateLimit
+// field of GitHub's API: f can inspect the decoded query and return the time
+// until the budget resets when it's nearly exhausted, and 0 otherwise.
+func WithPageBudget(f func() time.Duration) PageOption {
+	return func(cfg *pageConfig) { cfg.budget = f }
+}
+
+// WithPageRetry calls f when a page has GraphQL errors, with the variables
+// of the page and the errors. If f returns true, the page is requested
+// again with the variables, which f may modify, and they're used for the
+// following pages too. It allows recovering from errors such as a page
+// exceeding the server's query complexity limit, by asking for fewer items.
+func WithPageRetry(f func(variables map[string]interface{}, dataErrors []DataError) bool) PageOption {
+	return func(cfg *pageConfig) { cfg.retryPage = f }
+}
+
+// WithAccumulatedItems makes QueryPaginated accumulate the items of the
+// connection across pages: the Nodes field of the connection, or else its
+// Edges field. Once QueryPaginated returns without error, the field holds
+// the items of every page, rather than those of the last one. With it,
+// onPage may be nil.
+func WithAccumulatedItems() PageOption {
+	return func(cfg *pageConfig) { cfg.accumulate = true }
+}
+
+// pace waits before requesting the next page, as configured.
+func (cfg *pageConfig) pace(ctx context.Context) error {
+	d := cfg.delay
+	if cfg.budget != nil {
+		if b := cfg.budget(); b > d {
+			d = b
+		}
+	}
+	if d > 0 {
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+	if cfg.limiter != nil {
+		return cfg.limiter.Wait(ctx)
+	}
+	return nil
+}
+
+// findConnection returns the first connection, a struct with a PageInfo
+// field, reachable from v through struct fields and non-nil pointers.
+func findConnection(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	if pageInfo := v.FieldByName("PageInfo"); pageInfo.IsValid() && isPageInfo(pageInfo) {
+		return v, true
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).PkgPath != "" {
+			continue
+		}
+		if conn, ok := findConnection(v.Field(i)); ok {
+			return conn, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// connectionItems returns the Nodes field of the connection conn,
+// or else its Edges field, if it's a slice.
+func connectionItems(conn reflect.Value) (reflect.Value, bool) {
+	for _, name := range [...]string{"Nodes", "Edges"} {
+		if items := conn.FieldByName(name); items.IsValid() && items.Kind() == reflect.Slice {
+			return items, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// isPageInfo reports whether v is a PageInfo struct with a boolean
+// HasNextPage field and an EndCursor field.
+func isPageInfo(v reflect.Value) bool {
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+	hasNextPage := v.FieldByName("HasNextPage")
+	return hasNextPage.IsValid() && hasNextPage.Kind() == reflect.Bool &&
+		v.FieldByName("EndCursor").IsValid()
+}
@@ -0,0 +1,159 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+// pagesHandler serves the pages of a repository's issues connection,
+// two issues per page, keyed by the "after" cursor.
+func pagesHandler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		w.Header().Set("Content-Type", "application/json")
+		switch body {
+		case `{"query":"query($after:String){repository{issues(first: 2, after: $after){nodes{number},pageInfo{hasNextPage,endCursor}}}}","variables":{"after":null}}` + "\n":
+			mustWrite(w, `{"data": {"repository": {"issues": {"nodes": [{"number": 1}, {"number": 2}], "pageInfo": {"hasNextPage": true, "endCursor": "c2"}}}}}`)
+		case `{"query":"query($after:String){repository{issues(first: 2, after: $after){nodes{number},pageInfo{hasNextPage,endCursor}}}}","variables":{"after":"c2"}}` + "\n":
+			mustWrite(w, `{"data": {"repository": {"issues": {"nodes": [{"number": 3}], "pageInfo": {"hasNextPage": false, "endCursor": "c3"}}}}}`)
+		default:
+			t.Errorf("unexpected body: %s", body)
+			http.Error(w, "unexpected body", http.StatusBadRequest)
+		}
+	})
+	return mux
+}
+
+type issuesQuery struct {
+	Repository struct {
+		Issues struct {
+			Nodes []struct {
+				Number graphql.Int
+			}
+			PageInfo struct {
+				HasNextPage graphql.Boolean
+				EndCursor   graphql.String
+			}
+		} `graphql:"issues(first: 2, after: $after)"`
+	}
+}
+
+func TestClient_QueryPaginated(t *testing.T) {
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: pagesHandler(t)}})
+
+	var q issuesQuery
+	var got []int
+	dataErrors, err := client.QueryPaginated(context.Background(), &q, map[string]interface{}{
+		"after": (*graphql.String)(nil),
+	}, func() error {
+		for _, n := range q.Repository.Issues.Nodes {
+			got = append(got, int(n.Number))
+		}
+		return nil
+	})
+	if err != nil || dataErrors != nil {
+		t.Fatal(dataErrors, err)
+	}
+	if want := []int{1, 2, 3}; len(got) != len(want) || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Errorf("got nodes: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_QueryPaginated_accumulatedItems(t *testing.T) {
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: pagesHandler(t)}})
+
+	var q issuesQuery
+	dataErrors, err := client.QueryPaginated(context.Background(), &q, map[string]interface{}{
+		"after": (*graphql.String)(nil),
+	}, nil, graphql.WithAccumulatedItems())
+	if err != nil || dataErrors != nil {
+		t.Fatal(dataErrors, err)
+	}
+	var got []int
+	for _, n := range q.Repository.Issues.Nodes {
+		got = append(got, int(n.Number))
+	}
+	if want := []int{1, 2, 3}; len(got) != len(want) || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Errorf("got nodes: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_QueryPaginated_stop(t *testing.T) {
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: pagesHandler(t)}})
+
+	var q issuesQuery
+	errStop := errors.New("stop")
+	pages := 0
+	_, err := client.QueryPaginated(context.Background(), &q, map[string]interface{}{
+		"after": (*graphql.String)(nil),
+	}, func() error {
+		pages++
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("got error: %v, want: %v", err, errStop)
+	}
+	if pages != 1 {
+		t.Errorf("got %v pages, want 1", pages)
+	}
+}
+
+type countingLimiter struct{ waits int }
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.waits++
+	return ctx.Err()
+}
+
+func TestClient_QueryPaginated_pacing(t *testing.T) {
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: pagesHandler(t)}})
+
+	var q issuesQuery
+	var limiter countingLimiter
+	budgetCalls := 0
+	start := time.Now()
+	_, err := client.QueryPaginated(context.Background(), &q, map[string]interface{}{
+		"after": (*graphql.String)(nil),
+	}, func() error { return nil },
+		graphql.WithPageDelay(10*time.Millisecond),
+		graphql.WithPageLimiter(&limiter),
+		graphql.WithPageBudget(func() time.Duration {
+			budgetCalls++
+			return 20 * time.Millisecond
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("pagination took %v, want at least the 20ms budget wait", elapsed)
+	}
+	// Pacing only happens between pages, not before the first one.
+	if limiter.waits != 1 {
+		t.Errorf("got %v limiter waits, want 1", limiter.waits)
+	}
+	if budgetCalls != 1 {
+		t.Errorf("got %v budget calls, want 1", budgetCalls)
+	}
+}
+
+func TestClient_QueryPaginated_pacingCanceled(t *testing.T) {
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: pagesHandler(t)}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var q issuesQuery
+	_, err := client.QueryPaginated(ctx, &q, map[string]interface{}{
+		"after": (*graphql.String)(nil),
+	}, func() error {
+		cancel()
+		return nil
+	}, graphql.WithPageDelay(time.Hour))
+	if err != context.Canceled {
+		t.Errorf("got error: %v, want: %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,134 @@
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// persistedQueriesMode is how a client uses persisted queries.
+type persistedQueriesMode uint8
+
+const (
+	persistedQueriesOff  persistedQueriesMode = iota // Send query text only.
+	persistedQueriesOnly                             // Send hashes only, never query text.
+	persistedQueriesGET                              // Send query hashes with GET, falling back to POST.
+	persistedQueriesPOST                             // Send hashes with POST, falling back to query text.
+)
+
+// WithAutomaticPersistedQueries makes the client send only the SHA-256
+// hash of each document, as specified by the Automatic Persisted Queries
+// protocol, which cuts the size of requests with large documents.
+//
+// If the server doesn't recognize a hash, the request is sent again with
+// the document, which registers it with the server so that subsequent
+// requests with the hash succeed. Requests are sent as POST requests;
+// see WithCacheablePersistedQueries for GET requests.
+func WithAutomaticPersistedQueries() ClientOption {
+	return func(c *Client) {
+		c.persistedQueries = persistedQueriesPOST
+	}
+}
+
+// WithPersistedQueriesOnly makes the client send only the SHA-256 hash of
+// each document, as specified by the Automatic Persisted Queries protocol,
+// and never its text. The documents must be registered with the server
+// ahead of time.
+//
+// It's meant for production environments where servers reject arbitrary
+// documents. If the server doesn't recognize a hash, the request fails with
+// an error wrapping ErrPersistedQueryNotFound; the query text isn't sent.
+func WithPersistedQueriesOnly() ClientOption {
+	return func(c *Client) {
+		c.persistedQueries = persistedQueriesOnly
+	}
+}
+
+// WithCacheablePersistedQueries makes the client send queries as GET
+// requests carrying only the SHA-256 hash of the document and the variables
+// in the URL, as specified by the Automatic Persisted Queries protocol.
+// Unlike POST requests, their responses can be cached by CDNs and gateways.
+//
+// If the server doesn't recognize a hash, the query is sent again as a POST
+// request with the document, which registers it with the server so
+// subsequent GET requests succeed. Mutations are always sent as POST requests.
+func WithCacheablePersistedQueries() ClientOption {
+	return func(c *Client) {
+		c.persistedQueries = persistedQueriesGET
+	}
+}
+
+// ErrPersistedQueryNotFound is returned, wrapped, when the server doesn't
+// recognize the hash of a persisted query and the client isn't allowed
+// to send the query text.
+var ErrPersistedQueryNotFound = errors.New("persisted query not found")
+
+// persistedQuery is the "persistedQuery" request extension.
+// See https://github.com/apollographql/apollo-link-persisted-queries#protocol.
+type persistedQuery struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+// doPersisted sends the GraphQL request in as a persisted query,
+// replacing its query text with the query's hash.
+func (c *Client) doPersisted(ctx context.Context, in requestBody, cfg *requestConfig) (*Response, error) {
+	get := c.persistedQueries == persistedQueriesGET
+	if get && operationType(in.Query) != "query" {
+		return c.send(ctx, in, false, cfg)
+	}
+	hash := queryHash(in.Query)
+	extensions := map[string]interface{}{
+		"persistedQuery": persistedQuery{Version: 1, SHA256Hash: hash},
+	}
+	for k, v := range in.Extensions {
+		extensions[k] = v
+	}
+	in.Extensions = extensions
+	query := in.Query
+	in.Query = ""
+	resp, err := c.send(ctx, in, get, cfg)
+	if !isPersistedQueryNotFound(resp, err) {
+		return resp, err
+	}
+	if c.persistedQueries == persistedQueriesOnly {
+		return resp, fmt.Errorf("%w: the server doesn't recognize query hash %s, and sending query text is disabled", ErrPersistedQueryNotFound, hash)
+	}
+	// Send the query text along with its hash, registering it with the server.
+	in.Query = query
+	return c.send(ctx, in, false, cfg)
+}
+
+// queryHash returns the hex-encoded SHA-256 hash of query.
+func queryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// isPersistedQueryNotFound reports whether the server responded that it
+// doesn't recognize a persisted query hash. Servers report it either in
+// a successful response, or in the body of a non-200 one, with the
+// PersistedQueryNotFound message or the PERSISTED_QUERY_NOT_FOUND code.
+func isPersistedQueryNotFound(resp *Response, err error) bool {
+	var errs []DataError
+	var statusErr *HTTPStatusError
+	switch {
+	case err == nil:
+		errs = resp.Errors
+	case errors.As(err, &statusErr):
+		var envelope struct{ Errors dataErrors }
+		if json.Unmarshal(statusErr.Body, &envelope) != nil {
+			return false
+		}
+		errs = envelope.Errors
+	}
+	for _, e := range errs {
+		if e.Message == "PersistedQueryNotFound" || e.Code() == "PERSISTED_QUERY_NOT_FOUND" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package graphql_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+// persistedQueryServer returns a handler serving the persisted queries
+// registered in known, keyed by hash, and counting requests with query text.
+// It accepts both POST requests and GET requests.
+func persistedQueryServer(t *testing.T, known map[string]string, textRequests *int) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var in struct {
+			Query      string
+			Extensions struct {
+				PersistedQuery struct {
+					Version    int
+					SHA256Hash string
+				}
+			}
+		}
+		switch req.Method {
+		case http.MethodGet:
+			in.Query = req.URL.Query().Get("query")
+			if err := json.Unmarshal([]byte(req.URL.Query().Get("extensions")), &in.Extensions); err != nil {
+				t.Fatal(err)
+			}
+		default:
+			if err := json.NewDecoder(req.Body).Decode(&in); err != nil {
+				t.Fatal(err)
+			}
+		}
+		hash := in.Extensions.PersistedQuery.SHA256Hash
+		if in.Query != "" {
+			*textRequests++
+			sum := sha256.Sum256([]byte(in.Query))
+			if got, want := hash, hex.EncodeToString(sum[:]); got != want {
+				t.Errorf("got hash: %v, want: %v", got, want)
+			}
+			known[hash] = in.Query
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, ok := known[hash]; !ok {
+			mustWrite(w, `{"errors": [{"message": "PersistedQueryNotFound", "extensions": {"code": "PERSISTED_QUERY_NOT_FOUND"}}]}`)
+			return
+		}
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	return mux
+}
+
+func TestWithPersistedQueriesOnly(t *testing.T) {
+	sum := sha256.Sum256([]byte("{viewer{login}}"))
+	known := map[string]string{hex.EncodeToString(sum[:]): "{viewer{login}}"}
+	var textRequests int
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: persistedQueryServer(t, known, &textRequests)}},
+		graphql.WithPersistedQueriesOnly())
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Viewer.Login, "gopher"; got != want {
+		t.Errorf("got q.Viewer.Login: %q, want: %q", got, want)
+	}
+
+	var unknown struct {
+		Viewer struct {
+			Name string
+		}
+	}
+	_, err = client.Query(context.Background(), &unknown, nil)
+	if !errors.Is(err, graphql.ErrPersistedQueryNotFound) {
+		t.Errorf("got error: %v, want: ErrPersistedQueryNotFound", err)
+	}
+	if textRequests != 0 {
+		t.Errorf("got %d requests with query text, want: 0", textRequests)
+	}
+}
+
+func TestWithCacheablePersistedQueries(t *testing.T) {
+	known := map[string]string{}
+	var textRequests int
+	var methods []string
+	handler := persistedQueryServer(t, known, &textRequests)
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		methods = append(methods, req.Method)
+		handler.ServeHTTP(w, req)
+	})}}, graphql.WithCacheablePersistedQueries())
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	for i := 0; i < 2; i++ {
+		_, err := client.Query(context.Background(), &q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := q.Viewer.Login, "gopher"; got != want {
+			t.Errorf("got q.Viewer.Login: %q, want: %q", got, want)
+		}
+	}
+	// The first query isn't known, so it's registered with a POST request.
+	if got, want := fmt.Sprint(methods), "[GET POST GET]"; got != want {
+		t.Errorf("got methods: %v, want: %v", got, want)
+	}
+	if textRequests != 1 {
+		t.Errorf("got %d requests with query text, want: 1", textRequests)
+	}
+}
+
+func TestWithAutomaticPersistedQueries(t *testing.T) {
+	known := map[string]string{}
+	var textRequests int
+	var methods []string
+	handler := persistedQueryServer(t, known, &textRequests)
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		methods = append(methods, req.Method)
+		handler.ServeHTTP(w, req)
+	})}}, graphql.WithAutomaticPersistedQueries())
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	for i := 0; i < 2; i++ {
+		_, err := client.Query(context.Background(), &q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := q.Viewer.Login, "gopher"; got != want {
+			t.Errorf("got q.Viewer.Login: %q, want: %q", got, want)
+		}
+	}
+	// The first query isn't known, so it's sent again with its text.
+	if got, want := fmt.Sprint(methods), "[POST POST POST]"; got != want {
+		t.Errorf("got methods: %v, want: %v", got, want)
+	}
+	if textRequests != 1 {
+		t.Errorf("got %d requests with query text, want: 1", textRequests)
+	}
+}
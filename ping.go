@@ -0,0 +1,75 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Ping checks that the server is reachable and accepts the client's
+// credentials, by querying {__typename}, which any GraphQL server
+// answers. It returns the time the query took. It's meant for readiness
+// probes, and to validate connections at startup.
+//
+// It returns an *HTTPStatusError if the server rejected the request, such
+// as with 401 Unauthorized for invalid credentials, and the first GraphQL
+// error as a DataError if the server responded with errors.
+func (c *Client) Ping(ctx context.Context, opts ...RequestOption) (time.Duration, error) {
+	start := time.Now()
+	resp, err := c.do(ctx, "{__typename}", nil, append(opts[:len(opts):len(opts)], NoCache()))
+	latency := time.Since(start)
+	if err != nil {
+		return latency, err
+	}
+	if len(resp.Errors) > 0 {
+		return latency, resp.Errors[0]
+	}
+	return latency, nil
+}
+
+// ClientStats are counts of the HTTP requests a client has sent, for
+// health checks and dashboards. Responses served from the client's cache
+// aren't counted.
+type ClientStats struct {
+	InFlight int   // Requests being sent or waiting for their responses.
+	Requests int64 // Requests sent, including retries.
+	Failures int64 // Requests that failed, such as with a network error or a non-200 status code.
+
+	// LastError is the error of the last request that failed, and
+	// LastErrorTime when it failed. LastError is nil if none has.
+	LastError     error
+	LastErrorTime time.Time
+}
+
+// Stats returns counts of the HTTP requests c has sent so far.
+// It may be called concurrently with operations.
+func (c *Client) Stats() ClientStats {
+	c.requestStats.mu.Lock()
+	defer c.requestStats.mu.Unlock()
+	return c.requestStats.stats
+}
+
+// requestStats are the ClientStats of a client.
+type requestStats struct {
+	mu    sync.Mutex
+	stats ClientStats
+}
+
+// start records a request being sent, and returns
+// a function to call with its error once it's done.
+func (s *requestStats) start() func(err error) {
+	s.mu.Lock()
+	s.stats.InFlight++
+	s.stats.Requests++
+	s.mu.Unlock()
+	return func(err error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.stats.InFlight--
+		if err != nil {
+			s.stats.Failures++
+			s.stats.LastError = err
+			s.stats.LastErrorTime = time.Now()
+		}
+	}
+}
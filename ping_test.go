@@ -0,0 +1,63 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestClient_Ping(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := mustRead(req.Body), `{"query":"{__typename}"}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		switch req.Header.Get("Authorization") {
+		case "bearer good":
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"data": {"__typename": "Query"}}`)
+		case "bearer expired":
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"errors": [{"message": "token expired"}]}`)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	var client *graphql.Client
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if got := client.Stats().InFlight; got != 1 {
+			t.Errorf("got %d requests in flight, want 1", got)
+		}
+		mux.ServeHTTP(w, req)
+	})
+	client = graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+
+	latency, err := client.Ping(context.Background(), graphql.WithHeader("Authorization", "bearer good"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latency <= 0 {
+		t.Errorf("got latency: %v, want > 0", latency)
+	}
+	_, err = client.Ping(context.Background(), graphql.WithHeader("Authorization", "bearer expired"))
+	var dataErr graphql.DataError
+	if !errors.As(err, &dataErr) || dataErr.Message != "token expired" {
+		t.Errorf("got error: %v, want: token expired", err)
+	}
+	_, err = client.Ping(context.Background())
+	var statusErr *graphql.HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got error: %v, want: 401 Unauthorized", err)
+	}
+
+	stats := client.Stats()
+	if stats.InFlight != 0 || stats.Requests != 3 || stats.Failures != 1 {
+		t.Errorf("got stats: %+v, want 3 requests with 1 failure", stats)
+	}
+	if stats.LastError != err || stats.LastErrorTime.IsZero() {
+		t.Errorf("got last error: %v at %v, want: %v", stats.LastError, stats.LastErrorTime, err)
+	}
+}
@@ -0,0 +1,205 @@
+package graphql
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/merico-dev/graphql/internal/jsonutil"
+)
+
+// queryPlan is what's needed to construct documents from the operation
+// structs of a type, worked out with reflection once per type, so that
+// constructing documents from them again doesn't walk the type.
+type queryPlan struct {
+	t       reflect.Type
+	gen     uint32        // Value of registrations it was worked out with.
+	err     error         // Why documents can't be constructed from t, if non-nil.
+	refs    []variableRef // Variables referenced by the tags of t, in the order they're checked.
+	extends []string      // graphql-extend variables of t, sorted.
+
+	// Selection sets of t, followed by the definitions of the named
+	// fragments spread in them, keyed by the numbers of elements of the
+	// graphql-extend variables and the construct options, which are all
+	// they depend on. At most maxPlanQueries are kept.
+	queries  sync.Map // map[queryKey]string
+	nqueries int32    // Number of entries of queries; accessed atomically.
+}
+
+// queryKey identifies a document constructed from the operation structs
+// of a type.
+type queryKey struct {
+	extends   string // Numbers of elements of the graphql-extend variables, comma-terminated.
+	mask      string // Key of the field mask, if any.
+	comments  bool
+	typenames bool
+}
+
+// maxPlanQueries is the number of documents kept per type, so that types
+// constructed with many masks or numbers of graphql-extend elements don't
+// grow the cache without bound. Documents past it are constructed anew
+// each time.
+const maxPlanQueries = 64
+
+// variableRef is a reference to a variable by the tag of a field.
+type variableRef struct {
+	path   string // Path of the field.
+	name   string // Name of the variable, or "" for the graphql-extend variable itself.
+	extend string // graphql-extend variable whose elements hold the variable, if non-empty.
+}
+
+// queryPlans holds the query plans of the types that documents
+// were constructed from.
+var queryPlans sync.Map // map[reflect.Type]*queryPlan
+
+// planFor returns the query plan of the operation structs of type t,
+// working it out if it's not known yet, or types were registered since,
+// which changes what's selected of them.
+func planFor(t reflect.Type) *queryPlan {
+	gen := atomic.LoadUint32(&registrations)
+	if p, ok := queryPlans.Load(t); ok && p.(*queryPlan).gen == gen {
+		return p.(*queryPlan)
+	}
+	p := &queryPlan{t: t, gen: gen}
+	switch {
+	case derefType(t).Kind() != reflect.Struct:
+		p.err = fmt.Errorf("can't construct a document from %v; want a struct or pointer to struct", t)
+	case derefType(t).NumField() == 0:
+		p.err = fmt.Errorf("%v has no fields to select", t)
+	default:
+		p.err = checkSelection("", derefType(t), true, make(map[reflect.Type]bool)) // Checked for emptiness above.
+	}
+	if p.err == nil {
+		p.collectVariables("", derefType(t), make(map[reflect.Type]bool))
+		seen := make(map[string]bool)
+		for _, r := range p.refs {
+			if r.name == "" && !seen[r.extend] {
+				seen[r.extend] = true
+				p.extends = append(p.extends, r.extend)
+			}
+		}
+		sort.Strings(p.extends)
+	}
+	queryPlans.Store(t, p)
+	return p
+}
+
+// checkOperation returns an error if a document can't be constructed from
+// the query, mutation or subscription struct v and its variables, rather
+// than letting it be constructed into a document that the server rejects
+// confusingly.
+func checkOperation(v interface{}, variables map[string]interface{}) error {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return errors.New("can't construct a document from nil")
+	}
+	p := planFor(t)
+	if p.err != nil {
+		return p.err
+	}
+	return p.checkVariables(variables)
+}
+
+// collectVariables collects the variables referenced by the graphql tags
+// of the fields of struct t at path, including the variables of the
+// elements of graphql-extend fields.
+func (p *queryPlan) collectVariables(path string, t reflect.Type, visiting map[reflect.Type]bool) {
+	if visiting[t] {
+		return
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fieldPath := joinPath(path, f.Name)
+		value, _, _ := jsonutil.LookupTag(f.Tag)
+		if extend, _ := f.Tag.Lookup("graphql-extend"); extend == "true" {
+			name := value
+			if i := strings.IndexAny(value, `(:[$!@{`); i != -1 {
+				name = value[:i]
+			}
+			p.refs = append(p.refs, variableRef{path: fieldPath, extend: name})
+			for _, ref := range sortedNames(referencedVariables(value)) {
+				p.refs = append(p.refs, variableRef{path: fieldPath, name: ref, extend: name})
+			}
+		} else {
+			for _, ref := range sortedNames(referencedVariables(value)) {
+				p.refs = append(p.refs, variableRef{path: fieldPath, name: ref})
+			}
+		}
+		ft := derefType(f.Type)
+		for ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array {
+			ft = derefType(ft.Elem())
+		}
+		if ft.Kind() == reflect.Struct && !decodedWhole(ft) {
+			p.collectVariables(fieldPath, ft, visiting)
+		}
+	}
+}
+
+// checkVariables returns an error if variables miss variables referenced
+// by the graphql tags of the plan's type, or the elements of its
+// graphql-extend variables miss variables referenced by their fields.
+func (p *queryPlan) checkVariables(variables map[string]interface{}) error {
+	for _, r := range p.refs {
+		switch {
+		case r.name == "":
+			if _, ok := variables[r.extend].([]map[string]interface{}); !ok {
+				return fmt.Errorf("field %s is a graphql-extend field, so variable $%s must be a []map[string]interface{}, not %T", r.path, r.extend, variables[r.extend])
+			}
+		case r.extend != "":
+			for j, elem := range variables[r.extend].([]map[string]interface{}) {
+				if _, ok := elem[r.name]; !ok {
+					return fmt.Errorf("field %s references variable $%s, which is missing from element %d of variable $%s", r.path, r.name, j, r.extend)
+				}
+			}
+		default:
+			if _, ok := variables[r.name]; !ok {
+				return fmt.Errorf("field %s references variable $%s, which is missing from the variables", r.path, r.name)
+			}
+		}
+	}
+	return nil
+}
+
+// query returns the selection set of the plan's type, followed by the
+// definitions of any named fragments spread in it, for variables,
+// constructed with opts. It's constructed once per number of elements of
+// each graphql-extend variable and opts, and reused after.
+func (p *queryPlan) query(variables map[string]interface{}, opts constructOptions) (string, error) {
+	var extends []byte
+	for _, name := range p.extends {
+		elems, _ := variables[name].([]map[string]interface{})
+		extends = strconv.AppendInt(extends, int64(len(elems)), 10)
+		extends = append(extends, ',')
+	}
+	key := queryKey{extends: string(extends), comments: opts.comments, typenames: opts.typenames}
+	if opts.mask != nil {
+		key.mask = opts.mask.key()
+	}
+	if q, ok := p.queries.Load(key); ok {
+		return q.(string), nil
+	}
+	var buf bytes.Buffer
+	fragments := fragmentDefinitions{comments: opts.comments, typenames: opts.typenames}
+	writeQuery(&buf, p.t, false, variables, &fragments, opts.mask, "")
+	if err := opts.mask.err(); err != nil {
+		return "", err
+	}
+	fragments.writeTo(&buf)
+	q := buf.String()
+	if atomic.AddInt32(&p.nqueries, 1) <= maxPlanQueries {
+		if _, loaded := p.queries.LoadOrStore(key, q); loaded {
+			atomic.AddInt32(&p.nqueries, -1)
+		}
+	} else {
+		atomic.AddInt32(&p.nqueries, -1)
+	}
+	return q, nil
+}
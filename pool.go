@@ -0,0 +1,62 @@
+package graphql
+
+import (
+	"net/http"
+	"sync"
+)
+
+// ClientPool is a set of clients keyed by tenant, for multi-tenant services
+// that talk to many GraphQL servers, or to one server on behalf of many
+// tenants. The clients are created on first use, and share the HTTP client
+// of the pool, and so its transport and connections.
+//
+// A ClientPool is safe for concurrent use by multiple goroutines.
+type ClientPool struct {
+	httpClient *http.Client
+	configure  TenantFunc
+
+	mu      sync.Mutex
+	clients map[string]*Client
+}
+
+// TenantFunc returns the GraphQL server URL and the options of the client
+// for tenant, such as WithRequestOptions(WithHeader(...)) for its credentials
+// and WithRateLimiter for its quota. It returns an error if there's no such
+// tenant.
+type TenantFunc func(tenant string) (url string, opts []ClientOption, err error)
+
+// NewClientPool creates a pool of clients that use httpClient,
+// configured for each tenant by configure.
+// If httpClient is nil, then http.DefaultClient is used.
+func NewClientPool(httpClient *http.Client, configure TenantFunc) *ClientPool {
+	return &ClientPool{
+		httpClient: httpClient,
+		configure:  configure,
+		clients:    make(map[string]*Client),
+	}
+}
+
+// Client returns the client for tenant, creating it if needed.
+func (p *ClientPool) Client(tenant string) (*Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.clients[tenant]; ok {
+		return c, nil
+	}
+	url, opts, err := p.configure(tenant)
+	if err != nil {
+		return nil, err
+	}
+	c := NewClient(url, p.httpClient, opts...)
+	p.clients[tenant] = c
+	return c, nil
+}
+
+// Remove removes the client for tenant from the pool, so that the next call
+// to Client creates a new one, such as after the tenant's configuration
+// changed. Clients already returned by Client remain usable.
+func (p *ClientPool) Remove(tenant string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.clients, tenant)
+}
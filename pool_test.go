@@ -0,0 +1,74 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestClientPool(t *testing.T) {
+	mux := http.NewServeMux()
+	for _, tenant := range []string{"acme", "globex"} {
+		tenant := tenant
+		mux.HandleFunc("/"+tenant+"/graphql", func(w http.ResponseWriter, req *http.Request) {
+			if got, want := req.Header.Get("Authorization"), "Bearer "+tenant+"-token"; got != want {
+				t.Errorf("got Authorization: %q, want: %q", got, want)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"data": {"viewer": {"login": "`+tenant+`"}}}`)
+		})
+	}
+	limiter := new(countingLimiter)
+	configured := 0
+	pool := graphql.NewClientPool(&http.Client{Transport: localRoundTripper{handler: mux}}, func(tenant string) (string, []graphql.ClientOption, error) {
+		configured++
+		if tenant != "acme" && tenant != "globex" {
+			return "", nil, fmt.Errorf("unknown tenant %q", tenant)
+		}
+		return "/" + tenant + "/graphql", []graphql.ClientOption{
+			graphql.WithRequestOptions(graphql.WithHeader("Authorization", "Bearer "+tenant+"-token")),
+			graphql.WithRateLimiter(limiter),
+		}, nil
+	})
+
+	for _, tenant := range []string{"acme", "globex", "acme"} {
+		client, err := pool.Client(tenant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var q struct {
+			Viewer struct {
+				Login graphql.String
+			}
+		}
+		_, err = client.Query(context.Background(), &q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(q.Viewer.Login), tenant; got != want {
+			t.Errorf("got login: %q, want: %q", got, want)
+		}
+	}
+	if configured != 2 {
+		t.Errorf("got %v tenants configured, want 2", configured)
+	}
+	if limiter.waits != 3 {
+		t.Errorf("got %v limiter waits, want 3", limiter.waits)
+	}
+
+	_, err := pool.Client("initech")
+	if got, want := fmt.Sprint(err), `unknown tenant "initech"`; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+
+	pool.Remove("acme")
+	if _, err := pool.Client("acme"); err != nil {
+		t.Fatal(err)
+	}
+	if configured != 4 {
+		t.Errorf("got %v tenants configured, want 4", configured)
+	}
+}
@@ -0,0 +1,78 @@
+package graphql
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// WithPreconnect makes NewClient start connecting to the server in the
+// background, so that the first operation doesn't pay for resolving its
+// name and establishing a TLS connection, which matters in latency-sensitive
+// paths. If ping is true, it runs Ping, which also warms up the server side,
+// rather than only connecting. Failures are ignored; they're reported by
+// the operations that follow.
+func WithPreconnect(ping bool) ClientOption {
+	return func(c *Client) {
+		c.preconnect = preconnectOnly
+		if ping {
+			c.preconnect = preconnectPing
+		}
+	}
+}
+
+// preconnectMode is whether and how a client warms up its connection.
+type preconnectMode uint8
+
+const (
+	preconnectOff preconnectMode = iota
+	preconnectOnly
+	preconnectPing
+)
+
+// preconnectTimeout bounds the warm-up of clients created with WithPreconnect.
+const preconnectTimeout = 30 * time.Second
+
+// warmUp warms up the connection of the client according to c.preconnect.
+func (c *Client) warmUp() {
+	ctx, cancel := context.WithTimeout(context.Background(), preconnectTimeout)
+	defer cancel()
+	switch c.preconnect {
+	case preconnectOnly:
+		_ = c.Preconnect(ctx)
+	case preconnectPing:
+		_, _ = c.Ping(ctx)
+	}
+}
+
+// Preconnect establishes a connection to the server, which the client keeps
+// idle for the operations that follow, by sending it a HEAD request. The
+// status code of the response doesn't matter.
+func (c *Client) Preconnect(ctx context.Context, opts ...RequestOption) error {
+	cfg, err := c.requestConfig(ctx, opts)
+	if err != nil {
+		return err
+	}
+	endpoint, err := c.endpoint(cfg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodHead, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	for k, vs := range cfg.header {
+		req.Header[k] = vs
+	}
+	resp, err := ctxhttp.Do(ctx, c.httpClient, req)
+	if err != nil {
+		return err
+	}
+	// Drain the body so the connection can be reused.
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return resp.Body.Close()
+}
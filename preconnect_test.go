@@ -0,0 +1,63 @@
+package graphql_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestClient_Preconnect(t *testing.T) {
+	var conns int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodHead {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"__typename": "Query"}}`)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.StartTLS()
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, srv.Client())
+
+	err := client.Preconnect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Do(context.Background(), "{__typename}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&conns); got != 1 {
+		t.Errorf("got %v connections, want: 1", got)
+	}
+}
+
+func TestWithPreconnect(t *testing.T) {
+	pinged := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		pinged <- mustRead(req.Body)
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"__typename": "Query"}}`)
+	}))
+	defer srv.Close()
+	graphql.NewClient(srv.URL, nil, graphql.WithPreconnect(true))
+	select {
+	case got := <-pinged:
+		if want := `{"query":"{__typename}"}` + "\n"; got != want {
+			t.Errorf("got body: %q, want: %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("client didn't ping the server")
+	}
+}
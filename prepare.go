@@ -0,0 +1,59 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+)
+
+// PreparedOperation is an operation as a client sends it.
+type PreparedOperation struct {
+	Type  string // "query" or "mutation".
+	Name  string // Name of the operation, or "" if anonymous.
+	Query string // Document, as sent.
+
+	// Variables are the variables as sent, once the ones of graphql-extend
+	// fields are expanded into one per alias, such as "$id__0__id", and
+	// values are marshaled as they are for sending.
+	Variables map[string]interface{}
+}
+
+// Prepare constructs the operation op as Query or Mutate would send it
+// with opts, without sending it, so that tools can log, lint, hash or
+// persist the exact operation. op.Name, if set, names the operation, as
+// WithOperationName does. It fails as Query and Mutate would before
+// sending it, such as if the document is larger than WithMaxQuerySize
+// allows, or isn't in the allow list of WithAllowList.
+func (c *Client) Prepare(ctx context.Context, op Operation, opts ...RequestOption) (PreparedOperation, error) {
+	typ := op.Type
+	if typ == "" {
+		typ = "query"
+	}
+	if typ != "query" && typ != "mutation" {
+		return PreparedOperation{}, fmt.Errorf("can't prepare operation of type %q; only queries and mutations can be", op.Type)
+	}
+	if op.Name != "" {
+		opts = append(opts[:len(opts):len(opts)], WithOperationName(op.Name))
+	}
+	query, variables, err := c.construct(ctx, typ, op.V, op.Variables, opts)
+	if err != nil {
+		return PreparedOperation{}, err
+	}
+	err = c.checkQuery(query)
+	if err != nil {
+		return PreparedOperation{}, err
+	}
+	variables, err = c.transformVariables(ctx, query, variables)
+	if err != nil {
+		return PreparedOperation{}, err
+	}
+	variables, err = sentVariables(variables)
+	if err != nil {
+		return PreparedOperation{}, err
+	}
+	return PreparedOperation{
+		Type:      typ,
+		Name:      operationName(query),
+		Query:     query,
+		Variables: variables,
+	}, nil
+}
@@ -0,0 +1,77 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestClient_Prepare(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user__0": {"login": "a"}, "user__1": {"login": "b"}}}`)
+	})
+	var sentQuery string
+	var sentVariables map[string]interface{}
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRequestLogger(func(ctx context.Context, operation, query string, variables map[string]interface{}) {
+			sentQuery, sentVariables = query, variables
+		}))
+
+	var q struct {
+		Users []struct {
+			Login graphql.String
+		} `graphql:"user(login: $login)" graphql-extend:"true"`
+	}
+	variables := map[string]interface{}{
+		"user": []map[string]interface{}{
+			{"login": graphql.String("a")},
+			{"login": graphql.String("b")},
+		},
+	}
+	op, err := client.Prepare(context.Background(), graphql.Operation{Name: "GetUsers", V: &q, Variables: variables})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := graphql.PreparedOperation{
+		Type:  "query",
+		Name:  "GetUsers",
+		Query: `query GetUsers($user__0__login:String!$user__1__login:String!){user__0:user(login: $user__0__login){login},user__1:user(login: $user__1__login){login}}`,
+		Variables: map[string]interface{}{
+			"user__0__login": graphql.String("a"),
+			"user__1__login": graphql.String("b"),
+		},
+	}
+	if !reflect.DeepEqual(op, want) {
+		t.Errorf("got operation: %#v, want: %#v", op, want)
+	}
+
+	_, err = client.Query(context.Background(), &q, variables, graphql.WithOperationName("GetUsers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sentQuery != op.Query || !reflect.DeepEqual(sentVariables, op.Variables) {
+		t.Errorf("got sent operation: %q %v, want the prepared one: %q %v", sentQuery, sentVariables, op.Query, op.Variables)
+	}
+
+	var m struct {
+		AddStar struct {
+			Starred graphql.Boolean
+		} `graphql:"addStar(id: $id)"`
+	}
+	op, err = client.Prepare(context.Background(), graphql.Operation{Type: "mutation", V: &m, Variables: map[string]interface{}{"id": graphql.ID("R_1")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := op.Query, `mutation($id:ID!){addStar(id: $id){starred}}`; op.Type != "mutation" || op.Name != "" || got != want {
+		t.Errorf("got operation: %+v, want an anonymous mutation %q", op, want)
+	}
+
+	if _, err := client.Prepare(context.Background(), graphql.Operation{Type: "subscription", V: &q}); err == nil {
+		t.Error("got no error for a subscription")
+	}
+}
@@ -0,0 +1,87 @@
+package graphql
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// ProgressFunc is called as the body of a response is received, with the
+// number of bytes received so far, and the total size of the body, which
+// is -1 if the server didn't send a Content-Length.
+type ProgressFunc func(received, total int64)
+
+// WithProgress makes the client call f as the body of the response to the
+// request is received, so that long-running jobs can report progress on
+// large responses. f is called from the goroutine making the request.
+func WithProgress(f ProgressFunc) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.progress = f
+	}
+}
+
+// ErrResponseStalled is returned when no bytes of the body of a response
+// are received for longer than the stall timeout set with WithStallTimeout.
+var ErrResponseStalled = errors.New("response stalled")
+
+// WithStallTimeout makes the request fail with ErrResponseStalled if no
+// bytes of the body of its response are received for d. Unlike a timeout
+// on the whole request, it doesn't fail large responses that take long but
+// keep arriving. The time the server takes to start responding isn't
+// limited by it.
+func WithStallTimeout(d time.Duration) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.stallTimeout = d
+	}
+}
+
+// progressReader reads the body of a response from r, reporting progress
+// and detecting stalls according to the request's configuration.
+type progressReader struct {
+	r        io.Reader
+	received int64
+	total    int64 // -1 if unknown.
+	progress ProgressFunc
+
+	stallTimeout time.Duration
+	timer        *time.Timer // Fires when the response stalls, if non-nil.
+	stalled      int32       // Set to 1 once the response stalled.
+}
+
+// newProgressReader returns a progressReader for the response body r of
+// size total, which calls abort to abort the request if it stalls.
+// The returned stop function must be called once it's done.
+func newProgressReader(r io.Reader, total int64, cfg *requestConfig, abort func()) (pr *progressReader, stop func()) {
+	pr = &progressReader{
+		r:            r,
+		total:        total,
+		progress:     cfg.progress,
+		stallTimeout: cfg.stallTimeout,
+	}
+	if pr.stallTimeout <= 0 {
+		return pr, func() {}
+	}
+	pr.timer = time.AfterFunc(pr.stallTimeout, func() {
+		atomic.StoreInt32(&pr.stalled, 1)
+		abort()
+	})
+	return pr, func() { pr.timer.Stop() }
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	if n > 0 {
+		pr.received += int64(n)
+		if pr.timer != nil {
+			pr.timer.Reset(pr.stallTimeout)
+		}
+		if pr.progress != nil {
+			pr.progress(pr.received, pr.total)
+		}
+	}
+	if err != nil && err != io.EOF && atomic.LoadInt32(&pr.stalled) == 1 {
+		err = ErrResponseStalled
+	}
+	return n, err
+}
@@ -0,0 +1,62 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestWithProgress(t *testing.T) {
+	const body = `{"data": {"viewer": {"login": "gopher"}}}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		mustWrite(w, body[:10])
+		w.(http.Flusher).Flush()
+		time.Sleep(10 * time.Millisecond)
+		mustWrite(w, body[10:])
+	}))
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil)
+
+	var received []int64
+	_, err := client.Do(context.Background(), "{viewer{login}}", nil, graphql.WithProgress(func(n, total int64) {
+		if total != int64(len(body)) {
+			t.Errorf("got total: %v, want: %v", total, len(body))
+		}
+		received = append(received, n)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received) < 2 || received[0] != 10 || received[len(received)-1] != int64(len(body)) {
+		t.Errorf("got progress: %v", received)
+	}
+}
+
+func TestWithStallTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": `)
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-req.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	client := graphql.NewClient(srv.URL, nil)
+
+	_, err := client.Do(context.Background(), "{viewer{login}}", nil, graphql.WithStallTimeout(50*time.Millisecond))
+	if !errors.Is(err, graphql.ErrResponseStalled) {
+		t.Errorf("got error: %v, want: %v", err, graphql.ErrResponseStalled)
+	}
+}
@@ -8,12 +8,40 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"unicode"
 
 	"github.com/merico-dev/graphql/ident"
+
+	"github.com/merico-dev/graphql/internal/jsonutil"
 )
 
-func ConstructQuery(v interface{}, variables map[string]interface{}) (string, map[string]interface{}) {
-	query := query(v, variables)
+// ConstructQuery constructs a query document from the query struct v and
+// its variables, and returns it along with the variables to send, which
+// differ from variables if v has graphql-extend fields. It returns an error
+// if v isn't a struct, has fields that can't be selected, or references
+// variables missing from variables in its tags.
+func ConstructQuery(v interface{}, variables map[string]interface{}) (string, map[string]interface{}, error) {
+	return constructQuery(v, variables, constructOptions{})
+}
+
+// constructOptions is how documents are constructed from operation structs,
+// besides the defaults of ConstructQuery and ConstructMutation.
+type constructOptions struct {
+	mask      *fieldMask // Fields to select, if non-nil. See WithFieldMask.
+	comments  bool       // Precede fields by comments with their Go paths. See WithFieldPathComments.
+	typenames bool       // Select __typename in every nested selection set. See WithAutoTypename.
+}
+
+// constructQuery is ConstructQuery, constructing the document with opts.
+func constructQuery(v interface{}, variables map[string]interface{}, opts constructOptions) (string, map[string]interface{}, error) {
+	err := checkOperation(v, variables)
+	if err != nil {
+		return "", nil, err
+	}
+	query, err := query(v, variables, opts)
+	if err != nil {
+		return "", nil, err
+	}
 	if len(variables) > 0 {
 		newVariables := map[string]interface{}{}
 		for k, v := range variables {
@@ -27,22 +55,161 @@ func ConstructQuery(v interface{}, variables map[string]interface{}) (string, ma
 				newVariables[k] = v
 			}
 		}
-		return "query(" + queryArguments(newVariables) + ")" + query, newVariables
+		return "query(" + queryArguments(newVariables) + ")" + query, newVariables, nil
 	}
-	return query, variables
+	return query, variables, nil
+}
+
+// ConstructMutation constructs a mutation document from the mutation
+// struct v and its variables. It returns an error if v isn't a struct,
+// has fields that can't be selected, or references variables missing from
+// variables in its tags.
+func ConstructMutation(v interface{}, variables map[string]interface{}) (string, error) {
+	return constructMutation(v, variables, constructOptions{})
 }
 
-func ConstructMutation(v interface{}, variables map[string]interface{}) string {
-	query := query(v, variables)
+// constructMutation is ConstructMutation, constructing the document with opts.
+func constructMutation(v interface{}, variables map[string]interface{}, opts constructOptions) (string, error) {
+	err := checkOperation(v, variables)
+	if err != nil {
+		return "", err
+	}
+	query, err := query(v, variables, opts)
+	if err != nil {
+		return "", err
+	}
 	if len(variables) > 0 {
-		return "mutation(" + queryArguments(variables) + ")" + query
+		return "mutation(" + queryArguments(variables) + ")" + query, nil
 	}
-	return "mutation" + query
+	return "mutation" + query, nil
+}
+
+// ConstructSubscription constructs a subscription document from the
+// subscription struct v and its variables. It returns an error if v isn't
+// a struct, has fields that can't be selected, or references variables
+// missing from variables in its tags.
+func ConstructSubscription(v interface{}, variables map[string]interface{}) (string, error) {
+	err := checkOperation(v, variables)
+	if err != nil {
+		return "", err
+	}
+	query, err := query(v, variables, constructOptions{})
+	if err != nil {
+		return "", err
+	}
+	if len(variables) > 0 {
+		return "subscription(" + queryArguments(variables) + ")" + query, nil
+	}
+	return "subscription" + query, nil
+}
+
+// sortedNames returns the names in the set names, sorted.
+func sortedNames(names map[string]bool) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// checkSelection returns an error if the field at path, of type t, can't be
+// selected by writeQuery: if t is a kind that has no JSON representation,
+// such as a channel or func, or a struct with no fields to select.
+// inline reports whether the fields of t are inlined into the parent
+// struct. Maps are selected as JSON scalars.
+func checkSelection(path string, t reflect.Type, inline bool, visiting map[reflect.Type]bool) error {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if decodedWhole(t) {
+			return nil
+		}
+		return checkSelection(path, t.Elem(), false, visiting)
+	case reflect.Ptr:
+		return checkSelection(path, t.Elem(), false, visiting)
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return fmt.Errorf("field %s has type %v, which can't be selected", path, t)
+	case reflect.Struct:
+		if decodedWhole(t) || visiting[t] {
+			return nil
+		}
+		if t.NumField() == 0 && !inline {
+			return fmt.Errorf("field %s has an empty selection set; select at least one field", path)
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			_, _, tagged := jsonutil.LookupTag(f.Tag)
+			_, spread := fragmentSpread(f)
+			err := checkSelection(joinPath(path, f.Name), f.Type, f.Anonymous && !tagged && !spread, visiting)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// operationType returns the type of the operation in the GraphQL document
+// query: "query", "mutation", or "subscription". Documents starting with
+// a selection set are queries.
+func operationType(query string) string {
+	query = strings.TrimLeftFunc(query, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ','
+	})
+	for _, t := range [...]string{"mutation", "subscription"} {
+		if strings.HasPrefix(query, t) {
+			return t
+		}
+	}
+	return "query"
+}
+
+// operationName returns the name of the operation in the GraphQL document
+// query, or "" if it's anonymous.
+//
+// E.g., "query GetViewer($a:Int!){viewer{login}}" -> "GetViewer".
+func operationName(query string) string {
+	query = strings.TrimLeftFunc(query, unicode.IsSpace)
+	t := operationType(query)
+	if !strings.HasPrefix(query, t) {
+		return ""
+	}
+	query = strings.TrimLeftFunc(query[len(t):], unicode.IsSpace)
+	end := strings.IndexFunc(query, func(r rune) bool {
+		return !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+	if end == -1 {
+		end = len(query)
+	}
+	return query[:end]
+}
+
+// nameOperation returns the GraphQL document doc with its operation named
+// name, if the operation is anonymous, and doc otherwise.
+//
+// E.g., "query($a:Int!){viewer{login}}" -> "query GetViewer($a:Int!){viewer{login}}".
+func nameOperation(doc, name string) string {
+	trimmed := strings.TrimLeftFunc(doc, unicode.IsSpace)
+	if strings.HasPrefix(trimmed, "{") {
+		return "query " + name + trimmed
+	}
+	t := operationType(trimmed)
+	if !strings.HasPrefix(trimmed, t) || operationName(trimmed) != "" {
+		return doc
+	}
+	rest := strings.TrimLeftFunc(trimmed[len(t):], unicode.IsSpace)
+	if rest == "" || !strings.ContainsRune("({@", rune(rest[0])) {
+		return doc
+	}
+	return t + " " + name + rest
 }
 
 // queryArguments constructs a minified arguments string for variables.
 //
 // E.g., map[string]interface{}{"a": Int(123), "b": NewBoolean(true)} -> "$a:Int!$b:Boolean".
+// Variable values with a default value are declared with it. E.g., "$first:Int=10".
 func queryArguments(variables map[string]interface{}) string {
 	// Sort keys in order to produce deterministic output for testing purposes.
 	// TODO: If tests can be made to work with non-deterministic output, then no need to sort.
@@ -57,7 +224,10 @@ func queryArguments(variables map[string]interface{}) string {
 		io.WriteString(&buf, "$")
 		io.WriteString(&buf, k)
 		io.WriteString(&buf, ":")
-		writeArgumentType(&buf, reflect.TypeOf(variables[k]), true)
+		writeVariableType(&buf, variables[k])
+		if v, ok := variables[k].(Variable); ok && v.Default != "" {
+			io.WriteString(&buf, "="+v.Default)
+		}
 		// Don't insert a comma here.
 		// Commas in GraphQL are insignificant, and we want minified output.
 		// See https://facebook.github.io/graphql/October2016/#sec-Insignificant-Commas.
@@ -65,15 +235,44 @@ func queryArguments(variables map[string]interface{}) string {
 	return buf.String()
 }
 
+// writeVariableType writes a minified GraphQL type for the variable value v to w.
+func writeVariableType(w io.Writer, v interface{}) {
+	switch v := v.(type) {
+	case InputObject:
+		io.WriteString(w, v.Type)
+	case Variable:
+		io.WriteString(w, v.Type)
+	case GraphQLMarshaler:
+		// Errors are reported when the value is marshaled to be sent.
+		typ, _, _ := v.MarshalGraphQL()
+		io.WriteString(w, typ)
+	default:
+		writeArgumentType(w, reflect.TypeOf(v), true)
+	}
+}
+
 // writeArgumentType writes a minified GraphQL type for t to w.
 // value indicates whether t is a value (required) type or pointer (optional) type.
 // If value is true, then "!" is written at the end of t.
 func writeArgumentType(w io.Writer, t reflect.Type, value bool) {
+	if t.Implements(optionalValueType) {
+		// Optional is a nullable type, so no "!" at the end of its underlying type.
+		writeArgumentType(w, reflect.Zero(t).Interface().(optionalValue).optionalType(), false)
+		return
+	}
 	if t.Kind() == reflect.Ptr {
 		// Pointer is an optional type, so no "!" at the end of the pointer's underlying type.
 		writeArgumentType(w, t.Elem(), false)
 		return
 	}
+	if typ, ok := registeredType(t); ok {
+		// Registered types carry their own nullability; pointers are nullable.
+		if !value {
+			typ = strings.TrimSuffix(typ, "!")
+		}
+		io.WriteString(w, typ)
+		return
+	}
 
 	switch t.Kind() {
 	case reflect.Slice, reflect.Array:
@@ -96,79 +295,217 @@ func writeArgumentType(w io.Writer, t reflect.Type, value bool) {
 	}
 }
 
-// query uses writeQuery to recursively construct
-// a minified query string from the provided struct v.
+// query returns a minified query string constructed from the provided
+// struct v, followed by the definitions of any named fragments spread in
+// it. It's constructed by writeQuery once per query plan, and reused after.
+// It's constructed with opts, and an error is returned if its field mask
+// doesn't apply to v.
 //
 // E.g., struct{Foo Int, BarBaz *Boolean} -> "{foo,barBaz}".
-func query(v interface{}, variables map[string]interface{}) string {
-	var buf bytes.Buffer
-	writeQuery(&buf, reflect.TypeOf(v), false, variables)
-	return buf.String()
+func query(v interface{}, variables map[string]interface{}, opts constructOptions) (string, error) {
+	return planFor(reflect.TypeOf(v)).query(variables, opts)
 }
 
 // writeQuery writes a minified query for t to w.
 // If inline is true, the struct fields of t are inlined into parent struct.
-func writeQuery(w io.Writer, t reflect.Type, inline bool, variables map[string]interface{}) {
+// Named fragments spread in the query are added to fragments.
+// If mask is non-nil, only the fields it selects are written.
+// path is the Go path of t, for the comments written with
+// fragmentDefinitions.comments. It reports whether t is a scalar or
+// any of its fields were written, rather than its selection is empty.
+func writeQuery(w io.Writer, t reflect.Type, inline bool, variables map[string]interface{}, fragments *fragmentDefinitions, mask *fieldMask, path string) bool {
 	switch t.Kind() {
-	case reflect.Ptr, reflect.Slice:
-		writeQuery(w, t.Elem(), false, variables)
+	case reflect.Slice, reflect.Array:
+		// If the type implements json.Unmarshaler, such as OrderedMap,
+		// it's decoded as a whole. Don't expand it.
+		if decodedWhole(t) {
+			return true
+		}
+		return writeQuery(w, t.Elem(), false, variables, fragments, mask, path)
+	case reflect.Ptr:
+		return writeQuery(w, t.Elem(), false, variables, fragments, mask, path)
 	case reflect.Struct:
 		// If the type implements json.Unmarshaler, it's a scalar. Don't expand it.
-		if reflect.PtrTo(t).Implements(jsonUnmarshaler) {
-			return
+		if decodedWhole(t) {
+			return true
 		}
 		if !inline {
 			io.WriteString(w, "{")
 		}
+		written := false
+		if fragments.typenames && !inline && path != "" && !selectsTypename(t) {
+			io.WriteString(w, "__typename")
+			written = true
+		}
 		for i := 0; i < t.NumField(); i++ {
-			if i != 0 {
-				io.WriteString(w, ",")
-			}
-			f := t.Field(i)
-			value, ok := f.Tag.Lookup("graphql")
-			inlineField := f.Anonymous && !ok
-			graphqlValue := ``
-			graphqlVar := ``
-			if !inlineField {
-				if ok {
-					graphqlValue = value
-					index := strings.IndexAny(graphqlValue, `(:[$!@`)
-					if index == -1 {
-						graphqlVar = graphqlValue
-					} else {
-						graphqlVar = graphqlValue[:index]
-					}
-				} else {
-					graphqlValue = ident.ParseMixedCaps(f.Name).ToLowerCamelCase()
-					graphqlVar = value
+			if mask == nil {
+				if written || i != 0 {
+					io.WriteString(w, ",")
 				}
+				writeField(w, t.Field(i), variables, fragments, nil, path)
+				written = true
+				continue
 			}
+			// Fields may be masked out entirely, and so may all the
+			// fields of inline fragments, which are then left out.
+			var field bytes.Buffer
+			if !writeField(&field, t.Field(i), variables, fragments, mask, path) {
+				continue
+			}
+			if written {
+				io.WriteString(w, ",")
+			}
+			w.Write(field.Bytes())
+			written = true
+		}
+		if !inline {
+			io.WriteString(w, "}")
+		}
+		return written
+	default:
+		return true
+	}
+}
 
-			extendByKey, ifExtend := f.Tag.Lookup("graphql-extend")
-			if ifExtend && extendByKey == `true` {
-				times := len(variables[graphqlVar].([]map[string]interface{}))
-				for i := 0; i < times; i++ {
-					if i != 0 {
-						io.WriteString(w, ",")
-					}
-					io.WriteString(w, fmt.Sprintf(`%s__%d:`, graphqlVar, i))
-					if !inlineField {
-						io.WriteString(w, strings.ReplaceAll(graphqlValue, `$`, fmt.Sprintf(`$%s__%d__`, graphqlVar, i)))
-					}
-					writeQuery(w, f.Type, inlineField, variables)
-				}
-
+// writeField writes the selection of the struct field f, of the struct
+// at the Go path path, to w. If mask is non-nil, f is written only if it
+// selects it. It reports whether f was written with a non-empty selection,
+// as writeQuery does.
+func writeField(w io.Writer, f reflect.StructField, variables map[string]interface{}, fragments *fragmentDefinitions, mask *fieldMask, path string) bool {
+	path = joinPath(path, f.Name)
+	if on, ok := fragmentSpread(f); ok {
+		if mask != nil {
+			// Named fragments are defined once for all their spreads,
+			// so masked selections of them are written inline instead.
+			io.WriteString(w, "... on "+on)
+			return writeQuery(w, f.Type, false, variables, fragments, mask, path)
+		}
+		name := fragments.define(f.Type, on, variables)
+		io.WriteString(w, "..."+name)
+		return true
+	}
+	value, required, ok := jsonutil.LookupTag(f.Tag)
+	inlineField := f.Anonymous && !ok
+	graphqlValue := ``
+	graphqlVar := ``
+	if !inlineField {
+		if ok {
+			graphqlValue = value
+			index := strings.IndexAny(graphqlValue, `(:[$!@{`)
+			if index == -1 {
+				graphqlVar = graphqlValue
 			} else {
-				if !inlineField {
-					io.WriteString(w, graphqlValue)
-				}
-				writeQuery(w, f.Type, inlineField, variables)
+				graphqlVar = graphqlValue[:index]
 			}
+		} else {
+			graphqlValue = ident.ParseMixedCaps(f.Name).ToLowerCamelCase()
+			graphqlVar = value
+		}
+	}
 
+	if mask != nil && !inlineField && !strings.HasPrefix(graphqlValue, "...") {
+		key := graphqlVar
+		if !ok {
+			key = graphqlValue
 		}
-		if !inline {
-			io.WriteString(w, "}")
+		var selected bool
+		mask, selected = mask.field(strings.TrimSpace(key), required)
+		if !selected {
+			return false
+		}
+	}
+
+	if fragments.comments && !inlineField {
+		io.WriteString(w, "#"+path+"\n")
+	}
+
+	extendByKey, ifExtend := f.Tag.Lookup("graphql-extend")
+	if ifExtend && extendByKey == `true` {
+		times := len(variables[graphqlVar].([]map[string]interface{}))
+		written := false
+		for i := 0; i < times; i++ {
+			if i != 0 {
+				io.WriteString(w, ",")
+			}
+			io.WriteString(w, fmt.Sprintf(`%s__%d:`, graphqlVar, i))
+			if !inlineField {
+				io.WriteString(w, strings.ReplaceAll(graphqlValue, `$`, fmt.Sprintf(`$%s__%d__`, graphqlVar, i)))
+			}
+			written = writeQuery(w, f.Type, inlineField, variables, fragments, mask, path)
 		}
+		return written
+	}
+	if !inlineField {
+		io.WriteString(w, graphqlValue)
+	}
+	return writeQuery(w, f.Type, inlineField, variables, fragments, mask, path)
+}
+
+// selectsTypename reports whether the struct t has a field
+// selecting __typename, without an alias.
+func selectsTypename(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if value, _, _ := jsonutil.LookupTag(t.Field(i).Tag); strings.TrimSpace(value) == "__typename" {
+			return true
+		}
+	}
+	return false
+}
+
+// fragmentSpread reports whether struct field f is spread as a named
+// fragment, and returns the type condition of the fragment if so.
+//
+// Embedded structs with a graphql-fragment tag and no graphql tag are
+// spread as a fragment named after their Go type, with the tag value as
+// the type condition. E.g., an embedded ActorFields struct tagged with
+// `graphql-fragment:"User"` is selected as "...ActorFields", and defined
+// once as "fragment ActorFields on User{...}".
+func fragmentSpread(f reflect.StructField) (string, bool) {
+	on, ok := f.Tag.Lookup("graphql-fragment")
+	if _, tagged := f.Tag.Lookup("graphql"); !ok || tagged || !f.Anonymous {
+		return "", false
+	}
+	return strings.TrimSpace(on), true
+}
+
+// fragmentDefinitions collects the definitions of the named fragments
+// spread in a document, so each is defined once.
+type fragmentDefinitions struct {
+	names       []string          // Fragment names, in order of definition.
+	definitions map[string]string // Fragment definitions, keyed by name.
+
+	// How the fragments and the document they're spread in are written.
+	// See constructOptions.
+	comments  bool
+	typenames bool
+}
+
+// define defines the fragment on the type condition on for the struct t,
+// if it's not defined already, and returns its name.
+func (fs *fragmentDefinitions) define(t reflect.Type, on string, variables map[string]interface{}) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	name := t.Name()
+	if _, ok := fs.definitions[name]; ok {
+		return name
+	}
+	if fs.definitions == nil {
+		fs.definitions = make(map[string]string)
+	}
+	fs.definitions[name] = "" // Reserve the name before recursing into the fragment.
+	var buf bytes.Buffer
+	io.WriteString(&buf, "fragment "+name+" on "+on)
+	writeQuery(&buf, t, false, variables, fs, nil, name)
+	fs.definitions[name] = buf.String()
+	fs.names = append(fs.names, name)
+	return name
+}
+
+// writeTo writes the fragment definitions to w.
+func (fs *fragmentDefinitions) writeTo(w io.Writer) {
+	for _, name := range fs.names {
+		io.WriteString(w, fs.definitions[name])
 	}
 }
 
@@ -3,6 +3,7 @@ package graphql
 import (
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -31,6 +32,31 @@ func TestConstructQuery(t *testing.T) {
 			}{},
 			want: `{viewer{login,createdAt,id,databaseId},rateLimit{cost,limit,remaining,resetAt}}`,
 		},
+		{
+			inV: struct {
+				A [2]struct {
+					Name String
+				} `graphql:"a: user(login: \"a\")"`
+				B *[1]*struct {
+					Name String
+				} `graphql:"b: nodes(ids: [1])"`
+			}{},
+			want: `{a: user(login: "a"){name},b: nodes(ids: [1]){name}}`,
+		},
+		{
+			inV: struct {
+				Repository struct {
+					Name  String `graphql:"name,required"`
+					Owner struct {
+						Login String `graphql:"login,required"`
+					} `graphql:"owner,required"`
+					Issues []struct {
+						Number Int
+					} `graphql:"issues(first: 1, states: [OPEN, CLOSED]),required"`
+				} `graphql:"repository(owner: \"o\", name: \"n\")"`
+			}{},
+			want: `{repository(owner: "o", name: "n"){name,owner{login},issues(first: 1, states: [OPEN, CLOSED]){number}}}`,
+		},
 		{
 			inV: struct {
 				Repository struct {
@@ -255,9 +281,60 @@ func TestConstructQuery(t *testing.T) {
 				"user__2__name": String("c"),
 			},
 		},
+		// Embedded structs are inlined, unless tagged as a field or fragment.
+		{
+			inV: struct {
+				Issue struct {
+					ActorFields
+					Author ActorFields
+					Editor struct {
+						ActorFields `graphql:"... on User"`
+					}
+				}
+				ActorFields `graphql:"viewer"`
+			}{},
+			want: `{issue{login,author{login},editor{... on User{login}}},viewer{login}}`,
+		},
+		// Embedded structs tagged with graphql-fragment are spread as named fragments.
+		{
+			inV: struct {
+				Node struct {
+					ID          ID
+					ActorFields `graphql-fragment:"User"`
+				} `graphql:"node(id: $id)"`
+				Viewer struct {
+					ActorFields `graphql-fragment:"User"`
+				}
+			}{},
+			inVariables: map[string]interface{}{"id": ID("1")},
+			want:        `query($id:ID!){node(id: $id){id,...ActorFields},viewer{...ActorFields}}fragment ActorFields on User{login}`,
+		},
+		{
+			inV: struct {
+				Repository struct {
+					Name     String `graphql:"name @skip(if: $skipName)"`
+					Comments []struct {
+						Body String
+					} `graphql:"comments(first: $first) @include(if: $withComments)"`
+					Owner struct {
+						Login String
+					} `graphql:"owner: viewer @include(if: true)"`
+				} `graphql:"repository(owner: \"o\", name: \"n\")"`
+			}{},
+			inVariables: map[string]interface{}{
+				"first":        Int(10),
+				"skipName":     Boolean(false),
+				"withComments": Boolean(true),
+			},
+			want: `query($first:Int!$skipName:Boolean!$withComments:Boolean!){repository(owner: "o", name: "n"){name @skip(if: $skipName),comments(first: $first) @include(if: $withComments){body},owner: viewer @include(if: true){login}}}`,
+		},
 	}
 	for _, tc := range tests {
-		gotQuery, gotVariables := ConstructQuery(tc.inV, tc.inVariables)
+		gotQuery, gotVariables, err := ConstructQuery(tc.inV, tc.inVariables)
+		if err != nil {
+			t.Errorf("got error: %v", err)
+			continue
+		}
 		if gotQuery != tc.want {
 			t.Errorf("\ngot:  %q\nwant: %q\n", gotQuery, tc.want)
 		}
@@ -267,6 +344,260 @@ func TestConstructQuery(t *testing.T) {
 	}
 }
 
+func TestConstructQuery_errors(t *testing.T) {
+	type empty struct{}
+	tests := []struct {
+		inV  interface{}
+		want string
+	}{
+		{inV: nil, want: "can't construct a document from nil"},
+		{inV: "viewer", want: "can't construct a document from string; want a struct or pointer to struct"},
+		{inV: new([]int), want: "can't construct a document from *[]int; want a struct or pointer to struct"},
+		{
+			inV: struct {
+				Viewer struct {
+					Login  String
+					Events chan int
+				}
+			}{},
+			want: "field Viewer.Events has type chan int, which can't be selected",
+		},
+		{
+			inV: &struct {
+				Nodes []struct {
+					OnClick func()
+				}
+			}{},
+			want: "field Nodes.OnClick has type func(), which can't be selected",
+		},
+		{inV: &empty{}, want: "*graphql.empty has no fields to select"},
+		{
+			inV: struct {
+				Viewer empty
+			}{},
+			want: "field Viewer has an empty selection set; select at least one field",
+		},
+	}
+	for _, tc := range tests {
+		_, _, err := ConstructQuery(tc.inV, nil)
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("ConstructQuery(%T): got error: %v, want: %v", tc.inV, err, tc.want)
+		}
+	}
+	// Maps are selected as JSON scalars, and embedded empty structs add nothing.
+	_, _, err := ConstructQuery(struct {
+		Settings map[string]interface{}
+		empty
+	}{}, nil)
+	if err != nil {
+		t.Errorf("got error: %v", err)
+	}
+}
+
+func TestConstructQuery_missingVariables(t *testing.T) {
+	tests := []struct {
+		inV         interface{}
+		inVariables map[string]interface{}
+		want        string
+	}{
+		{
+			inV: struct {
+				Repository struct {
+					Issue struct {
+						Title String
+					} `graphql:"issue(number: $issueNumber)"`
+				} `graphql:"repository(owner: $repositoryOwner, name: $repositoryName)"`
+			}{},
+			inVariables: map[string]interface{}{
+				"repositoryOwner": String("shurcooL"),
+				"repositoryName":  String("githubv4"),
+			},
+			want: "field Repository.Issue references variable $issueNumber, which is missing from the variables",
+		},
+		{
+			inV: struct {
+				Users []struct {
+					Login String
+				} `graphql:"users(id: $id)" graphql-extend:"true"`
+			}{},
+			inVariables: map[string]interface{}{
+				"users": []map[string]interface{}{{"id": ID("1")}, {}},
+			},
+			want: "field Users references variable $id, which is missing from element 1 of variable $users",
+		},
+		{
+			inV: struct {
+				Users []struct {
+					Login String
+				} `graphql:"users(id: $id)" graphql-extend:"true"`
+			}{},
+			inVariables: map[string]interface{}{"users": Int(1)},
+			want:        "field Users is a graphql-extend field, so variable $users must be a []map[string]interface{}, not graphql.Int",
+		},
+		{
+			inV: struct {
+				Comments []struct {
+					Body String
+				} `graphql:"comments @include(if: $withComments)"`
+			}{},
+			want: "field Comments references variable $withComments, which is missing from the variables",
+		},
+	}
+	for _, tc := range tests {
+		_, _, err := ConstructQuery(tc.inV, tc.inVariables)
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("got error: %v, want: %v", err, tc.want)
+		}
+	}
+}
+
+// Documents constructed from the same type are reused, except for the
+// selections of graphql-extend fields, which depend on their variables.
+func TestConstructQuery_reused(t *testing.T) {
+	type user struct {
+		Login String
+	}
+	type query struct {
+		Users  []user `graphql:"user(name: $name)" graphql-extend:"true"`
+		Viewer user
+	}
+	tests := []struct {
+		names []string
+		want  string
+	}{
+		{[]string{"a"}, `query($user__0__name:String!){user__0:user(name: $user__0__name){login},viewer{login}}`},
+		{[]string{"a", "b"}, `query($user__0__name:String!$user__1__name:String!){user__0:user(name: $user__0__name){login},user__1:user(name: $user__1__name){login},viewer{login}}`},
+		{[]string{"c"}, `query($user__0__name:String!){user__0:user(name: $user__0__name){login},viewer{login}}`},
+	}
+	for i, tc := range tests {
+		var elems []map[string]interface{}
+		for _, name := range tc.names {
+			elems = append(elems, map[string]interface{}{"name": String(name)})
+		}
+		got, _, err := ConstructQuery(query{}, map[string]interface{}{"user": elems})
+		if err != nil {
+			t.Fatalf("test case %d: %v", i, err)
+		}
+		if got != tc.want {
+			t.Errorf("test case %d:\n got: %q\nwant: %q", i, got, tc.want)
+		}
+	}
+}
+
+// benchmarkQuery is a query struct with nested selections, aliases,
+// arguments and fragments, like those on hot paths.
+type benchmarkQuery struct {
+	Repository struct {
+		ID          ID
+		Name        String
+		Description String
+		Owner       struct {
+			ActorFields
+		}
+		Issues struct {
+			Nodes []struct {
+				Number    Int
+				Title     String
+				CreatedAt DateTime
+				Author    struct {
+					ActorFields `graphql:"... on User"`
+				}
+				Labels struct {
+					Nodes []struct {
+						Name  String
+						Color String
+					}
+				} `graphql:"labels(first: 10)"`
+			}
+			PageInfo struct {
+				EndCursor   String
+				HasNextPage Boolean
+			}
+		} `graphql:"issues(first: $first, after: $after)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+func BenchmarkConstructQuery(b *testing.B) {
+	variables := map[string]interface{}{
+		"owner": String("shurcooL"),
+		"name":  String("graphql"),
+		"first": Int(100),
+		"after": (*String)(nil),
+	}
+	for i := 0; i < b.N; i++ {
+		_, _, err := ConstructQuery(benchmarkQuery{}, variables)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkConstructQuery_uncached is the cost of constructing the same
+// document as BenchmarkConstructQuery by walking the type every time.
+func BenchmarkConstructQuery_uncached(b *testing.B) {
+	variables := map[string]interface{}{
+		"owner": String("shurcooL"),
+		"name":  String("graphql"),
+		"first": Int(100),
+		"after": (*String)(nil),
+	}
+	for i := 0; i < b.N; i++ {
+		queryPlans.Delete(reflect.TypeOf(benchmarkQuery{}))
+		_, _, err := ConstructQuery(benchmarkQuery{}, variables)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestQueryPlan_query(t *testing.T) {
+	type query struct {
+		Users []struct {
+			Login String
+		} `graphql:"user(name: $name)" graphql-extend:"true"`
+	}
+	p := planFor(reflect.TypeOf(query{}))
+	variables := func(n int) map[string]interface{} {
+		users := make([]map[string]interface{}, n)
+		for i := range users {
+			users[i] = map[string]interface{}{"name": String("a")}
+		}
+		return map[string]interface{}{"user": users}
+	}
+
+	// Documents constructed with different options are kept apart.
+	plain, err := p.query(variables(1), constructOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	typenames, err := p.query(variables(1), constructOptions{typenames: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain == typenames {
+		t.Errorf("got the same document with and without __typename: %s", plain)
+	}
+
+	// The documents kept per type are capped.
+	for n := 0; n < 2*maxPlanQueries; n++ {
+		q, err := p.query(variables(n), constructOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := strings.Count(q, ":user("), n; got != want {
+			t.Fatalf("got document with %d elements, want %d: %s", got, want, q)
+		}
+	}
+	kept := 0
+	p.queries.Range(func(key, value interface{}) bool {
+		kept++
+		return true
+	})
+	if kept != maxPlanQueries {
+		t.Errorf("got %d documents kept, want %d", kept, maxPlanQueries)
+	}
+}
+
 func TestConstructMutation(t *testing.T) {
 	tests := []struct {
 		inV         interface{}
@@ -295,13 +626,99 @@ func TestConstructMutation(t *testing.T) {
 		},
 	}
 	for _, tc := range tests {
-		got := ConstructMutation(tc.inV, tc.inVariables)
+		got, err := ConstructMutation(tc.inV, tc.inVariables)
+		if err != nil {
+			t.Errorf("got error: %v", err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("\ngot:  %q\nwant: %q\n", got, tc.want)
+		}
+	}
+}
+
+func TestConstructSubscription(t *testing.T) {
+	tests := []struct {
+		inV         interface{}
+		inVariables map[string]interface{}
+		want        string
+	}{
+		{
+			inV: struct {
+				Ticker struct {
+					Time DateTime
+				}
+			}{},
+			want: `subscription{ticker{time}}`,
+		},
+		{
+			inV: struct {
+				IssueUpdated struct {
+					Title String
+				} `graphql:"issueUpdated(number: $number)"`
+			}{},
+			inVariables: map[string]interface{}{
+				"number": Int(1),
+			},
+			want: `subscription($number:Int!){issueUpdated(number: $number){title}}`,
+		},
+	}
+	for _, tc := range tests {
+		got, err := ConstructSubscription(tc.inV, tc.inVariables)
+		if err != nil {
+			t.Errorf("got error: %v", err)
+			continue
+		}
 		if got != tc.want {
 			t.Errorf("\ngot:  %q\nwant: %q\n", got, tc.want)
 		}
 	}
 }
 
+func TestOperationName(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantType string
+		wantName string
+	}{
+		{in: `{viewer{login}}`, wantType: "query", wantName: ""},
+		{in: `query($a:Int!){viewer{login}}`, wantType: "query", wantName: ""},
+		{in: `query GetViewer{viewer{login}}`, wantType: "query", wantName: "GetViewer"},
+		{in: "\n  mutation AddStar($input:AddStarInput!) {addStar(input:$input){clientMutationId}}", wantType: "mutation", wantName: "AddStar"},
+		{in: `subscription{ticker{time}}`, wantType: "subscription", wantName: ""},
+	}
+	for _, tc := range tests {
+		if got := operationType(tc.in); got != tc.wantType {
+			t.Errorf("operationType(%q): got %q, want %q", tc.in, got, tc.wantType)
+		}
+		if got := operationName(tc.in); got != tc.wantName {
+			t.Errorf("operationName(%q): got %q, want %q", tc.in, got, tc.wantName)
+		}
+	}
+}
+
+func TestNamedOperationType(t *testing.T) {
+	const doc = `query GetViewer{viewer{login}} mutation AddStar($id:ID!){addStar(id:$id){count}} subscription
+		Ticker{ticker{time}} query GetViewerLogin{viewer{login}}`
+	tests := []struct {
+		name     string
+		wantType string
+		wantOK   bool
+	}{
+		{name: "GetViewer", wantType: "query", wantOK: true},
+		{name: "AddStar", wantType: "mutation", wantOK: true},
+		{name: "Ticker", wantType: "subscription", wantOK: true},
+		{name: "GetViewerLogin", wantType: "query", wantOK: true},
+		{name: "Missing", wantOK: false},
+	}
+	for _, tc := range tests {
+		got, ok := namedOperationType(doc, tc.name)
+		if got != tc.wantType || ok != tc.wantOK {
+			t.Errorf("namedOperationType(%q): got %q, %v, want %q, %v", tc.name, got, ok, tc.wantType, tc.wantOK)
+		}
+	}
+}
+
 func TestQueryArguments(t *testing.T) {
 	tests := []struct {
 		in   map[string]interface{}
@@ -344,6 +761,34 @@ func TestQueryArguments(t *testing.T) {
 			in:   map[string]interface{}{"ids": &[]ID{"someID", "anotherID"}},
 			want: `$ids:[ID!]`,
 		},
+		{
+			in:   map[string]interface{}{"matrix": [][]Int{{1, 2}, {3}}},
+			want: `$matrix:[[Int!]!]!`,
+		},
+		{
+			in: map[string]interface{}{
+				"a": []*[]Int{},
+				"b": (*[][]*Int)(nil),
+				"c": [][]Optional[Int]{},
+				"d": [2][3]*[]IssueState{},
+			},
+			want: `$a:[[Int!]]!$b:[[Int]!]$c:[[Int]!]!$d:[[[IssueState!]]!]!`,
+		},
+		{
+			in: map[string]interface{}{
+				"a": Variable{Type: "[String]!", Value: []string{"a"}},
+				"b": Variable{Type: "[String!]", Value: []string{"b"}},
+				"c": InputObject{Type: "Filter", Fields: nil},
+			},
+			want: `$a:[String]!$b:[String!]$c:Filter`,
+		},
+		{
+			in: map[string]interface{}{
+				"first":  Variable{Type: "Int", Default: "10", Value: (*Int)(nil)},
+				"states": Variable{Type: "[IssueState!]", Default: "[OPEN]"},
+			},
+			want: `$first:Int=10$states:[IssueState!]=[OPEN]`,
+		},
 	}
 	for i, tc := range tests {
 		got := queryArguments(tc.in)
@@ -364,6 +809,10 @@ type (
 
 func (u *URI) UnmarshalJSON(data []byte) error { panic("mock implementation") }
 
+type ActorFields struct {
+	Login String
+}
+
 // IssueState represents the possible states of an issue.
 type IssueState string
 
@@ -396,3 +845,119 @@ type AddReactionInput struct {
 	// A unique identifier for the client performing the mutation. (Optional.)
 	ClientMutationID *String `json:"clientMutationId,omitempty"`
 }
+
+func TestQueryPlan_registeredScalar(t *testing.T) {
+	type money struct {
+		Amount   Int
+		Currency String
+	}
+	type query struct {
+		Price money
+	}
+	q, _, err := ConstructQuery(query{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q, "{price{amount,currency}}"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+
+	// Registering the type as a scalar after documents were constructed
+	// from it stops it from being expanded.
+	RegisterScalar("Money", func(m money) (interface{}, error) {
+		return string(m.Currency), nil
+	}, func(data []byte) (money, error) {
+		return money{}, nil
+	})
+	q, _, err = ConstructQuery(query{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q, "{price}"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestQueryArguments_registeredType(t *testing.T) {
+	type issueFilters struct{ Labels []string }
+	type nullableFilters struct{ Labels []string }
+	RegisterType(issueFilters{}, "IssueFilters!")
+	RegisterType(nullableFilters{}, "IssueFilters")
+
+	tests := []struct {
+		in   map[string]interface{}
+		want string
+	}{
+		{
+			in:   map[string]interface{}{"filter": issueFilters{}, "optional": (*issueFilters)(nil)},
+			want: "$filter:IssueFilters!$optional:IssueFilters",
+		},
+		{
+			in:   map[string]interface{}{"filters": []issueFilters{}, "optional": &[]*issueFilters{}},
+			want: "$filters:[IssueFilters!]!$optional:[IssueFilters]",
+		},
+		{
+			in:   map[string]interface{}{"filters": []nullableFilters{}},
+			want: "$filters:[IssueFilters]!",
+		},
+	}
+	for i, tc := range tests {
+		got := queryArguments(tc.in)
+		if got != tc.want {
+			t.Errorf("test case %d:\n got: %q\nwant: %q", i, got, tc.want)
+		}
+	}
+}
+
+func TestNameOperation(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`{viewer{login}}`, `query Op{viewer{login}}`},
+		{`query($a:Int!){viewer{login}}`, `query Op($a:Int!){viewer{login}}`},
+		{`mutation($a:Int!){addStar{count}}`, `mutation Op($a:Int!){addStar{count}}`},
+		{"query {\n  viewer {\n    login\n  }\n}", "query Op{\n  viewer {\n    login\n  }\n}"},
+		{`query Named{viewer{login}}`, `query Named{viewer{login}}`},
+		{`query A{a} query B{b}`, `query A{a} query B{b}`},
+	}
+	for _, tc := range tests {
+		if got := nameOperation(tc.in, "Op"); got != tc.want {
+			t.Errorf("nameOperation(%q):\ngot:  %q\nwant: %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+type labelFilter struct{ Labels []string }
+
+func (labelFilter) GraphQLType() string { return "LabelFilter" }
+
+type milestoneFilter struct{ Title string }
+
+func (*milestoneFilter) GraphQLType() string { return "MilestoneFilter" }
+
+func TestQueryArguments_graphQLTyper(t *testing.T) {
+	tests := []struct {
+		in   map[string]interface{}
+		want string
+	}{
+		{
+			in:   map[string]interface{}{"filter": labelFilter{}, "optional": (*labelFilter)(nil)},
+			want: "$filter:LabelFilter!$optional:LabelFilter",
+		},
+		{
+			in:   map[string]interface{}{"filters": []labelFilter{}, "optional": &[]*labelFilter{}},
+			want: "$filters:[LabelFilter!]!$optional:[LabelFilter]",
+		},
+		{
+			in:   map[string]interface{}{"milestone": milestoneFilter{}, "milestones": []*milestoneFilter{}},
+			want: "$milestone:MilestoneFilter!$milestones:[MilestoneFilter]!",
+		},
+	}
+	for i, tc := range tests {
+		got := queryArguments(tc.in)
+		if got != tc.want {
+			t.Errorf("test case %d:\n got: %q\nwant: %q", i, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// BatchOperation is a query of a batch executed with QueryBatch.
+type BatchOperation struct {
+	Query     interface{}            // Pointer to query struct, as passed to Query.
+	Variables map[string]interface{} // Variables of Query.
+}
+
+// QueryBatch executes the independent queries ops in a single HTTP request,
+// whose body is the array of their requests, as understood by servers
+// supporting the common batching transport. The response of each query is
+// populated into its Query, so that many small queries cost one round trip.
+// The documents of the queries are constructed as by Query, with opts,
+// and the request is sent, retried and decoded like those of Query.
+//
+// QueryBatch returns the GraphQL errors of each query: errors[i] holds
+// the errors of the response to ops[i]. It returns an error if the server
+// doesn't respond with one response per query, such as because it doesn't
+// support batching. Responses to batches aren't cached.
+func (c *Client) QueryBatch(ctx context.Context, ops []BatchOperation, opts ...RequestOption) ([][]DataError, error) {
+	start := time.Now()
+	cfg, err := c.requestConfig(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	cfg.batch = make([]requestBody, len(ops))
+	for i, op := range ops {
+		query, variables, err := c.construct(ctx, "query", op.Query, op.Variables, opts)
+		if err != nil {
+			return nil, fmt.Errorf("query %d: %w", i, err)
+		}
+		err = c.checkQuery(query)
+		if err != nil {
+			return nil, fmt.Errorf("query %d: %w", i, err)
+		}
+		variables, err = c.transformVariables(ctx, query, variables)
+		if err != nil {
+			return nil, fmt.Errorf("query %d: %w", i, err)
+		}
+		variables, err = sentVariables(variables)
+		if err != nil {
+			return nil, fmt.Errorf("query %d: %w", i, err)
+		}
+		cfg.batch[i] = requestBody{Query: query, OperationName: cfg.operationName, Variables: variables, Extensions: cfg.extensions}
+	}
+	documents := make([]string, len(cfg.batch))
+	for i, in := range cfg.batch {
+		documents[i] = in.Query
+	}
+	ctx, done := c.trackStats(ctx, "", strings.Join(documents, "\n"), start)
+	resp, errs, err := c.queryBatch(ctx, ops, cfg)
+	done(resp, err)
+	return errs, err
+}
+
+// queryBatch sends the batch of cfg, and decodes the responses
+// into the queries of ops.
+func (c *Client) queryBatch(ctx context.Context, ops []BatchOperation, cfg *requestConfig) (*Response, [][]DataError, error) {
+	// The batch holds queries only, as the empty request body stands for.
+	resp, err := c.doRetrying(ctx, requestBody{}, func() (*Response, error) {
+		return c.failover(ctx, requestBody{}, cfg, func() (*Response, error) {
+			done := c.requestStats.start()
+			resp, err := c.send(ctx, requestBody{}, false, cfg)
+			done(err)
+			if c.responseFunc != nil {
+				c.responseFunc(resp, err)
+			}
+			return resp, err
+		})
+	})
+	if err != nil {
+		return resp, nil, err
+	}
+	errs := make([][]DataError, len(ops))
+	for i, resp := range cfg.responses {
+		err := c.decodeData(ctx, resp, ops[i].Query, cfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("query %d: %w", i, err)
+		}
+		errs[i] = resp.Errors
+	}
+	return resp, errs, nil
+}
+
+// newBatchRequest returns an HTTP request for the batch of GraphQL
+// requests ins, encoded in the client's wire format.
+func (c *Client) newBatchRequest(endpoint string, ins []requestBody) (*http.Request, error) {
+	contentType := "application/json"
+	var body []byte
+	var err error
+	if len(c.wireCodecs) > 0 {
+		contentType = c.wireCodecs[0].mediaType
+		body, err = marshalWire(c.wireCodecs[0].codec, ins)
+	} else {
+		body, err = c.marshal(ins)
+		body = bytes.TrimSuffix(body, []byte("\n"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return req, nil
+}
+
+// readBatch reads the responses to a batch of n requests from the body r
+// of the response out, whose Content-Type is ct, and returns them, in order.
+func (c *Client) readBatch(r io.Reader, ct string, out *Response, n int) ([]*Response, error) {
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	codec := c.wireCodec(ct)
+	if codec == nil && !isJSONContentType(ct) {
+		err := &ContentTypeError{ContentType: ct}
+		err.Body, err.Truncated = readTruncated(bytes.NewReader(body), maxNonJSONBody)
+		return nil, err
+	}
+	var envelopes []struct {
+		Data       json.RawMessage
+		Errors     dataErrors
+		Extensions map[string]json.RawMessage
+	}
+	if codec != nil {
+		err = unmarshalWire(codec, bytes.NewReader(body), &envelopes)
+	} else if trimmed := bytes.TrimSpace(body); len(trimmed) == 0 || trimmed[0] != '[' {
+		// A single response, such as an error of a server that
+		// doesn't support batching.
+		var envelope struct{ Errors dataErrors }
+		if json.Unmarshal(trimmed, &envelope) == nil && len(envelope.Errors) > 0 {
+			return nil, fmt.Errorf("server didn't respond to the batch with one response per query: %w", envelope.Errors[0])
+		}
+		return nil, errors.New("server didn't respond to the batch with one response per query")
+	} else {
+		err = c.unmarshal(bytes.NewReader(body), &envelopes)
+	}
+	if err != nil {
+		return nil, &DecodeError{Err: err}
+	}
+	if len(envelopes) != n {
+		return nil, fmt.Errorf("server responded to a batch of %d queries with %d responses", n, len(envelopes))
+	}
+	resps := make([]*Response, len(envelopes))
+	for i, e := range envelopes {
+		resps[i] = &Response{Header: out.Header, Status: out.Status, Extensions: e.Extensions}
+		if string(e.Data) != "null" {
+			resps[i].Data = e.Data
+		}
+		if len(e.Errors) > 0 {
+			resps[i].Errors = e.Errors
+		}
+	}
+	return resps, nil
+}
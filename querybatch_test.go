@@ -0,0 +1,120 @@
+package graphql_test
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestClient_QueryBatch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `[{"query":"query($id:ID!){node(id: $id){id}}","variables":{"id":"1"}},{"query":"{viewer{login}}"}]`; got != want {
+			t.Errorf("got body:\n%v\nwant:\n%v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `[
+			{"data": {"node": null}, "errors": [{"message": "not found", "path": ["node"]}]},
+			{"data": {"viewer": {"login": "gopher"}}}
+		]`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var node struct {
+		Node *struct {
+			ID graphql.ID
+		} `graphql:"node(id: $id)"`
+	}
+	var viewer struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	errs, err := client.QueryBatch(context.Background(), []graphql.BatchOperation{
+		{Query: &node, Variables: map[string]interface{}{"id": graphql.ID("1")}},
+		{Query: &viewer},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Node != nil {
+		t.Errorf("got node: %+v, want nil", node.Node)
+	}
+	if got, want := viewer.Viewer.Login, graphql.String("gopher"); got != want {
+		t.Errorf("got login: %v, want: %v", got, want)
+	}
+	if got, want := fmt.Sprint(errs), "[[not found] []]"; got != want {
+		t.Errorf("got errors: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_QueryBatch_unsupported(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"errors": [{"message": "batching is disabled"}]}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	_, err := client.QueryBatch(context.Background(), []graphql.BatchOperation{{Query: &q}, {Query: &q}})
+	if got, want := fmt.Sprint(err), "server didn't respond to the batch with one response per query: batching is disabled"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_QueryBatch_options(t *testing.T) {
+	// Batches are constructed and sent like single queries.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("Content-Encoding"), "gzip"; got != want {
+			t.Errorf("got Content-Encoding: %q, want: %q", got, want)
+		}
+		zr, err := gzip.NewReader(req.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := mustRead(zr), `[{"query":"query Viewer{viewer{__typename,login}}","operationName":"Viewer"},{"query":"query Viewer{viewer{__typename,login}}","operationName":"Viewer"}]`; got != want {
+			t.Errorf("got body:\n%v\nwant:\n%v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `[{"data": {"viewer": {"__typename": "User", "login": "a"}}}, {"data": {"viewer": {"__typename": "User", "login": "b"}}}]`)
+	})
+	var logged []string
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRequestCompression("gzip"),
+		graphql.WithRequestLogger(func(ctx context.Context, operation, query string, variables map[string]interface{}) {
+			logged = append(logged, operation)
+		}))
+
+	var q1, q2 struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	var stats graphql.Stats
+	ctx := graphql.WithStats(context.Background(), &stats)
+	_, err := client.QueryBatch(ctx, []graphql.BatchOperation{{Query: &q1}, {Query: &q2}},
+		graphql.WithAutoTypename(), graphql.WithOperationName("Viewer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q1.Viewer.Login != "a" || q2.Viewer.Login != "b" {
+		t.Errorf("got logins: %q, %q, want: a, b", q1.Viewer.Login, q2.Viewer.Login)
+	}
+	if got, want := fmt.Sprint(logged), "[Viewer Viewer]"; got != want {
+		t.Errorf("got logged operations: %v, want: %v", got, want)
+	}
+	if stats.Requests != 1 || stats.BytesReceived == 0 {
+		t.Errorf("got stats: %+v, want 1 request", stats)
+	}
+}
@@ -0,0 +1,288 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrMutationQueued is returned, wrapped, when a mutation couldn't be sent
+// because the server is unreachable, and was queued to be sent later.
+var ErrMutationQueued = errors.New("mutation queued")
+
+// QueuedMutation is a mutation waiting in an offline queue.
+type QueuedMutation struct {
+	// ID is a unique key of the mutation, sent in its Idempotency-Key
+	// header so that servers can recognize repeated attempts at it.
+	ID        string
+	Query     string
+	Variables json.RawMessage `json:",omitempty"`
+	Queued    time.Time
+}
+
+// QueueStore stores an offline mutation queue, in order.
+// Implementations must persist the queue if it's to survive restarts.
+type QueueStore interface {
+	// Load returns the queued mutations, oldest first.
+	Load() ([]QueuedMutation, error)
+	// Append adds m at the end of the queue.
+	Append(m QueuedMutation) error
+	// Remove removes the mutation with id from the queue.
+	Remove(id string) error
+}
+
+// WithOfflineQueue makes the client queue mutations in store when the server
+// is unreachable, instead of failing them. A mutation is queued if sending it
+// fails with a network error, a 429 or a 5xx status code; Mutate and Do then
+// return an error wrapping ErrMutationQueued. Mutations are also queued while
+// the queue isn't empty, so that they're executed in order.
+//
+// Queued mutations are sent, oldest first, before the next mutation or when
+// FlushQueue is called, such as when connectivity returns. Each mutation has
+// an idempotency key, sent in the Idempotency-Key header of every attempt at
+// it. Queued mutations are sent with the client's request options, but not
+// the options of the requests that queued them, which aren't persisted.
+//
+// If onFlush is non-nil, it's called with each queued mutation that's sent,
+// and its response. Mutations that fail with an error other than the ones
+// that queue them are removed from the queue, and reported to onFlush too.
+func WithOfflineQueue(store QueueStore, onFlush func(m QueuedMutation, resp *Response, err error)) ClientOption {
+	return func(c *Client) {
+		c.queue = &offlineQueue{store: store, onFlush: onFlush}
+	}
+}
+
+// offlineQueue is the offline mutation queue of a client.
+type offlineQueue struct {
+	store   QueueStore
+	onFlush func(m QueuedMutation, resp *Response, err error)
+
+	mu sync.Mutex // Serializes mutations, so that they're sent in order.
+}
+
+// FlushQueue sends the mutations in the client's offline queue, oldest first.
+// It stops at the first mutation that fails in a way that keeps it queued,
+// returning an error wrapping ErrMutationQueued. It does nothing if the
+// client has no offline queue.
+func (c *Client) FlushQueue(ctx context.Context) error {
+	if c.queue == nil {
+		return nil
+	}
+	c.queue.mu.Lock()
+	defer c.queue.mu.Unlock()
+	return c.flushQueue(ctx)
+}
+
+// flushQueue sends the queued mutations. c.queue.mu must be held.
+func (c *Client) flushQueue(ctx context.Context) error {
+	queued, err := c.queue.store.Load()
+	if err != nil {
+		return err
+	}
+	for _, m := range queued {
+		in := requestBody{Query: m.Query}
+		if len(m.Variables) > 0 {
+			dec := json.NewDecoder(bytes.NewReader(m.Variables))
+			dec.UseNumber()
+			err := dec.Decode(&in.Variables)
+			if err != nil {
+				return err
+			}
+		}
+		cfg, err := c.requestConfig(ctx, nil)
+		if err != nil {
+			return err
+		}
+		cfg.header.Set("Idempotency-Key", m.ID)
+		resp, err := c.execute(ctx, in, cfg)
+		if isTransient(ctx, err) {
+			return fmt.Errorf("%w: %d mutations remain queued: %v", ErrMutationQueued, len(queued), err)
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err2 := c.queue.store.Remove(m.ID)
+		if c.queue.onFlush != nil {
+			c.queue.onFlush(m, resp, err)
+		}
+		if err2 != nil {
+			return err2
+		}
+		queued = queued[1:]
+	}
+	return nil
+}
+
+// doQueued executes the mutation in, made with cfg, queuing it
+// if the server is unreachable or other mutations are queued.
+func (c *Client) doQueued(ctx context.Context, in requestBody, cfg *requestConfig) (*Response, error) {
+	c.queue.mu.Lock()
+	defer c.queue.mu.Unlock()
+	m := QueuedMutation{
+		ID:     newIdempotencyKey(),
+		Query:  in.Query,
+		Queued: time.Now(),
+	}
+	if len(in.Variables) > 0 {
+		b, err := json.Marshal(in.Variables)
+		if err != nil {
+			return nil, err
+		}
+		m.Variables = b
+	}
+	err := c.flushQueue(ctx)
+	if errors.Is(err, ErrMutationQueued) {
+		return nil, c.enqueue(m, err)
+	} else if err != nil {
+		return nil, err
+	}
+	cfg.header.Set("Idempotency-Key", m.ID)
+	resp, err := c.execute(ctx, in, cfg)
+	if isTransient(ctx, err) {
+		return nil, c.enqueue(m, err)
+	}
+	return resp, err
+}
+
+// enqueue appends m to the queue because of cause,
+// and returns an error wrapping ErrMutationQueued.
+func (c *Client) enqueue(m QueuedMutation, cause error) error {
+	err := c.queue.store.Append(m)
+	if err != nil {
+		return fmt.Errorf("queuing mutation failed: %v; mutation failed: %w", err, cause)
+	}
+	return fmt.Errorf("%w with idempotency key %s: %v", ErrMutationQueued, m.ID, cause)
+}
+
+// newIdempotencyKey returns a new random idempotency key.
+func newIdempotencyKey() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// NewMemoryQueueStore returns a QueueStore that keeps the queue in memory.
+// The queue is lost when the process exits.
+func NewMemoryQueueStore() QueueStore {
+	return new(memoryQueueStore)
+}
+
+type memoryQueueStore struct {
+	mu    sync.Mutex
+	queue []QueuedMutation
+}
+
+func (s *memoryQueueStore) Load() ([]QueuedMutation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]QueuedMutation(nil), s.queue...), nil
+}
+
+func (s *memoryQueueStore) Append(m QueuedMutation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, m)
+	return nil
+}
+
+func (s *memoryQueueStore) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = removeQueued(s.queue, id)
+	return nil
+}
+
+// NewFileQueueStore returns a QueueStore that persists the queue
+// in the JSON file at path, which is created if it doesn't exist.
+// The file is replaced atomically on every change.
+func NewFileQueueStore(path string) QueueStore {
+	return &fileQueueStore{path: path}
+}
+
+type fileQueueStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (s *fileQueueStore) Load() ([]QueuedMutation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+func (s *fileQueueStore) Append(m QueuedMutation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queue, err := s.load()
+	if err != nil {
+		return err
+	}
+	return s.save(append(queue, m))
+}
+
+func (s *fileQueueStore) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queue, err := s.load()
+	if err != nil {
+		return err
+	}
+	return s.save(removeQueued(queue, id))
+}
+
+func (s *fileQueueStore) load() ([]QueuedMutation, error) {
+	b, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var queue []QueuedMutation
+	err = json.Unmarshal(b, &queue)
+	return queue, err
+}
+
+func (s *fileQueueStore) save(queue []QueuedMutation) error {
+	b, err := json.Marshal(queue)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, b)
+}
+
+// writeFileAtomic replaces the file at path with one holding b, atomically.
+func writeFileAtomic(path string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// removeQueued removes the mutation with id from queue.
+func removeQueued(queue []QueuedMutation, id string) []QueuedMutation {
+	for i, m := range queue {
+		if m.ID == id {
+			return append(queue[:i:i], queue[i+1:]...)
+		}
+	}
+	return queue
+}
@@ -0,0 +1,92 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestWithOfflineQueue(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		store graphql.QueueStore
+	}{
+		{"memory", graphql.NewMemoryQueueStore()},
+		{"file", graphql.NewFileQueueStore(filepath.Join(t.TempDir(), "queue.json"))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			down := true
+			var received []string
+			keys := make(map[string]string)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+				body := mustRead(req.Body)
+				key := req.Header.Get("Idempotency-Key")
+				if key == "" {
+					t.Error("got no Idempotency-Key header")
+				}
+				if k, ok := keys[body]; ok && k != key {
+					t.Errorf("got Idempotency-Key %q for %s, want %q as in earlier attempts", key, body, k)
+				}
+				keys[body] = key
+				if down {
+					http.Error(w, "unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				received = append(received, body)
+				w.Header().Set("Content-Type", "application/json")
+				mustWrite(w, `{"data": {"addStar": {"count": 1}}}`)
+			})
+			var flushed int
+			client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+				graphql.WithOfflineQueue(tc.store, func(m graphql.QueuedMutation, resp *graphql.Response, err error) {
+					if err != nil {
+						t.Error(err)
+					}
+					flushed++
+				}))
+
+			addStar := func(id string) error {
+				var m struct {
+					AddStar struct {
+						Count graphql.Int
+					} `graphql:"addStar(id: $id)"`
+				}
+				_, err := client.Mutate(context.Background(), &m, map[string]interface{}{"id": graphql.ID(id)})
+				return err
+			}
+			for _, id := range []string{"1", "2"} {
+				if err := addStar(id); !errors.Is(err, graphql.ErrMutationQueued) {
+					t.Fatalf("got error: %v, want ErrMutationQueued", err)
+				}
+			}
+			if err := client.FlushQueue(context.Background()); !errors.Is(err, graphql.ErrMutationQueued) {
+				t.Fatalf("got error: %v, want ErrMutationQueued", err)
+			}
+
+			down = false
+			if err := addStar("3"); err != nil {
+				t.Fatal(err)
+			}
+			want := []string{
+				`{"query":"mutation($id:ID!){addStar(id: $id){count}}","variables":{"id":"1"}}` + "\n",
+				`{"query":"mutation($id:ID!){addStar(id: $id){count}}","variables":{"id":"2"}}` + "\n",
+				`{"query":"mutation($id:ID!){addStar(id: $id){count}}","variables":{"id":"3"}}` + "\n",
+			}
+			if fmt.Sprint(received) != fmt.Sprint(want) {
+				t.Errorf("got mutations:\n%v\nwant:\n%v", received, want)
+			}
+			if flushed != 2 {
+				t.Errorf("got %v flushed mutations, want 2", flushed)
+			}
+			if queued, err := tc.store.Load(); err != nil || len(queued) != 0 {
+				t.Errorf("got queue: %v, %v, want it empty", queued, err)
+			}
+		})
+	}
+}
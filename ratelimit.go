@@ -0,0 +1,58 @@
+package graphql
+
+import "context"
+
+// Limiter limits the rate of events. *rate.Limiter from
+// golang.org/x/time/rate implements it.
+type Limiter interface {
+	// Wait blocks until an event may happen, or ctx is done.
+	Wait(ctx context.Context) error
+}
+
+// WithRateLimiter makes the client wait for l before sending each request,
+// so that it stays within an upstream quota. Requests whose context is done
+// while waiting fail with the error returned by l.
+func WithRateLimiter(l Limiter) ClientOption {
+	return func(c *Client) {
+		c.limiter = l
+	}
+}
+
+// WithMaxConcurrent makes the client send at most n requests at a time,
+// across all of its operations, so that bursts of operations don't
+// overload the server. Requests beyond the limit wait until earlier ones
+// are done, or fail with the error of their context if it's done first.
+// If n is 0 or less, the number of requests isn't limited.
+func WithMaxConcurrent(n int) ClientOption {
+	return func(c *Client) {
+		c.concurrency = nil
+		if n > 0 {
+			c.concurrency = make(chan struct{}, n)
+		}
+	}
+}
+
+// acquire waits until the client may send a request, as limited by
+// WithMaxConcurrent, and returns a function to call once it's done.
+func (c *Client) acquire(ctx context.Context) (release func(), err error) {
+	if c.concurrency == nil {
+		return func() {}, nil
+	}
+	select {
+	case c.concurrency <- struct{}{}:
+		return func() { <-c.concurrency }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// WithResponseFunc makes the client call f with the response or failure
+// of each attempt at an operation, including retries, before it's retried
+// or returned. resp may be nil. It lets limiters that follow a quota the
+// server reports, in headers or response extensions, stay up to date.
+// f may be called concurrently, and should return quickly.
+func WithResponseFunc(f func(resp *Response, err error)) ClientOption {
+	return func(c *Client) {
+		c.responseFunc = f
+	}
+}
@@ -0,0 +1,87 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestWithMaxConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	arrived := make(chan struct{})
+	unblock := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		arrived <- struct{}{}
+		<-unblock
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithMaxConcurrent(2))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var q struct {
+				Viewer struct {
+					Login graphql.String
+				}
+			}
+			_, err := client.Query(context.Background(), &q, nil)
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	<-arrived
+	<-arrived
+	select {
+	case <-arrived:
+		t.Fatal("got a third request while two were in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(unblock)
+	for i := 0; i < 3; i++ {
+		<-arrived
+	}
+	wg.Wait()
+	if maxInFlight != 2 {
+		t.Errorf("got at most %d requests in flight, want 2", maxInFlight)
+	}
+}
+
+func TestWithMaxConcurrent_unlimited(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithMaxConcurrent(0))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	_, err := client.Query(ctx, &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,199 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/merico-dev/graphql/internal/jsonutil"
+)
+
+// RegisterType makes variables holding values of the Go type of v be
+// declared with the GraphQL type typ, instead of a type named after the
+// Go type. It's meant for input object and custom scalar types whose Go
+// names differ from their GraphQL names, and applies wherever the values
+// appear, including as list elements.
+//
+// typ is written as is for values, so its nullability is explicit:
+// registering "IssueFilter!" declares []IssueFilter as "[IssueFilter!]!",
+// while registering "IssueFilter" declares it as "[IssueFilter]!".
+// Pointers to the Go type are declared nullable either way.
+//
+// E.g., RegisterType(IssueFilters{}, "IssueFilter!"). Go types can also
+// name their GraphQL type themselves by implementing GraphQLTyper.
+//
+// RegisterType is meant to be called during initialization.
+// It panics if typ isn't a GraphQL named type, optionally followed by "!".
+func RegisterType(v interface{}, typ string) {
+	registerType("RegisterType", reflect.TypeOf(v), typ)
+	atomic.AddUint32(&registrations, 1)
+}
+
+// registerType registers the GraphQL type typ for t,
+// for the function fn, which panics if typ isn't valid.
+func registerType(fn string, t reflect.Type, typ string) {
+	name := strings.TrimSuffix(typ, "!")
+	if name == "" || strings.IndexFunc(name, func(r rune) bool { return r > 0x7f || !isNameByte(byte(r)) }) != -1 {
+		panic(fmt.Sprintf("graphql: %s: %q isn't a named type", fn, typ))
+	}
+	registeredTypes.Store(t, typ)
+}
+
+// registeredTypes maps Go types to the GraphQL types registered for them.
+var registeredTypes sync.Map // map[reflect.Type]string
+
+// registrations is the number of calls to RegisterType and RegisterScalar,
+// which query plans are worked out anew after; accessed atomically.
+var registrations uint32
+
+// GraphQLTyper is implemented by the Go types of variable values, such as
+// input objects, that name their GraphQL type themselves, instead of being
+// registered with RegisterType.
+//
+// GraphQLType returns the name of the GraphQL type, such as "IssueFilters".
+// Values of the Go type are declared non-null, and pointers to it nullable,
+// wherever they appear, including as list elements. It's called on the
+// zero value of the Go type, or a pointer to it if it has a pointer
+// receiver.
+type GraphQLTyper interface {
+	GraphQLType() string
+}
+
+var graphQLTyperType = reflect.TypeOf((*GraphQLTyper)(nil)).Elem()
+
+// registeredType returns the GraphQL type registered for t, or named by
+// its GraphQLType method, if any.
+func registeredType(t reflect.Type) (string, bool) {
+	if typ, ok := registeredTypes.Load(t); ok {
+		return typ.(string), true
+	}
+	switch {
+	case t.Implements(graphQLTyperType):
+		return reflect.Zero(t).Interface().(GraphQLTyper).GraphQLType() + "!", true
+	case t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(graphQLTyperType):
+		return reflect.New(t).Interface().(GraphQLTyper).GraphQLType() + "!", true
+	}
+	return "", false
+}
+
+// RegisterScalar makes values of the Go type T be handled as values of the
+// custom scalar typ, such as "DateTime!", "URI!" or "JSON!", wherever they
+// appear. Variables holding them are declared with typ, as with
+// RegisterType. They're sent as the values marshal returns, encoded as
+// JSON, including within lists and input objects. Response fields of type
+// T are decoded by unmarshal from the JSON encoding of their non-null
+// values, even if it's an object or array, and aren't expanded into
+// selection sets.
+//
+// E.g.:
+//
+//	graphql.RegisterScalar("URI!",
+//		func(u url.URL) (interface{}, error) { return u.String(), nil },
+//		func(data []byte) (url.URL, error) {
+//			var s string
+//			if err := json.Unmarshal(data, &s); err != nil {
+//				return url.URL{}, err
+//			}
+//			u, err := url.Parse(s)
+//			if err != nil {
+//				return url.URL{}, err
+//			}
+//			return *u, nil
+//		})
+//
+// RegisterScalar is meant to be called during initialization.
+// It panics if typ isn't a GraphQL named type, optionally followed by "!".
+func RegisterScalar[T any](typ string, marshal func(T) (interface{}, error), unmarshal func(data []byte) (T, error)) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	registerType("RegisterScalar", t, typ)
+	scalarMarshalers.Store(t, func(v interface{}) (interface{}, error) {
+		return marshal(v.(T))
+	})
+	jsonutil.RegisterScalar(t, func(data []byte, v interface{}) error {
+		value, err := unmarshal(data)
+		if err != nil {
+			return err
+		}
+		*v.(*T) = value
+		return nil
+	})
+	atomic.AddUint32(&registrations, 1)
+}
+
+// RegisterEnum makes values of the Go type T be handled as values of the
+// enum typ, such as "IssueState!", wherever they appear, with names mapping
+// the values of T to the names of the values of the enum. Variables holding
+// them are declared with typ, as with RegisterType, and sent as their
+// names; values missing from names fail the operation before it's sent,
+// rather than being rejected by the server, which catches mistakes such as
+// wrong casing. Response fields of type T are decoded from the names.
+// Names missing from names, such as those of values added to the enum
+// later, are kept as is if T is a string type, and fail decoding otherwise.
+//
+// E.g., for an enum of Go constants:
+//
+//	type IssueState int
+//
+//	const (
+//		Open IssueState = iota
+//		Closed
+//	)
+//
+//	func init() {
+//		graphql.RegisterEnum("IssueState!", map[IssueState]string{
+//			Open:   "OPEN",
+//			Closed: "CLOSED",
+//		})
+//	}
+//
+// RegisterEnum is meant to be called during initialization.
+// It panics if typ isn't a GraphQL named type, optionally followed by "!".
+func RegisterEnum[T comparable](typ string, names map[T]string) {
+	values := make(map[string]T, len(names))
+	allowed := make([]string, 0, len(names))
+	for v, name := range names {
+		values[name] = v
+		allowed = append(allowed, name)
+	}
+	sort.Strings(allowed)
+	enum := strings.TrimSuffix(typ, "!")
+	RegisterScalar(typ, func(v T) (interface{}, error) {
+		name, ok := names[v]
+		if !ok {
+			return nil, fmt.Errorf("%#v isn't a value of enum %s; allowed values: %s", v, enum, strings.Join(allowed, ", "))
+		}
+		return name, nil
+	}, func(data []byte) (T, error) {
+		var name string
+		err := json.Unmarshal(data, &name)
+		if err != nil {
+			var zero T
+			return zero, fmt.Errorf("enum %s: %w", enum, err)
+		}
+		v, ok := values[name]
+		if !ok {
+			rv := reflect.ValueOf(&v).Elem()
+			if rv.Kind() != reflect.String {
+				return v, fmt.Errorf("%q isn't a value of enum %s", name, enum)
+			}
+			rv.SetString(name)
+		}
+		return v, nil
+	})
+}
+
+// scalarMarshalers maps the Go types registered with RegisterScalar
+// to the functions that marshal their values.
+var scalarMarshalers sync.Map // map[reflect.Type]func(interface{}) (interface{}, error)
+
+// decodedWhole reports whether values of the type t are decoded as
+// a whole, because it implements json.Unmarshaler, such as OrderedMap,
+// or was registered with RegisterScalar. Such types aren't expanded
+// into selection sets.
+func decodedWhole(t reflect.Type) bool {
+	return reflect.PtrTo(t).Implements(jsonUnmarshaler) || jsonutil.IsScalar(t)
+}
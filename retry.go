@@ -0,0 +1,348 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backoff returns how long to wait before the given retry attempt,
+// starting from 1 for the first retry.
+type Backoff func(attempt int) time.Duration
+
+// ExponentialBackoff returns a Backoff that waits base before the first
+// retry, doubling the wait before each subsequent one, up to max.
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// WithRetry makes the client retry operations that fail transiently,
+// because of a network error, a 429 Too Many Requests status code, or
+// a 5xx status code, making at most maxAttempts attempts in total, and
+// waiting as backoff returns between them. If a 429 or 5xx response has
+// a Retry-After header asking to wait longer than backoff, the client
+// waits as long as it asks.
+//
+// Operations whose responses carry GraphQL errors aren't retried,
+// unless the server asked for it; see WithRetryHints.
+// Note that a mutation that failed with a network error or a 5xx status code
+// may have been executed by the server; retry mutations only if they're
+// idempotent.
+func WithRetry(maxAttempts int, backoff Backoff) ClientOption {
+	return func(c *Client) {
+		c.retry.maxAttempts = maxAttempts
+		c.retry.backoff = backoff
+	}
+}
+
+// WithRetryBudget limits the retries made by the client, across all of its
+// operations, to n per window. When the budget is exhausted, operations fail
+// with their last error instead of being retried, so that a widespread
+// outage doesn't turn many clients into a retry storm.
+// It has effect only along with WithRetry.
+func WithRetryBudget(n int, window time.Duration) ClientOption {
+	return func(c *Client) {
+		c.retry.budget = &retryBudget{max: n, window: window}
+	}
+}
+
+// WithMaxRetryElapsed stops retrying an operation once retrying it again
+// would take it past d since its first attempt.
+// It has effect only along with WithRetry.
+func WithMaxRetryElapsed(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.retry.maxElapsed = d
+	}
+}
+
+// WithRetryHints makes the client honor the retry hints that parsers find
+// in responses and failures, such as a rate-limit reset time reported in
+// error extensions. An operation with a hint is retried, even if its
+// response carries GraphQL errors, after waiting for the longer of the hint
+// and the backoff. If several parsers find a hint, the first one is used.
+// It has effect only along with WithRetry.
+//
+// ExtensionRetryHints parses common conventions; per-API conventions can be
+// plugged in by implementing RetryHintParser.
+func WithRetryHints(parsers ...RetryHintParser) ClientOption {
+	return func(c *Client) {
+		c.retry.hints = append(c.retry.hints, parsers...)
+	}
+}
+
+// WithBeforeRetry makes the client call f before each retry it's about to
+// make, such as to log it, to adjust how long to wait before it, or to veto
+// it, e.g., for mutations that aren't idempotent. f is called once the
+// retry policy decided to retry, before the retry counts against the
+// budget of WithRetryBudget.
+// It has effect only along with WithRetry, or on the failovers of clients
+// created with NewMultiClient.
+func WithBeforeRetry(f BeforeRetryFunc) ClientOption {
+	return func(c *Client) {
+		c.retry.beforeRetry = f
+	}
+}
+
+// BeforeRetryFunc is called before a retry described by info.
+// It returns how long to wait before making it, which is info.Wait
+// unless it's adjusted, and whether to make it at all. If it returns
+// false, the operation fails with the outcome of its last attempt.
+// It's called from the goroutine executing the operation.
+type BeforeRetryFunc func(info RetryInfo) (wait time.Duration, retry bool)
+
+// RetryInfo describes a retry that a client is about to make.
+type RetryInfo struct {
+	Operation     string // Name of the operation, "" if anonymous.
+	OperationType string // "query", "mutation" or "subscription".
+
+	// Attempt is the number of the retry, starting from 1 for the first
+	// retry, which is the second attempt.
+	Attempt int
+	// Response is the response to the previous attempt. It's nil if the
+	// attempt failed without one, such as because of a network error.
+	Response *Response
+	// Err is the error the previous attempt failed with. It's nil if the
+	// attempt is retried because of a retry hint in its response.
+	Err error
+	// Wait is how long the client is going to wait before the retry,
+	// according to the backoff and retry hints.
+	Wait time.Duration
+	// Failover is whether the retry sends the request to the next replica
+	// of a client created with NewMultiClient right away. Attempt then
+	// counts the replicas tried so far.
+	Failover bool
+}
+
+// RetryHintParser finds server-provided retry hints.
+type RetryHintParser interface {
+	// RetryAfter reports how long the server asked to wait before retrying
+	// an operation that got response resp or failed with err, if it did.
+	// resp may be nil.
+	RetryAfter(resp *Response, err error) (time.Duration, bool)
+}
+
+// ExtensionRetryHints returns a RetryHintParser that finds retry hints in
+// the extensions of GraphQL errors, including errors in the body of non-200
+// responses. It understands these extensions, in order:
+//
+//   - "retryAfter" or "retry_after": seconds to wait, as a number.
+//   - "resetAt", or "reset_at" in a "rateLimit" or "rate_limit" object:
+//     the time to retry at, in RFC 3339 format or as Unix seconds.
+func ExtensionRetryHints() RetryHintParser {
+	return extensionRetryHints{}
+}
+
+type extensionRetryHints struct{}
+
+func (extensionRetryHints) RetryAfter(resp *Response, err error) (time.Duration, bool) {
+	var errs []DataError
+	if resp != nil {
+		errs = resp.Errors
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		var body struct {
+			Errors dataErrors
+		}
+		if json.Unmarshal(statusErr.Body, &body) == nil {
+			errs = append(errs, body.Errors...)
+		}
+	}
+	for _, e := range errs {
+		for _, name := range [...]string{"retryAfter", "retry_after"} {
+			var seconds float64
+			if json.Unmarshal(e.Extensions[name], &seconds) == nil && seconds >= 0 {
+				return time.Duration(seconds * float64(time.Second)), true
+			}
+		}
+		for _, name := range [...]string{"rateLimit", "rate_limit"} {
+			var rateLimit struct {
+				ResetAt      json.RawMessage `json:"resetAt"`
+				ResetAtSnake json.RawMessage `json:"reset_at"`
+			}
+			if json.Unmarshal(e.Extensions[name], &rateLimit) != nil {
+				continue
+			}
+			for _, resetAt := range [...]json.RawMessage{rateLimit.ResetAt, rateLimit.ResetAtSnake} {
+				if t, ok := parseResetAt(resetAt); ok {
+					d := time.Until(t)
+					if d < 0 {
+						d = 0
+					}
+					return d, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// ParseRetryAfter returns how long the Retry-After header of h asks to
+// wait, which is given in seconds or as an HTTP date. It reports whether
+// h has a valid Retry-After header.
+func ParseRetryAfter(h http.Header) (time.Duration, bool) {
+	v := strings.TrimSpace(h.Get("Retry-After"))
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	d := time.Until(t)
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+// parseResetAt parses a time encoded as an RFC 3339 string or Unix seconds.
+func parseResetAt(data json.RawMessage) (time.Time, bool) {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		t, err := time.Parse(time.RFC3339, s)
+		return t, err == nil
+	}
+	var seconds int64
+	if json.Unmarshal(data, &seconds) == nil {
+		return time.Unix(seconds, 0), true
+	}
+	return time.Time{}, false
+}
+
+// retryPolicy is how a client retries failed operations.
+type retryPolicy struct {
+	maxAttempts int               // Maximum attempts per operation; retries are disabled if less than 2.
+	backoff     Backoff           // Wait before each retry, or none if nil.
+	maxElapsed  time.Duration     // Maximum time since the first attempt to start a retry, if positive.
+	budget      *retryBudget      // Retries allowed across operations, unlimited if nil.
+	hints       []RetryHintParser // Parsers of server-provided retry hints.
+	beforeRetry BeforeRetryFunc   // Called before each retry, if non-nil.
+}
+
+// hint returns the retry hint for an operation that got resp or failed
+// with err, if any.
+func (p *retryPolicy) hint(resp *Response, err error) (time.Duration, bool) {
+	for _, parser := range p.hints {
+		if d, ok := parser.RetryAfter(resp, err); ok {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// doRetrying calls attempt, which sends the request in, retrying it
+// according to the client's retry policy.
+func (c *Client) doRetrying(ctx context.Context, in requestBody, attempt func() (*Response, error)) (*Response, error) {
+	start := time.Now()
+	for n := 1; ; n++ {
+		resp, err := attempt()
+		if n >= c.retry.maxAttempts || ctx.Err() != nil {
+			return resp, err
+		}
+		hint, hinted := c.retry.hint(resp, err)
+		if !hinted && !isTransient(ctx, err) {
+			return resp, err
+		}
+		var statusErr *HTTPStatusError
+		if !hinted && errors.As(err, &statusErr) {
+			// Honor the Retry-After header of transient failures.
+			hint, _ = ParseRetryAfter(statusErr.Header)
+		}
+		var wait time.Duration
+		if c.retry.backoff != nil {
+			wait = c.retry.backoff(n)
+		}
+		if hint > wait {
+			wait = hint
+		}
+		if c.retry.maxElapsed > 0 && time.Since(start)+wait > c.retry.maxElapsed {
+			return resp, err
+		}
+		if c.retry.beforeRetry != nil {
+			var retry bool
+			wait, retry = c.retry.beforeRetry(RetryInfo{
+				Operation:     in.operationName(),
+				OperationType: in.operationType(),
+				Attempt:       n,
+				Response:      resp,
+				Err:           err,
+				Wait:          wait,
+			})
+			if !retry {
+				return resp, err
+			}
+		}
+		if c.retry.budget != nil && !c.retry.budget.take() {
+			return resp, err
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return resp, err
+		}
+	}
+}
+
+// isTransient reports whether err is a failure that may not happen again:
+// a network error, or a 429 or 5xx status code.
+func isTransient(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// retryBudget is a limit of max retries per sliding window of time.
+type retryBudget struct {
+	max    int
+	window time.Duration
+
+	mu      sync.Mutex
+	retries []time.Time // Times of the retries within the current window, oldest first.
+}
+
+// take reports whether a retry is within the budget, and counts it if so.
+func (b *retryBudget) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	i := 0
+	for i < len(b.retries) && now.Sub(b.retries[i]) >= b.window {
+		i++
+	}
+	b.retries = b.retries[i:]
+	if len(b.retries) >= b.max {
+		return false
+	}
+	b.retries = append(b.retries, now)
+	return true
+}
@@ -0,0 +1,237 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+// flakyHandler responds with status to the first failures requests,
+// and successfully to the rest. It counts the requests in *requests.
+func flakyHandler(status, failures int, requests *int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if n := atomic.AddInt32(requests, 1); int(n) <= failures {
+			http.Error(w, "unavailable", status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+}
+
+func TestWithRetry(t *testing.T) {
+	var requests int32
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: flakyHandler(http.StatusServiceUnavailable, 2, &requests)}},
+		graphql.WithRetry(3, graphql.ExponentialBackoff(time.Millisecond, 10*time.Millisecond)))
+
+	resp, err := client.Do(context.Background(), "{viewer{login}}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(resp.Data), `{"viewer": {"login": "gopher"}}`; got != want {
+		t.Errorf("got data: %v, want: %v", got, want)
+	}
+	if requests != 3 {
+		t.Errorf("got %v requests, want 3", requests)
+	}
+}
+
+func TestWithRetry_notTransient(t *testing.T) {
+	var requests int32
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: flakyHandler(http.StatusBadRequest, 1, &requests)}},
+		graphql.WithRetry(3, nil))
+
+	_, err := client.Do(context.Background(), "{viewer{login}}", nil)
+	var statusErr *graphql.HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got error: %v, want a 400 Bad Request status error", err)
+	}
+	if requests != 1 {
+		t.Errorf("got %v requests, want 1", requests)
+	}
+}
+
+func TestWithRetryBudget(t *testing.T) {
+	var requests int32
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: flakyHandler(http.StatusTooManyRequests, 100, &requests)}},
+		graphql.WithRetry(3, nil),
+		graphql.WithRetryBudget(3, time.Hour))
+
+	for i := 0; i < 3; i++ {
+		_, err := client.Do(context.Background(), "{viewer{login}}", nil)
+		if err == nil {
+			t.Fatal("got nil error")
+		}
+	}
+	// The first operation retries twice, the second once before the budget
+	// of 3 retries is exhausted, and the third isn't retried.
+	if requests != 6 {
+		t.Errorf("got %v requests, want 6", requests)
+	}
+}
+
+func TestWithMaxRetryElapsed(t *testing.T) {
+	var requests int32
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: flakyHandler(http.StatusBadGateway, 100, &requests)}},
+		graphql.WithRetry(10, graphql.ExponentialBackoff(20*time.Millisecond, time.Second)),
+		graphql.WithMaxRetryElapsed(50*time.Millisecond))
+
+	_, err := client.Do(context.Background(), "{viewer{login}}", nil)
+	if err == nil {
+		t.Fatal("got nil error")
+	}
+	// Retries start after 20ms and 20+40ms; the one after 20+40+80ms is past 50ms.
+	if requests != 2 {
+		t.Errorf("got %v requests, want 2", requests)
+	}
+}
+
+func TestWithBeforeRetry(t *testing.T) {
+	var requests int32
+	var infos []graphql.RetryInfo
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: flakyHandler(http.StatusServiceUnavailable, 100, &requests)}},
+		graphql.WithRetry(5, graphql.ExponentialBackoff(time.Hour, time.Hour)),
+		graphql.WithBeforeRetry(func(info graphql.RetryInfo) (time.Duration, bool) {
+			infos = append(infos, info)
+			return time.Millisecond, info.OperationType != "mutation" && info.Attempt < 3
+		}))
+
+	_, err := client.Do(context.Background(), "query GetViewer{viewer{login}}", nil)
+	var statusErr *graphql.HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("got error: %v, want a status error", err)
+	}
+	// The hook shortened the hour-long waits, and vetoed the third retry.
+	if requests != 3 {
+		t.Errorf("got %v requests, want 3", requests)
+	}
+	if len(infos) != 3 {
+		t.Fatalf("got %v calls, want 3", len(infos))
+	}
+	for i, info := range infos {
+		if info.Operation != "GetViewer" || info.OperationType != "query" || info.Attempt != i+1 || info.Wait != time.Hour || !errors.As(info.Err, &statusErr) || info.Response == nil {
+			t.Errorf("got info %d: %+v", i, info)
+		}
+	}
+
+	// Mutations aren't retried.
+	requests, infos = 0, nil
+	_, err = client.Do(context.Background(), "mutation{addStar{starrable{id}}}", nil)
+	if err == nil {
+		t.Fatal("got nil error")
+	}
+	if requests != 1 || len(infos) != 1 {
+		t.Errorf("got %v requests and %v calls, want 1 and 1", requests, len(infos))
+	}
+}
+
+func TestWithRetry_retryAfter(t *testing.T) {
+	var requests int32
+	var first time.Time
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})}}, graphql.WithRetry(2, graphql.ExponentialBackoff(time.Millisecond, time.Millisecond)))
+
+	_, err := client.Do(context.Background(), "{viewer{login}}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(first); d < time.Second {
+		t.Errorf("retried after %v, want at least the 1s Retry-After asked for", d)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	for _, tc := range []struct {
+		in     string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	} {
+		got, ok := graphql.ParseRetryAfter(http.Header{"Retry-After": {tc.in}})
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("%q: got %v, %v, want: %v, %v", tc.in, got, ok, tc.want, tc.wantOK)
+		}
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got, ok := graphql.ParseRetryAfter(http.Header{"Retry-After": {date}}); !ok || got < 59*time.Minute || got > time.Hour {
+		t.Errorf("%q: got %v, %v, want about an hour", date, got, ok)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := graphql.ExponentialBackoff(100*time.Millisecond, time.Second)
+	for _, tc := range []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{50, time.Second},
+	} {
+		if got := backoff(tc.attempt); got != tc.want {
+			t.Errorf("backoff(%v): got %v, want %v", tc.attempt, got, tc.want)
+		}
+	}
+}
+
+func TestWithRetryHints(t *testing.T) {
+	var requests int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			mustWrite(w, `{"data": null, "errors": [{"message": "rate limited", "extensions": {"retryAfter": 0.02}}]}`)
+		case 2:
+			w.WriteHeader(http.StatusForbidden)
+			mustWrite(w, `{"errors": [{"message": "slow down", "extensions": {"rate_limit": {"reset_at": "2000-01-01T00:00:00Z"}}}]}`)
+		default:
+			mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+		}
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}},
+		graphql.WithRetry(3, nil),
+		graphql.WithRetryHints(graphql.ExtensionRetryHints()))
+
+	start := time.Now()
+	resp, err := client.Do(context.Background(), "{viewer{login}}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Errors != nil {
+		t.Errorf("got errors: %v, want none", resp.Errors)
+	}
+	if requests != 3 {
+		t.Errorf("got %v requests, want 3", requests)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("retried after %v, want at least the hinted 20ms", elapsed)
+	}
+}
+
+func TestExtensionRetryHints(t *testing.T) {
+	resp := &graphql.Response{Errors: []graphql.DataError{{Message: "not a hint"}}}
+	if d, ok := graphql.ExtensionRetryHints().RetryAfter(resp, nil); ok {
+		t.Errorf("got hint %v, want none", d)
+	}
+}
@@ -0,0 +1,305 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/merico-dev/graphql/introspection"
+)
+
+// WithSchemaTTL makes the schema that Schema fetches expire ttl after
+// it's fetched, so that it's fetched again when needed next. By default,
+// it's kept for the lifetime of the client, or until RefreshSchema.
+func WithSchemaTTL(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.schema.ttl = ttl
+	}
+}
+
+// WithSchemaCacheDir makes the client keep the schema that Schema fetches
+// in a file in the directory dir, named after the client's URL, so that
+// cold starts and repeated runs of tools reuse it instead of introspecting
+// the server again. The file is used while it's fresh according to
+// WithSchemaTTL, and RefreshSchema replaces it. Failing to read or write
+// the file isn't an error; the schema is introspected instead.
+func WithSchemaCacheDir(dir string) ClientOption {
+	return func(c *Client) {
+		c.schema.dir = dir
+	}
+}
+
+// Schema returns the server's schema. It's introspected when first needed,
+// and shared by the features of the client that need schema knowledge,
+// so that a single introspection query is made, even by concurrent callers.
+// A failure is returned again, without introspecting the server, until
+// a backoff that grows with consecutive failures, from 10 seconds up to
+// 10 minutes, elapses, so that servers that disable introspection aren't
+// introspected for every operation.
+func (c *Client) Schema(ctx context.Context) (*introspection.Schema, error) {
+	return c.schema.get(ctx, c, false)
+}
+
+// RefreshSchema introspects the server's schema again, such as after
+// a deployment changed it, and returns it. Schema returns it from then on.
+func (c *Client) RefreshSchema(ctx context.Context) (*introspection.Schema, error) {
+	return c.schema.get(ctx, c, true)
+}
+
+// schemaCache is the cached schema of a client.
+type schemaCache struct {
+	ttl time.Duration // How long the schema is fresh, forever if not positive.
+	dir string        // Directory to keep the schema in, if any.
+
+	mu       sync.Mutex
+	schema   *introspection.Schema
+	fetched  time.Time
+	err      error        // Error of the last introspection, if it failed.
+	failures int          // Introspections that failed in a row.
+	retryAt  time.Time    // When the server may be introspected again after err.
+	fetch    *schemaFetch // Introspection in progress, if any.
+}
+
+// schemaFetch is an introspection in progress, whose result concurrent
+// callers share.
+type schemaFetch struct {
+	done   chan struct{} // Closed once schema and err are set.
+	schema *introspection.Schema
+	err    error
+}
+
+// get returns the cached schema, introspecting it with c if it's missing,
+// expired, or refresh is true. The mutex isn't held while introspecting.
+func (sc *schemaCache) get(ctx context.Context, c *Client, refresh bool) (*introspection.Schema, error) {
+	sc.mu.Lock()
+	if !refresh {
+		if sc.schema == nil && sc.dir != "" {
+			sc.schema, sc.fetched = sc.load(c.url)
+		}
+		if sc.schema != nil && (sc.ttl <= 0 || time.Since(sc.fetched) < sc.ttl) {
+			defer sc.mu.Unlock()
+			return sc.schema, nil
+		}
+		if sc.err != nil && time.Now().Before(sc.retryAt) {
+			defer sc.mu.Unlock()
+			return nil, sc.err
+		}
+	}
+	if f := sc.fetch; f != nil {
+		sc.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.schema, f.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	f := &schemaFetch{done: make(chan struct{})}
+	sc.fetch = f
+	sc.mu.Unlock()
+
+	f.schema, f.err = c.Introspect(ctx)
+	sc.mu.Lock()
+	sc.fetch = nil
+	switch {
+	case f.err == nil:
+		sc.schema, sc.fetched = f.schema, time.Now()
+		sc.err, sc.failures = nil, 0
+	case ctx.Err() == nil:
+		// Failures because ctx is done say nothing about the server.
+		sc.failures++
+		sc.err, sc.retryAt = f.err, time.Now().Add(schemaBackoff(sc.failures))
+	}
+	sc.mu.Unlock()
+	close(f.done)
+	if f.err == nil && sc.dir != "" {
+		sc.store(c.url, f.schema)
+	}
+	return f.schema, f.err
+}
+
+// schemaBackoff returns how long to wait before introspecting the server
+// again after failures in a row.
+func schemaBackoff(failures int) time.Duration {
+	const max = 10 * time.Minute
+	if failures > 6 {
+		return max
+	}
+	d := 10 * time.Second << (failures - 1)
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// file returns the name of the file that keeps the schema of the server
+// at url.
+func (sc *schemaCache) file(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(sc.dir, "graphql-schema-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// load returns the schema of the server at url kept on disk, and when it
+// was fetched. It returns a nil schema if there's none.
+func (sc *schemaCache) load(url string) (*introspection.Schema, time.Time) {
+	b, err := os.ReadFile(sc.file(url))
+	if err != nil {
+		return nil, time.Time{}
+	}
+	var cached cachedSchema
+	if json.Unmarshal(b, &cached) != nil || cached.URL != url || cached.Schema == nil {
+		return nil, time.Time{}
+	}
+	return cached.Schema, cached.Fetched
+}
+
+// store keeps the schema of the server at url on disk. The file is
+// replaced atomically, so that concurrent processes never read
+// a partially written one.
+func (sc *schemaCache) store(url string, schema *introspection.Schema) {
+	b, err := json.Marshal(cachedSchema{URL: url, Fetched: time.Now(), Hash: introspection.Hash(schema), Schema: schema})
+	if err != nil {
+		return
+	}
+	f, err := os.CreateTemp(sc.dir, "graphql-schema-*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), sc.file(url))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+// cachedSchema is the content of a file kept by WithSchemaCacheDir.
+type cachedSchema struct {
+	URL     string                `json:"url"`
+	Fetched time.Time             `json:"fetched"`
+	Hash    string                `json:"hash"` // introspection.Hash of Schema, for tools keying generated code by it.
+	Schema  *introspection.Schema `json:"schema"`
+}
+
+// WithEnumValidation makes the client check, before sending an operation
+// derived from a struct, that its variables of enum types hold values of
+// those enums, including in lists and input objects. Invalid values are
+// reported by an *EnumValueError listing the allowed values, rather than
+// by a server error about coercing them.
+//
+// The enum types are looked up in the schema that Schema returns. If it
+// can't be introspected, such as when the server disables introspection,
+// variables aren't validated.
+func WithEnumValidation() ClientOption {
+	return func(c *Client) {
+		c.enumValidation = true
+	}
+}
+
+// EnumValueError is returned when a variable holds a value that isn't
+// a value of the enum type it has in the server's schema.
+type EnumValueError struct {
+	Path    string   // Path of the value. E.g., "$review.episode".
+	Enum    string   // Name of the enum type.
+	Value   string   // JSON encoding of the value.
+	Allowed []string // Values of the enum type.
+}
+
+func (e *EnumValueError) Error() string {
+	return fmt.Sprintf("%s: %s isn't a value of enum %s; allowed values: %s", e.Path, e.Value, e.Enum, strings.Join(e.Allowed, ", "))
+}
+
+// validateEnums checks the enum values of variables against the schema,
+// if the client validates them and the schema is available.
+func (c *Client) validateEnums(ctx context.Context, variables map[string]interface{}) error {
+	if !c.enumValidation || len(variables) == 0 {
+		return nil
+	}
+	schema, err := c.Schema(ctx)
+	if err != nil {
+		return nil // Validation is best effort.
+	}
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var typ bytes.Buffer
+		writeVariableType(&typ, variables[name])
+		b, err := json.Marshal(variables[name])
+		if err != nil {
+			continue // Sending it fails with this error.
+		}
+		var value interface{}
+		if json.Unmarshal(b, &value) != nil {
+			continue
+		}
+		err = validateEnumValue(schema, "$"+name, strings.Trim(typ.String(), "[]!"), value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateEnumValue checks that value, at path, is a valid value
+// of the type named typeName as far as enum types are concerned.
+// Lists are checked element by element.
+func validateEnumValue(schema *introspection.Schema, path, typeName string, value interface{}) error {
+	if list, ok := value.([]interface{}); ok {
+		for i, v := range list {
+			err := validateEnumValue(schema, fmt.Sprintf("%s[%d]", path, i), typeName, v)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	typ := schema.Type(typeName)
+	if value == nil || typ == nil {
+		return nil
+	}
+	switch typ.Kind {
+	case introspection.Enum:
+		s, _ := value.(string)
+		allowed := make([]string, 0, len(typ.EnumValues))
+		for _, v := range typ.EnumValues {
+			if v.Name == s {
+				return nil
+			}
+			allowed = append(allowed, v.Name)
+		}
+		b, _ := json.Marshal(value)
+		return &EnumValueError{Path: path, Enum: typ.Name, Value: string(b), Allowed: allowed}
+	case introspection.InputObject:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, f := range typ.InputFields {
+			v, ok := fields[f.Name]
+			if !ok {
+				continue
+			}
+			err := validateEnumValue(schema, path+"."+f.Name, f.Type.NamedType(), v)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package graphql
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/merico-dev/graphql/internal/jsonutil"
+)
+
+// TypeDefinitions returns GraphQL SDL type definitions for the parts of a
+// schema that the query struct v selects, rooted at the type rootType
+// (e.g., "Query"). It documents which parts of a remote schema a service
+// actually depends on.
+//
+// Types are named after named Go struct types, after the type condition of
+// inline fragments, or else after the Go field that holds them. Go types
+// don't tell whether a field is nullable, so field types are written as
+// nullable. Arguments are written only when they're bound to variables,
+// whose types are derived from variables as in queries.
+func TypeDefinitions(rootType string, v interface{}, variables map[string]interface{}) string {
+	d := typeDefiner{variables: variables, types: make(map[string]*typeDefinition), scalars: make(map[string]bool)}
+	d.define(rootType, derefType(reflect.TypeOf(v)))
+
+	var buf bytes.Buffer
+	for _, name := range d.scalarOrder {
+		buf.WriteString("scalar " + name + "\n\n")
+	}
+	for i, name := range d.order {
+		if i != 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("type " + name + " {\n")
+		for _, f := range d.types[name].fields {
+			buf.WriteString("  " + f + "\n")
+		}
+		buf.WriteString("}\n")
+	}
+	return buf.String()
+}
+
+// typeDefiner collects type definitions from query structs.
+type typeDefiner struct {
+	variables   map[string]interface{}
+	types       map[string]*typeDefinition
+	order       []string // Type names in order of first use.
+	scalars     map[string]bool
+	scalarOrder []string // Custom scalar names in order of first use.
+}
+
+type typeDefinition struct {
+	fields []string // Field definitions. E.g., "user(login: String!): User".
+	names  map[string]bool
+}
+
+// define adds the fields selected by the struct t to the type name.
+func (d *typeDefiner) define(name string, t reflect.Type) {
+	def, ok := d.types[name]
+	if !ok {
+		def = &typeDefinition{names: make(map[string]bool)}
+		d.types[name] = def
+		d.order = append(d.order, name)
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if on, ok := fragmentSpread(f); ok {
+			d.define(on, derefType(f.Type))
+			continue
+		}
+		value, _, ok := jsonutil.LookupTag(f.Tag)
+		if !ok && f.Anonymous {
+			d.define(name, derefType(f.Type))
+			continue
+		}
+		if value = strings.TrimSpace(value); strings.HasPrefix(value, "...") {
+			fragmentType := name
+			if on := strings.TrimSpace(strings.TrimPrefix(value, "...")); strings.HasPrefix(on, "on ") {
+				fragmentType = strings.TrimSpace(on[len("on "):])
+			}
+			d.define(fragmentType, derefType(f.Type))
+			continue
+		}
+		field, _ := selectedField(f)
+		if field == "__typename" || def.names[field] {
+			continue
+		}
+		def.names[field] = true
+		var args string
+		if ok {
+			_, selection := splitAlias(value)
+			args = d.arguments(selection)
+		}
+		def.fields = append(def.fields, field+args+": "+d.typeOf(f.Type, f.Name))
+	}
+}
+
+// typeOf returns the GraphQL type of the Go type t of the field named
+// fieldName, defining any types that it selects.
+func (d *typeDefiner) typeOf(t reflect.Type, fieldName string) string {
+	t = derefType(t)
+	switch {
+	case t.Kind() == reflect.Interface || decodedWhole(t):
+		name := t.Name()
+		if name == "" {
+			name = "JSON"
+		}
+		d.scalar(name)
+		return name
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return "[" + d.typeOf(t.Elem(), fieldName) + "]"
+	case t.Kind() == reflect.Struct:
+		name := t.Name()
+		if name == "" {
+			name = fieldName
+		}
+		d.define(name, t)
+		return name
+	case t.Kind() == reflect.Bool:
+		return "Boolean"
+	case reflect.Int <= t.Kind() && t.Kind() <= reflect.Uint64:
+		return "Int"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return "Float"
+	case t.Kind() == reflect.String:
+		return "String"
+	default:
+		d.scalar("JSON")
+		return "JSON"
+	}
+}
+
+// scalar records that the scalar name is used,
+// so custom scalars get a definition.
+func (d *typeDefiner) scalar(name string) {
+	switch name {
+	case "Boolean", "Int", "Float", "String", "ID":
+		return
+	}
+	if !d.scalars[name] {
+		d.scalars[name] = true
+		d.scalarOrder = append(d.scalarOrder, name)
+	}
+}
+
+// arguments returns the definitions of the arguments of the field selection
+// that are bound to variables. E.g., "user(login:$login,first:10)" ->
+// "(login: String!)" if variables has a String value for "login".
+func (d *typeDefiner) arguments(selection string) string {
+	open := strings.IndexByte(selection, '(')
+	if open == -1 {
+		return ""
+	}
+	var defs []string
+	for _, m := range variableArgument.FindAllStringSubmatch(topLevelArguments(selection[open+1:]), -1) {
+		v, ok := d.variables[m[2]]
+		if !ok || v == nil {
+			continue
+		}
+		var buf bytes.Buffer
+		writeVariableType(&buf, v)
+		defs = append(defs, m[1]+": "+buf.String())
+	}
+	if len(defs) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(defs, ", ") + ")"
+}
+
+// variableArgument matches an argument bound to a variable. E.g., "login: $login".
+var variableArgument = regexp.MustCompile(`([_A-Za-z][_0-9A-Za-z]*)\s*:\s*\$([_A-Za-z][_0-9A-Za-z]*)`)
+
+// topLevelArguments returns the arguments s, which follow an opening
+// parenthesis, up to the closing parenthesis, with nested lists and
+// input objects blanked out.
+func topLevelArguments(s string) string {
+	b := []byte(s)
+	depth := 0
+	for i := range b {
+		switch c := b[i]; {
+		case c == ')' && depth == 0:
+			return string(b[:i])
+		case c == '(' || c == '[' || c == '{':
+			depth++
+			b[i] = ' '
+		case c == ')' || c == ']' || c == '}':
+			depth--
+			b[i] = ' '
+		case depth > 0:
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
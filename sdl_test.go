@@ -0,0 +1,88 @@
+package graphql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestTypeDefinitions(t *testing.T) {
+	type Actor struct {
+		Login     graphql.String
+		AvatarURL graphql.String `graphql:"avatarUrl(size:$avatarSize)"`
+	}
+	var q struct {
+		Repository struct {
+			ID     graphql.ID
+			Issues struct {
+				Nodes []struct {
+					Number    graphql.Int
+					CreatedAt time.Time
+					Author    *Actor
+				}
+			} `graphql:"issues(first:$first, states:[OPEN])"`
+			Owner struct {
+				Actor
+				OnOrganization struct {
+					Name graphql.String
+				} `graphql:"... on Organization"`
+			}
+		} `graphql:"repo: repository(owner:$owner name:$name, filter:{label:$label})"`
+		Viewer struct {
+			Login    graphql.String
+			Typename string `graphql:"__typename"`
+		}
+	}
+	variables := map[string]interface{}{
+		"owner":      graphql.String("golang"),
+		"name":       graphql.String("go"),
+		"first":      graphql.Int(10),
+		"avatarSize": (*graphql.Int)(nil),
+	}
+	got := graphql.TypeDefinitions("Query", q, variables)
+	want := `scalar Time
+
+type Query {
+  repository(owner: String!, name: String!): Repository
+  viewer: Viewer
+}
+
+type Repository {
+  id: ID
+  issues(first: Int!): Issues
+  owner: Owner
+}
+
+type Issues {
+  nodes: [Nodes]
+}
+
+type Nodes {
+  number: Int
+  createdAt: Time
+  author: Actor
+}
+
+type Actor {
+  login: String
+  avatarUrl(size: Int): String
+}
+
+type Owner {
+  login: String
+  avatarUrl(size: Int): String
+}
+
+type Organization {
+  name: String
+}
+
+type Viewer {
+  login: String
+}
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,222 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// MutationStep is a step of Client.MutateSequence.
+type MutationStep struct {
+	// Mutation is a pointer to struct that corresponds to the GraphQL
+	// schema, like the argument of Mutate, and Variables its variables.
+	Mutation  interface{}
+	Variables map[string]interface{}
+
+	// Compensate, if non-nil, undoes the step once it was applied,
+	// such as by running the inverse mutation, when a later step fails.
+	// ctx carries the values of the context passed to MutateSequence,
+	// but not its cancellation, so that steps are compensated even once
+	// it's canceled; see WithCompensationTimeout.
+	Compensate func(ctx context.Context) error
+}
+
+// FailurePolicy is what Client.MutateSequence does when a step fails.
+type FailurePolicy uint8
+
+const (
+	// CompensateOnFailure stops at the first failed step, and compensates
+	// the steps applied so far, last first. It's the default.
+	CompensateOnFailure FailurePolicy = iota
+
+	// StopOnFailure stops at the first failed step,
+	// leaving the steps applied so far as they are.
+	StopOnFailure
+
+	// ContinueOnFailure applies every step regardless of failures.
+	ContinueOnFailure
+)
+
+// SequenceOption configures Client.MutateSequence.
+type SequenceOption func(*sequenceConfig)
+
+type sequenceConfig struct {
+	policy              FailurePolicy
+	groupSize           int           // Number of steps sent per request.
+	compensationTimeout time.Duration // Time limit for compensating the steps.
+	opts                []RequestOption
+}
+
+// WithFailurePolicy sets what MutateSequence does when a step fails.
+func WithFailurePolicy(policy FailurePolicy) SequenceOption {
+	return func(cfg *sequenceConfig) { cfg.policy = policy }
+}
+
+// WithGroupSize makes MutateSequence send the steps in groups of n, as
+// a single request per group, with MutateBatch. The steps of a group are
+// all sent even if one of them fails; the failure policy applies once the
+// group is done.
+func WithGroupSize(n int) SequenceOption {
+	return func(cfg *sequenceConfig) { cfg.groupSize = n }
+}
+
+// WithCompensationTimeout sets the time limit for compensating the steps
+// applied, as a whole. The default is one minute.
+func WithCompensationTimeout(d time.Duration) SequenceOption {
+	return func(cfg *sequenceConfig) { cfg.compensationTimeout = d }
+}
+
+// WithSequenceRequestOptions makes MutateSequence send the requests
+// of the steps with opts.
+func WithSequenceRequestOptions(opts ...RequestOption) SequenceOption {
+	return func(cfg *sequenceConfig) { cfg.opts = append(cfg.opts, opts...) }
+}
+
+// StepError is the failure of a step of Client.MutateSequence,
+// or of its compensation.
+type StepError struct {
+	Index int   // Index of the step.
+	Err   error // The error of the request, or its first GraphQL error.
+}
+
+func (e StepError) Error() string {
+	return fmt.Sprintf("step %d: %v", e.Index, e.Err)
+}
+
+func (e StepError) Unwrap() error { return e.Err }
+
+// SequenceError is returned by Client.MutateSequence when steps failed.
+type SequenceError struct {
+	Failures []StepError // The failed steps, in order.
+
+	Compensated          []int       // Indices of the steps compensated, in the order they were.
+	CompensationFailures []StepError // The compensations that failed.
+}
+
+func (e *SequenceError) Error() string {
+	msg := fmt.Sprintf("mutation %v failed", e.Failures[0])
+	if n := len(e.Failures); n > 1 {
+		msg += fmt.Sprintf(" (and %d more steps)", n-1)
+	}
+	if len(e.Compensated) > 0 {
+		msg += fmt.Sprintf("; compensated %d steps", len(e.Compensated))
+	}
+	if n := len(e.CompensationFailures); n > 0 {
+		msg += fmt.Sprintf("; %d compensations failed, first: %v", n, e.CompensationFailures[0])
+	}
+	return msg
+}
+
+// Unwrap returns the error of the first failed step.
+func (e *SequenceError) Unwrap() error { return e.Failures[0].Err }
+
+// MutateSequence applies the mutations of steps one after the other, in
+// order, populating the response of each into its Mutation. A step fails
+// if its request fails, or if the server responds with GraphQL errors.
+// What happens then depends on the failure policy, which by default stops
+// at the first failure, and calls the Compensate functions of the steps
+// already applied, last first, giving callers a saga-like primitive.
+//
+// It returns a *SequenceError if any step failed.
+func (c *Client) MutateSequence(ctx context.Context, steps []MutationStep, opts ...SequenceOption) error {
+	cfg := sequenceConfig{groupSize: 1, compensationTimeout: time.Minute}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.groupSize < 1 {
+		cfg.groupSize = 1
+	}
+	var seqErr SequenceError
+	var applied []int
+	for start := 0; start < len(steps); start += cfg.groupSize {
+		end := start + cfg.groupSize
+		if end > len(steps) {
+			end = len(steps)
+		}
+		failures := c.applyGroup(ctx, steps, start, end, cfg.opts)
+		for i := start; i < end; i++ {
+			if !containsStep(failures, i) {
+				applied = append(applied, i)
+			}
+		}
+		seqErr.Failures = append(seqErr.Failures, failures...)
+		if len(failures) > 0 && cfg.policy != ContinueOnFailure {
+			break
+		}
+	}
+	if len(seqErr.Failures) == 0 {
+		return nil
+	}
+	if cfg.policy == CompensateOnFailure {
+		// Steps often fail because ctx is canceled or times out,
+		// which mustn't keep them from being compensated.
+		ctx, cancel := context.WithTimeout(detachedContext{ctx}, cfg.compensationTimeout)
+		defer cancel()
+		for i := len(applied) - 1; i >= 0; i-- {
+			step := steps[applied[i]]
+			if step.Compensate == nil {
+				continue
+			}
+			err := step.Compensate(ctx)
+			if err != nil {
+				seqErr.CompensationFailures = append(seqErr.CompensationFailures, StepError{Index: applied[i], Err: err})
+				continue
+			}
+			seqErr.Compensated = append(seqErr.Compensated, applied[i])
+		}
+	}
+	return &seqErr
+}
+
+// applyGroup applies steps[start:end] in a single request,
+// and returns the failures of the steps.
+func (c *Client) applyGroup(ctx context.Context, steps []MutationStep, start, end int, opts []RequestOption) []StepError {
+	if end-start == 1 {
+		step := steps[start]
+		errs, err := c.Mutate(ctx, step.Mutation, step.Variables, opts...)
+		if err == nil && len(errs) > 0 {
+			err = errs[0]
+		}
+		if err != nil {
+			return []StepError{{Index: start, Err: err}}
+		}
+		return nil
+	}
+	ms := make([]interface{}, 0, end-start)
+	vars := make([]map[string]interface{}, 0, end-start)
+	for _, step := range steps[start:end] {
+		ms = append(ms, step.Mutation)
+		vars = append(vars, step.Variables)
+	}
+	errs, err := c.MutateBatch(ctx, ms, vars, opts...)
+	var failures []StepError
+	for i := range ms {
+		switch {
+		case err != nil:
+			failures = append(failures, StepError{Index: start + i, Err: err})
+		case len(errs[i]) > 0:
+			failures = append(failures, StepError{Index: start + i, Err: errs[i][0]})
+		}
+	}
+	return failures
+}
+
+// detachedContext is a context with the values of its parent,
+// but not its deadline and cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+func containsStep(failures []StepError, i int) bool {
+	for _, f := range failures {
+		if f.Index == i {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestClient_MutateSequence(t *testing.T) {
+	var requests int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		var in struct {
+			Variables map[string]string
+		}
+		err := json.NewDecoder(req.Body).Decode(&in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Items with ID "3" can't be added.
+		data := make(map[string]interface{})
+		var errs []interface{}
+		for name, id := range in.Variables {
+			field := strings.TrimSuffix(name, "id") + "addItem"
+			if id == "3" {
+				data[field] = nil
+				errs = append(errs, map[string]interface{}{"message": "out of stock", "path": []string{field}})
+				continue
+			}
+			data[field] = map[string]string{"id": id}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(map[string]interface{}{"data": data, "errors": errs})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type addItem struct {
+		AddItem *struct {
+			ID graphql.ID
+		} `graphql:"addItem(id: $id)"`
+	}
+	var compensated []int
+	newSteps := func() []graphql.MutationStep {
+		var steps []graphql.MutationStep
+		for i, id := range []string{"1", "2", "3", "4"} {
+			i := i
+			steps = append(steps, graphql.MutationStep{
+				Mutation:  new(addItem),
+				Variables: map[string]interface{}{"id": graphql.ID(id)},
+				Compensate: func(ctx context.Context) error {
+					compensated = append(compensated, i)
+					if i == 0 {
+						return errors.New("already shipped")
+					}
+					return nil
+				},
+			})
+		}
+		return steps
+	}
+
+	for _, tc := range []struct {
+		name                 string
+		opts                 []graphql.SequenceOption
+		wantRequests         int
+		wantFailures         []int
+		wantCompensated      []int
+		wantCompensatedCalls []int
+	}{
+		{
+			name:                 "compensate",
+			wantRequests:         3,
+			wantFailures:         []int{2},
+			wantCompensated:      []int{1},
+			wantCompensatedCalls: []int{1, 0},
+		},
+		{
+			name:         "stop",
+			opts:         []graphql.SequenceOption{graphql.WithFailurePolicy(graphql.StopOnFailure)},
+			wantRequests: 3,
+			wantFailures: []int{2},
+		},
+		{
+			name:         "continue",
+			opts:         []graphql.SequenceOption{graphql.WithFailurePolicy(graphql.ContinueOnFailure)},
+			wantRequests: 4,
+			wantFailures: []int{2},
+		},
+		{
+			name:                 "groups",
+			opts:                 []graphql.SequenceOption{graphql.WithGroupSize(2)},
+			wantRequests:         2,
+			wantFailures:         []int{2},
+			wantCompensated:      []int{3, 1},
+			wantCompensatedCalls: []int{3, 1, 0},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			requests, compensated = 0, nil
+			steps := newSteps()
+			err := client.MutateSequence(context.Background(), steps, tc.opts...)
+			var seqErr *graphql.SequenceError
+			if !errors.As(err, &seqErr) {
+				t.Fatalf("got error: %v, want a *SequenceError", err)
+			}
+			var failures []int
+			for _, f := range seqErr.Failures {
+				failures = append(failures, f.Index)
+			}
+			if requests != tc.wantRequests {
+				t.Errorf("got %v requests, want: %v", requests, tc.wantRequests)
+			}
+			if !reflect.DeepEqual(failures, tc.wantFailures) {
+				t.Errorf("got failures: %v, want: %v", failures, tc.wantFailures)
+			}
+			if !reflect.DeepEqual(seqErr.Compensated, tc.wantCompensated) {
+				t.Errorf("got compensated: %v, want: %v", seqErr.Compensated, tc.wantCompensated)
+			}
+			if !reflect.DeepEqual(compensated, tc.wantCompensatedCalls) {
+				t.Errorf("got compensations: %v, want: %v", compensated, tc.wantCompensatedCalls)
+			}
+			if steps[1].Mutation.(*addItem).AddItem == nil {
+				t.Error("got no response for step 1")
+			}
+		})
+	}
+}
+
+func TestClient_MutateSequence_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if strings.Contains(mustRead(req.Body), "removeItem") {
+			// The second step is canceled while it's in flight.
+			cancel()
+			http.Error(w, "canceled", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"addItem": {"id": "1"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var add struct {
+		AddItem struct {
+			ID graphql.ID
+		} `graphql:"addItem(id: \"1\")"`
+	}
+	var remove struct {
+		RemoveItem struct {
+			ID graphql.ID
+		} `graphql:"removeItem(id: \"2\")"`
+	}
+	var compensateErr error
+	steps := []graphql.MutationStep{
+		{Mutation: &add, Compensate: func(ctx context.Context) error {
+			_, hasDeadline := ctx.Deadline()
+			if !hasDeadline {
+				t.Error("got compensation context without a deadline")
+			}
+			compensateErr = ctx.Err()
+			return compensateErr
+		}},
+		{Mutation: &remove},
+	}
+	err := client.MutateSequence(ctx, steps, graphql.WithCompensationTimeout(time.Minute))
+	var seqErr *graphql.SequenceError
+	if !errors.As(err, &seqErr) || ctx.Err() == nil {
+		t.Fatalf("got error: %v, want a *SequenceError from a canceled step", err)
+	}
+	if compensateErr != nil || !reflect.DeepEqual(seqErr.Compensated, []int{0}) {
+		t.Errorf("got compensated: %v, compensation error: %v, want step 0 compensated", seqErr.Compensated, compensateErr)
+	}
+}
@@ -0,0 +1,97 @@
+package graphql
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerTiming is a metric that a server reported in the Server-Timing
+// response header, such as the time spent resolving an operation.
+// Specification: https://www.w3.org/TR/server-timing/.
+type ServerTiming struct {
+	Name        string
+	Duration    time.Duration // 0 if not reported.
+	Description string        // "" if not reported.
+}
+
+// ParseServerTiming returns the metrics of the Server-Timing fields of h,
+// in order. Malformed metrics are skipped.
+func ParseServerTiming(h http.Header) []ServerTiming {
+	var timings []ServerTiming
+	for _, field := range h.Values("Server-Timing") {
+		for _, metric := range splitQuoted(field, ',') {
+			params := splitQuoted(metric, ';')
+			t := ServerTiming{Name: strings.TrimSpace(params[0])}
+			if t.Name == "" {
+				continue
+			}
+			for _, param := range params[1:] {
+				name, value, _ := strings.Cut(param, "=")
+				value = unquote(strings.TrimSpace(value))
+				switch strings.ToLower(strings.TrimSpace(name)) {
+				case "dur":
+					ms, err := strconv.ParseFloat(value, 64)
+					if err == nil && ms >= 0 {
+						t.Duration = time.Duration(ms * float64(time.Millisecond))
+					}
+				case "desc":
+					t.Description = value
+				}
+			}
+			timings = append(timings, t)
+		}
+	}
+	return timings
+}
+
+// serverDuration returns the time the server reported spending on a
+// request in timings: the duration of the metric named "total" if there's
+// one, and else the sum of the durations of the metrics.
+func serverDuration(timings []ServerTiming) time.Duration {
+	var sum time.Duration
+	for _, t := range timings {
+		if strings.EqualFold(t.Name, "total") {
+			return t.Duration
+		}
+		sum += t.Duration
+	}
+	return sum
+}
+
+// splitQuoted splits s around the separator sep, except where it's
+// inside a quoted string.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	inString := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && inString:
+			i++
+		case c == '"':
+			inString = !inString
+		case c == sep && !inString:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote returns the content of the quoted string s,
+// or s if it's not quoted.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	var b strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' && i+1 < len(s)-1 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
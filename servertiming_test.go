@@ -0,0 +1,65 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestParseServerTiming(t *testing.T) {
+	h := http.Header{"Server-Timing": {
+		`db;dur=53.5, cache;desc="Cache Read, miss";dur=0.25`,
+		`total;dur=120`,
+		`cdn-hit, ;dur=1, app;dur=bad`,
+	}}
+	want := []graphql.ServerTiming{
+		{Name: "db", Duration: 53500 * time.Microsecond},
+		{Name: "cache", Duration: 250 * time.Microsecond, Description: "Cache Read, miss"},
+		{Name: "total", Duration: 120 * time.Millisecond},
+		{Name: "cdn-hit"},
+		{Name: "app"},
+	}
+	if got := graphql.ParseServerTiming(h); !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+}
+
+func TestStats_server(t *testing.T) {
+	for _, tc := range []struct {
+		serverTiming string
+		want         time.Duration
+	}{
+		{"", 0},
+		{"db;dur=20, app;dur=30", 50 * time.Millisecond},
+		{"db;dur=20, total;dur=40", 40 * time.Millisecond},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if tc.serverTiming != "" {
+				w.Header().Set("Server-Timing", tc.serverTiming)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+		}))
+		client := graphql.NewClient(srv.URL, nil)
+		var stats graphql.Stats
+		_, err := client.Do(graphql.WithStats(context.Background(), &stats), "{viewer{login}}", nil)
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Server != tc.want {
+			t.Errorf("%q: got Server: %v, want: %v", tc.serverTiming, stats.Server, tc.want)
+		}
+		if got, want := stats.Network(), stats.TTFB-stats.Server; stats.Server < stats.TTFB && got != want {
+			t.Errorf("%q: got Network: %v, want: %v", tc.serverTiming, got, want)
+		}
+	}
+	if got := (graphql.Stats{TTFB: time.Millisecond, Server: time.Second}).Network(); got != 0 {
+		t.Errorf("got Network: %v, want: 0 when the server reports more than TTFB", got)
+	}
+}
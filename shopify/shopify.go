@@ -0,0 +1,167 @@
+// Package shopify configures GraphQL clients for Shopify's Admin GraphQL
+// API, which limits clients with a leaky bucket of query cost points: each
+// query costs points, and points are restored at a fixed rate. The status
+// of the bucket is reported in the "cost" extension of every response, and
+// queries that cost more than what's available fail with THROTTLED errors.
+//
+// Documentation: https://shopify.dev/docs/api/usage/rate-limits.
+package shopify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+// URL returns the URL of the Admin GraphQL API of shop, such as
+// "example" for example.myshopify.com, in the API version, such as "2024-04".
+func URL(shop, version string) string {
+	return "https://" + shop + ".myshopify.com/admin/api/" + version + "/graphql.json"
+}
+
+// NewClient creates a GraphQL client for the Shopify Admin GraphQL API
+// at url, such as returned by URL. httpClient should authenticate requests,
+// e.g., with the X-Shopify-Access-Token header. If httpClient is nil,
+// then http.DefaultClient is used.
+//
+// The client waits for throttle before each request, and keeps it up to
+// date with the cost extension of responses. It retries throttled requests
+// once enough points are restored, and transient failures with exponential
+// backoff. opts are applied after these options, so they take precedence.
+func NewClient(url string, httpClient *http.Client, throttle *Throttle, opts ...graphql.ClientOption) *graphql.Client {
+	opts = append([]graphql.ClientOption{
+		graphql.WithRateLimiter(throttle),
+		graphql.WithResponseFunc(throttle.Observe),
+		graphql.WithRetry(3, graphql.ExponentialBackoff(time.Second, time.Minute)),
+		graphql.WithRetryHints(throttle),
+	}, opts...)
+	return graphql.NewClient(url, httpClient, opts...)
+}
+
+// Cost is the "cost" extension of responses.
+type Cost struct {
+	RequestedQueryCost float64        `json:"requestedQueryCost"` // Cost estimated before executing the query.
+	ActualQueryCost    float64        `json:"actualQueryCost"`    // Cost charged, which is 0 if the query was throttled.
+	ThrottleStatus     ThrottleStatus `json:"throttleStatus"`
+}
+
+// ThrottleStatus is the status of the leaky bucket of query cost points.
+type ThrottleStatus struct {
+	MaximumAvailable   float64 `json:"maximumAvailable"`   // Points the bucket holds when full.
+	CurrentlyAvailable float64 `json:"currentlyAvailable"` // Points available after the query.
+	RestoreRate        float64 `json:"restoreRate"`        // Points restored per second.
+}
+
+// CostOf returns the "cost" extension of resp, if present.
+func CostOf(resp *graphql.Response) (Cost, bool) {
+	var cost Cost
+	if resp == nil || json.Unmarshal(resp.Extensions["cost"], &cost) != nil {
+		return Cost{}, false
+	}
+	return cost, cost.ThrottleStatus.RestoreRate > 0
+}
+
+// Throttle tracks the points available in the bucket, and delays requests
+// so that they don't cost more than what's available, rather than letting
+// them fail. The cost of a request is estimated as the highest requested
+// cost observed so far. It implements graphql.Limiter and
+// graphql.RetryHintParser. Its zero value isn't usable; use NewThrottle.
+type Throttle struct {
+	mu         sync.Mutex
+	status     ThrottleStatus // Status as last observed, with the points reserved by requests since deducted.
+	observedAt time.Time      // When status was observed, or zero if it hasn't been yet.
+	estimate   float64        // Estimated cost of a request.
+}
+
+// NewThrottle returns a Throttle that hasn't observed the bucket yet,
+// so that it lets requests through until it does.
+func NewThrottle() *Throttle {
+	return &Throttle{}
+}
+
+// available returns the points available at now. t.mu must be held.
+func (t *Throttle) available(now time.Time) float64 {
+	a := t.status.CurrentlyAvailable + now.Sub(t.observedAt).Seconds()*t.status.RestoreRate
+	if a > t.status.MaximumAvailable {
+		a = t.status.MaximumAvailable
+	}
+	return a
+}
+
+// Wait implements graphql.Limiter. It blocks until enough points are
+// available for a request, or ctx is done, and reserves them.
+func (t *Throttle) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	if t.observedAt.IsZero() {
+		t.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	cost := t.estimate
+	if cost > t.status.MaximumAvailable {
+		cost = t.status.MaximumAvailable
+	}
+	available := t.available(now)
+	var wait time.Duration
+	if available < cost {
+		wait = time.Duration((cost - available) / t.status.RestoreRate * float64(time.Second))
+	}
+	// Reserve the points, so that concurrent requests wait for their own.
+	t.status.CurrentlyAvailable = available - cost
+	t.observedAt = now
+	t.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Observe updates t with the cost extension of resp, if present.
+// err is ignored; its signature suits graphql.WithResponseFunc.
+func (t *Throttle) Observe(resp *graphql.Response, err error) {
+	cost, ok := CostOf(resp)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status = cost.ThrottleStatus
+	t.observedAt = time.Now()
+	if cost.RequestedQueryCost > t.estimate {
+		t.estimate = cost.RequestedQueryCost
+	}
+}
+
+// RetryAfter implements graphql.RetryHintParser. It finds a hint in
+// responses with THROTTLED errors: how long it takes for the points
+// the query requested to be restored.
+func (t *Throttle) RetryAfter(resp *graphql.Response, err error) (time.Duration, bool) {
+	if resp == nil || !isThrottled(resp.Errors) {
+		return 0, false
+	}
+	cost, ok := CostOf(resp)
+	if !ok {
+		return 0, true // Retry after the backoff.
+	}
+	missing := cost.RequestedQueryCost - cost.ThrottleStatus.CurrentlyAvailable
+	if missing <= 0 {
+		return 0, true
+	}
+	return time.Duration(missing / cost.ThrottleStatus.RestoreRate * float64(time.Second)), true
+}
+
+// isThrottled reports whether errs has a THROTTLED error.
+func isThrottled(errs []graphql.DataError) bool {
+	return graphql.DataErrors(errs).HasCode("THROTTLED")
+}
@@ -0,0 +1,96 @@
+package shopify_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+	"github.com/merico-dev/graphql/shopify"
+)
+
+func TestNewClient_throttled(t *testing.T) {
+	var requests int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&requests, 1) == 1 {
+			io.WriteString(w, `{"errors": [{"message": "Throttled", "extensions": {"code": "THROTTLED"}}], "extensions": {"cost": {"requestedQueryCost": 100, "actualQueryCost": null, "throttleStatus": {"maximumAvailable": 1000, "currentlyAvailable": 50, "restoreRate": 1000}}}}`)
+			return
+		}
+		io.WriteString(w, `{"data": {"shop": {"name": "Gophers"}}, "extensions": {"cost": {"requestedQueryCost": 100, "actualQueryCost": 2, "throttleStatus": {"maximumAvailable": 1000, "currentlyAvailable": 998, "restoreRate": 1000}}}}`)
+	})
+	throttle := shopify.NewThrottle()
+	client := shopify.NewClient("/graphql.json", &http.Client{Transport: localRoundTripper{handler: handler}}, throttle,
+		graphql.WithRetry(3, nil))
+
+	var q struct {
+		Shop struct {
+			Name graphql.String
+		}
+	}
+	start := time.Now()
+	dataErrors, err := client.Query(context.Background(), &q, nil)
+	if err != nil || dataErrors != nil {
+		t.Fatal(dataErrors, err)
+	}
+	if got, want := q.Shop.Name, graphql.String("Gophers"); got != want {
+		t.Errorf("got name: %q, want: %q", got, want)
+	}
+	if got, want := atomic.LoadInt32(&requests), int32(2); got != want {
+		t.Errorf("got %v requests, want: %v", got, want)
+	}
+	// 50 points were missing, restored at 1000 per second.
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("got retry after %v, want at least 50ms", elapsed)
+	}
+}
+
+func TestThrottle_Wait(t *testing.T) {
+	throttle := shopify.NewThrottle()
+	ctx := context.Background()
+	if err := throttle.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	throttle.Observe(&graphql.Response{Extensions: map[string]json.RawMessage{
+		"cost": json.RawMessage(`{"requestedQueryCost": 100, "actualQueryCost": 100, "throttleStatus": {"maximumAvailable": 1000, "currentlyAvailable": 150, "restoreRate": 1000}}`),
+	}}, nil)
+
+	start := time.Now()
+	if err := throttle.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed >= 50*time.Millisecond {
+		t.Errorf("got wait of %v with enough points available, want none", elapsed)
+	}
+	// The first request reserved 100 of the 150 points,
+	// so the second has to wait for 50 more.
+	if err := throttle.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("got wait of %v, want about 50ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := throttle.Wait(ctx); err != context.Canceled {
+		t.Errorf("got error: %v, want: %v", err, context.Canceled)
+	}
+}
+
+// localRoundTripper is an http.RoundTripper that executes HTTP transactions
+// by using handler directly, instead of going over an HTTP connection.
+type localRoundTripper struct {
+	handler http.Handler
+}
+
+func (l localRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	l.handler.ServeHTTP(w, req)
+	return w.Result(), nil
+}
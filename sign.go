@@ -0,0 +1,236 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Signer authenticates HTTP requests to the server, such as by setting an
+// Authorization header computed from the request. Sign is called with each
+// request once it's complete, including its body and headers, right before
+// it's sent, and again for each retry. If it returns an error, the request
+// fails with it. The request's context is the operation's.
+type Signer interface {
+	Sign(req *http.Request) error
+}
+
+// SignerFunc is an adapter to allow the use of ordinary functions as Signers.
+type SignerFunc func(req *http.Request) error
+
+// Sign calls f(req).
+func (f SignerFunc) Sign(req *http.Request) error {
+	return f(req)
+}
+
+// WithSigner makes the client sign the HTTP requests of operations, batches
+// and subscriptions over Server-Sent Events with s. Subscriptions over
+// WebSocket aren't signed; their connection is authenticated with
+// WithConnectionInitPayload instead.
+func WithSigner(s Signer) ClientOption {
+	return func(c *Client) {
+		c.signer = s
+	}
+}
+
+// sign signs req, sent in ctx, with the client's signer, if any,
+// and returns it.
+func (c *Client) sign(ctx context.Context, req *http.Request) (*http.Request, error) {
+	if c.signer == nil {
+		return req, nil
+	}
+	req = req.WithContext(ctx)
+	return req, c.signer.Sign(req)
+}
+
+// BearerToken returns a Signer that sets the Authorization header of
+// requests to "Bearer " followed by token.
+func BearerToken(token string) Signer {
+	return SignerFunc(func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// APIKey returns a Signer that sets the header named header of requests
+// to key, such as "x-api-key" for AWS AppSync APIs authenticated with
+// API keys.
+func APIKey(header, key string) Signer {
+	return SignerFunc(func(req *http.Request) error {
+		req.Header.Set(header, key)
+		return nil
+	})
+}
+
+// AWSCredentials are the credentials of an AWS principal.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Token of temporary credentials, if any.
+}
+
+// AWSSigV4 returns a Signer that signs requests with AWS Signature
+// Version 4 for the service service, such as "appsync", in the region
+// region, such as "us-east-1", with the credentials credentials returns
+// for the request's context. It's meant for AWS AppSync APIs with IAM
+// authorization, e.g., with credentials from an AWS SDK:
+//
+//	graphql.AWSSigV4("us-east-1", "appsync", func(ctx context.Context) (graphql.AWSCredentials, error) {
+//		creds, err := cfg.Credentials.Retrieve(ctx)
+//		return graphql.AWSCredentials{
+//			AccessKeyID:     creds.AccessKeyID,
+//			SecretAccessKey: creds.SecretAccessKey,
+//			SessionToken:    creds.SessionToken,
+//		}, err
+//	})
+func AWSSigV4(region, service string, credentials func(ctx context.Context) (AWSCredentials, error)) Signer {
+	return &sigV4{region: region, service: service, credentials: credentials, now: time.Now}
+}
+
+// sigV4 signs requests with AWS Signature Version 4.
+type sigV4 struct {
+	region      string
+	service     string
+	credentials func(ctx context.Context) (AWSCredentials, error)
+	now         func() time.Time
+}
+
+func (s *sigV4) Sign(req *http.Request) error {
+	creds, err := s.credentials(req.Context())
+	if err != nil {
+		return err
+	}
+	payload, err := requestPayload(req)
+	if err != nil {
+		return err
+	}
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	// Canonical request.
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for k, vs := range req.Header {
+		k = strings.ToLower(k)
+		if k == "content-type" || strings.HasPrefix(k, "x-amz-") {
+			for i, v := range vs {
+				vs[i] = strings.Join(strings.Fields(v), " ")
+			}
+			headers[k] = strings.Join(vs, ",")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsEscape(path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hexSHA256(payload),
+	}, "\n")
+
+	// String to sign, and signature.
+	date := now.Format("20060102")
+	scope := date + "/" + s.region + "/" + s.service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hexSHA256([]byte(canonicalRequest))
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	for _, part := range []string{s.region, s.service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+	return nil
+}
+
+// requestPayload returns the body of req, leaving it to be sent.
+func requestPayload(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return ioutil.ReadAll(body)
+	}
+	payload, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(payload))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(payload)), nil
+	}
+	return payload, nil
+}
+
+// canonicalQuery returns the query parameters query in the canonical form
+// of AWS Signature Version 4: sorted by name and value, and escaped.
+func canonicalQuery(query map[string][]string) string {
+	var params []string
+	for k, vs := range query {
+		for _, v := range vs {
+			params = append(params, awsEscape(k, true)+"="+awsEscape(v, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// awsEscape percent-encodes the bytes of s other than unreserved ones,
+// and other than slashes unless slash is true, as AWS Signature Version 4
+// expects.
+func awsEscape(s string, slash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && !slash {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+	}
+	return b.String()
+}
+
+func hexSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	io.WriteString(h, data)
+	return h.Sum(nil)
+}
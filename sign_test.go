@@ -0,0 +1,65 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestWithSigner(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("Authorization"), "Bearer secret"; got != want {
+			t.Errorf("got authorization: %q, want: %q", got, want)
+		}
+		if got, want := req.Header.Get("X-Api-Key"), "da2-key"; got != want {
+			t.Errorf("got API key: %q, want: %q", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	signers := []graphql.Signer{graphql.BearerToken("secret"), graphql.APIKey("x-api-key", "da2-key")}
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithSigner(graphql.SignerFunc(func(req *http.Request) error {
+			for _, s := range signers {
+				err := s.Sign(req)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})))
+
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Viewer.Login, graphql.String("gopher"); got != want {
+		t.Errorf("got login: %q, want: %q", got, want)
+	}
+}
+
+func TestWithSigner_error(t *testing.T) {
+	errExpired := errors.New("credentials expired")
+	client := graphql.NewClient("/graphql", &http.Client{Transport: failingRoundTripper{err: errors.New("request sent")}},
+		graphql.WithSigner(graphql.AWSSigV4("us-east-1", "appsync", func(context.Context) (graphql.AWSCredentials, error) {
+			return graphql.AWSCredentials{}, errExpired
+		})))
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil)
+	if !errors.Is(err, errExpired) {
+		t.Errorf("got error: %v, want: %v", err, errExpired)
+	}
+}
@@ -0,0 +1,74 @@
+package graphql
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAWSSigV4(t *testing.T) {
+	tests := []struct {
+		method      string
+		url         string
+		body        string
+		service     string
+		credentials AWSCredentials
+		want        string
+	}{
+		{
+			method:      http.MethodGet,
+			url:         "https://example.amazon.com/",
+			service:     "service",
+			credentials: AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"},
+			want:        "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=7ab4567ae243ee168f6bf18206b2b40b61ce08277323168138fa113ed23c538e",
+		},
+		{
+			method:      http.MethodGet,
+			url:         "https://example.amazon.com/?Param2=value2&Param1=value1",
+			service:     "service",
+			credentials: AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"},
+			want:        "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=ca0a842792a27475df455b2925aa79d50a98e27d1733a46b57c22810e6b1a7bc",
+		},
+		{
+			method:      http.MethodPost,
+			url:         "https://example.appsync-api.us-east-1.amazonaws.com/graphql",
+			body:        `{"query":"{viewer{login}}"}`,
+			service:     "appsync",
+			credentials: AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", SessionToken: "TOKEN"},
+			want:        "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/appsync/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-security-token, Signature=6e0bd645a5c3cc1e512dcbd810d0fdc35dff96059e6c2bd8ada1303a98f1884a",
+		},
+	}
+	for _, tc := range tests {
+		signer := AWSSigV4("us-east-1", tc.service, func(context.Context) (AWSCredentials, error) {
+			return tc.credentials, nil
+		})
+		signer.(*sigV4).now = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+		req, err := http.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		err = signer.Sign(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := req.Header.Get("Authorization"); got != tc.want {
+			t.Errorf("%s: got authorization: %q, want: %q", tc.url, got, tc.want)
+		}
+		if got, want := req.Header.Get("X-Amz-Date"), "20150830T123600Z"; got != want {
+			t.Errorf("%s: got date: %q, want: %q", tc.url, got, want)
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(body); got != tc.body {
+			t.Errorf("%s: got body: %q, want: %q", tc.url, got, tc.body)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package graphql
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// WithSpillToDisk makes the client spool the bodies of responses larger
+// than threshold bytes to a temporary file in dir, and decode them from
+// there, so that rare giant responses don't exhaust the memory of
+// containers with tight limits. If dir is empty, the default directory
+// for temporary files is used. Files are removed once decoded.
+//
+// Spooling frees the connection as soon as the response is received, but
+// on its own doesn't bound the memory used to decode responses: a spooled
+// body is decoded as usual, which reads its data into memory as a whole.
+// Use it along with WithStreamingDecode, which makes the responses of Query
+// and Mutate be decoded from disk into query structs without ever being
+// held in memory as a whole.
+func WithSpillToDisk(threshold int64, dir string) ClientOption {
+	return func(c *Client) {
+		c.spill = &spill{threshold: threshold, dir: dir}
+	}
+}
+
+// spill is the configuration of spooling response bodies to disk.
+type spill struct {
+	threshold int64
+	dir       string
+}
+
+// spool reads r, and returns a reader of its content, from memory if it's
+// at most s.threshold bytes, or from a temporary file otherwise.
+// cleanup must be called once the returned reader isn't needed anymore.
+func (s *spill) spool(r io.Reader) (_ io.Reader, cleanup func(), err error) {
+	var buf bytes.Buffer
+	_, err = io.CopyN(&buf, r, s.threshold+1)
+	if err == io.EOF {
+		return &buf, func() {}, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	f, err := ioutil.TempFile(s.dir, "graphql-response-*.json")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup = func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	defer func() {
+		if err != nil {
+			cleanup()
+		}
+	}()
+	_, err = buf.WriteTo(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = io.Copy(f, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, cleanup, nil
+}
@@ -0,0 +1,148 @@
+package graphql_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestWithSpillToDisk(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	dir := t.TempDir()
+	for _, streaming := range []bool{false, true} {
+		opts := []graphql.ClientOption{graphql.WithSpillToDisk(10, dir)}
+		if streaming {
+			opts = append(opts, graphql.WithStreamingDecode())
+		}
+		client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, opts...)
+		var q struct {
+			Viewer struct {
+				Login graphql.String
+			}
+		}
+		_, err := client.Query(context.Background(), &q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := q.Viewer.Login, graphql.String("gopher"); got != want {
+			t.Errorf("streaming %v: got q.Viewer.Login: %q, want: %q", streaming, got, want)
+		}
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("got spooled files left behind: %v", files)
+	}
+
+	// Responses are spooled to disk only once larger than the threshold.
+	missing := filepath.Join(dir, "missing")
+	small := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithSpillToDisk(1000, missing))
+	if _, err := small.Do(context.Background(), "{viewer{login}}", nil); err != nil {
+		t.Errorf("got error below threshold: %v", err)
+	}
+	large := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithSpillToDisk(10, missing))
+	if _, err := large.Do(context.Background(), "{viewer{login}}", nil); !os.IsNotExist(err) {
+		t.Errorf("got error above threshold: %v, want a missing directory error", err)
+	}
+}
+
+// paddedRoundTripper responds with a JSON response of about size bytes,
+// generated while it's read, whose data has a viewer login of gopher
+// padded with strings.
+type paddedRoundTripper struct {
+	size int
+}
+
+func (p paddedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	padding := `"` + strings.Repeat("x", 1022) + `",`
+	body := io.MultiReader(
+		strings.NewReader(`{"data": {"viewer": {"padding": [`),
+		io.LimitReader(&repeatReader{s: padding}, int64(p.size/len(padding)*len(padding))),
+		strings.NewReader(`null], "login": "gopher"}}}`),
+	)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(body),
+		Request:    req,
+	}, nil
+}
+
+// discarded is a scalar that discards its value.
+type discarded struct{}
+
+func (discarded) UnmarshalJSON([]byte) error { return nil }
+
+// repeatReader is a reader of s repeated forever.
+type repeatReader struct {
+	s string
+	i int // Offset in s of the next byte to read.
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		m := copy(p[n:], r.s[r.i:])
+		n += m
+		r.i = (r.i + m) % len(r.s)
+	}
+	return n, nil
+}
+
+func TestWithSpillToDisk_memory(t *testing.T) {
+	const size = 64 << 20
+	client := graphql.NewClient("/graphql", &http.Client{Transport: paddedRoundTripper{size: size}},
+		graphql.WithSpillToDisk(1<<20, t.TempDir()), graphql.WithStreamingDecode())
+	var q struct {
+		Viewer struct {
+			Login   graphql.String
+			Padding []discarded
+		}
+	}
+
+	// Sample the heap while the response is decoded from disk.
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	base, peak := stats.HeapAlloc, stats.HeapAlloc
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		for {
+			var stats runtime.MemStats
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > peak {
+				peak = stats.HeapAlloc
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}()
+	_, err := client.Query(context.Background(), &q, nil)
+	close(done)
+	<-sampled
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Viewer.Login, graphql.String("gopher"); got != want {
+		t.Errorf("got q.Viewer.Login: %q, want: %q", got, want)
+	}
+	if peak-base > size/4 {
+		t.Errorf("got heap growing by %d bytes decoding a %d byte response, want it not held in memory", peak-base, size)
+	}
+}
@@ -0,0 +1,276 @@
+package graphql
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// WithSSESubscriptions makes the client carry subscriptions over
+// Server-Sent Events, as specified by the GraphQL over SSE protocol in its
+// distinct connections mode, instead of WebSocket. Each subscription is
+// a request to the GraphQL server URL, whose response streams its events.
+// See https://github.com/enisdenjo/graphql-sse/blob/master/PROTOCOL.md.
+//
+// If the stream breaks before the server completes the subscription, the
+// client reconnects, sending the id of the last event it received in the
+// Last-Event-ID header, so that servers supporting it resume the stream
+// where it broke rather than replay or drop events. It stops reconnecting
+// on terminal errors, such as a 401 status code, as subscriptions over
+// WebSocket do.
+func WithSSESubscriptions() ClientOption {
+	return func(c *Client) {
+		c.sseSubscriptions = true
+	}
+}
+
+const (
+	// defaultSSERetry is how long to wait before reconnecting a broken
+	// stream, unless the server sets it with a retry field.
+	defaultSSERetry = time.Second
+
+	// maxSSEReconnects is how many consecutive times reconnecting
+	// a broken stream is attempted.
+	maxSSEReconnects = 3
+)
+
+// errStreamEnded is the error of an event stream that ended
+// before the subscription completed.
+var errStreamEnded = errors.New("event stream ended before the subscription completed")
+
+// sseSubscription is a GraphQL subscription over Server-Sent Events.
+type sseSubscription struct {
+	c   *Client
+	in  requestBody
+	cfg *requestConfig
+
+	mu          sync.Mutex    // Guards body.
+	body        io.ReadCloser // Body of the current response.
+	lastEventID string        // Id of the last event received, if any.
+	retry       time.Duration // How long to wait before reconnecting.
+
+	closed    chan struct{} // Closed once the subscription is shut down.
+	closeOnce sync.Once
+}
+
+// subscribeSSE starts a subscription with query and variables
+// over Server-Sent Events.
+func (c *Client) subscribeSSE(ctx context.Context, query string, variables map[string]interface{}, cfg *requestConfig) (*sseSubscription, error) {
+	variables, err := c.transformVariables(ctx, query, variables)
+	if err != nil {
+		return nil, err
+	}
+	variables, err = sentVariables(variables)
+	if err != nil {
+		return nil, err
+	}
+	s := &sseSubscription{
+		c: c,
+		in: requestBody{
+			Query:      query,
+			Variables:  variables,
+			Extensions: cfg.extensions,
+		},
+		cfg:    cfg,
+		retry:  defaultSSERetry,
+		closed: make(chan struct{}),
+	}
+	err = s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// connect sends the subscription request, resuming after
+// the last event received if any, and starts reading its response.
+func (s *sseSubscription) connect(ctx context.Context) error {
+	req, err := s.c.newRequest(s.in, false, s.cfg)
+	if err != nil {
+		return err
+	}
+	for k, vs := range s.cfg.header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if s.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", s.lastEventID)
+	}
+	req, err = s.c.sign(ctx, req)
+	if err != nil {
+		return err
+	}
+	resp, err := ctxhttp.Do(ctx, s.c.httpClient, req)
+	if err != nil {
+		return err
+	}
+	ct := resp.Header.Get("Content-Type")
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		err := &HTTPStatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Header:     resp.Header,
+		}
+		if isJSONContentType(ct) {
+			err.Body, err.Truncated = s.c.readErrorBody(resp.Body)
+		} else {
+			err.Body, err.Truncated = readTruncated(resp.Body, maxNonJSONBody)
+		}
+		return err
+	}
+	if mediaType, _, _ := mime.ParseMediaType(ct); mediaType != "text/event-stream" {
+		defer resp.Body.Close()
+		err := &ContentTypeError{ContentType: ct}
+		err.Body, err.Truncated = readTruncated(resp.Body, maxNonJSONBody)
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body = resp.Body
+	select {
+	case <-s.closed:
+		// Shut down while connecting.
+		s.body.Close()
+	default:
+	}
+	return nil
+}
+
+// shutdown implements eventStream. In the distinct connections mode,
+// closing the stream is how the client completes the subscription.
+func (s *sseSubscription) shutdown() {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.body != nil {
+			s.body.Close()
+		}
+	})
+}
+
+// run implements eventStream. It reconnects the stream if it breaks
+// before the server completes the subscription, unless it fails with
+// a terminal error, such as a 401 status code.
+func (s *sseSubscription) run(ctx context.Context, handle func(data json.RawMessage, dataErrors []DataError) error, interrupted func(err error) error) error {
+	for {
+		done, err := s.read(handle)
+		s.body.Close()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		} else if done {
+			return err
+		}
+		select {
+		case <-s.closed:
+			return nil
+		default:
+		}
+		if !isTransientSubscriptionError(err) {
+			return err
+		}
+		// The stream broke; resume it after the last event received.
+		err = interrupted(err)
+		if err != nil {
+			return err
+		}
+		for attempt := 1; ; attempt++ {
+			t := time.NewTimer(s.retry)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			case <-s.closed:
+				t.Stop()
+				return nil
+			}
+			err = s.connect(ctx)
+			if err == nil {
+				break
+			} else if ctx.Err() != nil {
+				return ctx.Err()
+			} else if attempt == maxSSEReconnects || !isTransientSubscriptionError(err) {
+				return err
+			}
+		}
+	}
+}
+
+// read reads events from the current response, calling handle with
+// the payload of each next event. done reports whether the subscription
+// ended, rather than the stream broke.
+func (s *sseSubscription) read(handle func(data json.RawMessage, dataErrors []DataError) error) (done bool, err error) {
+	r := bufio.NewReader(s.body)
+	var event, id string
+	var data strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return false, errStreamEnded
+		} else if err != nil && err != io.EOF {
+			return false, err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if line != "" {
+			field, value := line, ""
+			if i := strings.IndexByte(line, ':'); i != -1 {
+				field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+			}
+			switch field {
+			case "event":
+				event = value
+			case "data":
+				if data.Len() > 0 {
+					data.WriteByte('\n')
+				}
+				data.WriteString(value)
+			case "id":
+				id = value
+			case "retry":
+				if ms, err := strconv.Atoi(value); err == nil {
+					s.retry = time.Duration(ms) * time.Millisecond
+				}
+			}
+			// Lines starting with ':' are comments, such as keepalives.
+			continue
+		}
+
+		// A blank line dispatches the event.
+		if id != "" {
+			s.lastEventID = id
+		}
+		switch event {
+		case "", "next":
+			if data.Len() == 0 {
+				break
+			}
+			var payload struct {
+				Data   json.RawMessage
+				Errors dataErrors
+			}
+			err := json.Unmarshal([]byte(data.String()), &payload)
+			if err == nil {
+				err = handle(payload.Data, payload.Errors)
+			}
+			if err != nil {
+				return true, err
+			}
+		case "complete":
+			return true, nil
+		}
+		event, id = "", ""
+		data.Reset()
+	}
+}
@@ -0,0 +1,137 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestSubscribe_sse(t *testing.T) {
+	var lastEventIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("Accept"), "text/event-stream"; got != want {
+			t.Errorf("got Accept: %q, want: %q", got, want)
+		}
+		if got, want := mustRead(req.Body), `{"query":"subscription{starAdded{login}}"}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		lastEventIDs = append(lastEventIDs, req.Header.Get("Last-Event-ID"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch req.Header.Get("Last-Event-ID") {
+		case "":
+			mustWrite(w, "retry: 1\n\n")
+			mustWrite(w, ": keepalive\n\n")
+			mustWrite(w, "event: next\nid: 1\ndata: {\"data\": {\"starAdded\": {\"login\": \"gopher\"}}}\n\n")
+			// Break the stream before completing the subscription.
+		case "1":
+			mustWrite(w, "event: next\nid: 2\ndata: {\"data\": {\"starAdded\": {\"login\": \"gordon\"}},\ndata:  \"errors\": [{\"message\": \"partial\"}]}\n\n")
+			mustWrite(w, "event: complete\ndata:\n\n")
+		default:
+			t.Errorf("unexpected Last-Event-ID: %q", req.Header.Get("Last-Event-ID"))
+		}
+	}))
+	defer server.Close()
+	client := graphql.NewClient(server.URL, nil, graphql.WithSSESubscriptions())
+
+	type starAdded struct {
+		StarAdded struct {
+			Login graphql.String
+		}
+	}
+	events, errs, err := graphql.Subscribe[starAdded](context.Background(), client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logins []graphql.String
+	var gotErrs []error
+	for events != nil || errs != nil {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			logins = append(logins, e.StarAdded.Login)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			gotErrs = append(gotErrs, err)
+		}
+	}
+	if got, want := fmt.Sprint(logins), "[gopher gordon]"; got != want {
+		t.Errorf("got logins: %v, want: %v", got, want)
+	}
+	if got, want := fmt.Sprint(gotErrs), "[event stream ended before the subscription completed partial]"; got != want {
+		t.Errorf("got errors: %v, want: %v", got, want)
+	}
+	// The broken stream is reported as a transient interruption.
+	if err, ok := gotErrs[0].(*graphql.SubscriptionError); !ok || !err.Transient {
+		t.Errorf("got error: %#v, want a transient *graphql.SubscriptionError", gotErrs[0])
+	}
+	if got, want := fmt.Sprintf("%q", lastEventIDs), `["" "1"]`; got != want {
+		t.Errorf("got Last-Event-ID headers: %v, want: %v", got, want)
+	}
+}
+
+func TestSubscribe_sseStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	client := graphql.NewClient(server.URL, nil, graphql.WithSSESubscriptions())
+
+	_, _, err := graphql.Subscribe[struct{ Foo graphql.String }](context.Background(), client, nil)
+	if got, want := fmt.Sprint(err), `non-200 OK status code: 401 Unauthorized body: "unauthorized\n"`; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestSubscribe_sseTerminal(t *testing.T) {
+	var mu sync.Mutex
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+		if n > 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		mustWrite(w, "retry: 1\n\n")
+		// Break the stream before completing the subscription.
+	}))
+	defer server.Close()
+	client := graphql.NewClient(server.URL, nil, graphql.WithSSESubscriptions())
+
+	_, errs, err := graphql.Subscribe[struct{ Foo graphql.String }](context.Background(), client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var subErr *graphql.SubscriptionError
+	if err := <-errs; !errors.As(err, &subErr) || !subErr.Transient {
+		t.Errorf("got error: %#v, want a transient *graphql.SubscriptionError", err)
+	}
+	err = <-errs
+	var statusErr *graphql.HTTPStatusError
+	if !errors.As(err, &subErr) || subErr.Transient || !errors.As(err, &statusErr) {
+		t.Errorf("got error: %#v, want a terminal *graphql.SubscriptionError with a *graphql.HTTPStatusError", err)
+	}
+	for err := range errs {
+		t.Errorf("got error: %v, want none", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 2 {
+		t.Errorf("got %d requests, want: 2", requests)
+	}
+}
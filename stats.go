@@ -0,0 +1,230 @@
+package graphql
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Stats are byte counts and timings of an operation, to tell whether
+// a slow operation is slow because of the network, the server, or
+// decoding the response. Durations of operations that took several
+// requests, because of retries or persisted queries, are summed over
+// the requests.
+type Stats struct {
+	Requests      int   // Number of HTTP requests sent. 0 for cached responses.
+	BytesSent     int64 // Size of the request bodies, or URL queries of GET requests.
+	BytesReceived int64 // Size of the response bodies.
+
+	Construct time.Duration // Time spent constructing the document from query structs.
+	DNS       time.Duration // Time spent resolving host names.
+	Connect   time.Duration // Time spent establishing TCP connections.
+	TLS       time.Duration // Time spent in TLS handshakes.
+	TTFB      time.Duration // Time from sending requests to their first response byte.
+	Decode    time.Duration // Time spent decoding response data into query structs.
+	Total     time.Duration // Time the operation took overall.
+
+	// Server is the time the server reported spending on the requests in
+	// their Server-Timing header: the duration of the metric named "total"
+	// if there's one, and else the sum of the durations of the metrics.
+	// It's 0 if the server doesn't report it. The individual metrics are
+	// available from the Header of a Response with ParseServerTiming.
+	Server time.Duration
+}
+
+// Network returns the part of TTFB not accounted for by the time the
+// server reported spending, which is the time spent on the network and
+// in parts of the server its metrics don't cover. It's TTFB if the server
+// doesn't report Server-Timing metrics.
+func (s Stats) Network() time.Duration {
+	if s.Server > s.TTFB {
+		return 0
+	}
+	return s.TTFB - s.Server
+}
+
+func (s *Stats) add(o *Stats) {
+	s.Requests += o.Requests
+	s.BytesSent += o.BytesSent
+	s.BytesReceived += o.BytesReceived
+	s.Construct += o.Construct
+	s.DNS += o.DNS
+	s.Connect += o.Connect
+	s.TLS += o.TLS
+	s.TTFB += o.TTFB
+	s.Decode += o.Decode
+	s.Total += o.Total
+	s.Server += o.Server
+}
+
+// WithStats returns a copy of ctx that makes operations executed with it
+// add their Stats to s. s must not be shared by concurrent operations.
+func WithStats(ctx context.Context, s *Stats) context.Context {
+	return context.WithValue(ctx, statsKey{}, s)
+}
+
+// StatsFunc is called after each operation a client executes, with the
+// name of the operation ("" if anonymous), and its Stats.
+type StatsFunc func(operation string, stats Stats)
+
+// WithStatsFunc makes the client call f after each operation it executes.
+// f may be called concurrently, and should return quickly.
+func WithStatsFunc(f StatsFunc) ClientOption {
+	return func(c *Client) {
+		c.statsFunc = f
+	}
+}
+
+type statsKey struct{}
+
+// OperationInfo describes an operation a client starts executing,
+// as seen by OperationHooks.
+type OperationInfo struct {
+	Name  string    // Name of the operation, or "" if anonymous.
+	Type  string    // "query", "mutation" or "subscription".
+	Query string    // Document, as constructed.
+	Start time.Time // When the operation started, including constructing its document.
+}
+
+// OperationHook is called when a client starts executing an operation,
+// once its document is constructed, such as to start a trace span for it.
+// It returns the context to execute the operation with, which may carry
+// the span, and a function to call once the operation is done, with its
+// Stats, its response, which is nil if there's none, and its error.
+type OperationHook func(ctx context.Context, op OperationInfo) (context.Context, func(stats Stats, resp *Response, err error))
+
+// WithOperationHook makes the client call hook for each operation it
+// executes, other than subscriptions. Hooks are called in the order
+// they're given, and the functions they return in reverse order.
+// See the otelgraphql module for OpenTelemetry instrumentation.
+func WithOperationHook(hook OperationHook) ClientOption {
+	return func(c *Client) {
+		c.operationHooks = append(c.operationHooks, hook)
+	}
+}
+
+// trackedStatsKey is the context key of the Stats
+// of the operation being executed.
+type trackedStatsKey struct{}
+
+// trackStats returns a copy of ctx that records the Stats of the operation
+// named operation, with the document query, if they're wanted, and
+// a function to call with its outcome once it's done. It calls the
+// client's operation hooks. start is when the document started being
+// constructed, or zero if it wasn't.
+// If ctx is already recording them, for an enclosing call, the function
+// does nothing.
+func (c *Client) trackStats(ctx context.Context, operation, query string, start time.Time) (context.Context, func(resp *Response, err error)) {
+	if _, ok := ctx.Value(trackedStatsKey{}).(*Stats); ok {
+		return ctx, func(*Response, error) {}
+	}
+	dst, _ := ctx.Value(statsKey{}).(*Stats)
+	if dst == nil && c.statsFunc == nil && len(c.operationHooks) == 0 {
+		return ctx, func(*Response, error) {}
+	}
+	s := new(Stats)
+	now := time.Now()
+	if start.IsZero() {
+		start = now
+	}
+	s.Construct = now.Sub(start)
+	ctx = context.WithValue(ctx, trackedStatsKey{}, s)
+	ends := make([]func(Stats, *Response, error), len(c.operationHooks))
+	for i, hook := range c.operationHooks {
+		ctx, ends[i] = hook(ctx, OperationInfo{Name: operation, Type: operationType(query), Query: query, Start: start})
+	}
+	return ctx, func(resp *Response, err error) {
+		s.Total = time.Since(start)
+		if dst != nil {
+			dst.add(s)
+		}
+		if c.statsFunc != nil {
+			c.statsFunc(operation, *s)
+		}
+		for i := len(ends) - 1; i >= 0; i-- {
+			ends[i](*s, resp, err)
+		}
+	}
+}
+
+// trackedStats returns the Stats of the operation executed with ctx,
+// or nil if they're not wanted.
+func trackedStats(ctx context.Context) *Stats {
+	s, _ := ctx.Value(trackedStatsKey{}).(*Stats)
+	return s
+}
+
+// requestTrace records the DNS, connect, TLS and TTFB timings of
+// a request. Its hooks may be called concurrently, e.g., when dialing
+// several addresses in parallel.
+type requestTrace struct {
+	mu                                      sync.Mutex
+	start, dnsStart, connectStart, tlsStart time.Time
+	dns, connect, tls, ttfb                 time.Duration
+}
+
+// traceRequest returns a copy of ctx that records the timings of the
+// request sent with it, starting now, and the requestTrace they're
+// recorded in.
+func traceRequest(ctx context.Context) (context.Context, *requestTrace) {
+	t := &requestTrace{start: time.Now()}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.begin(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.end(&t.dnsStart, &t.dns) },
+		ConnectStart: func(network, addr string) {
+			t.begin(&t.connectStart)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				t.end(&t.connectStart, &t.connect)
+			}
+		},
+		TLSHandshakeStart:    func() { t.begin(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.end(&t.tlsStart, &t.tls) },
+		GotFirstResponseByte: func() { t.end(&t.start, &t.ttfb) },
+	}), t
+}
+
+// begin records now as the start of a phase, unless it's already started.
+func (t *requestTrace) begin(start *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if start.IsZero() {
+		*start = time.Now()
+	}
+}
+
+// end records the time since the start of a phase as its duration,
+// unless it's already ended.
+func (t *requestTrace) end(start *time.Time, d *time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if *d == 0 && !start.IsZero() {
+		*d = time.Since(*start)
+	}
+}
+
+// addTo adds the timings recorded so far to s.
+func (t *requestTrace) addTo(s *Stats) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s.DNS += t.dns
+	s.Connect += t.connect
+	s.TLS += t.tls
+	s.TTFB += t.ttfb
+}
+
+// countingReader counts the bytes read from r into n.
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (cr countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	*cr.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,117 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestWithStats(t *testing.T) {
+	const body = `{"data": {"viewer": {"login": "gopher"}}}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, body)
+	}))
+	defer srv.Close()
+	var reported []graphql.Stats
+	var operations []string
+	client := graphql.NewClient(srv.URL, nil, graphql.WithStatsFunc(func(operation string, stats graphql.Stats) {
+		operations = append(operations, operation)
+		reported = append(reported, stats)
+	}))
+
+	var stats graphql.Stats
+	ctx := graphql.WithStats(context.Background(), &stats)
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	_, err := client.Query(ctx, &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Requests != 1 {
+		t.Errorf("got Requests: %v, want: 1", stats.Requests)
+	}
+	if got, want := stats.BytesSent, int64(len(`{"query":"{viewer{login}}"}`)+1); got != want {
+		t.Errorf("got BytesSent: %v, want: %v", got, want)
+	}
+	if got, want := stats.BytesReceived, int64(len(body)); got != want {
+		t.Errorf("got BytesReceived: %v, want: %v", got, want)
+	}
+	if stats.Construct <= 0 || stats.Connect <= 0 || stats.TTFB <= 0 || stats.Decode <= 0 || stats.Total < stats.Construct+stats.TTFB+stats.Decode {
+		t.Errorf("got bad timings: %+v", stats)
+	}
+	if len(reported) != 1 || reported[0] != stats {
+		t.Errorf("got reported stats: %+v, want: %+v", reported, stats)
+	}
+
+	// Stats accumulate over the operations executed with ctx.
+	_, err = client.Do(ctx, "query GetViewer{viewer{login}}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Requests != 2 || stats.BytesReceived != 2*int64(len(body)) {
+		t.Errorf("got accumulated stats: %+v", stats)
+	}
+	if want := []string{"", "GetViewer"}; !reflect.DeepEqual(operations, want) {
+		t.Errorf("got operations: %q, want: %q", operations, want)
+	}
+}
+
+func TestWithOperationHook(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("X-Span"), "span1"; got != want {
+			t.Errorf("got X-Span header: %q, want: %q", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": null}, "errors": [{"message": "not logged in"}]}`)
+	})
+	type spanKey struct{}
+	var events []string
+	hook := func(name string) graphql.OperationHook {
+		return func(ctx context.Context, op graphql.OperationInfo) (context.Context, func(graphql.Stats, *graphql.Response, error)) {
+			events = append(events, fmt.Sprintf("start %s: %s %s %s", name, op.Type, op.Name, op.Query))
+			if op.Start.IsZero() || time.Since(op.Start) < 0 {
+				t.Errorf("got start time: %v", op.Start)
+			}
+			ctx = context.WithValue(ctx, spanKey{}, "span1")
+			return ctx, func(stats graphql.Stats, resp *graphql.Response, err error) {
+				events = append(events, fmt.Sprintf("end %s: %d request, %d errors, %v", name, stats.Requests, len(resp.Errors), err))
+			}
+		}
+	}
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithOperationHook(hook("a")),
+		graphql.WithOperationHook(hook("b")),
+		graphql.WithHeaderProvider(func(ctx context.Context) (http.Header, error) {
+			return http.Header{"X-Span": {ctx.Value(spanKey{}).(string)}}, nil
+		}))
+
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil, graphql.WithOperationName("GetViewer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"start a: query GetViewer query GetViewer{viewer{login}}",
+		"start b: query GetViewer query GetViewer{viewer{login}}",
+		"end b: 1 request, 1 errors, <nil>",
+		"end a: 1 request, 1 errors, <nil>",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("got events:\n%q\nwant:\n%q", events, want)
+	}
+}
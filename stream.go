@@ -0,0 +1,93 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/merico-dev/graphql/internal/jsonutil"
+)
+
+// WithStreamingDecode makes Query and Mutate decode the data of JSON
+// responses into the query struct while the response body is received,
+// rather than after buffering all of it, which cuts the time to the first
+// result and peak memory for big responses.
+//
+// The data isn't streamed, and is buffered as usual, for responses that
+// are cached, for queued mutations, and for queries merging aliases with
+// graphql-extend fields. Streamed responses have a nil Data, as seen by
+// functions such as the one set with WithResponseFunc. If a request is
+// retried after its data was decoded, the query struct is reset first.
+func WithStreamingDecode() ClientOption {
+	return func(c *Client) {
+		c.streamingDecode = true
+	}
+}
+
+// streamInto returns opts with an option that makes the response data be
+// decoded into v while it's received, if responses can be streamed for
+// variables. JSON responses are streamed only if the client streams
+// responses; incrementally delivered ones always are.
+func (c *Client) streamInto(v interface{}, variables map[string]interface{}, opts []RequestOption) []RequestOption {
+	if c.codec != nil {
+		return opts
+	}
+	for _, v := range variables {
+		if _, ok := v.([]map[string]interface{}); ok {
+			// Extended fields are merged from aliases.
+			return opts
+		}
+	}
+	return append(opts[:len(opts):len(opts)], func(cfg *requestConfig) {
+		cfg.into = v
+	})
+}
+
+// decodeStreaming decodes the JSON response read from r into out,
+// and its data into cfg.into.
+func (c *Client) decodeStreaming(r io.Reader, out *Response, cfg *requestConfig) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("unexpected token %v at the start of the response", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+		switch {
+		case strings.EqualFold(key, "data"):
+			if cfg.dataDecoded {
+				// Reset what an earlier attempt decoded.
+				v := reflect.ValueOf(cfg.into).Elem()
+				v.Set(reflect.Zero(v.Type()))
+			}
+			cfg.dataDecoded = true
+			err = jsonutil.DecodeGraphQL(dec, cfg.into, c.dataDecodeOptions(cfg)...)
+		case strings.EqualFold(key, "errors"):
+			var errs dataErrors
+			err = dec.Decode(&errs)
+			if len(errs) > 0 {
+				out.Errors = errs
+			}
+		case strings.EqualFold(key, "extensions"):
+			err = dec.Decode(&out.Extensions)
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
@@ -0,0 +1,91 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestWithStreamingDecode(t *testing.T) {
+	decoded := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"first": {"login": "gopher"}, `)
+		w.(http.Flusher).Flush()
+		// Finish the response only once the client decoded its start.
+		select {
+		case <-decoded:
+		case <-time.After(5 * time.Second):
+			return
+		}
+		mustWrite(w, `"second": null}, "errors": [{"message": "second is gone"}], "extensions": {"cost": 1}}`)
+	}))
+	defer srv.Close()
+	var resp *graphql.Response
+	client := graphql.NewClient(srv.URL, nil,
+		graphql.WithStreamingDecode(),
+		graphql.WithDecodeHook("first.login", func(v interface{}) error {
+			close(decoded)
+			return nil
+		}),
+		graphql.WithResponseFunc(func(r *graphql.Response, err error) { resp = r }),
+	)
+
+	var q struct {
+		First struct {
+			Login graphql.String
+		}
+		Second *struct {
+			Login graphql.String
+		}
+	}
+	dataErrors, err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dataErrors) != 1 || dataErrors[0].Message != "second is gone" {
+		t.Errorf("got data errors: %v", dataErrors)
+	}
+	if got, want := q.First.Login, graphql.String("gopher"); got != want || q.Second != nil {
+		t.Errorf("got q: %+v", q)
+	}
+	if resp == nil || resp.Data != nil || string(resp.Extensions["cost"]) != "1" {
+		t.Errorf("got response: %+v", resp)
+	}
+}
+
+func TestWithStreamingDecode_retry(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		w.Header().Set("Content-Type", "application/json")
+		if attempts == 1 {
+			mustWrite(w, `{"data": {"nodes": [{"id": "1"}, {"id": "2"}]}, "errors": [{"message": "throttled", "extensions": {"retryAfter": 0}}]}`)
+			return
+		}
+		mustWrite(w, `{"data": {"nodes": [{"id": "3"}]}}`)
+	}))
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil,
+		graphql.WithStreamingDecode(),
+		graphql.WithRetry(2, nil),
+		graphql.WithRetryHints(graphql.ExtensionRetryHints()),
+	)
+
+	var q struct {
+		Nodes []struct {
+			ID graphql.ID
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Nodes) != 1 || q.Nodes[0].ID != "3" {
+		t.Errorf("got nodes: %v", q.Nodes)
+	}
+}
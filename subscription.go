@@ -0,0 +1,983 @@
+package graphql
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/merico-dev/graphql/internal/jsonutil"
+	"golang.org/x/net/websocket"
+)
+
+// Subscribe executes a single GraphQL subscription request using c,
+// with a subscription derived from the type T, and delivers each event
+// decoded into a new T on the returned events channel.
+// T should be a struct type that corresponds to the GraphQL schema.
+//
+// The subscription is carried over a WebSocket connection speaking the
+// graphql-transport-ws protocol, or over Server-Sent Events if the client
+// was created with WithSSESubscriptions. Subscribe returns an error if the
+// connection can't be established or isn't acknowledged by the server. After that,
+// errors are delivered on the returned errors channel: errors reported
+// alongside an event are delivered as DataError values, interruptions the
+// transport recovers from as transient *SubscriptionError values, and any
+// error that ends the subscription is delivered last as a *SubscriptionError.
+// Both channels are closed when the subscription ends, which happens when
+// the server completes it, when it fails, when ctx is done, or when the
+// client is closed with Close or Drain. Callers must receive from both
+// channels.
+//
+// By default, the events channel is unbuffered, and the subscription waits
+// for each event to be received before reading the next one from the
+// connection. Use WithEventBuffer to change that for high-volume streams.
+func Subscribe[T any](ctx context.Context, c *Client, variables map[string]interface{}, opts ...RequestOption) (<-chan T, <-chan error, error) {
+	var s T
+	query, err := ConstructSubscription(&s, variables)
+	if err != nil {
+		return nil, nil, err
+	}
+	query, variables, err = c.prepareDocument(ctx, "subscription", query, variables)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.subscriptions.isClosed() {
+		return nil, nil, ErrClientClosed
+	}
+	sub, cfg, err := c.subscribe(ctx, query, variables, opts)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, newSubscriptionError(err)
+	}
+	done, ok := c.subscriptions.add(sub)
+	if !ok {
+		// The client was closed while subscribing.
+		sub.shutdown()
+		return nil, nil, ErrClientClosed
+	}
+	events := make(chan T, cfg.eventBuffer)
+	errs := make(chan error)
+	go func() {
+		defer c.subscriptions.remove(sub, done)
+		defer close(errs)
+		defer close(events)
+		err := sub.run(ctx, func(data json.RawMessage, dataErrors []DataError) error {
+			for _, e := range dataErrors {
+				select {
+				case errs <- e:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if len(data) == 0 || string(data) == "null" {
+				return nil
+			}
+			var v T
+			err := jsonutil.UnmarshalGraphQL(data, &v, c.decodeOptions...)
+			if err != nil {
+				return err
+			}
+			return deliver(ctx, events, v, cfg)
+		}, func(err error) error {
+			select {
+			case errs <- &SubscriptionError{Err: err, Transient: true}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			errs <- newSubscriptionError(err)
+		}
+	}()
+	return events, errs, nil
+}
+
+// Subscribe executes a single GraphQL subscription request, with
+// a subscription derived from v, which should be a pointer to struct that
+// corresponds to the GraphQL schema, and calls handle with each event
+// decoded into a new value of the type of v. It's an alternative to the
+// Subscribe function for callers that prefer a callback, or only know the
+// subscription type at runtime. v itself isn't modified.
+//
+// handle is called with the errors reported alongside each event, and with
+// a nil event if an event has errors but no data. Events are read one at
+// a time: the next one is read once handle returns.
+//
+// Subscribe blocks until the subscription ends. It returns nil if the
+// server completed it or the client was closed, ctx.Err() if ctx is done,
+// the error handle returned if it returned one, and a *SubscriptionError
+// if it failed.
+func (c *Client) Subscribe(ctx context.Context, v interface{}, variables map[string]interface{}, handle func(event interface{}, dataErrors []DataError) error, opts ...RequestOption) error {
+	query, err := ConstructSubscription(v, variables)
+	if err != nil {
+		return err
+	}
+	t := reflect.TypeOf(v)
+	if t.Kind() != reflect.Ptr {
+		return fmt.Errorf("can't subscribe with %v; want a pointer to struct", t)
+	}
+	query, variables, err = c.prepareDocument(ctx, "subscription", query, variables)
+	if err != nil {
+		return err
+	}
+	if c.subscriptions.isClosed() {
+		return ErrClientClosed
+	}
+	sub, _, err := c.subscribe(ctx, query, variables, opts)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return newSubscriptionError(err)
+	}
+	done, ok := c.subscriptions.add(sub)
+	if !ok {
+		// The client was closed while subscribing.
+		sub.shutdown()
+		return ErrClientClosed
+	}
+	defer c.subscriptions.remove(sub, done)
+	var handleErr error
+	err = sub.run(ctx, func(data json.RawMessage, dataErrors []DataError) error {
+		var event interface{}
+		if len(data) != 0 && string(data) != "null" {
+			event = reflect.New(t.Elem()).Interface()
+			err := jsonutil.UnmarshalGraphQL(data, event, c.decodeOptions...)
+			if err != nil {
+				return err
+			}
+		} else if len(dataErrors) == 0 {
+			return nil
+		}
+		handleErr = handle(event, dataErrors)
+		return handleErr
+	}, func(error) error { return nil })
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case handleErr != nil:
+		return handleErr
+	}
+	return newSubscriptionError(err)
+}
+
+// ErrClientClosed is the error that Subscribe returns after the client
+// was closed with Close or Drain.
+var ErrClientClosed = errors.New("client is closed")
+
+// Close ends the client's subscriptions gracefully: it lets the server
+// know that they're complete and closes their connections cleanly, rather
+// than leaving the server to notice reset connections. Their events
+// channels are closed once the events already read from the server are
+// delivered, without an error. Close also closes the idle connections of
+// the client's HTTP client.
+//
+// After Close, Subscribe returns ErrClientClosed. Queries and mutations
+// can still be made. Close doesn't wait for the subscriptions to end;
+// use Drain for that.
+func (c *Client) Close() error {
+	c.subscriptions.close()
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// Drain closes the client like Close, and waits for its subscriptions to
+// deliver the events already read from the server and end. It returns
+// ctx.Err() if ctx is done first, such as because a consumer stopped
+// receiving events.
+func (c *Client) Drain(ctx context.Context) error {
+	c.Close()
+	return c.subscriptions.wait(ctx)
+}
+
+// subscriptionSet is the set of active subscriptions of a client.
+type subscriptionSet struct {
+	mu     sync.Mutex
+	active map[eventStream]chan struct{} // Closed when the subscription ended.
+	closed bool
+}
+
+// add adds the subscription s to the set. It returns a channel to close
+// when s ended, and false if the set is closed.
+func (ss *subscriptionSet) add(s eventStream) (chan struct{}, bool) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.closed {
+		return nil, false
+	}
+	if ss.active == nil {
+		ss.active = make(map[eventStream]chan struct{})
+	}
+	done := make(chan struct{})
+	ss.active[s] = done
+	return done, true
+}
+
+// isClosed reports whether the set is closed.
+func (ss *subscriptionSet) isClosed() bool {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.closed
+}
+
+// remove removes the subscription s, which ended, from the set.
+func (ss *subscriptionSet) remove(s eventStream, done chan struct{}) {
+	ss.mu.Lock()
+	delete(ss.active, s)
+	ss.mu.Unlock()
+	close(done)
+}
+
+// close closes the set, and shuts down its subscriptions.
+func (ss *subscriptionSet) close() {
+	ss.mu.Lock()
+	ss.closed = true
+	active := make([]eventStream, 0, len(ss.active))
+	for s := range ss.active {
+		active = append(active, s)
+	}
+	ss.mu.Unlock()
+	for _, s := range active {
+		s.shutdown()
+	}
+}
+
+// wait waits for the subscriptions in the set to end, or ctx to be done.
+func (ss *subscriptionSet) wait(ctx context.Context) error {
+	ss.mu.Lock()
+	dones := make([]chan struct{}, 0, len(ss.active))
+	for _, done := range ss.active {
+		dones = append(dones, done)
+	}
+	ss.mu.Unlock()
+	for _, done := range dones {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// SubscriptionError is an error that interrupted or ended a subscription,
+// classified by whether it's transient, so that consumers know whether to
+// wait, or to act before subscribing again, such as to re-authenticate.
+type SubscriptionError struct {
+	Err error
+
+	// Transient reports whether Err is a failure that may not happen again,
+	// such as a network failure or a 5xx status code. Transient errors that
+	// don't end the subscription are recovered from by the transport, which
+	// reconnects; one that ends it is the last failure of reconnecting.
+	// Other errors are terminal, such as authentication failures or the
+	// server rejecting the subscription: subscribing again is bound to fail
+	// the same way unless something changes.
+	Transient bool
+}
+
+func (e *SubscriptionError) Error() string { return e.Err.Error() }
+
+func (e *SubscriptionError) Unwrap() error { return e.Err }
+
+// newSubscriptionError returns err classified as a *SubscriptionError.
+func newSubscriptionError(err error) *SubscriptionError {
+	return &SubscriptionError{Err: err, Transient: isTransientSubscriptionError(err)}
+}
+
+// isTransientSubscriptionError reports whether err is a transient failure
+// of a subscription: a network failure, a timeout waiting for the server,
+// or a 429 or 5xx status code.
+func isTransientSubscriptionError(err error) bool {
+	// DialError doesn't unwrap to the failure of dialing.
+	var dialErr *websocket.DialError
+	if errors.As(err, &dialErr) {
+		err = dialErr.Err
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	var timeoutErr timeoutError
+	return errors.As(err, &netErr) || errors.As(err, &timeoutErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, errStreamEnded)
+}
+
+// timeoutError is an error reporting that the server didn't respond in time.
+type timeoutError string
+
+func (e timeoutError) Error() string { return string(e) }
+
+// OverflowPolicy is what a subscription does with an event when its events
+// channel is full because the consumer is slow.
+type OverflowPolicy uint8
+
+const (
+	// OverflowBlock waits for the consumer to receive the event.
+	// Events from the server are held back meanwhile.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest event in the buffer to make
+	// room for the new one.
+	OverflowDropOldest
+	// OverflowDropNewest drops the new event.
+	OverflowDropNewest
+	// OverflowError ends the subscription with ErrSlowConsumer.
+	OverflowError
+)
+
+// ErrSlowConsumer is the error that ends a subscription using OverflowError
+// when its consumer doesn't keep up with events.
+var ErrSlowConsumer = errors.New("subscription events channel is full")
+
+// WithEventBuffer makes subscriptions deliver events on a channel that
+// buffers up to size events, handling events that don't fit according
+// to policy. It has no effect on queries and mutations.
+func WithEventBuffer(size int, policy OverflowPolicy) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.eventBuffer = size
+		cfg.overflow = policy
+	}
+}
+
+// WithDroppedEventFunc makes subscriptions call f each time they drop
+// an event because of their overflow policy, such as to count dropped
+// events in a metric. f is called from the goroutine reading events,
+// and should return quickly.
+func WithDroppedEventFunc(f func()) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.droppedEvent = f
+	}
+}
+
+// deliver sends v on events, handling a full events channel
+// according to the overflow policy of cfg.
+func deliver[T any](ctx context.Context, events chan T, v T, cfg *requestConfig) error {
+	if cfg.overflow == OverflowBlock {
+		select {
+		case events <- v:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	for {
+		select {
+		case events <- v:
+			return nil
+		default:
+		}
+		switch cfg.overflow {
+		case OverflowDropOldest:
+			select {
+			case <-events:
+			default:
+				// The consumer received the oldest event meanwhile;
+				// try again, as there may be room now.
+				continue
+			}
+		case OverflowError:
+			return ErrSlowConsumer
+		}
+		if cfg.droppedEvent != nil {
+			cfg.droppedEvent()
+		}
+		if cfg.overflow == OverflowDropNewest {
+			return nil
+		}
+	}
+}
+
+// WithWebSocketPing makes subscriptions send a ping message every interval,
+// and fail if the server doesn't answer with a pong within pongTimeout.
+// It keeps idle connections alive through proxies that close them after
+// a period of inactivity, and detects connections that silently died.
+// By default, subscriptions only answer the server's pings.
+func WithWebSocketPing(interval, pongTimeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.wsKeepalive.pingInterval = interval
+		c.wsKeepalive.pongTimeout = pongTimeout
+	}
+}
+
+// WithWebSocketReconnect makes subscriptions over WebSocket reconnect when
+// their connection fails transiently, such as because of a network failure
+// or a missed pong, making at most maxAttempts consecutive attempts, and
+// waiting as backoff returns before each. Reconnecting opens a new connection,
+// initialized with a fresh payload if the client was created with
+// WithConnectionInitFunc, and subscribes again; events published meanwhile
+// are missed. By default, subscriptions make at most 3 attempts, waiting
+// 1 second before the first and twice as long before each subsequent one.
+// A maxAttempts of 0 disables reconnecting.
+func WithWebSocketReconnect(maxAttempts int, backoff Backoff) ClientOption {
+	return func(c *Client) {
+		c.wsKeepalive.maxReconnects = maxAttempts
+		c.wsKeepalive.reconnectBackoff = backoff
+	}
+}
+
+// WithConnectionAckTimeout makes subscriptions fail if the server doesn't
+// acknowledge their connection within d. By default, they wait for as long
+// as the context passed to Subscribe allows.
+func WithConnectionAckTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.wsKeepalive.ackTimeout = d
+	}
+}
+
+// WithConnectionInitPayload makes subscriptions send payload with the
+// connection_init message, such as to authenticate with servers that
+// expect credentials there rather than in HTTP headers.
+// payload is encoded as JSON.
+func WithConnectionInitPayload(payload interface{}) ClientOption {
+	return WithConnectionInitFunc(func(context.Context) (interface{}, error) {
+		return payload, nil
+	})
+}
+
+// WithConnectionInitFunc makes subscriptions call f for the payload of the
+// connection_init message each time they open a connection, including when
+// reconnecting, so that short-lived credentials, such as rotated tokens, are
+// refreshed for every connection. If f returns an error, the subscription fails with it.
+// The payload is encoded as JSON, and omitted if it's nil.
+func WithConnectionInitFunc(f func(ctx context.Context) (interface{}, error)) ClientOption {
+	return func(c *Client) {
+		c.connectionInit = f
+	}
+}
+
+// wsKeepalive is the keepalive configuration of subscription connections.
+// Zero durations disable the respective behavior.
+type wsKeepalive struct {
+	pingInterval time.Duration // Interval between pings sent by the client.
+	pongTimeout  time.Duration // How long to wait for a pong after a ping.
+	ackTimeout   time.Duration // How long to wait for connection_ack.
+
+	maxReconnects    int     // Consecutive reconnection attempts after a failure.
+	reconnectBackoff Backoff // How long to wait before each attempt.
+}
+
+// defaultWSKeepalive is the keepalive configuration of subscription
+// connections unless changed with options.
+var defaultWSKeepalive = wsKeepalive{
+	maxReconnects:    3,
+	reconnectBackoff: ExponentialBackoff(time.Second, 30*time.Second),
+}
+
+// Message types of the graphql-transport-ws protocol.
+// See https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md.
+const (
+	wsConnectionInit = "connection_init"
+	wsConnectionAck  = "connection_ack"
+	wsPing           = "ping"
+	wsPong           = "pong"
+	wsSubscribe      = "subscribe"
+	wsNext           = "next"
+	wsError          = "error"
+	wsComplete       = "complete"
+)
+
+// wsProtocol is the WebSocket subprotocol of graphql-transport-ws.
+const wsProtocol = "graphql-transport-ws"
+
+// wsMessage is a graphql-transport-ws protocol message.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// subscription is a GraphQL subscription over a WebSocket connection.
+// Each connection carries a single subscription.
+type subscription struct {
+	c         *Client
+	query     string
+	variables map[string]interface{}
+	cfg       *requestConfig
+	keepalive wsKeepalive
+
+	mu   sync.Mutex      // Guards conn, and writes to it.
+	conn *websocket.Conn // Current connection.
+
+	awaitingPong int32         // 1 if a ping is awaiting its pong; accessed atomically.
+	closed       chan struct{} // Closed once the subscription is being shut down.
+	closeOnce    sync.Once     // Shuts the subscription down once.
+}
+
+// subscriptionID is the id of the only subscription on a connection.
+const subscriptionID = "1"
+
+// eventStream is a started subscription, carried by some transport.
+type eventStream interface {
+	// run reads events until the subscription ends, calling handle with
+	// the payload of each event, and releases the transport's resources.
+	// If the transport is interrupted by an error it recovers from, such as
+	// a network failure it reconnects after, run calls interrupted with it.
+	// run returns nil if the server completed the subscription, and a non-nil
+	// error if the subscription failed, a callback returned an error,
+	// or ctx is done.
+	run(ctx context.Context, handle func(data json.RawMessage, dataErrors []DataError) error, interrupted func(err error) error) error
+
+	// shutdown ends the subscription gracefully, letting the server know
+	// it's complete and closing the transport cleanly. A pending or later
+	// call to run returns nil once the event being handled, if any,
+	// is handled. shutdown may be called concurrently with run, and
+	// more than once.
+	shutdown()
+}
+
+// subscribe starts a subscription with query and variables, made with opts,
+// using the client's subscription transport. It returns the subscription
+// along with the configuration of its request.
+func (c *Client) subscribe(ctx context.Context, query string, variables map[string]interface{}, opts []RequestOption) (eventStream, *requestConfig, error) {
+	err := c.checkQuery(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := c.requestConfig(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	var s eventStream
+	if c.sseSubscriptions {
+		s, err = c.subscribeSSE(ctx, query, variables, cfg)
+	} else {
+		s, err = c.subscribeWS(ctx, query, variables, cfg)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, cfg, nil
+}
+
+// subscribeWS opens a WebSocket connection to the server, and starts
+// a subscription with query and variables on it.
+func (c *Client) subscribeWS(ctx context.Context, query string, variables map[string]interface{}, cfg *requestConfig) (*subscription, error) {
+	variables, err := c.transformVariables(ctx, query, variables)
+	if err != nil {
+		return nil, err
+	}
+	s := &subscription{
+		c:         c,
+		query:     query,
+		variables: variables,
+		cfg:       cfg,
+		keepalive: c.wsKeepalive,
+		closed:    make(chan struct{}),
+	}
+	err = s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// connect opens a connection to the server, calling the client's
+// connection init function for the payload of its connection_init message,
+// and starts the subscription on it.
+func (s *subscription) connect(ctx context.Context) error {
+	endpoint, err := s.c.endpoint(s.cfg)
+	if err != nil {
+		return err
+	}
+	wsURL, err := wsURL(endpoint)
+	if err != nil {
+		return err
+	}
+	config, err := websocket.NewConfig(wsURL, endpoint)
+	if err != nil {
+		return err
+	}
+	config.Protocol = []string{wsProtocol}
+	for k, vs := range s.cfg.header {
+		config.Header[k] = vs
+	}
+	var initPayload interface{}
+	if s.c.connectionInit != nil {
+		initPayload, err = s.c.connectionInit(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	conn, err := dialWS(ctx, config)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.conn = conn
+	select {
+	case <-s.closed:
+		// Shut down while connecting.
+		conn.Close()
+	default:
+	}
+	s.mu.Unlock()
+	atomic.StoreInt32(&s.awaitingPong, 0)
+	// Unblock reads if ctx is done while the subscription is starting.
+	stop := onDone(ctx, func() { conn.Close() })
+	defer stop()
+	err = s.start(initPayload)
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// dialWS opens a WebSocket connection with config, like
+// config.DialContext, but returns an *HTTPStatusError if the server
+// responds to the handshake with a status code other than 101.
+func dialWS(ctx context.Context, config *websocket.Config) (*websocket.Conn, error) {
+	dialer := config.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	addr := config.Location.Host
+	if config.Location.Port() == "" {
+		port := "80"
+		if config.Location.Scheme == "wss" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(config.Location.Hostname(), port)
+	}
+	var nc net.Conn
+	var err error
+	if config.Location.Scheme == "wss" {
+		nc, err = (&tls.Dialer{NetDialer: dialer, Config: config.TlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		nc, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, &websocket.DialError{Config: config, Err: err}
+	}
+	// Unblock the handshake if ctx is done.
+	stop := onDone(ctx, func() { nc.SetDeadline(time.Now()) })
+	defer stop()
+	hc := &handshakeConn{Conn: nc, recording: true}
+	conn, err := websocket.NewClient(config, hc)
+	if err == websocket.ErrBadStatus {
+		// Read the response again from what the handshake read of it.
+		resp, rerr := http.ReadResponse(bufio.NewReader(io.MultiReader(&hc.recorded, nc)), &http.Request{Method: http.MethodGet})
+		if rerr == nil {
+			statusErr := &HTTPStatusError{
+				StatusCode: resp.StatusCode,
+				Status:     resp.Status,
+				Header:     resp.Header,
+			}
+			statusErr.Body, statusErr.Truncated = readTruncated(resp.Body, maxNonJSONBody)
+			nc.Close()
+			return nil, statusErr
+		}
+	}
+	if err != nil {
+		nc.Close()
+		if ctx.Err() != nil {
+			return nil, &websocket.DialError{Config: config, Err: ctx.Err()}
+		}
+		return nil, &websocket.DialError{Config: config, Err: err}
+	}
+	hc.recording = false
+	return conn, nil
+}
+
+// handshakeConn is a connection recording what's read from it during
+// the WebSocket handshake.
+type handshakeConn struct {
+	net.Conn
+	recording bool
+	recorded  bytes.Buffer
+}
+
+func (c *handshakeConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if c.recording {
+		c.recorded.Write(p[:n])
+	}
+	return n, err
+}
+
+// start initializes the connection with initPayload, and sends
+// the subscribe message once the server acknowledges it.
+func (s *subscription) start(initPayload interface{}) error {
+	err := s.send(s.conn, wsMessage{Type: wsConnectionInit}, initPayload)
+	if err != nil {
+		return err
+	}
+	if s.keepalive.ackTimeout > 0 {
+		s.conn.SetReadDeadline(time.Now().Add(s.keepalive.ackTimeout))
+		defer s.conn.SetReadDeadline(time.Time{})
+	}
+	for {
+		var msg wsMessage
+		err := websocket.JSON.Receive(s.conn, &msg)
+		if isTimeout(err) {
+			return timeoutError(fmt.Sprintf("server didn't acknowledge the connection within %v", s.keepalive.ackTimeout))
+		} else if err != nil {
+			return err
+		}
+		switch msg.Type {
+		case wsPing:
+			err = s.send(s.conn, wsMessage{Type: wsPong}, nil)
+			if err != nil {
+				return err
+			}
+			continue
+		case wsConnectionAck:
+		default:
+			return fmt.Errorf("unexpected %q message before connection_ack", msg.Type)
+		}
+		break
+	}
+	variables, err := sentVariables(s.variables)
+	if err != nil {
+		return err
+	}
+	payload := struct {
+		Query      string                 `json:"query"`
+		Variables  map[string]interface{} `json:"variables,omitempty"`
+		Extensions map[string]interface{} `json:"extensions,omitempty"`
+	}{
+		Query:      s.query,
+		Variables:  variables,
+		Extensions: s.cfg.extensions,
+	}
+	return s.send(s.conn, wsMessage{ID: subscriptionID, Type: wsSubscribe}, payload)
+}
+
+// run implements eventStream. It reads messages from the connection until
+// the subscription ends, and completes the subscription and closes the
+// connection before returning. If the connection fails transiently, such as
+// because of a network failure, run opens a new one and subscribes again.
+func (s *subscription) run(ctx context.Context, handle func(data json.RawMessage, dataErrors []DataError) error, interrupted func(err error) error) error {
+	// Complete the subscription if ctx is done, which unblocks reads.
+	stop := onDone(ctx, s.shutdown)
+	defer stop()
+	for {
+		done, err := s.read(ctx, handle)
+		s.conn.Close()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		} else if s.isClosed() {
+			return nil
+		} else if done || !isTransientSubscriptionError(err) || s.keepalive.maxReconnects == 0 {
+			return err
+		}
+		// The connection failed; subscribe again on a new one.
+		err = interrupted(err)
+		if err != nil {
+			return err
+		}
+		for attempt := 1; ; attempt++ {
+			t := time.NewTimer(s.keepalive.reconnectBackoff(attempt))
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			case <-s.closed:
+				t.Stop()
+				return nil
+			}
+			err = s.connect(ctx)
+			if err == nil {
+				break
+			} else if ctx.Err() != nil {
+				return ctx.Err()
+			} else if s.isClosed() {
+				return nil
+			} else if attempt == s.keepalive.maxReconnects || !isTransientSubscriptionError(err) {
+				return err
+			}
+		}
+	}
+}
+
+// read reads messages from the current connection, calling handle with
+// the payload of each next message. done reports whether the subscription
+// ended, rather than the connection failed.
+func (s *subscription) read(ctx context.Context, handle func(data json.RawMessage, dataErrors []DataError) error) (done bool, err error) {
+	if s.keepalive.pingInterval > 0 {
+		stopPinging := s.keepPinging(s.conn)
+		defer stopPinging()
+	}
+	for {
+		var msg wsMessage
+		err := websocket.JSON.Receive(s.conn, &msg)
+		if ctx.Err() != nil {
+			return true, ctx.Err()
+		} else if s.isClosed() {
+			return true, nil
+		} else if isTimeout(err) {
+			return false, timeoutError(fmt.Sprintf("server didn't answer ping with pong within %v", s.keepalive.pongTimeout))
+		} else if err != nil {
+			return false, err
+		}
+		switch msg.Type {
+		case wsPing:
+			err = s.send(s.conn, wsMessage{Type: wsPong}, nil)
+			if err != nil {
+				return false, err
+			}
+		case wsPong:
+			// The server is alive; stop waiting for it.
+			if atomic.CompareAndSwapInt32(&s.awaitingPong, 1, 0) {
+				s.conn.SetReadDeadline(time.Time{})
+			}
+		case wsNext:
+			var payload struct {
+				Data   json.RawMessage
+				Errors []DataError
+			}
+			err = json.Unmarshal(msg.Payload, &payload)
+			if err == nil {
+				err = handle(payload.Data, payload.Errors)
+			}
+		case wsError:
+			var dataErrors []DataError
+			err = json.Unmarshal(msg.Payload, &dataErrors)
+			if err == nil && len(dataErrors) > 0 {
+				err = dataErrors[0]
+			} else if err == nil {
+				err = errors.New("subscription failed with no error message")
+			}
+			return true, err
+		case wsComplete:
+			return true, nil
+		}
+		if err != nil && s.isClosed() {
+			return true, nil
+		} else if err != nil {
+			// Let the server know the subscription is no longer wanted.
+			s.send(s.conn, wsMessage{ID: subscriptionID, Type: wsComplete}, nil)
+			return true, err
+		}
+	}
+}
+
+// wsCloseTimeout is how long shutting down a subscription waits to write
+// the complete message and the close frame.
+const wsCloseTimeout = 5 * time.Second
+
+// shutdown implements eventStream. It sends the complete message for the
+// subscription, and closes the connection with a close frame.
+func (s *subscription) shutdown() {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.conn.SetWriteDeadline(time.Now().Add(wsCloseTimeout))
+		websocket.JSON.Send(s.conn, wsMessage{ID: subscriptionID, Type: wsComplete})
+		s.conn.Close()
+	})
+}
+
+// isClosed reports whether the subscription is being shut down.
+func (s *subscription) isClosed() bool {
+	select {
+	case <-s.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// keepPinging sends a ping on conn every keepalive interval until the
+// returned stop function is called. If there's a pong timeout, a ping that
+// isn't preceded by one awaiting its pong sets a read deadline, which
+// receiving a pong clears.
+func (s *subscription) keepPinging(conn *websocket.Conn) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(s.keepalive.pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+			if s.keepalive.pongTimeout > 0 && atomic.CompareAndSwapInt32(&s.awaitingPong, 0, 1) {
+				conn.SetReadDeadline(time.Now().Add(s.keepalive.pongTimeout))
+			}
+			if s.send(conn, wsMessage{Type: wsPing}, nil) != nil {
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// isTimeout reports whether err is a timeout reading from a connection.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// send writes a message with payload to conn, a connection of s.
+// payload is omitted if it's nil.
+func (s *subscription) send(conn *websocket.Conn, msg wsMessage, payload interface{}) error {
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		msg.Payload = b
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return websocket.JSON.Send(conn, msg)
+}
+
+// onDone calls f when ctx is done, such as to close a connection and
+// unblock pending reads. Calling the returned stop function stops
+// watching ctx.
+func onDone(ctx context.Context, f func()) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			f()
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+// wsURL returns the WebSocket URL used for subscriptions to the GraphQL
+// server URL endpoint. It's endpoint with its scheme changed from http(s)
+// to ws(s).
+func wsURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	case "ws", "wss":
+	default:
+		return "", fmt.Errorf("can't subscribe using GraphQL server URL %q: not an absolute http(s) URL", endpoint)
+	}
+	return u.String(), nil
+}
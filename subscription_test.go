@@ -0,0 +1,766 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+	"golang.org/x/net/websocket"
+)
+
+// wsMessage is a graphql-transport-ws protocol message.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// newSubscriptionServer starts a graphql-transport-ws server that
+// acknowledges the connection, and then passes it to handler
+// along with the subscribe message it receives.
+func newSubscriptionServer(t *testing.T, handler func(ws *websocket.Conn, subscribe wsMessage)) *httptest.Server {
+	return httptest.NewServer(websocket.Server{
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			config.Protocol = []string{"graphql-transport-ws"}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			var msg wsMessage
+			if err := websocket.JSON.Receive(ws, &msg); err != nil || msg.Type != "connection_init" {
+				t.Errorf("got message %+v, %v, want: connection_init", msg, err)
+				return
+			}
+			mustSend(ws, wsMessage{Type: "connection_ack"})
+			if err := websocket.JSON.Receive(ws, &msg); err != nil || msg.Type != "subscribe" {
+				t.Errorf("got message %+v, %v, want: subscribe", msg, err)
+				return
+			}
+			handler(ws, msg)
+		},
+	})
+}
+
+func mustSend(ws *websocket.Conn, msg wsMessage) {
+	err := websocket.JSON.Send(ws, msg)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	server := newSubscriptionServer(t, func(ws *websocket.Conn, subscribe wsMessage) {
+		if got, want := string(subscribe.Payload), `{"query":"subscription($repo:String!){starAdded(repo: $repo){login}}","variables":{"repo":"graphql"}}`; got != want {
+			t.Errorf("got payload: %v, want: %v", got, want)
+		}
+		mustSend(ws, wsMessage{ID: subscribe.ID, Type: "next", Payload: json.RawMessage(`{"data": {"starAdded": {"login": "gopher"}}}`)})
+		mustSend(ws, wsMessage{Type: "ping"})
+		mustSend(ws, wsMessage{ID: subscribe.ID, Type: "next", Payload: json.RawMessage(`{"data": {"starAdded": {"login": "gordon"}}, "errors": [{"message": "partial"}]}`)})
+		var msg wsMessage
+		if err := websocket.JSON.Receive(ws, &msg); err != nil || msg.Type != "pong" {
+			t.Errorf("got message %+v, %v, want: pong", msg, err)
+		}
+		mustSend(ws, wsMessage{ID: subscribe.ID, Type: "complete"})
+	})
+	defer server.Close()
+	client := graphql.NewClient(server.URL, nil)
+
+	type starAdded struct {
+		StarAdded struct {
+			Login graphql.String
+		} `graphql:"starAdded(repo: $repo)"`
+	}
+	variables := map[string]interface{}{
+		"repo": graphql.String("graphql"),
+	}
+	events, errs, err := graphql.Subscribe[starAdded](context.Background(), client, variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logins []graphql.String
+	var gotErrs []error
+	for events != nil || errs != nil {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			logins = append(logins, e.StarAdded.Login)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			gotErrs = append(gotErrs, err)
+		}
+	}
+	if len(logins) != 2 || logins[0] != "gopher" || logins[1] != "gordon" {
+		t.Errorf("got logins: %v, want: [gopher gordon]", logins)
+	}
+	if len(gotErrs) != 1 || gotErrs[0].Error() != "partial" {
+		t.Errorf("got errors: %v, want: [partial]", gotErrs)
+	}
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	server := newSubscriptionServer(t, func(ws *websocket.Conn, subscribe wsMessage) {
+		mustSend(ws, wsMessage{ID: subscribe.ID, Type: "next", Payload: json.RawMessage(`{"data": {"starAdded": {"login": "gopher"}}}`)})
+		mustSend(ws, wsMessage{ID: subscribe.ID, Type: "next", Payload: json.RawMessage(`{"data": null, "errors": [{"message": "partial"}]}`)})
+		mustSend(ws, wsMessage{ID: subscribe.ID, Type: "next", Payload: json.RawMessage(`{"data": {"starAdded": {"login": "gordon"}}}`)})
+		mustSend(ws, wsMessage{ID: subscribe.ID, Type: "complete"})
+	})
+	defer server.Close()
+	client := graphql.NewClient(server.URL, nil)
+
+	var s struct {
+		StarAdded struct {
+			Login graphql.String
+		}
+	}
+	var logins []graphql.String
+	var gotErrs []graphql.DataError
+	err := client.Subscribe(context.Background(), &s, nil, func(event interface{}, dataErrors []graphql.DataError) error {
+		if event != nil {
+			logins = append(logins, event.(*struct {
+				StarAdded struct {
+					Login graphql.String
+				}
+			}).StarAdded.Login)
+		}
+		gotErrs = append(gotErrs, dataErrors...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logins) != 2 || logins[0] != "gopher" || logins[1] != "gordon" {
+		t.Errorf("got logins: %v, want: [gopher gordon]", logins)
+	}
+	if len(gotErrs) != 1 || gotErrs[0].Message != "partial" {
+		t.Errorf("got errors: %v, want: [partial]", gotErrs)
+	}
+	if s.StarAdded.Login != "" {
+		t.Errorf("got v modified: %+v", s)
+	}
+
+	// An error returned by handle ends the subscription.
+	server = newSubscriptionServer(t, func(ws *websocket.Conn, subscribe wsMessage) {
+		mustSend(ws, wsMessage{ID: subscribe.ID, Type: "next", Payload: json.RawMessage(`{"data": {"starAdded": {"login": "gopher"}}}`)})
+		var msg wsMessage
+		if err := websocket.JSON.Receive(ws, &msg); err != nil || msg.Type != "complete" {
+			t.Errorf("got message %+v, %v, want: complete", msg, err)
+		}
+	})
+	defer server.Close()
+	client = graphql.NewClient(server.URL, nil)
+	errStop := errors.New("stop")
+	err = client.Subscribe(context.Background(), &s, nil, func(interface{}, []graphql.DataError) error { return errStop })
+	if err != errStop {
+		t.Errorf("got error: %v, want: %v", err, errStop)
+	}
+}
+
+func TestSubscribe_error(t *testing.T) {
+	server := newSubscriptionServer(t, func(ws *websocket.Conn, subscribe wsMessage) {
+		mustSend(ws, wsMessage{ID: subscribe.ID, Type: "error", Payload: json.RawMessage(`[{"message": "unknown field"}]`)})
+	})
+	defer server.Close()
+	client := graphql.NewClient(server.URL, nil)
+
+	events, errs, err := graphql.Subscribe[struct{ Foo graphql.String }](context.Background(), client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = <-errs
+	if err == nil || err.Error() != "unknown field" {
+		t.Errorf("got error: %v, want: unknown field", err)
+	}
+	if _, ok := <-events; ok {
+		t.Error("got event, want events channel closed")
+	}
+}
+
+func TestSubscribe_cancel(t *testing.T) {
+	done := make(chan struct{})
+	server := newSubscriptionServer(t, func(ws *websocket.Conn, subscribe wsMessage) {
+		var msg wsMessage
+		if err := websocket.JSON.Receive(ws, &msg); err != nil || msg.Type != "complete" || msg.ID != subscribe.ID {
+			t.Errorf("got message %+v, %v, want: complete", msg, err)
+		}
+		close(done)
+	})
+	defer server.Close()
+	client := graphql.NewClient(server.URL, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, errs, err := graphql.Subscribe[struct{ Foo graphql.String }](ctx, client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("got event, want events channel closed")
+	}
+	if err, ok := <-errs; ok {
+		t.Errorf("got error: %v, want errors channel closed", err)
+	}
+	<-done
+}
+
+func TestClient_Drain(t *testing.T) {
+	ready := make(chan struct{})
+	completed := make(chan struct{})
+	server := newSubscriptionServer(t, func(ws *websocket.Conn, subscribe wsMessage) {
+		mustSend(ws, wsMessage{ID: subscribe.ID, Type: "next", Payload: json.RawMessage(`{"data": {"login": "a"}}`)})
+		mustSend(ws, wsMessage{ID: subscribe.ID, Type: "next", Payload: json.RawMessage(`{"data": {"login": "b"}}`)})
+		// The client answers the ping after buffering both events.
+		mustSend(ws, wsMessage{Type: "ping"})
+		var msg wsMessage
+		if err := websocket.JSON.Receive(ws, &msg); err != nil || msg.Type != "pong" {
+			t.Errorf("got message %+v, %v, want: pong", msg, err)
+		}
+		close(ready)
+		if err := websocket.JSON.Receive(ws, &msg); err != nil || msg.Type != "complete" || msg.ID != subscribe.ID {
+			t.Errorf("got message %+v, %v, want: complete", msg, err)
+		}
+		close(completed)
+	})
+	defer server.Close()
+	client := graphql.NewClient(server.URL, nil)
+
+	events, errs, err := graphql.Subscribe[struct{ Login graphql.String }](context.Background(), client, nil,
+		graphql.WithEventBuffer(2, graphql.OverflowBlock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ready
+	err = client.Drain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-completed
+	var logins string
+	for e := range events {
+		logins += string(e.Login)
+	}
+	if logins != "ab" {
+		t.Errorf("got logins: %q, want: %q", logins, "ab")
+	}
+	if err, ok := <-errs; ok {
+		t.Errorf("got error: %v, want errors channel closed", err)
+	}
+
+	_, _, err = graphql.Subscribe[struct{ Login graphql.String }](context.Background(), client, nil)
+	if !errors.Is(err, graphql.ErrClientClosed) {
+		t.Errorf("got error: %v, want: %v", err, graphql.ErrClientClosed)
+	}
+}
+
+func TestSubscribe_eventBuffer(t *testing.T) {
+	server := newSubscriptionServer(t, func(ws *websocket.Conn, subscribe wsMessage) {
+		for _, login := range []string{"a", "b", "c", "d", "e"} {
+			mustSend(ws, wsMessage{ID: subscribe.ID, Type: "next", Payload: json.RawMessage(`{"data": {"login": "` + login + `"}}`)})
+		}
+		mustSend(ws, wsMessage{ID: subscribe.ID, Type: "complete"})
+	})
+	defer server.Close()
+	client := graphql.NewClient(server.URL, nil)
+
+	for _, tc := range []struct {
+		policy      graphql.OverflowPolicy
+		wantLogins  string
+		wantDropped int
+		wantErr     error
+	}{
+		{graphql.OverflowDropOldest, "de", 3, nil},
+		{graphql.OverflowDropNewest, "ab", 3, nil},
+		{graphql.OverflowError, "ab", 0, graphql.ErrSlowConsumer},
+	} {
+		dropped := 0
+		events, errs, err := graphql.Subscribe[struct{ Login graphql.String }](context.Background(), client, nil,
+			graphql.WithEventBuffer(2, tc.policy),
+			graphql.WithDroppedEventFunc(func() { dropped++ }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Don't receive events until the subscription ends,
+		// so that they overflow the buffer.
+		var gotErr error
+		for err := range errs {
+			gotErr = err
+		}
+		var logins string
+		for e := range events {
+			logins += string(e.Login)
+		}
+		if logins != tc.wantLogins {
+			t.Errorf("policy %v: got logins: %q, want: %q", tc.policy, logins, tc.wantLogins)
+		}
+		if dropped != tc.wantDropped {
+			t.Errorf("policy %v: got %v dropped events, want %v", tc.policy, dropped, tc.wantDropped)
+		}
+		if !errors.Is(gotErr, tc.wantErr) {
+			t.Errorf("policy %v: got error: %v, want: %v", tc.policy, gotErr, tc.wantErr)
+		}
+	}
+}
+
+func TestWithConnectionAckTimeout(t *testing.T) {
+	server := httptest.NewServer(websocket.Server{
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			config.Protocol = []string{"graphql-transport-ws"}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			var msg wsMessage
+			websocket.JSON.Receive(ws, &msg) // connection_init, never acknowledged.
+			websocket.JSON.Receive(ws, &msg) // Blocks until the client goes away.
+		},
+	})
+	defer server.Close()
+	client := graphql.NewClient(server.URL, nil, graphql.WithConnectionAckTimeout(10*time.Millisecond))
+
+	_, _, err := graphql.Subscribe[struct{ Foo graphql.String }](context.Background(), client, nil)
+	if got, want := fmt.Sprint(err), "server didn't acknowledge the connection within 10ms"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestWithWebSocketPing(t *testing.T) {
+	server := newSubscriptionServer(t, func(ws *websocket.Conn, subscribe wsMessage) {
+		// Answer the first ping only.
+		var msg wsMessage
+		if err := websocket.JSON.Receive(ws, &msg); err != nil || msg.Type != "ping" {
+			t.Errorf("got message %+v, %v, want: ping", msg, err)
+			return
+		}
+		mustSend(ws, wsMessage{Type: "pong"})
+		mustSend(ws, wsMessage{ID: subscribe.ID, Type: "next", Payload: json.RawMessage(`{"data": {"foo": "bar"}}`)})
+		for websocket.JSON.Receive(ws, &msg) == nil {
+		}
+	})
+	defer server.Close()
+	client := graphql.NewClient(server.URL, nil, graphql.WithWebSocketPing(10*time.Millisecond, 20*time.Millisecond), graphql.WithWebSocketReconnect(0, nil))
+
+	events, errs, err := graphql.Subscribe[struct{ Foo graphql.String }](context.Background(), client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := <-events; e.Foo != "bar" {
+		t.Errorf("got event: %+v, want foo: bar", e)
+	}
+	err = <-errs
+	if got, want := fmt.Sprint(err), "server didn't answer ping with pong within 20ms"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestWithConnectionInitFunc(t *testing.T) {
+	var payloads []string
+	server := httptest.NewServer(websocket.Server{
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			config.Protocol = []string{"graphql-transport-ws"}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			var msg wsMessage
+			if err := websocket.JSON.Receive(ws, &msg); err != nil || msg.Type != "connection_init" {
+				t.Errorf("got message %+v, %v, want: connection_init", msg, err)
+				return
+			}
+			payloads = append(payloads, string(msg.Payload))
+			mustSend(ws, wsMessage{Type: "connection_ack"})
+			websocket.JSON.Receive(ws, &msg) // subscribe.
+			mustSend(ws, wsMessage{ID: msg.ID, Type: "complete"})
+		},
+	})
+	defer server.Close()
+	tokens := 0
+	client := graphql.NewClient(server.URL, nil, graphql.WithConnectionInitFunc(func(context.Context) (interface{}, error) {
+		tokens++
+		return map[string]string{"token": fmt.Sprint("token-", tokens)}, nil
+	}))
+
+	for i := 0; i < 2; i++ {
+		_, errs, err := graphql.Subscribe[struct{ Foo graphql.String }](context.Background(), client, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range errs {
+		}
+	}
+	if got, want := fmt.Sprint(payloads), `[{"token":"token-1"} {"token":"token-2"}]`; got != want {
+		t.Errorf("got connection_init payloads: %v, want: %v", got, want)
+	}
+}
+
+func TestWithConnectionInitFunc_reconnect(t *testing.T) {
+	var mu sync.Mutex
+	var payloads []string
+	server := httptest.NewServer(websocket.Server{
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			config.Protocol = []string{"graphql-transport-ws"}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			var msg wsMessage
+			websocket.JSON.Receive(ws, &msg) // connection_init.
+			mu.Lock()
+			payloads = append(payloads, string(msg.Payload))
+			n := len(payloads)
+			mu.Unlock()
+			mustSend(ws, wsMessage{Type: "connection_ack"})
+			websocket.JSON.Receive(ws, &msg) // subscribe.
+			mustSend(ws, wsMessage{ID: msg.ID, Type: "next", Payload: json.RawMessage(fmt.Sprintf(`{"data": {"foo": "bar-%d"}}`, len(payloads)))})
+			if n == 2 {
+				mustSend(ws, wsMessage{ID: msg.ID, Type: "complete"})
+			}
+			// Drop the first connection.
+		},
+	})
+	defer server.Close()
+	tokens := 0
+	client := graphql.NewClient(server.URL, nil, graphql.WithConnectionInitFunc(func(context.Context) (interface{}, error) {
+		tokens++
+		return map[string]string{"token": fmt.Sprint("token-", tokens)}, nil
+	}), graphql.WithWebSocketReconnect(1, graphql.ExponentialBackoff(time.Millisecond, time.Millisecond)))
+
+	events, errs, err := graphql.Subscribe[struct{ Foo graphql.String }](context.Background(), client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for events != nil || errs != nil {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			got = append(got, string(e.Foo))
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			var subErr *graphql.SubscriptionError
+			if !errors.As(err, &subErr) || !subErr.Transient {
+				t.Errorf("got error: %#v, want a transient *graphql.SubscriptionError", err)
+			}
+		}
+	}
+	if got, want := fmt.Sprint(got), "[bar-1 bar-2]"; got != want {
+		t.Errorf("got events: %v, want: %v", got, want)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got, want := fmt.Sprint(payloads), `[{"token":"token-1"} {"token":"token-2"}]`; got != want {
+		t.Errorf("got connection_init payloads: %v, want: %v", got, want)
+	}
+}
+
+func TestWithConnectionInitFunc_error(t *testing.T) {
+	server := newSubscriptionServer(t, func(ws *websocket.Conn, subscribe wsMessage) {})
+	defer server.Close()
+	client := graphql.NewClient(server.URL, nil, graphql.WithConnectionInitFunc(func(context.Context) (interface{}, error) {
+		return nil, fmt.Errorf("token expired")
+	}))
+
+	_, _, err := graphql.Subscribe[struct{ Foo graphql.String }](context.Background(), client, nil)
+	if got, want := fmt.Sprint(err), "token expired"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestWithWebSocketReconnect(t *testing.T) {
+	var mu sync.Mutex
+	var connections int
+	server := httptest.NewServer(websocket.Server{
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			config.Protocol = []string{"graphql-transport-ws"}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			mu.Lock()
+			connections++
+			n := connections
+			mu.Unlock()
+			var msg wsMessage
+			websocket.JSON.Receive(ws, &msg) // connection_init.
+			if n == 2 {
+				return // Drop the connection before acknowledging it.
+			}
+			mustSend(ws, wsMessage{Type: "connection_ack"})
+			websocket.JSON.Receive(ws, &msg) // subscribe.
+			mustSend(ws, wsMessage{ID: msg.ID, Type: "next", Payload: json.RawMessage(fmt.Sprintf(`{"data": {"foo": "bar-%d"}}`, n))})
+			if n == 3 {
+				mustSend(ws, wsMessage{ID: msg.ID, Type: "complete"})
+			}
+		},
+	})
+	defer server.Close()
+	var attempts []int
+	client := graphql.NewClient(server.URL, nil, graphql.WithWebSocketReconnect(2, func(attempt int) time.Duration {
+		attempts = append(attempts, attempt)
+		return time.Millisecond
+	}))
+
+	events, errs, err := graphql.Subscribe[struct{ Foo graphql.String }](context.Background(), client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := <-events; e.Foo != "bar-1" {
+		t.Errorf("got event: %+v, want foo: bar-1", e)
+	}
+	var subErr *graphql.SubscriptionError
+	if err := <-errs; !errors.As(err, &subErr) || !subErr.Transient {
+		t.Errorf("got error: %#v, want a transient *graphql.SubscriptionError", err)
+	}
+	if e := <-events; e.Foo != "bar-3" {
+		t.Errorf("got event: %+v, want foo: bar-3", e)
+	}
+	for err := range errs {
+		t.Errorf("got error: %v, want none", err)
+	}
+	if got, want := fmt.Sprint(attempts), "[1 2]"; got != want {
+		t.Errorf("got backoff attempts: %v, want: %v", got, want)
+	}
+}
+
+func TestWithWebSocketReconnect_terminal(t *testing.T) {
+	server := newSubscriptionServer(t, func(ws *websocket.Conn, subscribe wsMessage) {
+		// Drop the connection.
+	})
+	defer server.Close()
+	inits := 0
+	client := graphql.NewClient(server.URL, nil, graphql.WithConnectionInitFunc(func(context.Context) (interface{}, error) {
+		inits++
+		if inits > 1 {
+			return nil, fmt.Errorf("token expired")
+		}
+		return nil, nil
+	}), graphql.WithWebSocketReconnect(3, graphql.ExponentialBackoff(time.Millisecond, time.Millisecond)))
+
+	_, errs, err := graphql.Subscribe[struct{ Foo graphql.String }](context.Background(), client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var subErr *graphql.SubscriptionError
+	if err := <-errs; !errors.As(err, &subErr) || !subErr.Transient {
+		t.Errorf("got error: %#v, want a transient *graphql.SubscriptionError", err)
+	}
+	err = <-errs
+	if !errors.As(err, &subErr) || subErr.Transient || subErr.Error() != "token expired" {
+		t.Errorf("got error: %#v, want a terminal *graphql.SubscriptionError: token expired", err)
+	}
+	if inits != 2 {
+		t.Errorf("got %d connection inits, want: 2", inits)
+	}
+}
+
+func TestWithWebSocketReconnect_unavailable(t *testing.T) {
+	var mu sync.Mutex
+	var connections int
+	ws := websocket.Server{
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			config.Protocol = []string{"graphql-transport-ws"}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			mu.Lock()
+			n := connections
+			mu.Unlock()
+			var msg wsMessage
+			websocket.JSON.Receive(ws, &msg) // connection_init.
+			mustSend(ws, wsMessage{Type: "connection_ack"})
+			websocket.JSON.Receive(ws, &msg) // subscribe.
+			mustSend(ws, wsMessage{ID: msg.ID, Type: "next", Payload: json.RawMessage(fmt.Sprintf(`{"data": {"foo": "bar-%d"}}`, n))})
+			if n == 3 {
+				mustSend(ws, wsMessage{ID: msg.ID, Type: "complete"})
+			}
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		connections++
+		n := connections
+		mu.Unlock()
+		if n == 2 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		ws.ServeHTTP(w, req)
+	}))
+	defer server.Close()
+	var attempts []int
+	client := graphql.NewClient(server.URL, nil, graphql.WithWebSocketReconnect(2, func(attempt int) time.Duration {
+		attempts = append(attempts, attempt)
+		return time.Millisecond
+	}))
+
+	events, errs, err := graphql.Subscribe[struct{ Foo graphql.String }](context.Background(), client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := <-events; e.Foo != "bar-1" {
+		t.Errorf("got event: %+v, want foo: bar-1", e)
+	}
+	if err := <-errs; err == nil {
+		t.Error("got no error, want the connection failure")
+	}
+	if e := <-events; e.Foo != "bar-3" {
+		t.Errorf("got event: %+v, want foo: bar-3", e)
+	}
+	for err := range errs {
+		t.Errorf("got error: %v, want none", err)
+	}
+	if got, want := fmt.Sprint(attempts), "[1 2]"; got != want {
+		t.Errorf("got backoff attempts: %v, want: %v", got, want)
+	}
+}
+
+func TestWithWebSocketReconnect_dial(t *testing.T) {
+	var mu sync.Mutex
+	var connections int
+	handler := websocket.Server{
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			config.Protocol = []string{"graphql-transport-ws"}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			mu.Lock()
+			connections++
+			n := connections
+			mu.Unlock()
+			var msg wsMessage
+			websocket.JSON.Receive(ws, &msg) // connection_init.
+			mustSend(ws, wsMessage{Type: "connection_ack"})
+			websocket.JSON.Receive(ws, &msg) // subscribe.
+			mustSend(ws, wsMessage{ID: msg.ID, Type: "next", Payload: json.RawMessage(fmt.Sprintf(`{"data": {"foo": "bar-%d"}}`, n))})
+			if n == 2 {
+				mustSend(ws, wsMessage{ID: msg.ID, Type: "complete"})
+			}
+		},
+	}
+	server := httptest.NewServer(handler)
+	addr := server.Listener.Addr().String()
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+		server.Close()
+	}()
+	var attempts []int
+	client := graphql.NewClient(server.URL, nil, graphql.WithWebSocketReconnect(2, func(attempt int) time.Duration {
+		attempts = append(attempts, attempt)
+		mu.Lock()
+		defer mu.Unlock()
+		switch attempt {
+		case 1:
+			// The server is down for the first attempt.
+			server.Close()
+		case 2:
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				t.Errorf("listening again: %v", err)
+				break
+			}
+			server = httptest.NewUnstartedServer(handler)
+			server.Listener.Close()
+			server.Listener = ln
+			server.Start()
+		}
+		return time.Millisecond
+	}))
+
+	events, errs, err := graphql.Subscribe[struct{ Foo graphql.String }](context.Background(), client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := <-events; e.Foo != "bar-1" {
+		t.Errorf("got event: %+v, want foo: bar-1", e)
+	}
+	if err := <-errs; err == nil {
+		t.Error("got no error, want the connection failure")
+	}
+	if e := <-events; e.Foo != "bar-2" {
+		t.Errorf("got event: %+v, want foo: bar-2", e)
+	}
+	for err := range errs {
+		t.Errorf("got error: %v, want none", err)
+	}
+	if got, want := fmt.Sprint(attempts), "[1 2]"; got != want {
+		t.Errorf("got backoff attempts: %v, want: %v", got, want)
+	}
+}
+
+func TestSubscriptionError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/unauthorized":
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		case "/unavailable":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		path          string
+		wantTransient bool
+	}{
+		{"/unauthorized", false},
+		{"/unavailable", true},
+	} {
+		for _, opts := range [][]graphql.ClientOption{{graphql.WithSSESubscriptions()}, nil} {
+			client := graphql.NewClient(server.URL+tc.path, nil, opts...)
+			_, _, err := graphql.Subscribe[struct{ Foo graphql.String }](context.Background(), client, nil)
+			var subErr *graphql.SubscriptionError
+			if !errors.As(err, &subErr) {
+				t.Fatalf("%v: got error: %#v, want a *graphql.SubscriptionError", tc.path, err)
+			}
+			if subErr.Transient != tc.wantTransient {
+				t.Errorf("%v: got transient: %v, want: %v", tc.path, subErr.Transient, tc.wantTransient)
+			}
+			var statusErr *graphql.HTTPStatusError
+			if !errors.As(err, &statusErr) {
+				t.Errorf("%v: got error: %v, want it to wrap a *graphql.HTTPStatusError", tc.path, err)
+			}
+		}
+	}
+
+	// Failing to connect to the server is transient.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.Close()
+	client := graphql.NewClient("http://"+ln.Addr().String(), nil)
+	_, _, err = graphql.Subscribe[struct{ Foo graphql.String }](context.Background(), client, nil)
+	var subErr *graphql.SubscriptionError
+	if !errors.As(err, &subErr) || !subErr.Transient {
+		t.Errorf("got error: %#v, want a transient *graphql.SubscriptionError", err)
+	}
+
+	// Errors reported by the server over graphql-transport-ws are terminal.
+	ws := newSubscriptionServer(t, func(ws *websocket.Conn, subscribe wsMessage) {
+		mustSend(ws, wsMessage{ID: subscribe.ID, Type: "error", Payload: json.RawMessage(`[{"message": "forbidden"}]`)})
+	})
+	defer ws.Close()
+	client = graphql.NewClient(ws.URL, nil)
+	_, errs, err := graphql.Subscribe[struct{ Foo graphql.String }](context.Background(), client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = <-errs
+	if !errors.As(err, &subErr) || subErr.Transient {
+		t.Errorf("got error: %#v, want a terminal *graphql.SubscriptionError", err)
+	}
+}
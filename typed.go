@@ -0,0 +1,35 @@
+package graphql
+
+import "context"
+
+// Query executes a single GraphQL query request using c, with a query
+// derived from the type T, and returns the response decoded into a new T.
+// T should be a struct type that corresponds to the GraphQL schema.
+//
+// It's equivalent to calling c.Query with a pointer to a new T,
+// except that the zero value of T is returned if err is non-nil.
+func Query[T any](ctx context.Context, c *Client, variables map[string]interface{}, opts ...RequestOption) (T, []DataError, error) {
+	var q T
+	dataErrors, err := c.Query(ctx, &q, variables, opts...)
+	if err != nil {
+		var zero T
+		return zero, nil, err
+	}
+	return q, dataErrors, nil
+}
+
+// Mutate executes a single GraphQL mutation request using c, with a mutation
+// derived from the type T, and returns the response decoded into a new T.
+// T should be a struct type that corresponds to the GraphQL schema.
+//
+// It's equivalent to calling c.Mutate with a pointer to a new T,
+// except that the zero value of T is returned if err is non-nil.
+func Mutate[T any](ctx context.Context, c *Client, variables map[string]interface{}, opts ...RequestOption) (T, []DataError, error) {
+	var m T
+	dataErrors, err := c.Mutate(ctx, &m, variables, opts...)
+	if err != nil {
+		var zero T
+		return zero, nil, err
+	}
+	return m, dataErrors, nil
+}
@@ -0,0 +1,100 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestQuery(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("X-Request-Tag"), "typed"; got != want {
+			t.Errorf("got X-Request-Tag header: %q, want: %q", got, want)
+		}
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query($login:String!){user(login: $login){name}}","variables":{"login":"gopher"}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type userQuery struct {
+		User struct {
+			Name graphql.String
+		} `graphql:"user(login: $login)"`
+	}
+	variables := map[string]interface{}{
+		"login": graphql.String("gopher"),
+	}
+	q, dataErrors, err := graphql.Query[userQuery](context.Background(), client, variables, graphql.WithHeader("X-Request-Tag", "typed"))
+	if dataErrors != nil {
+		t.Fatal(dataErrors)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.User.Name, graphql.String("Gopher"); got != want {
+		t.Errorf("got q.User.Name: %q, want: %q", got, want)
+	}
+}
+
+func TestQuery_error(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "important message", http.StatusInternalServerError)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	q, _, err := graphql.Query[*struct{ Viewer struct{ Login string } }](context.Background(), client, nil)
+	if err == nil {
+		t.Fatal("got error: nil, want: non-nil")
+	}
+	if q != nil {
+		t.Errorf("got q: %v, want: nil", q)
+	}
+}
+
+func TestMutate(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"mutation($input:AddStarInput!){addStar(input: $input){starrable{stargazerCount}}}","variables":{"input":{"starrableId":"1"}}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{
+			"data": {"addStar": {"starrable": null}},
+			"errors": [{"message": "Could not resolve to a node with the global id of '1'"}]
+		}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type AddStarInput struct {
+		StarrableID graphql.ID `json:"starrableId"`
+	}
+	type addStarMutation struct {
+		AddStar struct {
+			Starrable *struct {
+				StargazerCount graphql.Int
+			}
+		} `graphql:"addStar(input: $input)"`
+	}
+	variables := map[string]interface{}{
+		"input": AddStarInput{StarrableID: "1"},
+	}
+	m, dataErrors, err := graphql.Mutate[addStarMutation](context.Background(), client, variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dataErrors) != 1 {
+		t.Fatalf("got %d dataErrors, want: 1", len(dataErrors))
+	}
+	if m.AddStar.Starrable != nil {
+		t.Errorf("got m.AddStar.Starrable: %v, want: nil", m.AddStar.Starrable)
+	}
+}
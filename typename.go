@@ -0,0 +1,45 @@
+package graphql
+
+import (
+	"reflect"
+
+	"github.com/merico-dev/graphql/internal/jsonutil"
+)
+
+// Typename is the __typename of an object, which selects the inline
+// fragments of a union or interface that apply to it. Fragments on other
+// types than the __typename decoded into a Typename field are reset to
+// their zero values, so that only the fragment on the object's type holds
+// the fields that several fragments select, and the Typename field tells
+// which type it is. E.g.:
+//
+//	var q struct {
+//		Node struct {
+//			Typename    graphql.Typename `graphql:"__typename"`
+//			Issue       struct{ Title graphql.String } `graphql:"... on Issue"`
+//			PullRequest struct{ Title graphql.String } `graphql:"... on PullRequest"`
+//		} `graphql:"node(id: $id)"`
+//	}
+//
+// If the node is a pull request, q.Node.Typename is "PullRequest", and
+// q.Node.Issue is zero. Fragments on interfaces and unions, which don't
+// name the object's type, are reset too, so they shouldn't be selected
+// next to a Typename field; fragments without a type condition are kept.
+type Typename string
+
+// WithAutoTypename makes Query, Mutate and Prepare select __typename in
+// every selection set of the document but the operation's, including those
+// of fragments, as normalized caches and union handling need, without
+// a Typename field in every struct. Structs that select __typename already
+// aren't selected it twice, and the __typename of those that don't isn't
+// decoded into them. It applies to every request of a client created
+// with WithRequestOptions(WithAutoTypename()).
+func WithAutoTypename() RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.autoTypename = true
+	}
+}
+
+func init() {
+	jsonutil.RegisterTypename(reflect.TypeOf(Typename("")))
+}
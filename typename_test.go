@@ -0,0 +1,94 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestTypename(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"{search{__typename,... on Issue{title,closed},... on PullRequest{title,merged}}}"}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"search": [
+			{"__typename": "Issue", "title": "Bug", "closed": true},
+			{"title": "Fix", "merged": false, "__typename": "PullRequest"}
+		]}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Search []struct {
+			Typename graphql.Typename `graphql:"__typename"`
+			Issue    struct {
+				Title  graphql.String
+				Closed graphql.Boolean
+			} `graphql:"... on Issue"`
+			PullRequest struct {
+				Title  graphql.String
+				Merged graphql.Boolean `graphql:"merged,required"`
+			} `graphql:"... on PullRequest"`
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Search) != 2 {
+		t.Fatalf("got %d results, want 2", len(q.Search))
+	}
+	issue, pr := q.Search[0], q.Search[1]
+	if issue.Typename != "Issue" || issue.Issue.Title != "Bug" || !bool(issue.Issue.Closed) || issue.PullRequest.Title != "" {
+		t.Errorf("got first result: %+v, want only the issue", issue)
+	}
+	if pr.Typename != "PullRequest" || pr.PullRequest.Title != "Fix" || pr.Issue.Title != "" {
+		t.Errorf("got second result: %+v, want only the pull request", pr)
+	}
+}
+
+func TestWithAutoTypename(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"{viewer{__typename,login,repositories{__typename,nodes{__typename,name}}},search{__typename,... on Issue{__typename,title}}}"}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {
+			"viewer": {"__typename": "User", "login": "gopher", "repositories": {"__typename": "RepositoryConnection", "nodes": [{"__typename": "Repository", "name": "graphql"}]}},
+			"search": {"__typename": "Issue", "title": "Bug"}
+		}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRequestOptions(graphql.WithAutoTypename()))
+
+	var q struct {
+		Viewer struct {
+			Login        graphql.String
+			Repositories struct {
+				Nodes []struct {
+					Name graphql.String
+				}
+			}
+		}
+		Search struct {
+			Typename graphql.Typename `graphql:"__typename"`
+			Issue    struct {
+				Title graphql.String
+			} `graphql:"... on Issue"`
+		}
+	}
+	_, err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Viewer.Repositories.Nodes[0].Name != "graphql" || q.Search.Typename != "Issue" || q.Search.Issue.Title != "Bug" {
+		t.Errorf("got: %+v", q)
+	}
+}
@@ -0,0 +1,173 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Upload represents a file to upload, as the value of a variable of the
+// Upload scalar type, or of an element or field of one. Operations with
+// Upload variables are sent as multipart/form-data requests, following
+// the GraphQL multipart request specification:
+// https://github.com/jaydenseric/graphql-multipart-request-spec.
+//
+// If Body is an io.Seeker, such as an *os.File, it's rewound before each
+// attempt of a request, so that it can be retried. Otherwise, it's read
+// once, and retrying the request fails.
+type Upload struct {
+	Name        string    // Filename sent for the file, e.g. "avatar.png".
+	ContentType string    // Media type of the file; "application/octet-stream" if empty.
+	Body        io.Reader // Content of the file.
+}
+
+// MarshalJSON encodes u as null, which is how files are represented in the
+// operations of multipart requests.
+func (Upload) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// upload is an Upload found in the variables of a request.
+type upload struct {
+	path string // Object path of the Upload in the request, e.g. "variables.files.0".
+	*Upload
+}
+
+var uploadType = reflect.TypeOf(Upload{})
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// findUploads returns the Uploads in variables, along with their paths,
+// sorted by path.
+func findUploads(variables map[string]interface{}) []upload {
+	var uploads []upload
+	for k, v := range variables {
+		findUploadsIn(reflect.ValueOf(v), "variables."+k, &uploads)
+	}
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].path < uploads[j].path })
+	return uploads
+}
+
+// findUploadsIn adds the Uploads in v, at path, to uploads.
+func findUploadsIn(v reflect.Value, path string, uploads *[]upload) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		if v.Type() == reflect.PtrTo(uploadType) {
+			*uploads = append(*uploads, upload{path: path, Upload: v.Interface().(*Upload)})
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == uploadType {
+			u := v.Interface().(Upload)
+			*uploads = append(*uploads, upload{path: path, Upload: &u})
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name := f.Name
+			if tag, ok := f.Tag.Lookup("json"); ok {
+				tagName, _, _ := strings.Cut(tag, ",")
+				if tagName == "-" {
+					continue
+				}
+				if tagName != "" {
+					name = tagName
+				}
+			}
+			findUploadsIn(v.Field(i), path+"."+name, uploads)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			findUploadsIn(iter.Value(), path+"."+iter.Key().String(), uploads)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			findUploadsIn(v.Index(i), path+"."+strconv.Itoa(i), uploads)
+		}
+	}
+}
+
+// newUploadRequest returns a multipart/form-data POST request to endpoint
+// for the GraphQL request in, whose variables hold uploads.
+func (c *Client) newUploadRequest(endpoint string, in requestBody, uploads []upload) (*http.Request, error) {
+	operations, err := c.marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	fileMap := make(map[string][]string, len(uploads))
+	for i, u := range uploads {
+		fileMap[strconv.Itoa(i)] = []string{u.path}
+	}
+	mapJSON, err := json.Marshal(fileMap)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	err = w.WriteField("operations", string(operations))
+	if err != nil {
+		return nil, err
+	}
+	err = w.WriteField("map", string(mapJSON))
+	if err != nil {
+		return nil, err
+	}
+	for i, u := range uploads {
+		if u.Body == nil {
+			return nil, fmt.Errorf("upload %s has no body", u.path)
+		}
+		if s, ok := u.Body.(io.Seeker); ok {
+			_, err := s.Seek(0, io.SeekStart)
+			if err != nil {
+				return nil, fmt.Errorf("upload %s: %w", u.path, err)
+			}
+		}
+		contentType := u.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%d"; filename="%s"`, i, quoteEscaper.Replace(u.Name)))
+		h.Set("Content-Type", contentType)
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(part, u.Body)
+		if err != nil {
+			return nil, fmt.Errorf("upload %s: %w", u.path, err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, &buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req, nil
+}
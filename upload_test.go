@@ -0,0 +1,70 @@
+package graphql_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestClient_Mutate_upload(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		err := req.ParseMultipartForm(1 << 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := req.MultipartForm.Value["operations"], []string{`{"query":"mutation($files:[Upload!]!$id:ID!){uploadFiles(id: $id, files: $files){count}}","variables":{"files":[null,null],"id":"1"}}` + "\n"}; len(got) != 1 || got[0] != want[0] {
+			t.Errorf("got operations:\n%q\nwant:\n%q", got, want)
+		}
+		if got, want := req.MultipartForm.Value["map"], []string{`{"0":["variables.files.0"],"1":["variables.files.1"]}`}; len(got) != 1 || got[0] != want[0] {
+			t.Errorf("got map: %q, want: %q", got, want)
+		}
+		for name, want := range map[string]string{"0": "a.txt: hello", "1": "b.txt: world"} {
+			fh := req.MultipartForm.File[name]
+			if len(fh) != 1 {
+				t.Fatalf("got %d files named %q, want 1", len(fh), name)
+			}
+			f, err := fh[0].Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fh[0].Filename + ": " + string(b); got != want {
+				t.Errorf("got file %q: %q, want: %q", name, got, want)
+			}
+		}
+		if got, want := req.MultipartForm.File["1"][0].Header.Get("Content-Type"), "text/plain"; got != want {
+			t.Errorf("got Content-Type: %q, want: %q", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"uploadFiles": {"count": 2}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var m struct {
+		UploadFiles struct {
+			Count graphql.Int
+		} `graphql:"uploadFiles(id: $id, files: $files)"`
+	}
+	_, err := client.Mutate(context.Background(), &m, map[string]interface{}{
+		"id": graphql.ID("1"),
+		"files": []graphql.Upload{
+			{Name: "a.txt", Body: strings.NewReader("hello")},
+			{Name: "b.txt", ContentType: "text/plain", Body: strings.NewReader("world")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.UploadFiles.Count, graphql.Int(2); got != want {
+		t.Errorf("got count: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,212 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/merico-dev/graphql/introspection"
+)
+
+// Validate checks the query, mutation or subscription struct v and its
+// variables against schema, as the server does before executing the
+// operation constructed from them: that the fields v selects exist, and
+// that its Go types line up with theirs, as CheckCompatibility does, and
+// also that the arguments of the fields exist, that required ones are
+// given, and that the variables they're bound to are given and have types
+// the arguments accept. Problems are reported by a *CompatibilityError,
+// prefixed by the Go path of the struct field they're found at.
+//
+// It's meant to be called from tests, with a schema introspected with
+// Client.Schema or parsed with introspection.ParseSDL, so that operations
+// the server would reject fail tests rather than requests.
+func Validate(schema *introspection.Schema, v interface{}, variables map[string]interface{}) error {
+	return validate(schema, nil, v, variables)
+}
+
+// validate validates v and its variables against schema, as an operation
+// on the root operation type root, or on the one rootType returns if root
+// is nil.
+func validate(schema *introspection.Schema, root *introspection.Type, v interface{}, variables map[string]interface{}) error {
+	cc := compatibilityChecker{schema: schema, validate: true, variables: variables}
+	cc.checkOperation("", v, root)
+	if len(cc.problems) > 0 {
+		return &CompatibilityError{Problems: cc.problems}
+	}
+	return nil
+}
+
+// WithSchemaValidation makes Client.Query and Client.Mutate validate their
+// operation structs and variables with Validate before sending them, so
+// that mistakes are reported with the Go paths of the struct fields they're
+// found at, rather than by the server.
+//
+// The schema is the one that Schema returns. If it can't be introspected,
+// such as when the server disables introspection, operations aren't
+// validated.
+func WithSchemaValidation() ClientOption {
+	return func(c *Client) {
+		c.schemaValidation = true
+	}
+}
+
+// validateOperation validates the operation struct v of type operation
+// and its variables against the schema, if the client validates them and
+// the schema is available.
+func (c *Client) validateOperation(ctx context.Context, operation string, v interface{}, variables map[string]interface{}) error {
+	if !c.schemaValidation {
+		return nil
+	}
+	schema, err := c.Schema(ctx)
+	if err != nil {
+		return nil // Validation is best effort.
+	}
+	root := schema.QueryType
+	if operation == "mutation" {
+		root = schema.MutationType
+	}
+	if root == nil || schema.Type(root.Name) == nil {
+		return validate(schema, nil, v, variables)
+	}
+	return validate(schema, schema.Type(root.Name), v, variables)
+}
+
+// checkArguments checks the arguments of the selection of field, at path,
+// and that the variables they reference are in variables.
+func (cc *compatibilityChecker) checkArguments(path, selection string, field *introspection.Field, variables map[string]interface{}) {
+	given := make(map[string]bool)
+	for _, arg := range fieldArguments(selection) {
+		given[arg.name] = true
+		def := findArgument(field.Args, arg.name)
+		if def == nil {
+			cc.problemf(path, "field %q has no argument %q", field.Name, arg.name)
+			continue
+		}
+		if !strings.HasPrefix(arg.value, "$") || variables[arg.value[1:]] == nil {
+			continue
+		}
+		value := variables[arg.value[1:]]
+		var buf bytes.Buffer
+		writeVariableType(&buf, value)
+		argType := def.Type
+		v, _ := value.(Variable)
+		if argType.Kind == introspection.NonNull && argType.OfType != nil && (def.DefaultValue != nil || v.Default != "" && v.Default != "null") {
+			// Nullable variables may be used for non-null arguments
+			// if either has a default value.
+			argType = *argType.OfType
+		}
+		if varType, ok := parseTypeRef(buf.String()); !ok || !typesCompatible(varType, argType) {
+			cc.problemf(path, "argument %q of field %q has type %v, but variable %s has type %s", arg.name, field.Name, def.Type, arg.value, buf.String())
+		}
+	}
+	for _, def := range field.Args {
+		if def.Type.Kind == introspection.NonNull && def.DefaultValue == nil && !given[def.Name] {
+			cc.problemf(path, "field %q needs argument %q of type %v", field.Name, def.Name, def.Type)
+		}
+	}
+	for _, name := range sortedNames(referencedVariables(selection)) {
+		if _, ok := variables[name]; !ok {
+			cc.problemf(path, "variable $%s is missing from the variables", name)
+		}
+	}
+}
+
+// findArgument returns the argument named name, or nil if there's none.
+func findArgument(args []introspection.InputValue, name string) *introspection.InputValue {
+	for i := range args {
+		if args[i].Name == name {
+			return &args[i]
+		}
+	}
+	return nil
+}
+
+// argument is an argument of a field selection.
+type argument struct {
+	name  string
+	value string // GraphQL value. E.g., "$login" or "{first: 10}".
+}
+
+// fieldArguments returns the arguments of the field selection s, in order.
+// E.g., `repository(owner: $owner, name: "graphql") @include(if: $x)` ->
+// [{owner $owner} {name "graphql"}].
+func fieldArguments(s string) []argument {
+	i := strings.IndexAny(s, "(@{")
+	if i == -1 || s[i] != '(' {
+		return nil
+	}
+	s = s[i+1:]
+	var args []argument
+	for {
+		s = strings.TrimLeft(s, " \t\r\n,")
+		end := 0
+		for end < len(s) && isNameByte(s[end]) {
+			end++
+		}
+		name := s[:end]
+		s = strings.TrimLeft(s[end:], " \t\r\n")
+		if name == "" || !strings.HasPrefix(s, ":") {
+			// The end of the arguments, or a malformed one, which Lint reports.
+			return args
+		}
+		s = strings.TrimLeft(s[1:], " \t\r\n")
+		end = valueEnd(s)
+		args = append(args, argument{name: name, value: s[:end]})
+		s = s[end:]
+	}
+}
+
+// valueEnd returns the length of the GraphQL value that s starts with.
+func valueEnd(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case ')', ',', ' ', '\t', '\r', '\n':
+			if depth <= 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// parseTypeRef parses the GraphQL type s. E.g., "[String!]!".
+func parseTypeRef(s string) (introspection.TypeRef, bool) {
+	switch {
+	case strings.HasSuffix(s, "!"):
+		of, ok := parseTypeRef(s[:len(s)-1])
+		return introspection.TypeRef{Kind: introspection.NonNull, OfType: &of}, ok && of.Kind != introspection.NonNull
+	case strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]"):
+		of, ok := parseTypeRef(s[1 : len(s)-1])
+		return introspection.TypeRef{Kind: introspection.List, OfType: &of}, ok
+	}
+	return introspection.TypeRef{Name: s}, s != "" && !strings.ContainsAny(s, "[]! ")
+}
+
+// typesCompatible reports whether a variable of type varType may be used
+// where a value of type argType is expected.
+//
+// Specification: https://spec.graphql.org/October2021/#AreTypesCompatible().
+func typesCompatible(varType, argType introspection.TypeRef) bool {
+	switch {
+	case argType.Kind == introspection.NonNull && argType.OfType != nil:
+		return varType.Kind == introspection.NonNull && typesCompatible(*varType.OfType, *argType.OfType)
+	case varType.Kind == introspection.NonNull:
+		return typesCompatible(*varType.OfType, argType)
+	case argType.Kind == introspection.List && argType.OfType != nil:
+		return varType.Kind == introspection.List && typesCompatible(*varType.OfType, *argType.OfType)
+	case varType.Kind == introspection.List:
+		return false
+	}
+	return varType.Name == argType.Name
+}
@@ -0,0 +1,139 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+	"github.com/merico-dev/graphql/introspection"
+)
+
+const validateSchema = `
+	type Query {
+		repository(owner: String!, name: String!, followRenames: Boolean = true): Repository
+		user(login: String!): User
+	}
+	type Mutation {
+		addStar(starrableId: ID!): Repository
+	}
+	type Repository {
+		issues(first: Int, states: [IssueState!]): [Issue!]!
+	}
+	type Issue {
+		number: Int!
+	}
+	type User {
+		login: String!
+	}
+	enum IssueState {
+		OPEN
+		CLOSED
+	}
+`
+
+func TestValidate(t *testing.T) {
+	schema, err := introspection.ParseSDL(validateSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var q struct {
+		Repository struct {
+			Issues []struct {
+				Number graphql.Int
+			} `graphql:"issues(first: $first, states: [OPEN])"`
+		} `graphql:"repo: repository(owner: $owner, name: \"graphql, go\")"`
+		Users []struct {
+			Login graphql.String
+		} `graphql:"user(login: $login)" graphql-extend:"true"`
+	}
+	variables := map[string]interface{}{
+		"owner": graphql.String("merico-dev"),
+		"first": graphql.NewInt(10),
+		"user": []map[string]interface{}{
+			{"login": graphql.String("gopher")},
+		},
+	}
+	if err := graphql.Validate(schema, &q, variables); err != nil {
+		t.Errorf("got error: %v", err)
+	}
+
+	var broken struct {
+		Repository struct {
+			Issues []struct {
+				Number graphql.Int
+			} `graphql:"issues(last: 10, first: $first)"`
+		} `graphql:"repository(owner: $owner)"`
+		User struct {
+			Login graphql.String
+		} `graphql:"user(login: $login)"`
+	}
+	err = graphql.Validate(schema, &broken, map[string]interface{}{
+		"owner": graphql.ID("merico-dev"),
+		"first": graphql.Int(10),
+	})
+	var compatErr *graphql.CompatibilityError
+	if !errors.As(err, &compatErr) {
+		t.Fatalf("got error: %v, want a *graphql.CompatibilityError", err)
+	}
+	want := []string{
+		`Repository: argument "owner" of field "repository" has type String!, but variable $owner has type ID!`,
+		`Repository: field "repository" needs argument "name" of type String!`,
+		`Repository.Issues: field "issues" has no argument "last"`,
+		`User: variable $login is missing from the variables`,
+	}
+	if !reflect.DeepEqual(compatErr.Problems, want) {
+		t.Errorf("got problems:\n%v\nwant:\n%v", strings.Join(compatErr.Problems, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWithSchemaValidation(t *testing.T) {
+	schema, err := introspection.ParseSDL(validateSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(body, "IntrospectionQuery") {
+			mustWrite(w, `{"data": {"__schema": `+string(b)+`}}`)
+			return
+		}
+		requests = append(requests, body)
+		mustWrite(w, `{"data": {"addStar": {"issues": []}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithSchemaValidation())
+
+	var m struct {
+		AddStar struct {
+			Issues []struct {
+				Number graphql.Int
+			}
+		} `graphql:"addStar(starrableId: $id)"`
+	}
+	_, err = client.Mutate(context.Background(), &m, map[string]interface{}{"id": graphql.String("R_1")})
+	if got, want := err.Error(), "query structs don't match the schema:\n\tAddStar: argument \"starrableId\" of field \"addStar\" has type ID!, but variable $id has type String!"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+	if len(requests) != 0 {
+		t.Errorf("got requests: %v, want none", requests)
+	}
+	_, err = client.Mutate(context.Background(), &m, map[string]interface{}{"id": graphql.ID("R_1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Errorf("got %d requests, want 1", len(requests))
+	}
+}
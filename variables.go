@@ -0,0 +1,291 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/merico-dev/graphql/ident"
+)
+
+// UnusedVariablePolicy is what a client does with variables that aren't
+// referenced by the document constructed by Client.Query, Client.Mutate
+// or Subscribe. Such variables are usually a copy-paste bug, and servers
+// such as GitHub's reject documents that declare them.
+type UnusedVariablePolicy int
+
+const (
+	// KeepUnusedVariables declares and sends unused variables. It's the default.
+	KeepUnusedVariables UnusedVariablePolicy = iota
+
+	// RejectUnusedVariables makes operations with unused variables fail
+	// with an *UnusedVariablesError before being sent.
+	RejectUnusedVariables
+
+	// StripUnusedVariables drops unused variables from the declarations
+	// and the variables sent.
+	StripUnusedVariables
+)
+
+// WithUnusedVariables sets what the client does with unused variables.
+func WithUnusedVariables(policy UnusedVariablePolicy) ClientOption {
+	return func(c *Client) {
+		c.unusedVariables = policy
+	}
+}
+
+// UnusedVariablesError is returned when variables aren't referenced
+// by the document and the client uses RejectUnusedVariables.
+type UnusedVariablesError struct {
+	Names []string // Names of the unused variables, sorted.
+}
+
+func (e *UnusedVariablesError) Error() string {
+	return fmt.Sprintf("variables not used by the document: $%s", strings.Join(e.Names, ", $"))
+}
+
+// handleUnusedVariables applies the client's policy to the variables of the
+// constructed document doc, which is an operation of type operation.
+// It returns the document and variables to send.
+func (c *Client) handleUnusedVariables(operation, doc string, variables map[string]interface{}) (string, map[string]interface{}, error) {
+	if c.unusedVariables == KeepUnusedVariables || len(variables) == 0 {
+		return doc, variables, nil
+	}
+	body := selectionSet(doc)
+	unused := unusedVariables(body, variables)
+	if len(unused) == 0 {
+		return doc, variables, nil
+	}
+	if c.unusedVariables == RejectUnusedVariables {
+		return "", nil, &UnusedVariablesError{Names: unused}
+	}
+	doc, variables = stripVariables(operation, body, variables, unused)
+	return doc, variables, nil
+}
+
+// stripVariables returns the document of type operation with the selection
+// set body, declaring variables without unused, along with those variables.
+func stripVariables(operation, body string, variables map[string]interface{}, unused []string) (string, map[string]interface{}) {
+	used := make(map[string]interface{}, len(variables)-len(unused))
+	for k, v := range variables {
+		used[k] = v
+	}
+	for _, k := range unused {
+		delete(used, k)
+	}
+	if len(used) == 0 {
+		if operation == "query" {
+			// Matches ConstructQuery, which omits the keyword without variables.
+			return body, used
+		}
+		return operation + body, used
+	}
+	return operation + "(" + queryArguments(used) + ")" + body, used
+}
+
+// selectionSet returns the selection set of the constructed document doc,
+// skipping the operation type and variable definitions.
+func selectionSet(doc string) string {
+	if i := strings.IndexByte(doc, '{'); i != -1 {
+		return doc[i:]
+	}
+	return doc
+}
+
+// unusedVariables returns the sorted names of the variables that aren't
+// referenced in the selection set body.
+func unusedVariables(body string, variables map[string]interface{}) []string {
+	referenced := referencedVariables(body)
+	var unused []string
+	for k := range variables {
+		if !referenced[k] {
+			unused = append(unused, k)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// referencedVariables returns the names of the variables referenced in s.
+// E.g., "{user(login:$login){name}}" -> {"login"}.
+func referencedVariables(s string) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' {
+			continue
+		}
+		end := i + 1
+		for end < len(s) && isNameByte(s[end]) {
+			end++
+		}
+		if end > i+1 {
+			names[s[i+1:end]] = true
+		}
+		i = end - 1
+	}
+	return names
+}
+
+func isNameByte(b byte) bool {
+	return b == '_' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9'
+}
+
+// InputObject is a variable value of an input object type whose fields are
+// only known at runtime, such as a filter expression built from user input.
+// Its fields are encoded as is, keys included, and a nil Fields as null.
+//
+// E.g., InputObject{Type: "issues_bool_exp!", Fields: map[string]interface{}{
+// "state": map[string]interface{}{"_eq": "OPEN"}}}.
+//
+// Variables holding an InputObject are declared with its Type. For lists of
+// input objects, use a named map type registered with RegisterType instead.
+type InputObject struct {
+	Type   string // GraphQL type. E.g., "IssueFilters!".
+	Fields map[string]interface{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o InputObject) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.Fields)
+}
+
+// Variable is a variable value declared with an explicit GraphQL type,
+// rather than with a type derived from the Go type of its Value. It allows
+// nullability that doesn't follow from the Go type, such as nullable
+// elements in a list of Go values, and type names that differ from the
+// names of Go types:
+//
+//	graphql.Variable{Type: "[String]!", Value: []string{"a", "b"}}
+//
+// A variable with a Default value is declared with it, e.g., "$first:Int=10",
+// and is left out of the variables sent when its Value is nil, a nil
+// pointer or an absent Optional, so that the server uses the default:
+//
+//	graphql.Variable{Type: "Int", Default: "10", Value: first}
+type Variable struct {
+	Type    string // GraphQL type. E.g., "[String]!".
+	Default string // Default value, as a GraphQL literal, if non-empty. E.g., "10" or "[OPEN]".
+	Value   interface{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v Variable) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.Value)
+}
+
+// StructVariables returns the variables held by the fields of the struct v,
+// or of the struct v points to, such as a generated variables struct.
+// Variables are named after the fields, in lowerCamelCase, unless a gqlvar
+// tag names them; e.g., a field tagged `gqlvar:"pullRequestId"` holds the
+// variable $pullRequestId. Fields tagged `gqlvar:"-"` and unexported fields
+// are skipped, and the fields of embedded structs without a gqlvar tag are
+// promoted, as with encoding/json.
+//
+// Variables are declared with GraphQL types derived from the Go types of
+// the fields, unless a gqlvar tag gives one after a comma, which makes the
+// field hold a Variable of that type; e.g., a field tagged
+// `gqlvar:"labels,[String!]"` holds the variable $labels of type
+// [String!], and one tagged `gqlvar:",URI!"` a variable of type URI!
+// named after the field. A default value may follow the type, after an
+// equals sign; e.g., `gqlvar:"first,Int=10"`. See Variable.
+func StructVariables(v interface{}) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("variables must be a struct, not %T", v)
+	}
+	variables := make(map[string]interface{})
+	addStructVariables(variables, rv)
+	return variables, nil
+}
+
+func addStructVariables(variables map[string]interface{}, v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		tag, tagged := f.Tag.Lookup("gqlvar")
+		name, typ, _ := strings.Cut(tag, ",")
+		if f.Anonymous && !tagged {
+			fv := v.Field(i)
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				addStructVariables(variables, fv)
+				continue
+			}
+		}
+		if f.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = ident.ParseMixedCaps(f.Name).ToLowerCamelCase()
+		}
+		value := v.Field(i).Interface()
+		if typ = strings.TrimSpace(typ); typ != "" {
+			typ, def, _ := strings.Cut(typ, "=")
+			value = Variable{Type: strings.TrimSpace(typ), Default: strings.TrimSpace(def), Value: value}
+		}
+		variables[name] = value
+	}
+}
+
+// VariableTransformer rewrites the variables of operations before they're
+// sent, such as to inject a tenant ID into every operation, or to format
+// time.Time values the way the server's DateTime scalar expects.
+//
+// TransformVariables is called with the document query and its variables
+// as constructed, including the variables of the elements of graphql-extend
+// fields under the names they're sent with, and returns the variables to
+// send, which are encoded as usual. It may modify variables in place, since
+// it's called with a copy.
+type VariableTransformer interface {
+	TransformVariables(ctx context.Context, query string, variables map[string]interface{}) (map[string]interface{}, error)
+}
+
+// VariableTransformerFunc is an adapter to allow the use of ordinary
+// functions as VariableTransformers.
+type VariableTransformerFunc func(ctx context.Context, query string, variables map[string]interface{}) (map[string]interface{}, error)
+
+// TransformVariables calls f(ctx, query, variables).
+func (f VariableTransformerFunc) TransformVariables(ctx context.Context, query string, variables map[string]interface{}) (map[string]interface{}, error) {
+	return f(ctx, query, variables)
+}
+
+// WithVariableTransformer makes the client pass the variables of every
+// operation it sends, including batched queries and subscriptions, through
+// the transformers ts, in order. Unlike middleware, transformers see the
+// variables before values such as those of GraphQLMarshaler and registered
+// scalar types are marshaled.
+func WithVariableTransformer(ts ...VariableTransformer) ClientOption {
+	return func(c *Client) {
+		c.transformers = append(c.transformers, ts...)
+	}
+}
+
+// transformVariables returns the variables of the document query
+// as transformed by the client's variable transformers.
+func (c *Client) transformVariables(ctx context.Context, query string, variables map[string]interface{}) (map[string]interface{}, error) {
+	if len(c.transformers) == 0 {
+		return variables, nil
+	}
+	transformed := make(map[string]interface{}, len(variables))
+	for k, v := range variables {
+		transformed[k] = v
+	}
+	for _, t := range c.transformers {
+		var err error
+		transformed, err = t.TransformVariables(ctx, query, transformed)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return transformed, nil
+}
@@ -0,0 +1,235 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestWithUnusedVariables(t *testing.T) {
+	var q struct {
+		User struct {
+			Name graphql.String
+		} `graphql:"user(login:$login)"`
+	}
+	variables := map[string]interface{}{
+		"login": graphql.String("gopher"),
+		"first": graphql.Int(10),
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: http.NotFoundHandler()}},
+			graphql.WithUnusedVariables(graphql.RejectUnusedVariables))
+		_, err := client.Query(context.Background(), &q, variables)
+		var unused *graphql.UnusedVariablesError
+		if !errors.As(err, &unused) {
+			t.Fatalf("got error: %v, want *UnusedVariablesError", err)
+		}
+		if got, want := err.Error(), "variables not used by the document: $first"; got != want {
+			t.Errorf("got error: %q, want: %q", got, want)
+		}
+	})
+
+	t.Run("strip", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+			body := mustRead(req.Body)
+			if got, want := body, `{"query":"query($login:String!){user(login:$login){name}}","variables":{"login":"gopher"}}`+"\n"; got != want {
+				t.Errorf("got body: %v, want %v", got, want)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+		})
+		client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+			graphql.WithUnusedVariables(graphql.StripUnusedVariables))
+		_, err := client.Query(context.Background(), &q, variables)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := q.User.Name, graphql.String("Gopher"); got != want {
+			t.Errorf("got name: %q, want: %q", got, want)
+		}
+		if len(variables) != 2 {
+			t.Error("caller's variables were modified")
+		}
+	})
+
+	t.Run("strip all", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+			body := mustRead(req.Body)
+			if got, want := body, `{"query":"mutation{reset}"}`+"\n"; got != want {
+				t.Errorf("got body: %v, want %v", got, want)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"data": {"reset": true}}`)
+		})
+		client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+			graphql.WithUnusedVariables(graphql.StripUnusedVariables))
+		var m struct {
+			Reset graphql.Boolean
+		}
+		_, err := client.Mutate(context.Background(), &m, map[string]interface{}{"id": graphql.ID("1")})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestInputObject(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query($where:issues_bool_exp!){issues(where:$where){id}}","variables":{"where":{"_or":[{"state":{"_eq":"OPEN"}}]}}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"issues": [{"id": "1"}]}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Issues []struct {
+			ID graphql.ID
+		} `graphql:"issues(where:$where)"`
+	}
+	where := graphql.InputObject{
+		Type: "issues_bool_exp!",
+		Fields: map[string]interface{}{
+			"_or": []interface{}{
+				map[string]interface{}{"state": map[string]interface{}{"_eq": "OPEN"}},
+			},
+		},
+	}
+	_, err := client.Query(context.Background(), &q, map[string]interface{}{"where": where})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Issues) != 1 {
+		t.Errorf("got issues: %v, want one", q.Issues)
+	}
+}
+
+func TestVariable_default(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query($first:Int=10$states:[IssueState!]=[OPEN]){issues(first: $first, states: $states){id}}","variables":{"states":["CLOSED"]}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"issues": [{"id": "1"}]}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Issues []struct {
+			ID graphql.ID
+		} `graphql:"issues(first: $first, states: $states)"`
+	}
+	_, err := client.Query(context.Background(), &q, map[string]interface{}{
+		"first":  graphql.Variable{Type: "Int", Default: "10", Value: (*graphql.Int)(nil)},
+		"states": graphql.Variable{Type: "[IssueState!]", Default: "[OPEN]", Value: []string{"CLOSED"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStructVariables(t *testing.T) {
+	type Page struct {
+		First graphql.Int
+		After *graphql.String
+	}
+	type variables struct {
+		PullRequestID graphql.ID `gqlvar:"pullRequestId"`
+		Body          graphql.String
+		Page
+		Labels   []string `gqlvar:"labelNames,[String!]"`
+		Homepage string   `gqlvar:",URI!"`
+		Last     *int     `gqlvar:"last,Int = 20"`
+		Internal string   `gqlvar:"-"`
+		secret   string
+	}
+	got, err := graphql.StructVariables(&variables{PullRequestID: "PR_1", Body: "LGTM", Page: Page{First: 10}, Labels: []string{"bug"}, Homepage: "https://example.com", secret: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"pullRequestId": graphql.ID("PR_1"),
+		"body":          graphql.String("LGTM"),
+		"first":         graphql.Int(10),
+		"after":         (*graphql.String)(nil),
+		"labelNames":    graphql.Variable{Type: "[String!]", Value: []string{"bug"}},
+		"homepage":      graphql.Variable{Type: "URI!", Value: "https://example.com"},
+		"last":          graphql.Variable{Type: "Int", Default: "20", Value: (*int)(nil)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got variables: %#v, want: %#v", got, want)
+	}
+	if _, err := graphql.StructVariables(map[string]interface{}{}); err == nil {
+		t.Error("got no error for a map")
+	}
+}
+
+func TestWithVariableTransformer(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query($tenant:ID!$user__0__login:String!$user__1__login:String!){viewer(tenant: $tenant){login},user__0:user(login: $user__0__login){login},user__1:user(login: $user__1__login){login}}","variables":{"tenant":"acme","user__0__login":"A","user__1__login":"B"}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "acme"}, "user__0": {"login": "a"}, "user__1": {"login": "b"}}}`)
+	})
+	upper := graphql.VariableTransformerFunc(func(ctx context.Context, query string, variables map[string]interface{}) (map[string]interface{}, error) {
+		for k, v := range variables {
+			if s, ok := v.(graphql.String); ok {
+				variables[k] = graphql.String(strings.ToUpper(string(s)))
+			}
+		}
+		return variables, nil
+	})
+	tenant := graphql.VariableTransformerFunc(func(ctx context.Context, query string, variables map[string]interface{}) (map[string]interface{}, error) {
+		variables["tenant"] = graphql.ID("acme")
+		return variables, nil
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithVariableTransformer(upper, tenant))
+
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		} `graphql:"viewer(tenant: $tenant)"`
+		Users []struct {
+			Login graphql.String
+		} `graphql:"user(login: $login)" graphql-extend:"true"`
+	}
+	variables := map[string]interface{}{
+		"tenant": graphql.ID(""),
+		"user": []map[string]interface{}{
+			{"login": graphql.String("a")},
+			{"login": graphql.String("b")},
+		},
+	}
+	_, err := client.Query(context.Background(), &q, variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := variables["tenant"]; got != graphql.ID("") {
+		t.Errorf("got the caller's tenant variable modified: %v", got)
+	}
+
+	failing := graphql.VariableTransformerFunc(func(ctx context.Context, query string, variables map[string]interface{}) (map[string]interface{}, error) {
+		return nil, errors.New("no tenant")
+	})
+	client = graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithVariableTransformer(failing))
+	_, err = client.Query(context.Background(), &q, variables)
+	if err == nil || err.Error() != "no tenant" {
+		t.Errorf("got error: %v, want: no tenant", err)
+	}
+}
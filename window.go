@@ -0,0 +1,166 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// CheckpointStore stores the progress of CollectWindows.
+// Implementations must persist it if collection is to resume after restarts.
+type CheckpointStore interface {
+	// Load returns the end of the last collected window.
+	// It reports false if no window was collected yet.
+	Load() (time.Time, bool, error)
+	// Save stores t as the end of the last collected window.
+	Save(t time.Time) error
+}
+
+// CollectWindows collects data incrementally, in consecutive time windows
+// of size, by calling collect with variables and the bounds of each window.
+// The first window starts at the checkpoint in store, or at start if there's
+// none, and the last one ends at the time CollectWindows is called.
+// The end of each window is saved in store once collect returns nil for it,
+// so that a later call resumes after the last window collected.
+//
+// The bounds are added to variables as "since" and "until" variables of
+// type DateTime!, in RFC 3339 format, to be bound to arguments such as
+// updatedAfter and updatedBefore; WithWindowVariables changes that.
+// collect typically calls Query or QueryPaginated with the variables.
+// CollectWindows stops at the first error collect returns, and returns it.
+// It returns an error if size isn't positive.
+func CollectWindows(ctx context.Context, store CheckpointStore, start time.Time, size time.Duration, variables map[string]interface{}, collect func(ctx context.Context, variables map[string]interface{}) error, opts ...WindowOption) error {
+	if size <= 0 {
+		return fmt.Errorf("window size must be positive, not %v", size)
+	}
+	cfg := windowConfig{
+		since:        "since",
+		until:        "until",
+		variableType: "DateTime!",
+		end:          time.Now(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	from, ok, err := store.Load()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		from = start
+	}
+	for from.Before(cfg.end) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		to := from.Add(size)
+		if to.After(cfg.end) {
+			to = cfg.end
+		}
+		vars := make(map[string]interface{}, len(variables)+2)
+		for k, v := range variables {
+			vars[k] = v
+		}
+		vars[cfg.since] = Variable{Type: cfg.variableType, Value: from.UTC().Format(time.RFC3339)}
+		if cfg.until != "" {
+			vars[cfg.until] = Variable{Type: cfg.variableType, Value: to.UTC().Format(time.RFC3339)}
+		}
+		err := collect(ctx, vars)
+		if err != nil {
+			return err
+		}
+		err = store.Save(to)
+		if err != nil {
+			return err
+		}
+		from = to
+	}
+	return nil
+}
+
+// WindowOption configures CollectWindows.
+type WindowOption func(*windowConfig)
+
+type windowConfig struct {
+	since, until string    // Names of the variables holding the bounds of windows.
+	variableType string    // GraphQL type of the variables.
+	end          time.Time // End of the last window.
+}
+
+// WithWindowVariables makes CollectWindows pass the bounds of windows in
+// the variables since and until, of the GraphQL type variableType, such as
+// "Time!". If until is empty, then only the start of windows is passed,
+// for APIs that only filter on a lower bound.
+func WithWindowVariables(since, until, variableType string) WindowOption {
+	return func(cfg *windowConfig) {
+		cfg.since = since
+		cfg.until = until
+		cfg.variableType = variableType
+	}
+}
+
+// WithWindowEnd makes CollectWindows collect windows up to end, rather
+// than up to the time it's called. E.g., an end a few minutes in the past
+// leaves time for recent changes to become visible to queries.
+func WithWindowEnd(end time.Time) WindowOption {
+	return func(cfg *windowConfig) { cfg.end = end }
+}
+
+// NewMemoryCheckpointStore returns a CheckpointStore that keeps the
+// checkpoint in memory. The checkpoint is lost when the process exits.
+func NewMemoryCheckpointStore() CheckpointStore {
+	return new(memoryCheckpointStore)
+}
+
+type memoryCheckpointStore struct {
+	mu         sync.Mutex
+	checkpoint time.Time
+	ok         bool
+}
+
+func (s *memoryCheckpointStore) Load() (time.Time, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.checkpoint, s.ok, nil
+}
+
+func (s *memoryCheckpointStore) Save(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoint, s.ok = t, true
+	return nil
+}
+
+// NewFileCheckpointStore returns a CheckpointStore that persists the
+// checkpoint in the JSON file at path, which is created if it doesn't exist.
+// The file is replaced atomically on every change.
+func NewFileCheckpointStore(path string) CheckpointStore {
+	return fileCheckpointStore{path: path}
+}
+
+type fileCheckpointStore struct {
+	path string
+}
+
+func (s fileCheckpointStore) Load() (time.Time, bool, error) {
+	b, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return time.Time{}, false, nil
+	} else if err != nil {
+		return time.Time{}, false, err
+	}
+	var t time.Time
+	err = json.Unmarshal(b, &t)
+	return t, err == nil, err
+}
+
+func (s fileCheckpointStore) Save(t time.Time) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, b)
+}
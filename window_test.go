@@ -0,0 +1,93 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/merico-dev/graphql"
+)
+
+func TestCollectWindows(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		store graphql.CheckpointStore
+	}{
+		{"memory", graphql.NewMemoryCheckpointStore()},
+		{"file", graphql.NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			end := start.Add(50 * time.Hour)
+			errFailed := errors.New("failed")
+			var windows []string
+			collect := func(ctx context.Context, variables map[string]interface{}) error {
+				b, err := json.Marshal(variables)
+				if err != nil {
+					return err
+				}
+				windows = append(windows, string(b))
+				if variables["since"].(graphql.Variable).Value == "2024-01-02T00:00:00Z" && len(windows) == 2 {
+					return errFailed
+				}
+				return nil
+			}
+			variables := map[string]interface{}{"project": graphql.String("gopher")}
+
+			err := graphql.CollectWindows(context.Background(), tc.store, start, 24*time.Hour, variables, collect, graphql.WithWindowEnd(end))
+			if err != errFailed {
+				t.Fatalf("got error: %v, want: %v", err, errFailed)
+			}
+			// Resume from the window that failed.
+			err = graphql.CollectWindows(context.Background(), tc.store, start, 24*time.Hour, variables, collect, graphql.WithWindowEnd(end))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{
+				`{"project":"gopher","since":"2024-01-01T00:00:00Z","until":"2024-01-02T00:00:00Z"}`,
+				`{"project":"gopher","since":"2024-01-02T00:00:00Z","until":"2024-01-03T00:00:00Z"}`,
+				`{"project":"gopher","since":"2024-01-02T00:00:00Z","until":"2024-01-03T00:00:00Z"}`,
+				`{"project":"gopher","since":"2024-01-03T00:00:00Z","until":"2024-01-03T02:00:00Z"}`,
+			}
+			if !reflect.DeepEqual(windows, want) {
+				t.Errorf("got windows:\n%v\nwant:\n%v", windows, want)
+			}
+			checkpoint, ok, err := tc.store.Load()
+			if err != nil || !ok || !checkpoint.Equal(end) {
+				t.Errorf("got checkpoint: %v, %v, %v, want: %v", checkpoint, ok, err, end)
+			}
+		})
+	}
+}
+
+func TestCollectWindows_variables(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var got []string
+	err := graphql.CollectWindows(context.Background(), graphql.NewMemoryCheckpointStore(), start, time.Hour, nil, func(ctx context.Context, variables map[string]interface{}) error {
+		for name, v := range variables {
+			got = append(got, name+": "+v.(graphql.Variable).Type)
+		}
+		return nil
+	}, graphql.WithWindowVariables("updatedAfter", "", "Time!"), graphql.WithWindowEnd(start.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"updatedAfter: Time!"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got variables: %v, want: %v", got, want)
+	}
+}
+
+func TestCollectWindows_size(t *testing.T) {
+	collect := func(ctx context.Context, variables map[string]interface{}) error {
+		t.Error("collect called with a window size of 0")
+		return nil
+	}
+	err := graphql.CollectWindows(context.Background(), graphql.NewMemoryCheckpointStore(), time.Now().Add(-time.Hour), 0, nil, collect)
+	if want := "window size must be positive, not 0s"; err == nil || err.Error() != want {
+		t.Errorf("got error: %v, want: %v", err, want)
+	}
+}